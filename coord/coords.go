@@ -2,8 +2,11 @@ package coord
 
 import "math"
 
+// Coordinate is a location with an optional z-coordinate (elevation) and
+// m-coordinate (measure). Which of Z and M are meaningful is determined by
+// the layout of the geometry containing the coordinate.
 type Coordinate struct {
-	X, Y, Z float64
+	X, Y, Z, M float64
 }
 type Coordinates []Coordinate
 type MultiLine []Coordinates
@@ -13,7 +16,18 @@ func (c Coordinate) Equals(other Coordinate) bool {
 }
 
 func (c Coordinate) Equals2D(other Coordinate) bool {
-	return c.X != other.X && c.Y != other.Y
+	return c.X == other.X && c.Y == other.Y
+}
+
+// XY is the position of a coordinate in the plane. It is used as a map key
+// to match coordinates while ignoring Z and M.
+type XY struct {
+	X, Y float64
+}
+
+// XY returns the position of the coordinate in the plane.
+func (c Coordinate) XY() XY {
+	return XY{c.X, c.Y}
 }
 
 func (c Coordinate) Envelope() *Envelope {
@@ -56,3 +70,27 @@ func (cs Coordinates) Envelope() *Envelope {
 	e.ExpandCoords(cs)
 	return e
 }
+
+// RemoveRepeated returns a copy of the coordinates with consecutive
+// duplicate points (compared in 2D) removed.
+func (cs Coordinates) RemoveRepeated() Coordinates {
+	if len(cs) == 0 {
+		return cs
+	}
+	out := Coordinates{cs[0]}
+	for _, c := range cs[1:] {
+		if !c.Equals2D(out[len(out)-1]) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Reverse returns a copy of the coordinates in reverse order.
+func (cs Coordinates) Reverse() Coordinates {
+	out := make(Coordinates, len(cs))
+	for i, c := range cs {
+		out[len(cs)-1-i] = c
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package coord
+
+import "testing"
+
+func TestEquals2D(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Coordinate
+		want bool
+	}{
+		{"equal", Coordinate{X: 1, Y: 2}, Coordinate{X: 1, Y: 2}, true},
+		{"different z", Coordinate{X: 1, Y: 2, Z: 3}, Coordinate{X: 1, Y: 2, Z: 4}, true},
+		{"different x", Coordinate{X: 1, Y: 2}, Coordinate{X: 3, Y: 2}, false},
+		{"different y", Coordinate{X: 1, Y: 2}, Coordinate{X: 1, Y: 3}, false},
+		{"different x and y", Coordinate{X: 1, Y: 2}, Coordinate{X: 3, Y: 4}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.a.Equals2D(test.b); got != test.want {
+				t.Errorf("Equals2D() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestXY(t *testing.T) {
+	a := Coordinate{X: 1, Y: 2, Z: 3, M: 4}
+	b := Coordinate{X: 1, Y: 2, Z: 5}
+	if a.XY() != b.XY() {
+		t.Errorf("XY() of %v and %v differ", a, b)
+	}
+	if got, want := a.XY(), (XY{X: 1, Y: 2}); got != want {
+		t.Errorf("XY() = %v, want %v", got, want)
+	}
+}
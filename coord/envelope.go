@@ -28,7 +28,7 @@ func NewEnvelope(minX, maxX, minY, maxY float64) *Envelope {
 }
 
 func NewEnvelopeFromCoords(p1, p2 Coordinate) *Envelope {
-	return NewEnvelope(p1.X, p1.Y, p2.X, p2.Y)
+	return NewEnvelope(p1.X, p2.X, p1.Y, p2.Y)
 }
 
 func (e *Envelope) Expand(c Coordinate) {
@@ -77,6 +77,28 @@ func (e *Envelope) Intersects(other *Envelope) bool {
 		other.MaxY < e.MinY)
 }
 
+// Intersection returns the envelope shared by two envelopes, or nil if they
+// don't intersect.
+func (e *Envelope) Intersection(other *Envelope) *Envelope {
+	if !e.Intersects(other) {
+		return nil
+	}
+	return &Envelope{
+		MinX: math.Max(e.MinX, other.MinX),
+		MaxX: math.Min(e.MaxX, other.MaxX),
+		MinY: math.Max(e.MinY, other.MinY),
+		MaxY: math.Min(e.MaxY, other.MaxY),
+	}
+}
+
+// Distance returns the distance between two envelopes, which is zero if
+// they intersect.
+func (e *Envelope) Distance(other *Envelope) float64 {
+	dx := math.Max(0, math.Max(other.MinX-e.MaxX, e.MinX-other.MaxX))
+	dy := math.Max(0, math.Max(other.MinY-e.MaxY, e.MinY-other.MaxY))
+	return math.Hypot(dx, dy)
+}
+
 func (e *Envelope) IntersectsPoint(point Coordinate) bool {
 	return e.IntersectsXY(point.X, point.Y)
 }
@@ -0,0 +1,23 @@
+package coord
+
+import "testing"
+
+func TestNewEnvelopeFromCoords(t *testing.T) {
+	tests := []struct {
+		name   string
+		p1, p2 Coordinate
+		want   Envelope
+	}{
+		{"ordered", Coordinate{X: 1, Y: 2}, Coordinate{X: 5, Y: 8}, Envelope{MinX: 1, MaxX: 5, MinY: 2, MaxY: 8}},
+		{"reversed", Coordinate{X: 5, Y: 8}, Coordinate{X: 1, Y: 2}, Envelope{MinX: 1, MaxX: 5, MinY: 2, MaxY: 8}},
+		{"crossed", Coordinate{X: 1, Y: 8}, Coordinate{X: 5, Y: 2}, Envelope{MinX: 1, MaxX: 5, MinY: 2, MaxY: 8}},
+		{"y below x", Coordinate{X: 10, Y: -3}, Coordinate{X: 20, Y: -1}, Envelope{MinX: 10, MaxX: 20, MinY: -3, MaxY: -1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := NewEnvelopeFromCoords(test.p1, test.p2); *got != test.want {
+				t.Errorf("NewEnvelopeFromCoords() = %v, want %v", *got, test.want)
+			}
+		})
+	}
+}
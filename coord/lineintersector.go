@@ -102,11 +102,10 @@ func (rli *RobustLineIntersector) computeEdgeDistance(p, p0, p1 Coordinate) floa
 }
 
 func (rli *RobustLineIntersector) IntersectsPoint(point Coordinate) bool {
-	for _, intPt := range rli.intPts {
+	for _, intPt := range rli.intPts[:rli.NumIntersections()] {
 		if intPt.Equals2D(point) {
 			return true
 		}
-
 	}
 	return false
 }
@@ -1,5 +1,7 @@
 package coord
 
+import "math"
+
 type Location int
 
 const (
@@ -23,8 +25,84 @@ func PointOnLine(point Coordinate, line []Coordinate) bool {
 	return false
 }
 
+// PointInRing determines the location of a point relative to a closed ring,
+// by counting the crossings of a ray extending to the right of the point.
 func PointInRing(point Coordinate, ring []Coordinate) Location {
-	// TODO:
-	//    return RayCrossingCounter.locatePointInRing(p, ring);
+	counter := NewRayCrossingCounter(point)
+	for i := 1; i < len(ring); i++ {
+		counter.CountSegment(ring[i-1], ring[i])
+		if counter.IsOnSegment() {
+			return LocationBoundary
+		}
+	}
+	return counter.Location()
+}
 
+// RayCrossingCounter counts the crossings of segments with a ray extending
+// to the right of a point, to locate the point in the area bounded by the
+// segments. Segments may be counted in any order and may come from several
+// rings, such as the shell and holes of a polygon.
+type RayCrossingCounter struct {
+	point     Coordinate
+	crossings int
+	onSegment bool
+}
+
+func NewRayCrossingCounter(point Coordinate) *RayCrossingCounter {
+	return &RayCrossingCounter{point: point}
+}
+
+// CountSegment counts a segment of a ring.
+func (rc *RayCrossingCounter) CountSegment(p1, p2 Coordinate) {
+	point := rc.point
+
+	// segment is entirely to the left of the point
+	if p1.X < point.X && p2.X < point.X {
+		return
+	}
+	if point.X == p2.X && point.Y == p2.Y {
+		rc.onSegment = true
+		return
+	}
+
+	// horizontal segment: the point is either on it or it is ignored
+	if p1.Y == point.Y && p2.Y == point.Y {
+		if point.X >= math.Min(p1.X, p2.X) && point.X <= math.Max(p1.X, p2.X) {
+			rc.onSegment = true
+		}
+		return
+	}
+
+	// only count segments which straddle the ray, including the upper
+	// endpoint but not the lower one
+	if (p1.Y > point.Y && p2.Y <= point.Y) || (p2.Y > point.Y && p1.Y <= point.Y) {
+		orient := OrientationIndex(p1, p2, point)
+		if orient == 0 {
+			rc.onSegment = true
+			return
+		}
+		if p2.Y < p1.Y {
+			orient = -orient
+		}
+		if orient > 0 {
+			rc.crossings++
+		}
+	}
+}
+
+// IsOnSegment returns whether the point lies on a segment counted so far,
+// in which case no more segments need be counted.
+func (rc *RayCrossingCounter) IsOnSegment() bool {
+	return rc.onSegment
+}
+
+// Location returns the location of the point given the segments counted.
+func (rc *RayCrossingCounter) Location() Location {
+	if rc.onSegment {
+		return LocationBoundary
+	}
+	if rc.crossings%2 == 1 {
+		return LocationInterior
+	}
+	return LocationExterior
 }
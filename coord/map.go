@@ -27,7 +27,7 @@ func (cm *CoordinatesMap) Add(coords Coordinates, value interface{}) {
 	}
 
 	for _, val := range vals {
-		if coords.Equal(val.Coordinates) {
+		if coords.Equals(val.Coordinates) {
 			val.Value = value
 			return
 		}
@@ -44,7 +44,7 @@ func (cm *CoordinatesMap) Get(coords Coordinates) (interface{}, bool) {
 	}
 
 	for _, val := range vals {
-		if coords.Equal(val.Coordinates) {
+		if coords.Equals(val.Coordinates) {
 			return val.Value, true
 		}
 	}
@@ -137,3 +137,21 @@ func signum(x float64) int {
 	}
 	return 0
 }
+
+// SignedArea computes the signed area of a closed ring using the shoelace
+// formula. The area is positive if the ring is oriented counter clockwise.
+func SignedArea(ring Coordinates) float64 {
+	if len(ring) < 3 {
+		return 0
+	}
+	// shift by the first point to reduce round-off
+	x0, y0 := ring[0].X, ring[0].Y
+	sum := 0.0
+	for i := 1; i < len(ring)-1; i++ {
+		x := ring[i].X - x0
+		y1 := ring[i+1].Y - y0
+		y2 := ring[i-1].Y - y0
+		sum += x * (y1 - y2)
+	}
+	return sum / 2
+}
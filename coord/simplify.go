@@ -0,0 +1,46 @@
+package coord
+
+// Simplify returns a simplified copy of the line using the Douglas-Peucker
+// algorithm: vertices closer than tolerance to the simplified line are
+// removed. The first and last points are always kept, so closed rings stay
+// closed (but may collapse).
+func (cs Coordinates) Simplify(tolerance float64) Coordinates {
+	if len(cs) < 3 || tolerance <= 0 {
+		return cs
+	}
+
+	keep := make([]bool, len(cs))
+	keep[0] = true
+	keep[len(cs)-1] = true
+	simplifySection(cs, 0, len(cs)-1, tolerance, keep)
+
+	var out Coordinates
+	for i, c := range cs {
+		if keep[i] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func simplifySection(cs Coordinates, i, j int, tolerance float64, keep []bool) {
+	if j-i < 2 {
+		return
+	}
+
+	maxDist := -1.0
+	maxIndex := i
+	for k := i + 1; k < j; k++ {
+		if dist := DistancePointToSegment(cs[k], cs[i], cs[j]); dist > maxDist {
+			maxDist = dist
+			maxIndex = k
+		}
+	}
+
+	if maxDist <= tolerance {
+		return
+	}
+	keep[maxIndex] = true
+	simplifySection(cs, i, maxIndex, tolerance, keep)
+	simplifySection(cs, maxIndex, j, tolerance, keep)
+}
@@ -0,0 +1,366 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// This file implements the small part of the FlatBuffers binary format
+// needed for the FlatGeobuf schemas. Unlike the reference builder, objects
+// are written front to back: each table is followed by the objects it
+// references, so that all offsets point forwards as the format requires.
+
+var le = binary.LittleEndian
+
+// object writes itself to a builder, returning its position.
+type object func(b *builder) int
+
+// field is a table field: a scalar stored inline or a reference to an
+// object.
+type field struct {
+	size int
+	bits uint64
+	ref  object
+}
+
+func uint8Field(v uint8) *field   { return &field{size: 1, bits: uint64(v)} }
+func uint16Field(v uint16) *field { return &field{size: 2, bits: uint64(v)} }
+func int32Field(v int32) *field   { return &field{size: 4, bits: uint64(uint32(v))} }
+func uint64Field(v uint64) *field { return &field{size: 8, bits: v} }
+func refField(o object) *field    { return &field{size: 4, ref: o} }
+func boolField(v bool) *field {
+	if v {
+		return uint8Field(1)
+	}
+	return uint8Field(0)
+}
+
+// builder builds a size prefixed FlatBuffer. Positions are relative to the
+// start of the size prefix, which is assumed to be 8 byte aligned.
+type builder struct {
+	buf []byte
+}
+
+// finish returns the size prefixed buffer with the given root table.
+func finish(root object) []byte {
+	b := &builder{buf: make([]byte, 8)}
+	pos := root(b)
+	le.PutUint32(b.buf, uint32(len(b.buf)-4))
+	le.PutUint32(b.buf[4:], uint32(pos-4))
+	return b.buf
+}
+
+func (b *builder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *builder) grow(n int) int {
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, n)...)
+	return pos
+}
+
+// table writes a table with the fields in schema order; nil fields are
+// absent.
+func (b *builder) table(fields ...*field) int {
+	for len(fields) > 0 && fields[len(fields)-1] == nil {
+		fields = fields[:len(fields)-1]
+	}
+
+	// lay out the fields after the vtable offset, each aligned to its size
+	offsets := make([]int, len(fields))
+	size := 4
+	for i, f := range fields {
+		if f == nil {
+			continue
+		}
+		for size%f.size != 0 {
+			size++
+		}
+		offsets[i] = size
+		size += f.size
+	}
+
+	b.pad(2)
+	vtable := b.grow(4 + 2*len(fields))
+	le.PutUint16(b.buf[vtable:], uint16(4+2*len(fields)))
+	le.PutUint16(b.buf[vtable+2:], uint16(size))
+	for i, off := range offsets {
+		le.PutUint16(b.buf[vtable+4+2*i:], uint16(off))
+	}
+
+	b.pad(8)
+	table := b.grow(size)
+	le.PutUint32(b.buf[table:], uint32(table-vtable))
+	for i, f := range fields {
+		if f == nil || f.ref != nil {
+			continue
+		}
+		pos := table + offsets[i]
+		switch f.size {
+		case 1:
+			b.buf[pos] = byte(f.bits)
+		case 2:
+			le.PutUint16(b.buf[pos:], uint16(f.bits))
+		case 4:
+			le.PutUint32(b.buf[pos:], uint32(f.bits))
+		case 8:
+			le.PutUint64(b.buf[pos:], f.bits)
+		}
+	}
+	for i, f := range fields {
+		if f != nil && f.ref != nil {
+			pos := table + offsets[i]
+			// write the object first, as it may reallocate the buffer
+			ref := f.ref(b)
+			le.PutUint32(b.buf[pos:], uint32(ref-pos))
+		}
+	}
+	return table
+}
+
+// vector writes the length of a vector followed by space for its elements,
+// aligned to the element size, returning the position of the length.
+func (b *builder) vector(n, elemSize int) int {
+	align := elemSize
+	if align < 4 {
+		align = 4
+	}
+	for (len(b.buf)+4)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+	pos := b.grow(4 + n*elemSize)
+	le.PutUint32(b.buf[pos:], uint32(n))
+	return pos
+}
+
+func float64s(vs []float64) object {
+	return func(b *builder) int {
+		pos := b.vector(len(vs), 8)
+		for i, v := range vs {
+			le.PutUint64(b.buf[pos+4+8*i:], math.Float64bits(v))
+		}
+		return pos
+	}
+}
+
+func uint32s(vs []uint32) object {
+	return func(b *builder) int {
+		pos := b.vector(len(vs), 4)
+		for i, v := range vs {
+			le.PutUint32(b.buf[pos+4+4*i:], v)
+		}
+		return pos
+	}
+}
+
+func bytesVector(bs []byte) object {
+	return func(b *builder) int {
+		pos := b.vector(len(bs), 1)
+		copy(b.buf[pos+4:], bs)
+		return pos
+	}
+}
+
+func stringObject(s string) object {
+	return func(b *builder) int {
+		pos := b.vector(len(s), 1)
+		copy(b.buf[pos+4:], s)
+		// strings are null terminated
+		b.buf = append(b.buf, 0)
+		return pos
+	}
+}
+
+func tables(objects []object) object {
+	return func(b *builder) int {
+		pos := b.vector(len(objects), 4)
+		for i, o := range objects {
+			elem := pos + 4 + 4*i
+			ref := o(b)
+			le.PutUint32(b.buf[elem:], uint32(ref-elem))
+		}
+		return pos
+	}
+}
+
+// stringField returns a reference to a string, or nil for an empty one.
+func stringField(s string) *field {
+	if s == "" {
+		return nil
+	}
+	return refField(stringObject(s))
+}
+
+// errCorrupt is panicked by table accessors reading outside the buffer,
+// and recovered by the decoding functions.
+var errCorrupt = errors.New("corrupt FlatBuffer")
+
+func recoverCorrupt(err *error) {
+	if r := recover(); r != nil {
+		if r != errCorrupt {
+			panic(r)
+		}
+		*err = errors.WithStack(errCorrupt)
+	}
+}
+
+// table reads the fields of a FlatBuffers table.
+type table struct {
+	buf []byte
+	pos int
+}
+
+// rootTable returns the root table of a buffer without size prefix.
+func rootTable(buf []byte) table {
+	t := table{buf: buf}
+	t.pos = int(t.u32(0))
+	return t
+}
+
+func (t table) check(pos, n int) {
+	if pos < 0 || n < 0 || pos > len(t.buf)-n {
+		panic(errCorrupt)
+	}
+}
+
+func (t table) u16(pos int) uint16 {
+	t.check(pos, 2)
+	return le.Uint16(t.buf[pos:])
+}
+
+func (t table) u32(pos int) uint32 {
+	t.check(pos, 4)
+	return le.Uint32(t.buf[pos:])
+}
+
+// field returns the position of a field, or 0 if it is absent.
+func (t table) field(id int) int {
+	vtable := t.pos - int(int32(t.u32(t.pos)))
+	if 4+2*id >= int(t.u16(vtable)) {
+		return 0
+	}
+	off := int(t.u16(vtable + 4 + 2*id))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t table) uint8(id int, def uint8) uint8 {
+	pos := t.field(id)
+	if pos == 0 {
+		return def
+	}
+	t.check(pos, 1)
+	return t.buf[pos]
+}
+
+func (t table) bool(id int, def bool) bool {
+	d := uint8(0)
+	if def {
+		d = 1
+	}
+	return t.uint8(id, d) != 0
+}
+
+func (t table) uint16(id int, def uint16) uint16 {
+	pos := t.field(id)
+	if pos == 0 {
+		return def
+	}
+	return t.u16(pos)
+}
+
+func (t table) int32(id int, def int32) int32 {
+	pos := t.field(id)
+	if pos == 0 {
+		return def
+	}
+	return int32(t.u32(pos))
+}
+
+func (t table) uint64(id int, def uint64) uint64 {
+	pos := t.field(id)
+	if pos == 0 {
+		return def
+	}
+	t.check(pos, 8)
+	return le.Uint64(t.buf[pos:])
+}
+
+// ref returns the position of the object referenced by a field, or 0 if
+// it is absent.
+func (t table) ref(id int) int {
+	pos := t.field(id)
+	if pos == 0 {
+		return 0
+	}
+	return pos + int(t.u32(pos))
+}
+
+func (t table) table(id int) (table, bool) {
+	pos := t.ref(id)
+	return table{buf: t.buf, pos: pos}, pos != 0
+}
+
+// vector returns the position of the first element of a vector and its
+// length.
+func (t table) vector(id, elemSize int) (int, int) {
+	pos := t.ref(id)
+	if pos == 0 {
+		return 0, 0
+	}
+	n := int(t.u32(pos))
+	if n > (len(t.buf)-pos-4)/elemSize {
+		panic(errCorrupt)
+	}
+	return pos + 4, n
+}
+
+func (t table) bytes(id int) []byte {
+	pos, n := t.vector(id, 1)
+	return t.buf[pos : pos+n]
+}
+
+func (t table) string(id int) string {
+	return string(t.bytes(id))
+}
+
+func (t table) float64s(id int) []float64 {
+	pos, n := t.vector(id, 8)
+	if n == 0 {
+		return nil
+	}
+	vs := make([]float64, n)
+	for i := range vs {
+		vs[i] = math.Float64frombits(le.Uint64(t.buf[pos+8*i:]))
+	}
+	return vs
+}
+
+func (t table) uint32s(id int) []uint32 {
+	pos, n := t.vector(id, 4)
+	if n == 0 {
+		return nil
+	}
+	vs := make([]uint32, n)
+	for i := range vs {
+		vs[i] = le.Uint32(t.buf[pos+4*i:])
+	}
+	return vs
+}
+
+func (t table) tables(id int) []table {
+	pos, n := t.vector(id, 4)
+	ts := make([]table, n)
+	for i := range ts {
+		elem := pos + 4*i
+		ts[i] = table{buf: t.buf, pos: elem + int(t.u32(elem))}
+	}
+	return ts
+}
@@ -0,0 +1,225 @@
+// Package flatgeobuf reads and writes FlatGeobuf (version 3): a header,
+// an optional packed Hilbert R-tree index and a sequence of features, each
+// a size prefixed FlatBuffer.
+package flatgeobuf
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+var magic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// maxBufferSize guards against allocating for corrupt header and feature
+// sizes.
+const maxBufferSize = 1 << 30
+
+// DefaultIndexNodeSize is the number of children of each index node.
+const DefaultIndexNodeSize = 16
+
+// FlatGeobuf geometry types.
+const (
+	fgbUnknown            = 0
+	fgbPoint              = 1
+	fgbLineString         = 2
+	fgbPolygon            = 3
+	fgbMultiPoint         = 4
+	fgbMultiLineString    = 5
+	fgbMultiPolygon       = 6
+	fgbGeometryCollection = 7
+)
+
+var fgbTypes = map[geom.Type]uint8{
+	geom.TypePoint:           fgbPoint,
+	geom.TypeLineString:      fgbLineString,
+	geom.TypePolygon:         fgbPolygon,
+	geom.TypeMultiPoint:      fgbMultiPoint,
+	geom.TypeMultiLineString: fgbMultiLineString,
+	geom.TypeMultiPolygon:    fgbMultiPolygon,
+	geom.TypeCollection:      fgbGeometryCollection,
+}
+
+func geomType(fgbType uint8) (geom.Type, error) {
+	for t, ft := range fgbTypes {
+		if ft == fgbType {
+			return t, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported FlatGeobuf geometry type: %d", fgbType)
+}
+
+// ColumnType is the type of a FlatGeobuf property column.
+type ColumnType uint8
+
+const (
+	ColumnByte ColumnType = iota
+	ColumnUByte
+	ColumnBool
+	ColumnShort
+	ColumnUShort
+	ColumnInt
+	ColumnUInt
+	ColumnLong
+	ColumnULong
+	ColumnFloat
+	ColumnDouble
+	ColumnString
+	ColumnJSON
+	ColumnDateTime
+	ColumnBinary
+)
+
+// Column describes a feature property. Properties are read as int8, uint8,
+// bool, int16, uint16, int32, uint32, int64, uint64, float32, float64,
+// string (String and JSON), time.Time (DateTime, or string if it is not
+// RFC 3339) and []byte (Binary).
+type Column struct {
+	Name        string
+	Type        ColumnType
+	Title       string
+	Description string
+}
+
+// Header describes the features of a FlatGeobuf file.
+type Header struct {
+	Name string
+	// Envelope is the extent of all features, if known.
+	Envelope *coord.Envelope
+	// GeometryType is the type of all geometries, unless Mixed is set.
+	GeometryType geom.Type
+	Mixed        bool
+	Layout       geom.Layout
+	Columns      []Column
+	// FeaturesCount is the number of features, or 0 if unknown.
+	FeaturesCount uint64
+	// IndexNodeSize is the node size of the spatial index, or 0 if there
+	// is none.
+	IndexNodeSize uint16
+	// SRID is the EPSG code of the CRS, or 0 if there is none.
+	SRID        int
+	Title       string
+	Description string
+	Metadata    string
+}
+
+// Feature is a geometry with properties keyed by column name.
+type Feature struct {
+	Geometry   *geom.Geometry
+	Properties map[string]interface{}
+}
+
+// Header and Crs table fields.
+const (
+	headerName = iota
+	headerEnvelope
+	headerGeometryType
+	headerHasZ
+	headerHasM
+	headerHasT
+	headerHasTM
+	headerColumns
+	headerFeaturesCount
+	headerIndexNodeSize
+	headerCRS
+	headerTitle
+	headerDescription
+	headerMetadata
+
+	crsOrg  = 0
+	crsCode = 1
+
+	columnName        = 0
+	columnType        = 1
+	columnTitle       = 2
+	columnDescription = 3
+)
+
+func (h *Header) object() object {
+	var envelope *field
+	if h.Envelope != nil {
+		e := h.Envelope
+		envelope = refField(float64s([]float64{e.MinX, e.MinY, e.MaxX, e.MaxY}))
+	}
+	geometryType := uint8(fgbUnknown)
+	if !h.Mixed {
+		geometryType = fgbTypes[h.GeometryType]
+	}
+	var crs *field
+	if h.SRID != 0 {
+		crs = refField(func(b *builder) int {
+			return b.table(refField(stringObject("EPSG")), int32Field(int32(h.SRID)))
+		})
+	}
+	columns := make([]object, len(h.Columns))
+	for i, c := range h.Columns {
+		c := c
+		columns[i] = func(b *builder) int {
+			return b.table(
+				refField(stringObject(c.Name)),
+				uint8Field(uint8(c.Type)),
+				stringField(c.Title),
+				stringField(c.Description),
+			)
+		}
+	}
+
+	return func(b *builder) int {
+		return b.table(
+			stringField(h.Name),
+			envelope,
+			uint8Field(geometryType),
+			boolField(h.Layout.HasZ()),
+			boolField(h.Layout.HasM()),
+			nil,
+			nil,
+			refField(tables(columns)),
+			uint64Field(h.FeaturesCount),
+			uint16Field(h.IndexNodeSize),
+			crs,
+			stringField(h.Title),
+			stringField(h.Description),
+			stringField(h.Metadata),
+		)
+	}
+}
+
+func decodeHeader(buf []byte) (h *Header, err error) {
+	defer recoverCorrupt(&err)
+
+	t := rootTable(buf)
+	h = &Header{
+		Name:          t.string(headerName),
+		Layout:        geom.NewLayout(t.bool(headerHasZ, false), t.bool(headerHasM, false)),
+		FeaturesCount: t.uint64(headerFeaturesCount, 0),
+		IndexNodeSize: t.uint16(headerIndexNodeSize, DefaultIndexNodeSize),
+		Title:         t.string(headerTitle),
+		Description:   t.string(headerDescription),
+		Metadata:      t.string(headerMetadata),
+	}
+	if env := t.float64s(headerEnvelope); len(env) >= 4 {
+		h.Envelope = coord.NewEnvelope(env[0], env[2], env[1], env[3])
+	}
+	if fgbType := t.uint8(headerGeometryType, fgbUnknown); fgbType == fgbUnknown {
+		h.Mixed = true
+	} else if h.GeometryType, err = geomType(fgbType); err != nil {
+		return nil, err
+	}
+	if crs, ok := t.table(headerCRS); ok {
+		org := crs.string(crsOrg)
+		if org == "" || strings.EqualFold(org, "EPSG") {
+			h.SRID = int(crs.int32(crsCode, 0))
+		}
+	}
+	for _, c := range t.tables(headerColumns) {
+		h.Columns = append(h.Columns, Column{
+			Name:        c.string(columnName),
+			Type:        ColumnType(c.uint8(columnType, 0)),
+			Title:       c.string(columnTitle),
+			Description: c.string(columnDescription),
+		})
+	}
+	return h, nil
+}
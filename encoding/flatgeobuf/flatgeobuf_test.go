@@ -0,0 +1,262 @@
+package flatgeobuf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// landmarks is the poly_landmarks.fgb reference file of the FlatGeobuf
+// project: 85 indexed polygons in Manhattan, without properties.
+const landmarks = "testdata/poly_landmarks.fgb"
+
+// landmarksQuery is the search of the reference tests, which finds 2
+// features.
+var landmarksQuery = &coord.Envelope{MinX: -73.976523, MinY: 40.715091, MaxX: -73.971893, MaxY: 40.727318}
+
+func readFile(t testing.TB, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func wkts(t testing.TB, features []*Feature) []string {
+	t.Helper()
+	texts := make([]string, len(features))
+	for i, f := range features {
+		var err error
+		if texts[i], err = wkt.Marshal(f.Geometry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+// bruteForce returns the features whose envelopes intersect env.
+func bruteForce(features []*Feature, env *coord.Envelope) []*Feature {
+	var found []*Feature
+	for _, f := range features {
+		if f.Geometry.Envelope().Intersects(env) {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+func TestReferenceFile(t *testing.T) {
+	data := readFile(t, landmarks)
+	h, features, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if h.FeaturesCount != 85 || len(features) != 85 {
+		t.Errorf("got %d features, header count %d, want 85", len(features), h.FeaturesCount)
+	}
+	if h.GeometryType != geom.TypePolygon || h.Mixed || h.Layout != geom.LayoutXY || h.IndexNodeSize != 16 {
+		t.Errorf("Header = %+v", h)
+	}
+	for i, f := range features {
+		env := f.Geometry.Envelope()
+		if f.Geometry.Type != geom.TypePolygon || !h.Envelope.Contains(env.MinX, env.MinY) || !h.Envelope.Contains(env.MaxX, env.MaxY) {
+			t.Errorf("feature %d = %v %v, outside %v", i, f.Geometry.Type, env, h.Envelope)
+		}
+	}
+
+	_, found, err := Search(bytes.NewReader(data), landmarksQuery)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Search() found %d features, want 2", len(found))
+	}
+	if got, want := wkts(t, found), wkts(t, bruteForce(features, landmarksQuery)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() = %v, want %v", got, want)
+	}
+}
+
+func TestRewriteReferenceFile(t *testing.T) {
+	_, features, err := Read(bytes.NewReader(readFile(t, landmarks)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nodeSize := range []uint16{0, 2, 16} {
+		var buf bytes.Buffer
+		w := &Writer{Name: "landmarks", IndexNodeSize: nodeSize}
+		if err := w.Write(&buf, features); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		h, got, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if h.Name != "landmarks" || h.IndexNodeSize != nodeSize {
+			t.Errorf("Header = %+v", h)
+		}
+		if !reflect.DeepEqual(wkts(t, got), wkts(t, features)) {
+			t.Errorf("node size %d: features differ after rewriting", nodeSize)
+		}
+
+		for _, env := range []*coord.Envelope{
+			landmarksQuery,
+			h.Envelope,
+			{MinX: -73.99, MinY: 40.70, MaxX: -73.98, MaxY: 40.75},
+			{MinX: 0, MinY: 0, MaxX: 1, MaxY: 1},
+		} {
+			_, found, err := Search(bytes.NewReader(buf.Bytes()), env)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if got, want := wkts(t, found), wkts(t, bruteForce(features, env)); !reflect.DeepEqual(got, want) {
+				t.Errorf("node size %d: Search(%v) found %d features, want %d", nodeSize, env, len(got), len(want))
+			}
+		}
+	}
+}
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRoundTrip(t *testing.T) {
+	day := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name  string
+		geoms []string
+		typ   geom.Type
+		mixed bool
+	}{
+		{"points", []string{"POINT (1 2)", "POINT (3 4)"}, geom.TypePoint, false},
+		{"lines z", []string{"LINESTRING Z (1 2 3, 4 5 6)"}, geom.TypeLineString, false},
+		{"polygons m", []string{"POLYGON M ((0 0 1, 1 0 2, 1 1 3, 0 0 1), (0.2 0.1 1, 0.8 0.1 1, 0.8 0.7 1, 0.2 0.1 1))"}, geom.TypePolygon, false},
+		{"multipolygons", []string{"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 5)))"}, geom.TypeMultiPolygon, false},
+		{"multilines", []string{"MULTILINESTRING ((0 0, 1 1), (2 2, 3 3, 4 4))"}, geom.TypeMultiLineString, false},
+		{"collection", []string{"GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (1 2, 3 4))"}, geom.TypeCollection, false},
+		{"mixed", []string{"POINT (1 2)", "LINESTRING (1 2, 3 4)", "MULTIPOINT ((1 2), (3 4))"}, geom.TypeCollection, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var features []*Feature
+			for i, text := range test.geoms {
+				features = append(features, &Feature{
+					Geometry: mustUnmarshal(t, text),
+					Properties: map[string]interface{}{
+						"byte":   int8(-i),
+						"bool":   i%2 == 0,
+						"int":    int32(i * 1000),
+						"long":   int64(-i) << 40,
+						"ulong":  uint64(i) << 63,
+						"float":  float32(0.5),
+						"double": 1.0 / 3,
+						"string": "ÄÖ",
+						"time":   day,
+						"binary": []byte{0, 1, 2},
+					},
+				})
+			}
+			// a feature without properties keeps only the geometry
+			features = append(features, &Feature{Geometry: mustUnmarshal(t, test.geoms[0])})
+
+			w := &Writer{IndexNodeSize: 0}
+			var buf bytes.Buffer
+			if err := w.Write(&buf, features); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			h, got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if h.Mixed != test.mixed || !test.mixed && h.GeometryType != test.typ {
+				t.Errorf("Header type = %v, mixed %v", h.GeometryType, h.Mixed)
+			}
+			if len(h.Columns) != 10 {
+				t.Errorf("got %d columns, want 10", len(h.Columns))
+			}
+			if len(got) != len(features) {
+				t.Fatalf("got %d features, want %d", len(got), len(features))
+			}
+			for i, f := range got {
+				want, _ := wkt.Marshal(features[i].Geometry)
+				if text, _ := wkt.Marshal(f.Geometry); text != want {
+					t.Errorf("feature %d geometry = %s, want %s", i, text, want)
+				}
+				if len(features[i].Properties) > 0 && !reflect.DeepEqual(f.Properties, features[i].Properties) {
+					t.Errorf("feature %d properties = %v, want %v", i, f.Properties, features[i].Properties)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	d, err := NewReader().NewDecoder(bytes.NewReader(readFile(t, landmarks)))
+	if err != nil {
+		t.Fatalf("NewDecoder() error = %v", err)
+	}
+	n := 0
+	for {
+		_, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		n++
+	}
+	if n != 85 {
+		t.Errorf("decoded %d features, want 85", n)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	data := readFile(t, landmarks)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("fgb\x02fgb\x00"), data[8:]...)},
+		{"truncated header", data[:20]},
+		{"truncated index", data[:200]},
+		{"truncated feature", data[:len(data)-10]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, features, err := Read(bytes.NewReader(test.data)); err == nil {
+				t.Errorf("Read() = %d features, want an error", len(features))
+			}
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	p := mustUnmarshal(t, "POINT (1 2)")
+	if err := (&Writer{IndexNodeSize: 1}).Write(io.Discard, []*Feature{{Geometry: p}}); err == nil {
+		t.Error("Write() with node size 1, want an error")
+	}
+	f := &Feature{Geometry: p, Properties: map[string]interface{}{"a": struct{}{}}}
+	if err := NewWriter().Write(io.Discard, []*Feature{f}); err == nil {
+		t.Error("Write() with an unsupported property, want an error")
+	}
+	empty, _ := geom.NewEmptyPoint()
+	if err := NewWriter().Write(io.Discard, []*Feature{{Geometry: p}, {Geometry: empty}}); err == nil {
+		t.Error("Write() of an indexed empty geometry, want an error")
+	}
+}
@@ -0,0 +1,201 @@
+package flatgeobuf
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Geometry table fields.
+const (
+	geometryEnds = iota
+	geometryXY
+	geometryZ
+	geometryM
+	geometryT
+	geometryTM
+	geometryType
+	geometryParts
+)
+
+// geometryObject encodes a geometry table. The type is only written when
+// it cannot be known from the header or the parent geometry.
+func geometryObject(g *geom.Geometry, layout geom.Layout, writeType bool) object {
+	var rings []coord.Coordinates
+	var parts []object
+	switch g.Type {
+	case geom.TypePoint:
+		if !g.IsEmpty() {
+			rings = append(rings, coord.Coordinates{g.Coord})
+		}
+	case geom.TypeLineString:
+		rings = append(rings, g.Line)
+	case geom.TypePolygon:
+		if len(g.Line) > 0 {
+			rings = append(append(rings, g.Line), g.MultiLine...)
+		}
+	case geom.TypeMultiPoint:
+		var points coord.Coordinates
+		for _, p := range g.Collection {
+			if !p.IsEmpty() {
+				points = append(points, p.Coord)
+			}
+		}
+		rings = append(rings, points)
+	case geom.TypeMultiLineString:
+		for _, line := range g.Collection {
+			rings = append(rings, line.Line)
+		}
+	case geom.TypeMultiPolygon:
+		for _, poly := range g.Collection {
+			parts = append(parts, geometryObject(poly, layout, false))
+		}
+	case geom.TypeCollection:
+		for _, col := range g.Collection {
+			parts = append(parts, geometryObject(col, layout, true))
+		}
+	}
+
+	var ends []uint32
+	var xy, z, m []float64
+	for _, ring := range rings {
+		for _, c := range ring {
+			xy = append(xy, c.X, c.Y)
+			if layout.HasZ() {
+				z = append(z, c.Z)
+			}
+			if layout.HasM() {
+				m = append(m, c.M)
+			}
+		}
+		ends = append(ends, uint32(len(xy)/2))
+	}
+
+	fields := make([]*field, geometryParts+1)
+	// ends are only needed to split several rings or lines
+	if len(ends) > 1 {
+		fields[geometryEnds] = refField(uint32s(ends))
+	}
+	if len(xy) > 0 {
+		fields[geometryXY] = refField(float64s(xy))
+	}
+	if len(z) > 0 {
+		fields[geometryZ] = refField(float64s(z))
+	}
+	if len(m) > 0 {
+		fields[geometryM] = refField(float64s(m))
+	}
+	if writeType {
+		fields[geometryType] = uint8Field(fgbTypes[g.Type])
+	}
+	if len(parts) > 0 {
+		fields[geometryParts] = refField(tables(parts))
+	}
+	return func(b *builder) int {
+		return b.table(fields...)
+	}
+}
+
+// decodeGeometry decodes a geometry table, whose type defaults to the type
+// given by the header or the parent geometry.
+func decodeGeometry(t table, fgbType uint8, layout geom.Layout) (*geom.Geometry, error) {
+	if typ := t.uint8(geometryType, fgbUnknown); typ != fgbUnknown {
+		fgbType = typ
+	}
+	typ, err := geomType(fgbType)
+	if err != nil {
+		return nil, err
+	}
+
+	xy := t.float64s(geometryXY)
+	z := t.float64s(geometryZ)
+	m := t.float64s(geometryM)
+	n := len(xy) / 2
+	if len(xy)%2 != 0 || layout.HasZ() && len(z) != n || layout.HasM() && len(m) != n {
+		return nil, errors.New("inconsistent FlatGeobuf coordinate arrays")
+	}
+	cs := make(coord.Coordinates, n)
+	for i := range cs {
+		cs[i].X, cs[i].Y = xy[2*i], xy[2*i+1]
+		if layout.HasZ() {
+			cs[i].Z = z[i]
+		}
+		if layout.HasM() {
+			cs[i].M = m[i]
+		}
+	}
+
+	// split into rings or lines
+	var rings []coord.Coordinates
+	start := 0
+	for _, end := range t.uint32s(geometryEnds) {
+		if int(end) < start || int(end) > n {
+			return nil, errors.Errorf("invalid FlatGeobuf ring end: %d", end)
+		}
+		rings = append(rings, cs[start:end:end])
+		start = int(end)
+	}
+	if start < n {
+		rings = append(rings, cs[start:])
+	}
+
+	var g *geom.Geometry
+	switch typ {
+	case geom.TypePoint:
+		switch n {
+		case 0:
+			g, err = geom.NewEmptyPoint()
+		case 1:
+			g, err = geom.NewPoint(cs[0])
+		default:
+			return nil, errors.Errorf("FlatGeobuf Point has %d coordinates", n)
+		}
+	case geom.TypeLineString:
+		g, err = geom.NewLineString(cs)
+	case geom.TypeMultiPoint:
+		points := make([]*geom.Geometry, n)
+		for i, c := range cs {
+			if points[i], err = geom.NewPoint(c); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		g, err = geom.NewMultiPoint(points)
+	case geom.TypePolygon:
+		if len(rings) == 0 {
+			g, err = geom.NewPolygon(nil, nil)
+		} else {
+			g, err = geom.NewPolygon(rings[0], rings[1:])
+		}
+	case geom.TypeMultiLineString:
+		lines := make([]*geom.Geometry, len(rings))
+		for i, ring := range rings {
+			if lines[i], err = geom.NewLineString(ring); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		g, err = geom.NewMultiLineString(lines)
+	default:
+		partType := uint8(fgbUnknown)
+		if typ == geom.TypeMultiPolygon {
+			partType = fgbPolygon
+		}
+		var collection []*geom.Geometry
+		for _, part := range t.tables(geometryParts) {
+			col, err := decodeGeometry(part, partType, layout)
+			if err != nil {
+				return nil, err
+			}
+			collection = append(collection, col)
+		}
+		if typ == geom.TypeMultiPolygon {
+			g, err = geom.NewMultiPolygon(collection)
+		} else {
+			g, err = geom.NewCollection(collection)
+		}
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	g.Layout = layout
+	return g, nil
+}
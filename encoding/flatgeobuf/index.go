@@ -0,0 +1,183 @@
+package flatgeobuf
+
+import (
+	"math"
+	"sort"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/index/curve"
+)
+
+// nodeItemSize is the size of an index node: its envelope as minX, minY,
+// maxX, maxY followed by an offset.
+const nodeItemSize = 40
+
+// nodeItem is a node of the packed R-tree. The offset of a leaf is the byte
+// offset of its feature in the features section; the offset of any other
+// node is the index of its first child.
+type nodeItem struct {
+	minX, minY, maxX, maxY float64
+	offset                 uint64
+}
+
+func newNodeItem(env *coord.Envelope, offset uint64) nodeItem {
+	return nodeItem{env.MinX, env.MinY, env.MaxX, env.MaxY, offset}
+}
+
+func emptyNodeItem() nodeItem {
+	return nodeItem{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1), 0}
+}
+
+func (n *nodeItem) expand(o nodeItem) {
+	n.minX = math.Min(n.minX, o.minX)
+	n.minY = math.Min(n.minY, o.minY)
+	n.maxX = math.Max(n.maxX, o.maxX)
+	n.maxY = math.Max(n.maxY, o.maxY)
+}
+
+func (n nodeItem) envelope() *coord.Envelope {
+	return coord.NewEnvelope(n.minX, n.maxX, n.minY, n.maxY)
+}
+
+func (n nodeItem) intersects(env *coord.Envelope) bool {
+	return n.minX <= env.MaxX && n.maxX >= env.MinX && n.minY <= env.MaxY && n.maxY >= env.MinY
+}
+
+func (n nodeItem) append(buf []byte) []byte {
+	buf = le.AppendUint64(buf, math.Float64bits(n.minX))
+	buf = le.AppendUint64(buf, math.Float64bits(n.minY))
+	buf = le.AppendUint64(buf, math.Float64bits(n.maxX))
+	buf = le.AppendUint64(buf, math.Float64bits(n.maxY))
+	return le.AppendUint64(buf, n.offset)
+}
+
+func decodeNodeItem(buf []byte) nodeItem {
+	return nodeItem{
+		minX:   math.Float64frombits(le.Uint64(buf)),
+		minY:   math.Float64frombits(le.Uint64(buf[8:])),
+		maxX:   math.Float64frombits(le.Uint64(buf[16:])),
+		maxY:   math.Float64frombits(le.Uint64(buf[24:])),
+		offset: le.Uint64(buf[32:]),
+	}
+}
+
+// levelBounds returns the node index ranges of each level of a packed
+// R-tree, from the leaves up to the root. Nodes are stored from the root
+// down, so the leaves come last. There is always a root above the leaves,
+// even for a single item.
+func levelBounds(numItems, nodeSize int) [][2]int {
+	counts := []int{numItems}
+	for n := numItems; ; {
+		n = (n + nodeSize - 1) / nodeSize
+		counts = append(counts, n)
+		if n == 1 {
+			break
+		}
+	}
+	numNodes := 0
+	for _, n := range counts {
+		numNodes += n
+	}
+	bounds := make([][2]int, len(counts))
+	end := numNodes
+	for i, n := range counts {
+		bounds[i] = [2]int{end - n, end}
+		end -= n
+	}
+	return bounds
+}
+
+// indexSize returns the size in bytes of a packed R-tree.
+func indexSize(numItems, nodeSize int) int {
+	if numItems == 0 || nodeSize < 2 {
+		return 0
+	}
+	bounds := levelBounds(numItems, nodeSize)
+	return bounds[0][1] * nodeItemSize
+}
+
+// buildIndex builds a packed R-tree over leaves already in Hilbert order.
+func buildIndex(leaves []nodeItem, nodeSize int) []byte {
+	bounds := levelBounds(len(leaves), nodeSize)
+	nodes := make([]nodeItem, bounds[0][1])
+	copy(nodes[bounds[0][0]:], leaves)
+	for i := 0; i < len(bounds)-1; i++ {
+		parent := bounds[i+1][0]
+		for pos := bounds[i][0]; pos < bounds[i][1]; pos += nodeSize {
+			node := emptyNodeItem()
+			node.offset = uint64(pos)
+			for j := pos; j < pos+nodeSize && j < bounds[i][1]; j++ {
+				node.expand(nodes[j])
+			}
+			nodes[parent] = node
+			parent++
+		}
+	}
+
+	buf := make([]byte, 0, len(nodes)*nodeItemSize)
+	for _, n := range nodes {
+		buf = n.append(buf)
+	}
+	return buf
+}
+
+// hilbertSort sorts items by the Hilbert value of the centres of their
+// envelopes within the extent.
+func hilbertSort(items []nodeItem, extent nodeItem, order []int) {
+	h := curve.NewHilbert(extent.envelope())
+	values := make([]uint32, len(items))
+	for i, n := range items {
+		values[i] = h.Encode(coord.Coordinate{X: (n.minX + n.maxX) / 2, Y: (n.minY + n.maxY) / 2})
+	}
+	sort.Sort(&hilbertSorter{items, values, order})
+}
+
+type hilbertSorter struct {
+	items  []nodeItem
+	values []uint32
+	order  []int
+}
+
+func (s *hilbertSorter) Len() int           { return len(s.items) }
+func (s *hilbertSorter) Less(i, j int) bool { return s.values[i] > s.values[j] }
+func (s *hilbertSorter) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+	s.order[i], s.order[j] = s.order[j], s.order[i]
+}
+
+// searchIndex returns the feature offsets of the leaves intersecting env,
+// in file order. readNodes reads count nodes starting at a node index.
+func searchIndex(numItems, nodeSize int, env *coord.Envelope, readNodes func(start, count int) ([]nodeItem, error)) ([]uint64, error) {
+	bounds := levelBounds(numItems, nodeSize)
+	leavesStart := bounds[0][0]
+
+	type entry struct{ index, level int }
+	queue := []entry{{0, len(bounds) - 1}}
+	var offsets []uint64
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+
+		end := e.index + nodeSize
+		if levelEnd := bounds[e.level][1]; end > levelEnd {
+			end = levelEnd
+		}
+		nodes, err := readNodes(e.index, end-e.index)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if !n.intersects(env) {
+				continue
+			}
+			if e.index >= leavesStart {
+				offsets = append(offsets, n.offset)
+			} else {
+				queue = append(queue, entry{int(n.offset), e.level - 1})
+			}
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
@@ -0,0 +1,286 @@
+package flatgeobuf
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// columnTypeOf returns the column type used to write a property value.
+func columnTypeOf(v interface{}) (ColumnType, bool) {
+	switch v.(type) {
+	case int8:
+		return ColumnByte, true
+	case uint8:
+		return ColumnUByte, true
+	case bool:
+		return ColumnBool, true
+	case int16:
+		return ColumnShort, true
+	case uint16:
+		return ColumnUShort, true
+	case int32:
+		return ColumnInt, true
+	case uint32:
+		return ColumnUInt, true
+	case int, int64:
+		return ColumnLong, true
+	case uint, uint64:
+		return ColumnULong, true
+	case float32:
+		return ColumnFloat, true
+	case float64:
+		return ColumnDouble, true
+	case string:
+		return ColumnString, true
+	case time.Time:
+		return ColumnDateTime, true
+	case []byte:
+		return ColumnBinary, true
+	}
+	return 0, false
+}
+
+// inferColumns returns a column for every property of the features, in
+// name order, typed by the first non-nil value.
+func inferColumns(features []*Feature) ([]Column, error) {
+	types := map[string]ColumnType{}
+	for _, f := range features {
+		for name, v := range f.Properties {
+			if _, ok := types[name]; ok || v == nil {
+				continue
+			}
+			t, ok := columnTypeOf(v)
+			if !ok {
+				return nil, errors.Errorf("unsupported type %T for property %q", v, name)
+			}
+			types[name] = t
+		}
+	}
+	columns := make([]Column, 0, len(types))
+	for name, t := range types {
+		columns = append(columns, Column{Name: name, Type: t})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].Name < columns[j].Name
+	})
+	return columns, nil
+}
+
+// encodeProperties encodes the non-nil properties of a feature as column
+// index and value pairs.
+func encodeProperties(properties map[string]interface{}, columns []Column) ([]byte, error) {
+	var buf []byte
+	for i, c := range columns {
+		v, ok := properties[c.Name]
+		if !ok || v == nil {
+			continue
+		}
+		buf = le.AppendUint16(buf, uint16(i))
+
+		var err error
+		if buf, err = appendValue(buf, c.Type, v); err != nil {
+			return nil, errors.Wrapf(err, "invalid value for property %q", c.Name)
+		}
+	}
+	return buf, nil
+}
+
+func appendValue(buf []byte, t ColumnType, v interface{}) ([]byte, error) {
+	switch t {
+	case ColumnBool:
+		if b, ok := v.(bool); ok {
+			if b {
+				return append(buf, 1), nil
+			}
+			return append(buf, 0), nil
+		}
+	case ColumnByte, ColumnShort, ColumnInt, ColumnLong:
+		if i, ok := toInt(v); ok {
+			switch t {
+			case ColumnByte:
+				return append(buf, byte(int8(i))), nil
+			case ColumnShort:
+				return le.AppendUint16(buf, uint16(int16(i))), nil
+			case ColumnInt:
+				return le.AppendUint32(buf, uint32(int32(i))), nil
+			}
+			return le.AppendUint64(buf, uint64(i)), nil
+		}
+	case ColumnUByte, ColumnUShort, ColumnUInt, ColumnULong:
+		if u, ok := toUint(v); ok {
+			switch t {
+			case ColumnUByte:
+				return append(buf, byte(u)), nil
+			case ColumnUShort:
+				return le.AppendUint16(buf, uint16(u)), nil
+			case ColumnUInt:
+				return le.AppendUint32(buf, uint32(u)), nil
+			}
+			return le.AppendUint64(buf, u), nil
+		}
+	case ColumnFloat, ColumnDouble:
+		var f float64
+		switch n := v.(type) {
+		case float32:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			i, ok := toInt(v)
+			if !ok {
+				return nil, errors.Errorf("expected number, found %T", v)
+			}
+			f = float64(i)
+		}
+		if t == ColumnFloat {
+			return le.AppendUint32(buf, math.Float32bits(float32(f))), nil
+		}
+		return le.AppendUint64(buf, math.Float64bits(f)), nil
+	case ColumnString, ColumnJSON, ColumnDateTime, ColumnBinary:
+		var b []byte
+		switch s := v.(type) {
+		case string:
+			b = []byte(s)
+		case []byte:
+			b = s
+		case time.Time:
+			b = []byte(s.Format(time.RFC3339Nano))
+		default:
+			return nil, errors.Errorf("expected string, found %T", v)
+		}
+		buf = le.AppendUint32(buf, uint32(len(b)))
+		return append(buf, b...), nil
+	default:
+		return nil, errors.Errorf("unsupported column type %d", t)
+	}
+	return nil, errors.Errorf("unexpected type %T for column type %d", v, t)
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func toUint(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	}
+	if i, ok := toInt(v); ok && i >= 0 {
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+var columnSizes = map[ColumnType]int{
+	ColumnByte:   1,
+	ColumnUByte:  1,
+	ColumnBool:   1,
+	ColumnShort:  2,
+	ColumnUShort: 2,
+	ColumnInt:    4,
+	ColumnUInt:   4,
+	ColumnLong:   8,
+	ColumnULong:  8,
+	ColumnFloat:  4,
+	ColumnDouble: 8,
+}
+
+// decodeProperties decodes column index and value pairs.
+func decodeProperties(buf []byte, columns []Column) (map[string]interface{}, error) {
+	properties := make(map[string]interface{}, len(columns))
+	for pos := 0; pos < len(buf); {
+		if pos+2 > len(buf) {
+			return nil, errors.New("truncated FlatGeobuf properties")
+		}
+		i := int(le.Uint16(buf[pos:]))
+		pos += 2
+		if i >= len(columns) {
+			return nil, errors.Errorf("invalid FlatGeobuf column index: %d", i)
+		}
+		c := columns[i]
+
+		size, fixed := columnSizes[c.Type]
+		if !fixed {
+			if pos+4 > len(buf) {
+				return nil, errors.New("truncated FlatGeobuf properties")
+			}
+			size = int(le.Uint32(buf[pos:]))
+			pos += 4
+		}
+		if size < 0 || size > len(buf)-pos {
+			return nil, errors.New("truncated FlatGeobuf properties")
+		}
+		b := buf[pos : pos+size]
+		pos += size
+
+		var v interface{}
+		switch c.Type {
+		case ColumnByte:
+			v = int8(b[0])
+		case ColumnUByte:
+			v = b[0]
+		case ColumnBool:
+			v = b[0] != 0
+		case ColumnShort:
+			v = int16(le.Uint16(b))
+		case ColumnUShort:
+			v = le.Uint16(b)
+		case ColumnInt:
+			v = int32(le.Uint32(b))
+		case ColumnUInt:
+			v = le.Uint32(b)
+		case ColumnLong:
+			v = int64(le.Uint64(b))
+		case ColumnULong:
+			v = le.Uint64(b)
+		case ColumnFloat:
+			v = math.Float32frombits(le.Uint32(b))
+		case ColumnDouble:
+			v = math.Float64frombits(le.Uint64(b))
+		case ColumnString, ColumnJSON:
+			v = string(b)
+		case ColumnDateTime:
+			t, err := time.Parse(time.RFC3339Nano, string(b))
+			if err != nil {
+				v = string(b)
+			} else {
+				v = t
+			}
+		case ColumnBinary:
+			v = append([]byte(nil), b...)
+		default:
+			return nil, errors.Errorf("unsupported FlatGeobuf column type: %d", c.Type)
+		}
+		properties[c.Name] = v
+	}
+	return properties, nil
+}
@@ -0,0 +1,229 @@
+package flatgeobuf
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader reads FlatGeobuf files.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Read reads all features using the default Reader.
+func Read(in io.Reader) (*Header, []*Feature, error) {
+	return NewReader().Read(in)
+}
+
+// Search reads the features intersecting env using the default Reader.
+func Search(in io.ReaderAt, env *coord.Envelope) (*Header, []*Feature, error) {
+	return NewReader().Search(in, env)
+}
+
+// Read reads the header and all features of a FlatGeobuf file.
+func (r *Reader) Read(in io.Reader) (*Header, []*Feature, error) {
+	d, err := r.NewDecoder(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	var features []*Feature
+	for {
+		f, err := d.Next()
+		if err == io.EOF {
+			return d.Header, features, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		features = append(features, f)
+	}
+}
+
+// Decoder reads the features of a FlatGeobuf file one at a time.
+type Decoder struct {
+	Header *Header
+
+	r  *Reader
+	in *bufio.Reader
+}
+
+// NewDecoder reads the header of a FlatGeobuf file and skips its index.
+func (r *Reader) NewDecoder(in io.Reader) (*Decoder, error) {
+	br := bufio.NewReader(in)
+	h, _, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if size := indexSize(int(h.FeaturesCount), int(h.IndexNodeSize)); size > 0 {
+		if _, err := br.Discard(size); err != nil {
+			return nil, errors.Wrap(unexpectedEOF(err), "cannot skip FlatGeobuf index")
+		}
+	}
+	return &Decoder{Header: h, r: r, in: br}, nil
+}
+
+// Next returns the next feature, or io.EOF after the last one.
+func (d *Decoder) Next() (*Feature, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(d.in, size[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrap(unexpectedEOF(err), "cannot read FlatGeobuf feature")
+	}
+	buf, err := readBuffer(d.in, le.Uint32(size[:]))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read FlatGeobuf feature")
+	}
+	return d.r.decodeFeature(buf, d.Header)
+}
+
+// Search reads the header of a FlatGeobuf file and the features whose
+// envelopes intersect env, in file order. The index is used to read only
+// those features; without an index, all features are read and filtered.
+func (r *Reader) Search(in io.ReaderAt, env *coord.Envelope) (*Header, []*Feature, error) {
+	h, headerEnd, err := readHeader(io.NewSectionReader(in, 0, 1<<63-1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if env == nil {
+		return h, nil, nil
+	}
+
+	size := indexSize(int(h.FeaturesCount), int(h.IndexNodeSize))
+	if size == 0 {
+		d, err := r.NewDecoder(io.NewSectionReader(in, 0, 1<<63-1))
+		if err != nil {
+			return nil, nil, err
+		}
+		var features []*Feature
+		for {
+			f, err := d.Next()
+			if err == io.EOF {
+				return h, features, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if f.Geometry != nil && env.Intersects(f.Geometry.Envelope()) {
+				features = append(features, f)
+			}
+		}
+	}
+
+	offsets, err := searchIndex(int(h.FeaturesCount), int(h.IndexNodeSize), env, func(start, count int) ([]nodeItem, error) {
+		buf := make([]byte, count*nodeItemSize)
+		if _, err := in.ReadAt(buf, headerEnd+int64(start*nodeItemSize)); err != nil {
+			return nil, errors.Wrap(unexpectedEOF(err), "cannot read FlatGeobuf index")
+		}
+		nodes := make([]nodeItem, count)
+		for i := range nodes {
+			nodes[i] = decodeNodeItem(buf[i*nodeItemSize:])
+		}
+		return nodes, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	featuresStart := headerEnd + int64(size)
+	features := make([]*Feature, 0, len(offsets))
+	for _, offset := range offsets {
+		pos := featuresStart + int64(offset)
+		var size [4]byte
+		if _, err := in.ReadAt(size[:], pos); err != nil {
+			return nil, nil, errors.Wrap(unexpectedEOF(err), "cannot read FlatGeobuf feature")
+		}
+		buf, err := readBuffer(io.NewSectionReader(in, pos+4, int64(le.Uint32(size[:]))), le.Uint32(size[:]))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "cannot read FlatGeobuf feature")
+		}
+		f, err := r.decodeFeature(buf, h)
+		if err != nil {
+			return nil, nil, err
+		}
+		features = append(features, f)
+	}
+	return h, features, nil
+}
+
+// readHeader reads the magic bytes and header, returning the header and the
+// number of bytes read.
+func readHeader(in io.Reader) (*Header, int64, error) {
+	var start [12]byte
+	if _, err := io.ReadFull(in, start[:]); err != nil {
+		return nil, 0, errors.Wrap(unexpectedEOF(err), "cannot read FlatGeobuf header")
+	}
+	// accept any minor version
+	if !bytes.Equal(start[:3], magic[:3]) || start[3] != magic[3] || !bytes.Equal(start[4:7], magic[4:7]) {
+		return nil, 0, errors.New("not a FlatGeobuf version 3 file")
+	}
+	size := le.Uint32(start[8:])
+	buf, err := readBuffer(in, size)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "cannot read FlatGeobuf header")
+	}
+	h, err := decodeHeader(buf)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "invalid FlatGeobuf header")
+	}
+	return h, int64(len(start)) + int64(size), nil
+}
+
+func readBuffer(in io.Reader, size uint32) ([]byte, error) {
+	if size > maxBufferSize {
+		return nil, errors.Errorf("invalid size: %d", size)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(in, buf); err != nil {
+		return nil, errors.WithStack(unexpectedEOF(err))
+	}
+	return buf, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (r *Reader) decodeFeature(buf []byte, h *Header) (f *Feature, err error) {
+	defer recoverCorrupt(&err)
+
+	t := rootTable(buf)
+	f = &Feature{}
+	if gt, ok := t.table(featureGeometry); ok {
+		fgbType := uint8(fgbUnknown)
+		if !h.Mixed {
+			fgbType = fgbTypes[h.GeometryType]
+		}
+		g, err := decodeGeometry(gt, fgbType, h.Layout)
+		if err != nil {
+			return nil, err
+		}
+		g.SRID = h.SRID
+		if r.Orientation != geom.OrientationNone {
+			if g, err = g.Orient(r.Orientation); err != nil {
+				return nil, err
+			}
+		}
+		f.Geometry = g
+	}
+	if props := t.bytes(featureProperties); len(props) > 0 {
+		if f.Properties, err = decodeProperties(props, h.Columns); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,154 @@
+package flatgeobuf
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer writes FlatGeobuf files.
+type Writer struct {
+	// Name is the name of the dataset written in the header.
+	Name string
+	// Columns, if set, are the property columns written. Otherwise they are
+	// inferred from the property values, in name order.
+	Columns []Column
+	// IndexNodeSize is the node size of the spatial index written, or 0 to
+	// write no index. Every feature must have a non-empty geometry to be
+	// indexed.
+	IndexNodeSize uint16
+}
+
+func NewWriter() *Writer {
+	return &Writer{IndexNodeSize: DefaultIndexNodeSize}
+}
+
+// Write writes features using the default Writer.
+func Write(out io.Writer, features []*Feature) error {
+	return NewWriter().Write(out, features)
+}
+
+// Write writes features to out. The header takes its geometry type and
+// SRID from the first geometry, and its layout from all geometries; if
+// the geometry types differ, the header type is unknown and each geometry
+// carries its own. Indexed features are written in Hilbert order.
+func (w *Writer) Write(out io.Writer, features []*Feature) error {
+	if w.IndexNodeSize == 1 {
+		return errors.New("FlatGeobuf index node size must be at least 2")
+	}
+
+	columns := w.Columns
+	if columns == nil {
+		var err error
+		if columns, err = inferColumns(features); err != nil {
+			return err
+		}
+	}
+
+	h := &Header{
+		Name:          w.Name,
+		Columns:       columns,
+		FeaturesCount: uint64(len(features)),
+	}
+	var first *geom.Geometry
+	hasZ, hasM := false, false
+	for _, f := range features {
+		g := f.Geometry
+		if g == nil {
+			continue
+		}
+		if first == nil {
+			first = g
+		} else if g.Type != first.Type {
+			h.Mixed = true
+		}
+		hasZ = hasZ || g.Layout.HasZ()
+		hasM = hasM || g.Layout.HasM()
+	}
+	if first == nil {
+		h.Mixed = true
+	} else {
+		h.GeometryType = first.Type
+		h.SRID = first.SRID
+	}
+	h.Layout = geom.NewLayout(hasZ, hasM)
+
+	// sort the features by Hilbert value, noting the envelope of each
+	var leaves []nodeItem
+	order := make([]int, len(features))
+	for i := range order {
+		order[i] = i
+	}
+	if w.IndexNodeSize > 0 && len(features) > 0 {
+		leaves = make([]nodeItem, len(features))
+		extent := emptyNodeItem()
+		for i, f := range features {
+			if f.Geometry == nil || f.Geometry.IsEmpty() {
+				return errors.Errorf("cannot index feature %d without geometry", i)
+			}
+			leaves[i] = newNodeItem(f.Geometry.Envelope(), 0)
+			extent.expand(leaves[i])
+		}
+		hilbertSort(leaves, extent, order)
+		h.Envelope = extent.envelope()
+		h.IndexNodeSize = w.IndexNodeSize
+	}
+
+	encoded := make([][]byte, len(features))
+	offset := uint64(0)
+	for i, j := range order {
+		buf, err := encodeFeature(features[j], h)
+		if err != nil {
+			return errors.Wrapf(err, "cannot encode feature %d", j)
+		}
+		encoded[i] = buf
+		if leaves != nil {
+			leaves[i].offset = offset
+		}
+		offset += uint64(len(buf))
+	}
+
+	if _, err := out.Write(magic); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := out.Write(finish(h.object())); err != nil {
+		return errors.WithStack(err)
+	}
+	if leaves != nil {
+		if _, err := out.Write(buildIndex(leaves, int(w.IndexNodeSize))); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, buf := range encoded {
+		if _, err := out.Write(buf); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Feature table fields.
+const (
+	featureGeometry = iota
+	featureProperties
+)
+
+func encodeFeature(f *Feature, h *Header) ([]byte, error) {
+	var geometry, properties *field
+	if f.Geometry != nil {
+		geometry = refField(geometryObject(f.Geometry, h.Layout, h.Mixed))
+	}
+	if len(f.Properties) > 0 {
+		buf, err := encodeProperties(f.Properties, h.Columns)
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) > 0 {
+			properties = refField(bytesVector(buf))
+		}
+	}
+	return finish(func(b *builder) int {
+		return b.table(geometry, properties)
+	}), nil
+}
@@ -0,0 +1,185 @@
+package geohash
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+	"github.com/simoncochrane/geoz/index/chain"
+)
+
+// Cover returns the sorted geohashes of the given precision whose cells
+// intersect a geometry. Cells are tested against the geometry itself, not
+// just its envelope. Like Encode, cells contain their south and west edges
+// but not their north and east ones, so a geometry which only touches a
+// cell along its north or east edge does not cover it.
+//
+// Cells are found by subdividing the cells which intersect the geometry,
+// testing each against the nearby segments found in a monotone chain
+// index, and cells within a polygon are included without further testing,
+// but the number of cells returned grows with the area covered: by a
+// factor of 32 for each additional character.
+func Cover(g *geom.Geometry, precision int) ([]string, error) {
+	if err := checkPrecision(precision); err != nil {
+		return nil, err
+	}
+	c := &coverer{precision: precision, set: map[string]bool{}}
+	if err := c.geometry(g); err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(c.set))
+	for hash := range c.set {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+type coverer struct {
+	precision int
+	set       map[string]bool
+	li        coord.RobustLineIntersector
+}
+
+// shape is a line or polygon being covered, with its segments indexed so
+// that each cell is only tested against the segments near it.
+type shape struct {
+	segments *chain.Index
+	// locator is nil for lines.
+	locator *graph.IndexedPointInAreaLocator
+}
+
+func (c *coverer) geometry(g *geom.Geometry) error {
+	if g.IsEmpty() {
+		return nil
+	}
+	switch g.Type {
+	case geom.TypePoint:
+		hash, err := Encode(g.Coord, c.precision)
+		if err != nil {
+			return err
+		}
+		c.set[hash] = true
+		return nil
+	case geom.TypeMultiPoint, geom.TypeMultiLineString, geom.TypeMultiPolygon, geom.TypeCollection:
+		for _, col := range g.Collection {
+			if err := c.geometry(col); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	env := g.Envelope()
+	if env.MinX < -180 || env.MaxX > 180 || env.MinY < -90 || env.MaxY > 90 {
+		return errors.Errorf("geometry out of range for geohash: %v", env)
+	}
+	if g.Type == geom.TypeLineString && len(g.Line) == 1 {
+		hash, err := Encode(g.Line[0], c.precision)
+		if err != nil {
+			return err
+		}
+		c.set[hash] = true
+		return nil
+	}
+	s := &shape{}
+	if g.Type == geom.TypePolygon {
+		s.segments = chain.NewIndex(append(coord.MultiLine{g.Line}, g.MultiLine...))
+		locator, err := graph.NewIndexedPointInAreaLocator(g)
+		if err != nil {
+			return err
+		}
+		s.locator = locator
+	} else {
+		s.segments = chain.NewIndex([]coord.Coordinates{g.Line})
+	}
+	c.cell("", env, s)
+	return nil
+}
+
+// cell adds the cells of the target precision within the cell of hash
+// which intersect a shape with envelope shapeEnv.
+func (c *coverer) cell(hash string, shapeEnv *coord.Envelope, s *shape) {
+	// hashes built here are always valid
+	env, _ := decode(hash)
+	env = halfOpen(env)
+	if !env.Intersects(shapeEnv) {
+		return
+	}
+	crosses := c.crosses(env, s)
+	if !crosses && !s.contains(env) {
+		return
+	}
+	if len(hash) == c.precision {
+		c.set[hash] = true
+		return
+	}
+	if !crosses {
+		// the cell is within a polygon, not touching its boundary
+		c.all(hash)
+		return
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c.cell(hash+alphabet[i:i+1], shapeEnv, s)
+	}
+}
+
+// halfOpen approximates a cell without its north and east edges, unless
+// they are the edges of the world.
+func halfOpen(env *coord.Envelope) *coord.Envelope {
+	const eps = 1e-9
+	if env.MaxX < 180 {
+		env.MaxX -= (env.MaxX - env.MinX) * eps
+	}
+	if env.MaxY < 90 {
+		env.MaxY -= (env.MaxY - env.MinY) * eps
+	}
+	return env
+}
+
+// all adds every cell of the target precision within the cell of hash.
+func (c *coverer) all(hash string) {
+	if len(hash) == c.precision {
+		c.set[hash] = true
+		return
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c.all(hash + alphabet[i:i+1])
+	}
+}
+
+// contains reports whether a cell which the boundary of a shape does not
+// cross is in the interior of the shape. Such a cell is either entirely
+// within a polygon or entirely outside it, so locating a corner is enough.
+func (s *shape) contains(env *coord.Envelope) bool {
+	return s.locator != nil && s.locator.Locate(coord.Coordinate{X: env.MinX, Y: env.MinY}) == coord.LocationInterior
+}
+
+// crosses reports whether any segment of a shape intersects a cell.
+func (c *coverer) crosses(env *coord.Envelope, s *shape) bool {
+	corners := [5]coord.Coordinate{
+		{X: env.MinX, Y: env.MinY},
+		{X: env.MaxX, Y: env.MinY},
+		{X: env.MaxX, Y: env.MaxY},
+		{X: env.MinX, Y: env.MaxY},
+		{X: env.MinX, Y: env.MinY},
+	}
+	found := false
+	s.segments.Query(env, func(seg chain.Segment) bool {
+		p, q := seg.Coordinates()
+		if env.ContainsCoord(p) || env.ContainsCoord(q) {
+			found = true
+			return false
+		}
+		for j := 1; j < len(corners); j++ {
+			c.li.ComputeLineIntersection(p, q, corners[j-1], corners[j])
+			if c.li.HasIntersection() {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,204 @@
+package geohash
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func mustDecode(t *testing.T, hash string) *coord.Envelope {
+	t.Helper()
+	env, err := Decode(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func mustEncode(t *testing.T, c coord.Coordinate, precision int) string {
+	t.Helper()
+	hash, err := Encode(c, precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// rect returns the ring of an envelope.
+func rect(env *coord.Envelope) coord.Coordinates {
+	return coord.Coordinates{
+		{X: env.MinX, Y: env.MinY},
+		{X: env.MaxX, Y: env.MinY},
+		{X: env.MaxX, Y: env.MaxY},
+		{X: env.MinX, Y: env.MaxY},
+		{X: env.MinX, Y: env.MinY},
+	}
+}
+
+// inset returns the ring of an envelope shrunk by a hundredth of its size
+// on each side, so that it does not touch the cells around it.
+func inset(env *coord.Envelope) coord.Coordinates {
+	dx, dy := (env.MaxX-env.MinX)/100, (env.MaxY-env.MinY)/100
+	return rect(coord.NewEnvelope(env.MinX+dx, env.MaxX-dx, env.MinY+dy, env.MaxY-dy))
+}
+
+func mustPolygon(t *testing.T, shell coord.Coordinates, holes ...coord.Coordinates) *geom.Geometry {
+	t.Helper()
+	g, err := geom.NewPolygon(shell, holes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// children returns every geohash of a precision within the cell of hash.
+func children(hash string, precision int) []string {
+	if len(hash) == precision {
+		return []string{hash}
+	}
+	var hashes []string
+	for i := 0; i < len(alphabet); i++ {
+		hashes = append(hashes, children(hash+alphabet[i:i+1], precision)...)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+func TestCover(t *testing.T) {
+	cell := mustDecode(t, "u4pr")
+	// the cells of 6 characters split a cell of 4 characters into 32 by 32;
+	// the hole contains the south and west edges of the 4 cells in the
+	// middle of it, but not of those around its edges
+	w, h := (cell.MaxX-cell.MinX)/32, (cell.MaxY-cell.MinY)/32
+	hole := coord.NewEnvelope(cell.MinX+10*w, cell.MinX+13*w, cell.MinY+10*h, cell.MinY+13*h)
+	inHole := map[string]bool{}
+	for _, x := range []float64{11.5, 12.5} {
+		for _, y := range []float64{11.5, 12.5} {
+			inHole[mustEncode(t, coord.Coordinate{X: cell.MinX + x*w, Y: cell.MinY + y*h}, 6)] = true
+		}
+	}
+	var aroundHole []string
+	for _, hash := range children("u4pr", 6) {
+		if !inHole[hash] {
+			aroundHole = append(aroundHole, hash)
+		}
+	}
+	// a line through the middle of the second row of cells of 5
+	// characters, which are 8 wide and 4 high
+	row := cell.MinY + 1.5*(cell.MaxY-cell.MinY)/4
+	line, err := geom.NewLineString(inset(coord.NewEnvelope(cell.MinX, cell.MaxX, row, row))[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rowCells []string
+	for _, hash := range children("u4pr", 5) {
+		if env := mustDecode(t, hash); env.MinY < row && row < env.MaxY {
+			rowCells = append(rowCells, hash)
+		}
+	}
+	far := mustPolygon(t, inset(mustDecode(t, "9q8y")))
+
+	tests := []struct {
+		name      string
+		g         *geom.Geometry
+		precision int
+		want      []string
+	}{
+		{"point", mustUnmarshal(t, "POINT (10.40744 57.64911)"), 11, []string{"u4pruydqqvj"}},
+		{"empty", mustUnmarshal(t, "POLYGON EMPTY"), 5, []string{}},
+		{"cell", mustPolygon(t, inset(cell)), 4, []string{"u4pr"}},
+		{"cell children", mustPolygon(t, inset(cell)), 5, children("u4pr", 5)},
+		{"touching the north and east cells", mustPolygon(t, rect(cell)), 4, []string{"u4pr", "u4px", "u4r2", "u4r8"}},
+		{"hole", mustPolygon(t, inset(cell), rect(hole)), 6, aroundHole},
+		{"line", line, 5, rowCells},
+		{"multipolygon", &geom.Geometry{Type: geom.TypeMultiPolygon, Collection: []*geom.Geometry{mustPolygon(t, inset(cell)), far}}, 4, []string{"9q8y", "u4pr"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Cover(test.g, test.precision)
+			if err != nil {
+				t.Fatalf("Cover() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Cover() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCoverNested(t *testing.T) {
+	// a triangle with a hole, covered at increasing precisions: every cell
+	// is within a cell of the previous precision, and every cell of the
+	// previous precision has at least one cell within it
+	g := mustUnmarshal(t, "POLYGON ((-0.5 51.3, 0.3 51.35, -0.1 51.7, -0.5 51.3), (-0.2 51.4, 0 51.42, -0.1 51.5, -0.2 51.4))")
+	prev, err := Cover(g, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for precision := 4; precision <= 6; precision++ {
+		hashes, err := Cover(g, precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parents := map[string]bool{}
+		for _, hash := range hashes {
+			parent := hash[:precision-1]
+			if i := sort.SearchStrings(prev, parent); i == len(prev) || prev[i] != parent {
+				t.Errorf("precision %d: cell %s is not within a cell of precision %d", precision, hash, precision-1)
+			}
+			parents[parent] = true
+		}
+		if len(parents) != len(prev) {
+			t.Errorf("precision %d: cells within %d of %d parent cells", precision, len(parents), len(prev))
+		}
+		prev = hashes
+	}
+
+	// the cell of the interior point of the hole is not covered, unlike
+	// the cell of a vertex
+	hashes, _ := Cover(g, 6)
+	if inHole := mustEncode(t, coord.Coordinate{X: -0.1, Y: 51.44}, 6); contains(hashes, inHole) {
+		t.Errorf("Cover() contains %s, in the hole", inHole)
+	}
+	if vertex := mustEncode(t, coord.Coordinate{X: 0.3, Y: 51.35}, 6); !contains(hashes, vertex) {
+		t.Errorf("Cover() does not contain %s, of a vertex", vertex)
+	}
+}
+
+func contains(hashes []string, hash string) bool {
+	i := sort.SearchStrings(hashes, hash)
+	return i < len(hashes) && hashes[i] == hash
+}
+
+func TestCoverErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		precision int
+	}{
+		{"precision 0", "POINT (1 2)", 0},
+		{"precision 13", "POINT (1 2)", 13},
+		{"out of range", "LINESTRING (170 0, 190 0)", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hashes, err := Cover(mustUnmarshal(t, test.text), test.precision); err == nil {
+				t.Errorf("Cover() = %s, want an error", strings.Join(hashes, ","))
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package geohash encodes longitude/latitude coordinates as geohashes: base
+// 32 strings identifying cells of a grid, where each character subdivides
+// the cell given by the preceding ones. X is longitude and Y is latitude.
+package geohash
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// MaxPrecision is the maximum number of characters of a geohash, at which
+// cells are a few centimetres wide.
+const MaxPrecision = 12
+
+const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Direction is the direction of a neighbouring cell.
+type Direction int
+
+const (
+	North Direction = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+var offsets = [...][2]float64{
+	North:     {0, 1},
+	NorthEast: {1, 1},
+	East:      {1, 0},
+	SouthEast: {1, -1},
+	South:     {0, -1},
+	SouthWest: {-1, -1},
+	West:      {-1, 0},
+	NorthWest: {-1, 1},
+}
+
+func world() *coord.Envelope {
+	return coord.NewEnvelope(-180, 180, -90, 90)
+}
+
+func checkPrecision(precision int) error {
+	if precision < 1 || precision > MaxPrecision {
+		return errors.Errorf("geohash precision must be between 1 and %d, found %d", MaxPrecision, precision)
+	}
+	return nil
+}
+
+// Encode returns the geohash of the cell containing a coordinate, with the
+// given number of characters.
+func Encode(c coord.Coordinate, precision int) (string, error) {
+	if err := checkPrecision(precision); err != nil {
+		return "", err
+	}
+	if !world().ContainsCoord(c) {
+		return "", errors.Errorf("coordinate out of range for geohash: %v, %v", c.X, c.Y)
+	}
+
+	env := world()
+	var sb strings.Builder
+	lon := true
+	for i := 0; i < precision; i++ {
+		index := 0
+		for bit := 0; bit < 5; bit++ {
+			index <<= 1
+			if lon {
+				mid := (env.MinX + env.MaxX) / 2
+				if c.X >= mid {
+					index |= 1
+					env.MinX = mid
+				} else {
+					env.MaxX = mid
+				}
+			} else {
+				mid := (env.MinY + env.MaxY) / 2
+				if c.Y >= mid {
+					index |= 1
+					env.MinY = mid
+				} else {
+					env.MaxY = mid
+				}
+			}
+			lon = !lon
+		}
+		sb.WriteByte(alphabet[index])
+	}
+	return sb.String(), nil
+}
+
+// Decode returns the cell of a geohash. Geohashes are case insensitive.
+func Decode(hash string) (*coord.Envelope, error) {
+	if err := checkPrecision(len(hash)); err != nil {
+		return nil, err
+	}
+	return decode(hash)
+}
+
+// decode returns the cell of a geohash, or the world for an empty one.
+func decode(hash string) (*coord.Envelope, error) {
+	env := world()
+	lon := true
+	for i := 0; i < len(hash); i++ {
+		index := strings.IndexByte(alphabet, toLower(hash[i]))
+		if index < 0 {
+			return nil, errors.Errorf("invalid geohash character %q in %q", hash[i], hash)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := index&(1<<uint(bit)) != 0
+			if lon {
+				mid := (env.MinX + env.MaxX) / 2
+				if set {
+					env.MinX = mid
+				} else {
+					env.MaxX = mid
+				}
+			} else {
+				mid := (env.MinY + env.MaxY) / 2
+				if set {
+					env.MinY = mid
+				} else {
+					env.MaxY = mid
+				}
+			}
+			lon = !lon
+		}
+	}
+	return env, nil
+}
+
+func toLower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// Neighbour returns the geohash of the adjacent cell in a direction, of
+// the same precision. Longitudes wrap around the antimeridian; there is no
+// neighbour beyond a pole, for which the empty string is returned.
+func Neighbour(hash string, d Direction) (string, error) {
+	env, err := Decode(hash)
+	if err != nil {
+		return "", err
+	}
+	if d < North || d > NorthWest {
+		return "", errors.Errorf("invalid direction: %d", d)
+	}
+	width, height := env.MaxX-env.MinX, env.MaxY-env.MinY
+	x := (env.MinX+env.MaxX)/2 + offsets[d][0]*width
+	y := (env.MinY+env.MaxY)/2 + offsets[d][1]*height
+	if y < -90 || y > 90 {
+		return "", nil
+	}
+	if x < -180 {
+		x += 360
+	} else if x > 180 {
+		x -= 360
+	}
+	return Encode(coord.Coordinate{X: x, Y: y}, len(hash))
+}
+
+// Neighbours returns the geohashes of the eight adjacent cells, indexed by
+// Direction. Neighbours beyond a pole are empty strings.
+func Neighbours(hash string) ([8]string, error) {
+	var neighbours [8]string
+	for d := range neighbours {
+		n, err := Neighbour(hash, Direction(d))
+		if err != nil {
+			return neighbours, err
+		}
+		neighbours[d] = n
+	}
+	return neighbours, nil
+}
@@ -0,0 +1,226 @@
+// Package geojson implements GeoJSON (RFC 7946) features and feature
+// collections. Geometries are encoded by geom.Geometry itself, which
+// implements json.Marshaler and json.Unmarshaler.
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Feature is a GeoJSON Feature: a geometry with properties.
+type Feature struct {
+	// ID is the optional identifier of the feature, a string or a number.
+	// Integer ids are read as int64 and other numbers as float64.
+	ID         interface{}
+	Geometry   *geom.Geometry
+	Properties map[string]interface{}
+	BBox       []float64
+}
+
+// FeatureCollection is a GeoJSON FeatureCollection.
+type FeatureCollection struct {
+	Features []*Feature
+	BBox     []float64
+}
+
+type featureJSON struct {
+	Type       string                 `json:"type"`
+	ID         json.RawMessage        `json:"id,omitempty"`
+	BBox       []float64              `json:"bbox,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type featureCollectionJSON struct {
+	Type     string            `json:"type"`
+	BBox     []float64         `json:"bbox,omitempty"`
+	Features []json.RawMessage `json:"features"`
+}
+
+func (f *Feature) MarshalJSON() ([]byte, error) {
+	return NewWriter().WriteFeature(f)
+}
+
+func (f *Feature) UnmarshalJSON(data []byte) error {
+	decoded, err := NewReader().ReadFeature(data)
+	if err != nil {
+		return err
+	}
+	*f = *decoded
+	return nil
+}
+
+func (fc *FeatureCollection) MarshalJSON() ([]byte, error) {
+	return NewWriter().WriteFeatureCollection(fc)
+}
+
+func (fc *FeatureCollection) UnmarshalJSON(data []byte) error {
+	decoded, err := NewReader().ReadFeatureCollection(data)
+	if err != nil {
+		return err
+	}
+	*fc = *decoded
+	return nil
+}
+
+// Reader decodes GeoJSON objects.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	// Use geom.OrientationRHR for the orientation required by RFC 7946.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// ReadGeometry decodes a GeoJSON geometry object.
+func (r *Reader) ReadGeometry(data []byte) (*geom.Geometry, error) {
+	g := &geom.Geometry{}
+	if err := g.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return g.Orient(r.Orientation)
+}
+
+// ReadFeature decodes a GeoJSON Feature object.
+func (r *Reader) ReadFeature(data []byte) (*Feature, error) {
+	var fj featureJSON
+	if err := json.Unmarshal(data, &fj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON Feature")
+	}
+	if fj.Type != "Feature" {
+		return nil, errors.Errorf("expected GeoJSON type Feature, found %q", fj.Type)
+	}
+	id, err := readID(fj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Feature{
+		ID:         id,
+		Properties: fj.Properties,
+		BBox:       fj.BBox,
+	}
+	if len(fj.Geometry) > 0 && string(fj.Geometry) != "null" {
+		g, err := r.ReadGeometry(fj.Geometry)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON Feature geometry")
+		}
+		f.Geometry = g
+	}
+	return f, nil
+}
+
+// readID decodes a Feature id, keeping integers exact rather than decoding
+// them as float64.
+func readID(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var id interface{}
+	if err := dec.Decode(&id); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON Feature id")
+	}
+	switch id := id.(type) {
+	case nil, string:
+		return id, nil
+	case json.Number:
+		if i, err := id.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := id.Float64()
+		return f, errors.Wrap(err, "invalid GeoJSON Feature id")
+	}
+	return nil, errors.Errorf("GeoJSON Feature id must be a string or number, found %s", raw)
+}
+
+// ReadFeatureCollection decodes a GeoJSON FeatureCollection object.
+func (r *Reader) ReadFeatureCollection(data []byte) (*FeatureCollection, error) {
+	var fcj featureCollectionJSON
+	if err := json.Unmarshal(data, &fcj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON FeatureCollection")
+	}
+	if fcj.Type != "FeatureCollection" {
+		return nil, errors.Errorf("expected GeoJSON type FeatureCollection, found %q", fcj.Type)
+	}
+
+	fc := &FeatureCollection{
+		Features: make([]*Feature, 0, len(fcj.Features)),
+		BBox:     fcj.BBox,
+	}
+	for i, raw := range fcj.Features {
+		f, err := r.ReadFeature(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid feature at index %d", i)
+		}
+		fc.Features = append(fc.Features, f)
+	}
+	return fc, nil
+}
+
+// Writer encodes GeoJSON objects.
+type Writer struct {
+	// Precision is the number of decimal places written for coordinates,
+	// or -1 for full precision.
+	Precision int
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		Precision: -1,
+	}
+}
+
+// WriteGeometry encodes a geometry as a GeoJSON geometry object.
+func (w *Writer) WriteGeometry(g *geom.Geometry) ([]byte, error) {
+	return g.AppendGeoJSON(nil, w.Precision)
+}
+
+// WriteFeature encodes a Feature. A nil geometry is written as null.
+func (w *Writer) WriteFeature(f *Feature) ([]byte, error) {
+	fj := featureJSON{
+		Type:       "Feature",
+		BBox:       f.BBox,
+		Geometry:   json.RawMessage("null"),
+		Properties: f.Properties,
+	}
+	if f.ID != nil {
+		id, err := json.Marshal(f.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write Feature id")
+		}
+		fj.ID = id
+	}
+	if f.Geometry != nil {
+		g, err := w.WriteGeometry(f.Geometry)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write Feature geometry")
+		}
+		fj.Geometry = g
+	}
+	return json.Marshal(fj)
+}
+
+// WriteFeatureCollection encodes a FeatureCollection.
+func (w *Writer) WriteFeatureCollection(fc *FeatureCollection) ([]byte, error) {
+	fcj := featureCollectionJSON{
+		Type:     "FeatureCollection",
+		BBox:     fc.BBox,
+		Features: make([]json.RawMessage, 0, len(fc.Features)),
+	}
+	for i, f := range fc.Features {
+		data, err := w.WriteFeature(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write feature at index %d", i)
+		}
+		fcj.Features = append(fcj.Features, data)
+	}
+	return json.Marshal(fcj)
+}
@@ -0,0 +1,129 @@
+package geojson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// rfc7946Example is the example FeatureCollection of RFC 7946 section 1.5.
+const rfc7946Example = `{
+  "type": "FeatureCollection",
+  "features": [{
+    "type": "Feature",
+    "geometry": {"type": "Point", "coordinates": [102.0, 0.5]},
+    "properties": {"prop0": "value0"}
+  }, {
+    "type": "Feature",
+    "geometry": {
+      "type": "LineString",
+      "coordinates": [[102.0, 0.0], [103.0, 1.0], [104.0, 0.0], [105.0, 1.0]]
+    },
+    "properties": {"prop0": "value0", "prop1": 0.0}
+  }, {
+    "type": "Feature",
+    "geometry": {
+      "type": "Polygon",
+      "coordinates": [[[100.0, 0.0], [101.0, 0.0], [101.0, 1.0], [100.0, 1.0], [100.0, 0.0]]]
+    },
+    "properties": {"prop0": "value0", "prop1": {"this": "that"}}
+  }]
+}`
+
+func TestRFC7946Example(t *testing.T) {
+	var fc FeatureCollection
+	if err := json.Unmarshal([]byte(rfc7946Example), &fc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	wantTypes := []geom.Type{geom.TypePoint, geom.TypeLineString, geom.TypePolygon}
+	if len(fc.Features) != len(wantTypes) {
+		t.Fatalf("got %d features, want %d", len(fc.Features), len(wantTypes))
+	}
+	for i, f := range fc.Features {
+		if f.Geometry.Type != wantTypes[i] {
+			t.Errorf("feature %d geometry = %v, want %v", i, f.Geometry.Type, wantTypes[i])
+		}
+	}
+	if got := fc.Features[2].Properties["prop1"]; !reflect.DeepEqual(got, map[string]interface{}{"this": "that"}) {
+		t.Errorf("prop1 = %v", got)
+	}
+
+	want := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":[102,0.5]},"properties":{"prop0":"value0"}},` +
+		`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[102,0],[103,1],[104,0],[105,1]]},"properties":{"prop0":"value0","prop1":0}},` +
+		`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[100,0],[101,0],[101,1],[100,1],[100,0]]]},"properties":{"prop0":"value0","prop1":{"this":"that"}}}]}`
+	data, err := json.Marshal(&fc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+func TestFeatureID(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want interface{}
+	}{
+		{"missing", `{"type":"Feature","geometry":null,"properties":null}`, nil},
+		{"null", `{"type":"Feature","id":null,"geometry":null,"properties":null}`, nil},
+		{"string", `{"type":"Feature","id":"a1","geometry":null,"properties":null}`, "a1"},
+		{"integer", `{"type":"Feature","id":42,"geometry":null,"properties":null}`, int64(42)},
+		{"large integer", `{"type":"Feature","id":9007199254740993,"geometry":null,"properties":null}`, int64(9007199254740993)},
+		{"float", `{"type":"Feature","id":1.5,"geometry":null,"properties":null}`, 1.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := NewReader().ReadFeature([]byte(test.json))
+			if err != nil {
+				t.Fatalf("ReadFeature() error = %v", err)
+			}
+			if !reflect.DeepEqual(f.ID, test.want) {
+				t.Errorf("ID = %#v, want %#v", f.ID, test.want)
+			}
+			data, err := NewWriter().WriteFeature(f)
+			if err != nil {
+				t.Fatalf("WriteFeature() error = %v", err)
+			}
+			if test.name != "null" && string(data) != test.json {
+				t.Errorf("WriteFeature() = %s, want %s", data, test.json)
+			}
+		})
+	}
+}
+
+func TestReadFeatureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"not an object", `[]`},
+		{"wrong type", `{"type":"Point","coordinates":[1,2]}`},
+		{"boolean id", `{"type":"Feature","id":true,"geometry":null,"properties":null}`},
+		{"object id", `{"type":"Feature","id":{},"geometry":null,"properties":null}`},
+		{"invalid geometry", `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[1,2]]},"properties":null}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if f, err := NewReader().ReadFeature([]byte(test.json)); err == nil {
+				t.Errorf("ReadFeature() = %+v, want an error", f)
+			}
+		})
+	}
+}
+
+func TestReaderOrientation(t *testing.T) {
+	r := &Reader{Orientation: geom.OrientationRHR}
+	g, err := r.ReadGeometry([]byte(`{"type":"Polygon","coordinates":[[[0,0],[0,1],[1,1],[1,0],[0,0]]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := NewWriter().WriteGeometry(g)
+	if want := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`; string(data) != want {
+		t.Errorf("ReadGeometry() = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,251 @@
+package geojson
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// recordSeparator starts each record of a GeoJSON Text Sequence (RFC 8142).
+const recordSeparator = 0x1e
+
+// Format is the layout of a stream of features.
+type Format int
+
+const (
+	// FormatSequence writes a GeoJSON Text Sequence (RFC 8142): each
+	// feature is preceded by an ASCII record separator and followed by a
+	// line feed.
+	FormatSequence Format = iota
+	// FormatNDJSON writes newline-delimited features.
+	FormatNDJSON
+	// FormatFeatureCollection writes a single FeatureCollection object.
+	FormatFeatureCollection
+)
+
+type decoderState int
+
+const (
+	stateStart decoderState = iota
+	stateObject
+	stateFeatures
+	stateValues
+	stateDone
+)
+
+// Decoder reads features one at a time from a stream, holding only the
+// current feature in memory. The stream may be a GeoJSON Text Sequence,
+// newline-delimited (or otherwise concatenated) Features, or a single
+// FeatureCollection, which is read incrementally.
+type Decoder struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+
+	dec   *json.Decoder
+	state decoderState
+
+	// members of the top-level object other than "features"
+	members map[string]json.RawMessage
+}
+
+func NewDecoder(in io.Reader) *Decoder {
+	return &Decoder{
+		dec:     json.NewDecoder(&separatorFilter{in: in}),
+		members: map[string]json.RawMessage{},
+	}
+}
+
+// separatorFilter replaces record separators with whitespace, turning a
+// GeoJSON Text Sequence into a stream of concatenated JSON values.
+type separatorFilter struct {
+	in io.Reader
+}
+
+func (sf *separatorFilter) Read(p []byte) (int, error) {
+	n, err := sf.in.Read(p)
+	for i, b := range p[:n] {
+		if b == recordSeparator {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
+
+// Next returns the next feature in the stream, or io.EOF when there are no
+// more features. Next returns the context error if ctx is done.
+func (d *Decoder) Next(ctx context.Context) (*Feature, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		switch d.state {
+		case stateStart:
+			tok, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if tok != json.Delim('{') {
+				return nil, errors.Errorf("expected GeoJSON object, found %v", tok)
+			}
+			d.state = stateObject
+
+		case stateObject:
+			f, err := d.nextMember()
+			if err != nil || f != nil {
+				return f, err
+			}
+
+		case stateFeatures:
+			if d.dec.More() {
+				var raw json.RawMessage
+				if err := d.dec.Decode(&raw); err != nil {
+					return nil, errors.Wrap(err, "failed to read feature")
+				}
+				return d.reader().ReadFeature(raw)
+			}
+			if _, err := d.dec.Token(); err != nil {
+				return nil, errors.Wrap(err, "failed to read end of features")
+			}
+			d.state = stateObject
+
+		case stateValues:
+			var raw json.RawMessage
+			if err := d.dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			return d.reader().ReadFeature(raw)
+
+		case stateDone:
+			return nil, io.EOF
+		}
+	}
+}
+
+func (d *Decoder) reader() *Reader {
+	return &Reader{Orientation: d.Orientation}
+}
+
+// nextMember reads the next member of the top-level object. It returns a
+// feature if the object ends and it was a Feature rather than a
+// FeatureCollection.
+func (d *Decoder) nextMember() (*Feature, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read GeoJSON object")
+	}
+
+	if tok == json.Delim('}') {
+		var typ string
+		if raw, ok := d.members["type"]; ok {
+			if err := json.Unmarshal(raw, &typ); err != nil {
+				return nil, errors.Wrap(err, "invalid GeoJSON type")
+			}
+		}
+		switch typ {
+		case "FeatureCollection":
+			d.state = stateDone
+			return nil, io.EOF
+		case "Feature":
+			data, err := json.Marshal(d.members)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			d.members = map[string]json.RawMessage{}
+			d.state = stateValues
+			return d.reader().ReadFeature(data)
+		}
+		return nil, errors.Errorf("expected GeoJSON Feature or FeatureCollection, found %q", typ)
+	}
+
+	key, ok := tok.(string)
+	if !ok {
+		return nil, errors.Errorf("expected GeoJSON object key, found %v", tok)
+	}
+	if key == "features" {
+		if tok, err := d.dec.Token(); err != nil {
+			return nil, errors.Wrap(err, "failed to read features")
+		} else if tok != json.Delim('[') {
+			return nil, errors.Errorf("expected features array, found %v", tok)
+		}
+		d.members["type"] = json.RawMessage(`"FeatureCollection"`)
+		d.state = stateFeatures
+		return nil, nil
+	}
+
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to read member %q", key)
+	}
+	d.members[key] = raw
+	return nil, nil
+}
+
+// Encoder writes features one at a time to a stream.
+type Encoder struct {
+	// Precision is the number of decimal places written for coordinates,
+	// or -1 for full precision.
+	Precision int
+
+	out     *bufio.Writer
+	format  Format
+	started bool
+}
+
+func NewEncoder(out io.Writer, format Format) *Encoder {
+	return &Encoder{
+		Precision: -1,
+		out:       bufio.NewWriter(out),
+		format:    format,
+	}
+}
+
+// Encode writes a feature to the stream. Output is buffered, so Close must
+// be called once all features have been written.
+func (e *Encoder) Encode(ctx context.Context, f *Feature) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := (&Writer{Precision: e.Precision}).WriteFeature(f)
+	if err != nil {
+		return err
+	}
+
+	switch e.format {
+	case FormatSequence:
+		e.out.WriteByte(recordSeparator)
+	case FormatFeatureCollection:
+		if e.started {
+			e.out.WriteByte(',')
+		} else {
+			e.out.WriteString(`{"type":"FeatureCollection","features":[`)
+		}
+	}
+	e.started = true
+
+	// bufio.Writer errors are sticky, so checking the last write is enough
+	e.out.Write(data)
+	if e.format != FormatFeatureCollection {
+		err = e.out.WriteByte('\n')
+	} else {
+		_, err = e.out.Write(nil)
+	}
+	return errors.Wrap(err, "failed to write feature")
+}
+
+// Close completes the stream and flushes any buffered output.
+// It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.format == FormatFeatureCollection {
+		if !e.started {
+			e.out.WriteString(`{"type":"FeatureCollection","features":[`)
+		}
+		e.out.WriteString("]}\n")
+	}
+	return errors.Wrap(e.out.Flush(), "failed to flush features")
+}
@@ -0,0 +1,155 @@
+package geojson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func decodeAll(t *testing.T, in string) []*Feature {
+	t.Helper()
+	d := NewDecoder(strings.NewReader(in))
+	var fs []*Feature
+	for {
+		f, err := d.Next(context.Background())
+		if err == io.EOF {
+			return fs
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		fs = append(fs, f)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	const a = `{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"a"}}`
+	const b = `{"type":"Feature","id":2,"geometry":null,"properties":{"name":"b"}}`
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"text sequence", "\x1e" + a + "\n\x1e" + b + "\n"},
+		{"ndjson", a + "\n" + b + "\n"},
+		{"concatenated", a + b},
+		{"feature collection", `{"type":"FeatureCollection","features":[` + a + "," + b + `]}`},
+		{"features before type", `{"features":[` + a + "," + b + `],"bbox":[1,2,1,2],"type":"FeatureCollection"}`},
+		{"foreign members", `{"type":"FeatureCollection","name":"x","features":[` + a + "," + b + `],"crs":{"a":[1]}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := decodeAll(t, test.in)
+			if len(fs) != 2 {
+				t.Fatalf("got %d features, want 2", len(fs))
+			}
+			if fs[0].ID != int64(1) || fs[1].ID != int64(2) {
+				t.Errorf("ids = %v, %v", fs[0].ID, fs[1].ID)
+			}
+			if fs[0].Geometry == nil || fs[0].Geometry.Coord != (geom.Coordinate{X: 1, Y: 2}) {
+				t.Errorf("geometry = %v", fs[0].Geometry)
+			}
+			if fs[1].Geometry != nil || fs[1].Properties["name"] != "b" {
+				t.Errorf("second feature = %+v", fs[1])
+			}
+		})
+	}
+}
+
+func TestDecoderEmptyCollection(t *testing.T) {
+	if fs := decodeAll(t, `{"type":"FeatureCollection","features":[]}`); len(fs) != 0 {
+		t.Errorf("got %d features, want none", len(fs))
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"array", `[1,2]`},
+		{"geometry", `{"type":"Point","coordinates":[1,2]}`},
+		{"features not an array", `{"type":"FeatureCollection","features":{}}`},
+		{"invalid feature", `{"type":"FeatureCollection","features":[{"type":"Point"}]}`},
+		{"truncated", `{"type":"FeatureCollection","features":[{"type":"Feature"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(test.in))
+			for {
+				_, err := d.Next(context.Background())
+				if err == io.EOF {
+					t.Fatal("Next() = io.EOF, want an error")
+				}
+				if err != nil {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := NewDecoder(strings.NewReader(`{"type":"FeatureCollection","features":[]}`))
+	if _, err := d.Next(ctx); err != context.Canceled {
+		t.Errorf("Next() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	p, err := geom.NewPoint(geom.Coordinate{X: 1.123456, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := []*Feature{
+		{ID: "a", Geometry: p, Properties: map[string]interface{}{"n": 1.0}},
+		{ID: int64(7)},
+	}
+	const a = `{"type":"Feature","id":"a","geometry":{"type":"Point","coordinates":[1.12,2]},"properties":{"n":1}}`
+	const b = `{"type":"Feature","id":7,"geometry":null,"properties":null}`
+	tests := []struct {
+		name   string
+		format Format
+		fs     []*Feature
+		want   string
+	}{
+		{"text sequence", FormatSequence, fs, "\x1e" + a + "\n\x1e" + b + "\n"},
+		{"ndjson", FormatNDJSON, fs, a + "\n" + b + "\n"},
+		{"feature collection", FormatFeatureCollection, fs, `{"type":"FeatureCollection","features":[` + a + "," + b + "]}\n"},
+		{"empty feature collection", FormatFeatureCollection, nil, `{"type":"FeatureCollection","features":[]}` + "\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf, test.format)
+			e.Precision = 2
+			for _, f := range test.fs {
+				if err := e.Encode(context.Background(), f); err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if buf.String() != test.want {
+				t.Errorf("output = %q, want %q", buf.String(), test.want)
+			}
+
+			got := decodeAll(t, buf.String())
+			if len(got) != len(test.fs) {
+				t.Fatalf("decoded %d features, want %d", len(got), len(test.fs))
+			}
+			for i, f := range got {
+				if !reflect.DeepEqual(f.ID, test.fs[i].ID) {
+					t.Errorf("feature %d id = %#v, want %#v", i, f.ID, test.fs[i].ID)
+				}
+			}
+		})
+	}
+}
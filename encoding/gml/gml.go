@@ -0,0 +1,36 @@
+// Package gml reads and writes GML 3.2 simple features geometry elements.
+//
+// GML coordinates follow the axis order of their CRS, which for geographic
+// CRSs identified by URN or URI (e.g. urn:ogc:def:crs:EPSG::4326) is
+// latitude first. Geometries keep the axis order of the document unless
+// the Reader or Writer is asked to swap X and Y.
+package gml
+
+import (
+	"regexp"
+	"strconv"
+)
+
+const namespace = "http://www.opengis.net/gml/3.2"
+
+// DefaultSRSNameFormat formats the srsName of geometries with an SRID.
+const DefaultSRSNameFormat = "http://www.opengis.net/def/crs/EPSG/0/%d"
+
+// epsgCode matches the EPSG code at the end of the common srsName forms:
+// EPSG:4326, urn:ogc:def:crs:EPSG::4326, urn:ogc:def:crs:EPSG:6.6:4326,
+// http://www.opengis.net/def/crs/EPSG/0/4326 and
+// http://www.opengis.net/gml/srs/epsg.xml#4326.
+var epsgCode = regexp.MustCompile(`(?i)epsg(?:\.xml#|:[^:/]*:|:|/[^/]*/)(\d+)$`)
+
+// sridOf returns the EPSG code of an srsName, or 0 if it has none.
+func sridOf(srsName string) int {
+	m := epsgCode.FindStringSubmatch(srsName)
+	if m == nil {
+		return 0
+	}
+	srid, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return srid
+}
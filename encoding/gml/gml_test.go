@@ -0,0 +1,207 @@
+package gml
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+const ns = ` xmlns:gml="http://www.opengis.net/gml/3.2"`
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		text string
+		gml  string
+	}{
+		{
+			"POINT (1 2)",
+			`<gml:Point gml:id="geom.1"` + ns + `><gml:pos>1 2</gml:pos></gml:Point>`,
+		},
+		{
+			"SRID=4326;POINT Z (1 2 3)",
+			`<gml:Point gml:id="geom.1"` + ns + ` srsName="http://www.opengis.net/def/crs/EPSG/0/4326" srsDimension="3"><gml:pos>1 2 3</gml:pos></gml:Point>`,
+		},
+		{
+			"LINESTRING (1 2, 3 4)",
+			`<gml:LineString gml:id="geom.1"` + ns + `><gml:posList>1 2 3 4</gml:posList></gml:LineString>`,
+		},
+		{
+			"POLYGON ((0 0, 10 0, 10 10, 0 0), (1 1, 2 2, 2 1, 1 1))",
+			`<gml:Polygon gml:id="geom.1"` + ns + `>` +
+				`<gml:exterior><gml:LinearRing gml:id="geom.2"><gml:posList>0 0 10 0 10 10 0 0</gml:posList></gml:LinearRing></gml:exterior>` +
+				`<gml:interior><gml:LinearRing gml:id="geom.3"><gml:posList>1 1 2 2 2 1 1 1</gml:posList></gml:LinearRing></gml:interior>` +
+				`</gml:Polygon>`,
+		},
+		{
+			"MULTIPOINT ((1 2), (3 4))",
+			`<gml:MultiPoint gml:id="geom.1"` + ns + `>` +
+				`<gml:pointMember><gml:Point gml:id="geom.2"><gml:pos>1 2</gml:pos></gml:Point></gml:pointMember>` +
+				`<gml:pointMember><gml:Point gml:id="geom.3"><gml:pos>3 4</gml:pos></gml:Point></gml:pointMember>` +
+				`</gml:MultiPoint>`,
+		},
+		{
+			"MULTILINESTRING ((1 2, 3 4))",
+			`<gml:MultiCurve gml:id="geom.1"` + ns + `>` +
+				`<gml:curveMember><gml:LineString gml:id="geom.2"><gml:posList>1 2 3 4</gml:posList></gml:LineString></gml:curveMember>` +
+				`</gml:MultiCurve>`,
+		},
+		{
+			"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)))",
+			`<gml:MultiSurface gml:id="geom.1"` + ns + `>` +
+				`<gml:surfaceMember><gml:Polygon gml:id="geom.2"><gml:exterior><gml:LinearRing gml:id="geom.3"><gml:posList>0 0 1 0 1 1 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>` +
+				`</gml:MultiSurface>`,
+		},
+		{
+			"GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (1 2, 3 4))",
+			`<gml:MultiGeometry gml:id="geom.1"` + ns + `>` +
+				`<gml:geometryMember><gml:Point gml:id="geom.2"><gml:pos>1 2</gml:pos></gml:Point></gml:geometryMember>` +
+				`<gml:geometryMember><gml:LineString gml:id="geom.3"><gml:posList>1 2 3 4</gml:posList></gml:LineString></gml:geometryMember>` +
+				`</gml:MultiGeometry>`,
+		},
+		{
+			"POINT EMPTY",
+			`<gml:Point gml:id="geom.1"` + ns + `></gml:Point>`,
+		},
+	}
+	ewkt := &wkt.Writer{Precision: -1, TrimZeros: true, EWKT: true}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			g, err := wkt.Unmarshal(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := Marshal(g)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != test.gml {
+				t.Errorf("Marshal() = %s, want %s", data, test.gml)
+			}
+			g, err = Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			want, _ := ewkt.Write(mustUnmarshal(t, test.text))
+			if got, _ := ewkt.Write(g); got != want {
+				t.Errorf("Unmarshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalVariants(t *testing.T) {
+	tests := []struct {
+		name string
+		gml  string
+		want string
+	}{
+		{
+			"urn srsName",
+			`<gml:Point srsName="urn:ogc:def:crs:EPSG::4326"` + ns + `><gml:pos>51.5 -0.1</gml:pos></gml:Point>`,
+			"SRID=4326;POINT (51.5 -0.1)",
+		},
+		{
+			"short srsName",
+			`<gml:Point srsName="EPSG:27700"` + ns + `><gml:pos>1 2</gml:pos></gml:Point>`,
+			"SRID=27700;POINT (1 2)",
+		},
+		{
+			"GML 2 coordinates",
+			`<gml:LineString` + ns + `><gml:coordinates>1,2 3,4</gml:coordinates></gml:LineString>`,
+			"LINESTRING (1 2, 3 4)",
+		},
+		{
+			"pos elements",
+			`<gml:LineString` + ns + `><gml:pos>1 2</gml:pos><gml:pos>3 4</gml:pos></gml:LineString>`,
+			"LINESTRING (1 2, 3 4)",
+		},
+		{
+			"srsDimension on posList",
+			`<gml:LineString` + ns + `><gml:posList srsDimension="3">1 2 3 4 5 6</gml:posList></gml:LineString>`,
+			"LINESTRING Z (1 2 3, 4 5 6)",
+		},
+		{
+			"GML 2 polygon",
+			`<gml:Polygon` + ns + `><gml:outerBoundaryIs><gml:LinearRing><gml:coordinates>0,0 1,0 1,1 0,0</gml:coordinates></gml:LinearRing></gml:outerBoundaryIs></gml:Polygon>`,
+			"POLYGON ((0 0, 1 0, 1 1, 0 0))",
+		},
+		{
+			"deprecated MultiPolygon",
+			`<gml:MultiPolygon` + ns + `><gml:polygonMember><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>0 0 1 0 1 1 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:polygonMember></gml:MultiPolygon>`,
+			"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)))",
+		},
+		{
+			"surfaceMembers",
+			`<gml:MultiSurface` + ns + `><gml:surfaceMembers><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>0 0 1 0 1 1 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>` +
+				`<gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>5 5 6 5 6 6 5 5</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMembers></gml:MultiSurface>`,
+			"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 5)))",
+		},
+	}
+	ewkt := &wkt.Writer{Precision: -1, TrimZeros: true, EWKT: true}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := Unmarshal([]byte(test.gml))
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			want, _ := ewkt.Write(mustUnmarshal(t, test.want))
+			if got, _ := ewkt.Write(g); got != want {
+				t.Errorf("Unmarshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		gml  string
+	}{
+		{"empty", ""},
+		{"unsupported", `<gml:Curve` + ns + `></gml:Curve>`},
+		{"invalid number", `<gml:Point` + ns + `><gml:pos>1 x</gml:pos></gml:Point>`},
+		{"short pos", `<gml:Point` + ns + `><gml:pos>1</gml:pos></gml:Point>`},
+		{"odd posList", `<gml:LineString` + ns + `><gml:posList>1 2 3</gml:posList></gml:LineString>`},
+		{"srsDimension", `<gml:Point srsDimension="4"` + ns + `><gml:pos>1 2 3 4</gml:pos></gml:Point>`},
+		{"unclosed ring", `<gml:Polygon` + ns + `><gml:exterior><gml:LinearRing><gml:posList>0 0 1 0 1 1 0 1</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`},
+		{"interior without exterior", `<gml:Polygon` + ns + `><gml:interior><gml:LinearRing><gml:posList>0 0 1 0 1 1 0 0</gml:posList></gml:LinearRing></gml:interior></gml:Polygon>`},
+		{"wrong member type", `<gml:MultiPoint` + ns + `><gml:pointMember><gml:LineString><gml:posList>1 2 3 4</gml:posList></gml:LineString></gml:pointMember></gml:MultiPoint>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if g, err := Unmarshal([]byte(test.gml)); err == nil {
+				t.Errorf("Unmarshal() = %v, want an error", g)
+			}
+		})
+	}
+}
+
+func TestSwapXY(t *testing.T) {
+	g := mustUnmarshal(t, "SRID=4326;POINT (-0.1 51.5)")
+	w := &Writer{Precision: -1, SRSNameFormat: "urn:ogc:def:crs:EPSG::%d", SwapXY: true}
+	data, err := w.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<gml:Point` + ns + ` srsName="urn:ogc:def:crs:EPSG::4326"><gml:pos>51.5 -0.1</gml:pos></gml:Point>`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+	g, err = (&Reader{SwapXY: true}).Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := wkt.Marshal(g); text != "POINT (-0.1 51.5)" {
+		t.Errorf("Read() = %s", text)
+	}
+}
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
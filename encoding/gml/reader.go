@@ -0,0 +1,326 @@
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader decodes GML geometries.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+	// SwapXY swaps the first two axes of all coordinates read, e.g. to read
+	// latitude, longitude data with longitude as X.
+	SwapXY bool
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unmarshal decodes a GML geometry element using the default Reader.
+func Unmarshal(data []byte) (*geom.Geometry, error) {
+	return NewReader().Read(data)
+}
+
+// Read decodes a GML geometry element: Point, LineString, LinearRing,
+// Polygon, MultiPoint, MultiCurve, MultiSurface or MultiGeometry, as well
+// as the deprecated MultiLineString and MultiPolygon. The SRID is taken
+// from the EPSG code of the srsName of the element.
+func (r *Reader) Read(data []byte) (*geom.Geometry, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root *node
+	for root == nil {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find GML geometry")
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if root, err = readNode(d, start); err != nil {
+				return nil, errors.Wrap(err, "invalid GML")
+			}
+		}
+	}
+
+	dec := &decoder{swapXY: r.SwapXY}
+	g, err := dec.geometry(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	setLayout(g, geom.NewLayout(dec.hasZ, false))
+	g.SRID = sridOf(root.attr("srsName"))
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, nil
+}
+
+func setLayout(g *geom.Geometry, layout geom.Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setLayout(col, layout)
+	}
+}
+
+// node is a parsed XML element. Only local names are kept, so any GML
+// namespace is accepted.
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	children []*node
+	text     string
+}
+
+func readNode(d *xml.Decoder, start xml.StartElement) (*node, error) {
+	n := &node{name: start.Name.Local, attrs: start.Attr}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readNode(d, t)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n.text = text.String()
+			return n, nil
+		}
+	}
+}
+
+func (n *node) attr(name string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// decoder decodes a geometry, recording whether any coordinate has Z so
+// that the whole geometry can be given a consistent layout.
+type decoder struct {
+	swapXY bool
+	hasZ   bool
+}
+
+// dimension returns the srsDimension of an element, inheriting that of its
+// parent if it has none.
+func dimension(n *node, parent int) (int, error) {
+	s := n.attr("srsDimension")
+	if s == "" {
+		return parent, nil
+	}
+	dim, err := strconv.Atoi(s)
+	if err != nil || dim < 2 || dim > 3 {
+		return 0, errors.Errorf("unsupported GML srsDimension %q", s)
+	}
+	return dim, nil
+}
+
+func (dec *decoder) geometry(n *node, dim int) (*geom.Geometry, error) {
+	dim, err := dimension(n, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.name {
+	case "Point":
+		cs, err := dec.positions(n, dim)
+		if err != nil {
+			return nil, err
+		}
+		switch len(cs) {
+		case 0:
+			return geom.NewEmptyPoint()
+		case 1:
+			return geom.NewPoint(cs[0])
+		}
+		return nil, errors.Errorf("GML Point has %d positions", len(cs))
+	case "LineString", "LinearRing":
+		cs, err := dec.positions(n, dim)
+		if err != nil {
+			return nil, err
+		}
+		return geom.NewLineString(cs)
+	case "Polygon":
+		return dec.polygon(n, dim)
+	}
+
+	// members of a MultiGeometry may have any type
+	memberType := geom.TypeCollection
+	switch n.name {
+	case "MultiPoint":
+		memberType = geom.TypePoint
+	case "MultiCurve", "MultiLineString":
+		memberType = geom.TypeLineString
+	case "MultiSurface", "MultiPolygon":
+		memberType = geom.TypePolygon
+	case "MultiGeometry":
+	default:
+		return nil, errors.Errorf("unsupported GML geometry: %s", n.name)
+	}
+
+	var collection []*geom.Geometry
+	for _, member := range n.children {
+		if !strings.HasSuffix(member.name, "Member") && !strings.HasSuffix(member.name, "Members") {
+			continue
+		}
+		memberDim, err := dimension(member, dim)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range member.children {
+			g, err := dec.geometry(child, memberDim)
+			if err != nil {
+				return nil, err
+			}
+			if memberType != geom.TypeCollection && g.Type != memberType {
+				return nil, errors.Errorf("GML %s may not contain %v", n.name, g.Type)
+			}
+			collection = append(collection, g)
+		}
+	}
+
+	switch memberType {
+	case geom.TypePoint:
+		return geom.NewMultiPoint(collection)
+	case geom.TypeLineString:
+		return geom.NewMultiLineString(collection)
+	case geom.TypePolygon:
+		return geom.NewMultiPolygon(collection)
+	}
+	return geom.NewCollection(collection)
+}
+
+func (dec *decoder) polygon(n *node, dim int) (*geom.Geometry, error) {
+	var shell geom.Coordinates
+	var holes geom.MultiLine
+	for _, boundary := range n.children {
+		exterior := boundary.name == "exterior" || boundary.name == "outerBoundaryIs"
+		if !exterior && boundary.name != "interior" && boundary.name != "innerBoundaryIs" {
+			continue
+		}
+		ring := boundary.child("LinearRing")
+		if ring == nil {
+			return nil, errors.Errorf("GML %s has no LinearRing", boundary.name)
+		}
+		ringDim, err := dimension(ring, dim)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := dec.positions(ring, ringDim)
+		if err != nil {
+			return nil, err
+		}
+		if len(cs) < 4 || !cs[0].Equals2D(cs[len(cs)-1]) {
+			return nil, errors.New("GML LinearRing must be closed and have at least 4 positions")
+		}
+		if exterior {
+			shell = cs
+		} else {
+			holes = append(holes, cs)
+		}
+	}
+	if shell == nil && len(holes) > 0 {
+		return nil, errors.New("GML Polygon has interior rings but no exterior")
+	}
+	return geom.NewPolygon(shell, holes)
+}
+
+// positions reads the coordinates of an element from its posList, pos or
+// (GML 2) coordinates children. Positions without a known dimension have
+// 2 values, except that a pos may have 3.
+func (dec *decoder) positions(n *node, dim int) (geom.Coordinates, error) {
+	var cs geom.Coordinates
+	for _, c := range n.children {
+		var values []float64
+		var err error
+		switch c.name {
+		case "posList":
+			listDim, err := dimension(c, dim)
+			if err != nil {
+				return nil, err
+			}
+			if listDim == 0 {
+				listDim = 2
+			}
+			if values, err = parseValues(c.text); err != nil {
+				return nil, err
+			}
+			if len(values)%listDim != 0 {
+				return nil, errors.Errorf("GML posList has %d values, which is not a multiple of its dimension %d", len(values), listDim)
+			}
+			for i := 0; i < len(values); i += listDim {
+				cs = append(cs, dec.coord(values[i:i+listDim]))
+			}
+		case "pos":
+			if values, err = parseValues(c.text); err != nil {
+				return nil, err
+			}
+			if posDim, _ := dimension(c, dim); posDim != 0 && len(values) != posDim || len(values) < 2 || len(values) > 3 {
+				return nil, errors.Errorf("GML pos has %d values", len(values))
+			}
+			cs = append(cs, dec.coord(values))
+		case "coordinates":
+			for _, tuple := range strings.Fields(c.text) {
+				if values, err = parseValues(strings.Replace(tuple, ",", " ", -1)); err != nil {
+					return nil, err
+				}
+				if len(values) < 2 || len(values) > 3 {
+					return nil, errors.Errorf("invalid GML coordinates tuple %q", tuple)
+				}
+				cs = append(cs, dec.coord(values))
+			}
+		}
+	}
+	return cs, nil
+}
+
+func parseValues(s string) ([]float64, error) {
+	fields := strings.Fields(s)
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid GML number %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (dec *decoder) coord(values []float64) geom.Coordinate {
+	c := geom.Coordinate{X: values[0], Y: values[1]}
+	if dec.swapXY {
+		c.X, c.Y = c.Y, c.X
+	}
+	if len(values) == 3 {
+		c.Z = values[2]
+		dec.hasZ = true
+	}
+	return c
+}
@@ -0,0 +1,186 @@
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer encodes geometries as GML 3.2.
+type Writer struct {
+	// Precision is the number of decimal places written, or -1 to write the
+	// fewest digits which represent each value exactly. Trailing zeros are
+	// trimmed.
+	Precision int
+	// SRSNameFormat formats the srsName written for geometries with an
+	// SRID, given the SRID.
+	SRSNameFormat string
+	// IDPrefix, if set, is used to give every geometry element the gml:id
+	// required by the GML 3.2 schema: the prefix followed by a sequence
+	// number.
+	IDPrefix string
+	// SwapXY swaps X and Y of all coordinates written, e.g. to write
+	// latitude first for a CRS with that axis order.
+	SwapXY bool
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		Precision:     -1,
+		SRSNameFormat: DefaultSRSNameFormat,
+		IDPrefix:      "geom.",
+	}
+}
+
+// Marshal encodes a geometry as a GML element using the default Writer.
+func Marshal(g *geom.Geometry) ([]byte, error) {
+	return NewWriter().Marshal(g)
+}
+
+// Marshal encodes a geometry as a GML element, declaring the GML namespace.
+// MultiLineStrings are written as MultiCurve, MultiPolygons as MultiSurface
+// and collections as MultiGeometry. M values are dropped.
+func (w *Writer) Marshal(g *geom.Geometry) ([]byte, error) {
+	enc := &encoder{w: w, hasZ: g.Layout.HasZ()}
+
+	attrs := ` xmlns:gml="` + namespace + `"`
+	if g.SRID != 0 {
+		attrs += ` srsName="` + escape(fmt.Sprintf(w.SRSNameFormat, g.SRID)) + `"`
+	}
+	if enc.hasZ {
+		attrs += ` srsDimension="3"`
+	}
+	if err := enc.geometry(g, attrs); err != nil {
+		return nil, err
+	}
+	return enc.buf.Bytes(), nil
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	// writing to a strings.Builder cannot fail
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+type encoder struct {
+	w    *Writer
+	buf  bytes.Buffer
+	hasZ bool
+	ids  int
+}
+
+// start writes the start tag of a geometry element with its gml:id.
+func (enc *encoder) start(name, attrs string) {
+	enc.buf.WriteString("<gml:" + name)
+	if enc.w.IDPrefix != "" {
+		enc.ids++
+		enc.buf.WriteString(` gml:id="` + escape(enc.w.IDPrefix) + strconv.Itoa(enc.ids) + `"`)
+	}
+	enc.buf.WriteString(attrs + ">")
+}
+
+func (enc *encoder) end(name string) {
+	enc.buf.WriteString("</gml:" + name + ">")
+}
+
+func (enc *encoder) geometry(g *geom.Geometry, attrs string) error {
+	switch g.Type {
+	case geom.TypePoint:
+		enc.start("Point", attrs)
+		if !g.IsEmpty() {
+			enc.buf.WriteString("<gml:pos>")
+			enc.coords(geom.Coordinates{g.Coord})
+			enc.buf.WriteString("</gml:pos>")
+		}
+		enc.end("Point")
+	case geom.TypeLineString:
+		enc.start("LineString", attrs)
+		enc.posList(g.Line)
+		enc.end("LineString")
+	case geom.TypePolygon:
+		enc.start("Polygon", attrs)
+		if !g.IsEmpty() {
+			enc.ring("exterior", g.Line)
+			for _, hole := range g.MultiLine {
+				enc.ring("interior", hole)
+			}
+		}
+		enc.end("Polygon")
+	case geom.TypeMultiPoint, geom.TypeMultiLineString, geom.TypeMultiPolygon, geom.TypeCollection:
+		name, member := "MultiGeometry", "geometryMember"
+		switch g.Type {
+		case geom.TypeMultiPoint:
+			name, member = "MultiPoint", "pointMember"
+		case geom.TypeMultiLineString:
+			name, member = "MultiCurve", "curveMember"
+		case geom.TypeMultiPolygon:
+			name, member = "MultiSurface", "surfaceMember"
+		}
+		enc.start(name, attrs)
+		for _, col := range g.Collection {
+			enc.buf.WriteString("<gml:" + member + ">")
+			if err := enc.geometry(col, ""); err != nil {
+				return err
+			}
+			enc.buf.WriteString("</gml:" + member + ">")
+		}
+		enc.end(name)
+	default:
+		return errors.Errorf("unsupported geometry type for GML: %v", g.Type)
+	}
+	return nil
+}
+
+func (enc *encoder) ring(boundary string, ring geom.Coordinates) {
+	enc.buf.WriteString("<gml:" + boundary + ">")
+	enc.start("LinearRing", "")
+	enc.posList(ring)
+	enc.end("LinearRing")
+	enc.buf.WriteString("</gml:" + boundary + ">")
+}
+
+func (enc *encoder) posList(cs geom.Coordinates) {
+	if len(cs) == 0 {
+		return
+	}
+	enc.buf.WriteString("<gml:posList>")
+	enc.coords(cs)
+	enc.buf.WriteString("</gml:posList>")
+}
+
+func (enc *encoder) coords(cs geom.Coordinates) {
+	for i, c := range cs {
+		if i > 0 {
+			enc.buf.WriteByte(' ')
+		}
+		x, y := c.X, c.Y
+		if enc.w.SwapXY {
+			x, y = y, x
+		}
+		enc.buf.WriteString(enc.w.formatFloat(x))
+		enc.buf.WriteByte(' ')
+		enc.buf.WriteString(enc.w.formatFloat(y))
+		if enc.hasZ {
+			enc.buf.WriteByte(' ')
+			enc.buf.WriteString(enc.w.formatFloat(c.Z))
+		}
+	}
+}
+
+func (w *Writer) formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', w.Precision, 64)
+	if w.Precision > 0 {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+// Package kml reads and writes KML geometries and the Placemarks which hold
+// them. KML coordinates are longitude, latitude and optional altitude,
+// mapped to X, Y and Z.
+package kml
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+const namespace = "http://www.opengis.net/kml/2.2"
+
+// Placemark is a KML feature with a geometry.
+type Placemark struct {
+	ID          string
+	Name        string
+	Description string
+	// Data holds the ExtendedData of the Placemark, both untyped Data and
+	// SimpleData of a schema.
+	Data     map[string]string
+	Geometry *geom.Geometry
+}
+
+// tupleSeparator matches the commas within coordinate tuples, which some
+// writers surround with spaces.
+var tupleSeparator = regexp.MustCompile(`\s*,\s*`)
+
+// parseCoordinates parses the content of a coordinates element, returning
+// whether any tuple has an altitude.
+func parseCoordinates(s string) (geom.Coordinates, bool, error) {
+	var cs geom.Coordinates
+	hasZ := false
+	for _, tuple := range strings.Fields(tupleSeparator.ReplaceAllString(s, ",")) {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 || len(values) > 3 {
+			return nil, false, errors.Errorf("invalid KML coordinate tuple %q", tuple)
+		}
+		var ords [3]float64
+		for i, v := range values {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, false, errors.Errorf("invalid KML coordinate tuple %q", tuple)
+			}
+			ords[i] = f
+		}
+		hasZ = hasZ || len(values) == 3
+		cs = append(cs, geom.Coordinate{X: ords[0], Y: ords[1], Z: ords[2]})
+	}
+	return cs, hasZ, nil
+}
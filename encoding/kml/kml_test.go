@@ -0,0 +1,223 @@
+package kml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		text string
+		kml  string
+	}{
+		{
+			"POINT (-122.0822035425683 37.42228990140251)",
+			"<Point><coordinates>-122.0822035425683,37.42228990140251</coordinates></Point>",
+		},
+		{
+			"POINT Z (1 2 3)",
+			"<Point><coordinates>1,2,3</coordinates></Point>",
+		},
+		{
+			"LINESTRING (-112.2550785337791 36.07954952145647, -112.2549277039738 36.08117083492122)",
+			"<LineString><coordinates>-112.2550785337791,36.07954952145647 -112.2549277039738,36.08117083492122</coordinates></LineString>",
+		},
+		{
+			"POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2), (3 3, 3 4, 4 4, 3 3))",
+			"<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 10,0 10,10 0,10 0,0</coordinates></LinearRing></outerBoundaryIs>" +
+				"<innerBoundaryIs><LinearRing><coordinates>2,2 2,8 8,8 8,2 2,2</coordinates></LinearRing></innerBoundaryIs>" +
+				"<innerBoundaryIs><LinearRing><coordinates>3,3 3,4 4,4 3,3</coordinates></LinearRing></innerBoundaryIs></Polygon>",
+		},
+		{
+			"MULTIPOINT ((1 2), (3 4))",
+			"<MultiGeometry><Point><coordinates>1,2</coordinates></Point><Point><coordinates>3,4</coordinates></Point></MultiGeometry>",
+		},
+		{
+			"GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (1 2, 3 4))",
+			"<MultiGeometry><Point><coordinates>1,2</coordinates></Point><LineString><coordinates>1,2 3,4</coordinates></LineString></MultiGeometry>",
+		},
+		{
+			"POINT EMPTY",
+			"<Point></Point>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			g, err := wkt.Unmarshal(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := Marshal(g)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != test.kml {
+				t.Errorf("Marshal() = %s, want %s", data, test.kml)
+			}
+			g, err = Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if text, _ := wkt.Marshal(g); text != test.text {
+				t.Errorf("Unmarshal() = %s, want %s", text, test.text)
+			}
+		})
+	}
+}
+
+func TestUnmarshalVariants(t *testing.T) {
+	tests := []struct {
+		name string
+		kml  string
+		want string
+	}{
+		{
+			"spaces around commas and newlines",
+			"<LineString><tessellate>1</tessellate><coordinates>\n  1 , 2\n\t3,4 \n</coordinates></LineString>",
+			"LINESTRING (1 2, 3 4)",
+		},
+		{
+			"mixed altitudes",
+			"<LineString><coordinates>1,2 3,4,5</coordinates></LineString>",
+			"LINESTRING Z (1 2 0, 3 4 5)",
+		},
+		{
+			"linear ring",
+			"<LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing>",
+			"LINESTRING (0 0, 1 0, 1 1, 0 0)",
+		},
+		{
+			"several rings in one inner boundary",
+			"<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 10,0 10,10 0,0</coordinates></LinearRing></outerBoundaryIs>" +
+				"<innerBoundaryIs><LinearRing><coordinates>5,1 6,1 6,2 5,1</coordinates></LinearRing>" +
+				"<LinearRing><coordinates>7,1 8,1 8,2 7,1</coordinates></LinearRing></innerBoundaryIs></Polygon>",
+			"POLYGON ((0 0, 10 0, 10 10, 0 0), (5 1, 6 1, 6 2, 5 1), (7 1, 8 1, 8 2, 7 1))",
+		},
+		{
+			"nested multi geometry",
+			"<MultiGeometry><MultiGeometry><Point><coordinates>1,2</coordinates></Point></MultiGeometry></MultiGeometry>",
+			"GEOMETRYCOLLECTION (MULTIPOINT ((1 2)))",
+		},
+		{
+			"namespaced",
+			`<kml:Point xmlns:kml="http://www.opengis.net/kml/2.2"><kml:coordinates>1,2</kml:coordinates></kml:Point>`,
+			"POINT (1 2)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := Unmarshal([]byte(test.kml))
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if text, _ := wkt.Marshal(g); text != test.want {
+				t.Errorf("Unmarshal() = %s, want %s", text, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		kml  string
+	}{
+		{"empty", ""},
+		{"unsupported", "<Model></Model>"},
+		{"short tuple", "<Point><coordinates>1</coordinates></Point>"},
+		{"long tuple", "<Point><coordinates>1,2,3,4</coordinates></Point>"},
+		{"invalid number", "<Point><coordinates>1,x</coordinates></Point>"},
+		{"several point coordinates", "<Point><coordinates>1,2 3,4</coordinates></Point>"},
+		{"unclosed ring", "<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,1</coordinates></LinearRing></outerBoundaryIs></Polygon>"},
+		{"inner without outer", "<Polygon><innerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing></innerBoundaryIs></Polygon>"},
+		{"truncated", "<MultiGeometry><Point><coordinates>1,2</coordinates></Point>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if g, err := Unmarshal([]byte(test.kml)); err == nil {
+				t.Errorf("Unmarshal() = %v, want an error", g)
+			}
+		})
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	g, err := wkt.Unmarshal("LINESTRING Z (1.23456 2.5 3, 4 -0.0001 6)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Writer{Precision: 2, AltitudeMode: "absolute"}
+	data, err := w.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<LineString><altitudeMode>absolute</altitudeMode><coordinates>1.23,2.5,3 4,0,6</coordinates></LineString>"; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+// documentExample is adapted from the Placemark examples of the KML
+// reference.
+const documentExample = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <Folder>
+      <Placemark id="p1">
+        <name>Google Inc.</name>
+        <description><![CDATA[1600 Amphitheatre Parkway & more]]></description>
+        <ExtendedData>
+          <Data name="holeNumber"><value>1</value></Data>
+          <SchemaData schemaUrl="#TrailHeadTypeId">
+            <SimpleData name="TrailHeadName">Pi in the sky</SimpleData>
+          </SchemaData>
+        </ExtendedData>
+        <Point>
+          <coordinates>-122.0822035425683,37.42228990140251,0</coordinates>
+        </Point>
+      </Placemark>
+    </Folder>
+    <Placemark>
+      <name>No geometry</name>
+      <Style><LineStyle><width>4</width></LineStyle></Style>
+    </Placemark>
+  </Document>
+</kml>`
+
+func TestPlacemarks(t *testing.T) {
+	placemarks, err := NewReader().ReadPlacemarks(strings.NewReader(documentExample))
+	if err != nil {
+		t.Fatalf("ReadPlacemarks() error = %v", err)
+	}
+	if len(placemarks) != 2 {
+		t.Fatalf("got %d Placemarks, want 2", len(placemarks))
+	}
+	p := placemarks[0]
+	if p.ID != "p1" || p.Name != "Google Inc." || p.Description != "1600 Amphitheatre Parkway & more" {
+		t.Errorf("Placemark = %+v", p)
+	}
+	if want := map[string]string{"holeNumber": "1", "TrailHeadName": "Pi in the sky"}; !reflect.DeepEqual(p.Data, want) {
+		t.Errorf("Data = %v, want %v", p.Data, want)
+	}
+	if text, _ := wkt.Marshal(p.Geometry); text != "POINT Z (-122.0822035425683 37.42228990140251 0)" {
+		t.Errorf("Geometry = %s", text)
+	}
+	if placemarks[1].Geometry != nil || placemarks[1].Name != "No geometry" {
+		t.Errorf("second Placemark = %+v", placemarks[1])
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter().WriteDocument(&buf, placemarks); err != nil {
+		t.Fatalf("WriteDocument() error = %v", err)
+	}
+	got, err := NewReader().ReadPlacemarks(&buf)
+	if err != nil {
+		t.Fatalf("ReadPlacemarks() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, placemarks) {
+		t.Errorf("round trip = %+v, want %+v", got, placemarks)
+	}
+}
@@ -0,0 +1,285 @@
+package kml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader decodes KML.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unmarshal decodes a KML geometry element using the default Reader.
+func Unmarshal(data []byte) (*geom.Geometry, error) {
+	return NewReader().Read(data)
+}
+
+// Read decodes a KML geometry element: Point, LineString, LinearRing,
+// Polygon or MultiGeometry. A homogeneous MultiGeometry is read as the
+// matching multi geometry, and any other as a GeometryCollection.
+func (r *Reader) Read(data []byte) (*geom.Geometry, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find KML geometry")
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return r.readGeometry(d, start)
+		}
+	}
+}
+
+// ReadPlacemarks decodes all Placemarks of a KML document, in any Document
+// or Folder. Placemarks without a geometry have a nil Geometry.
+func (r *Reader) ReadPlacemarks(in io.Reader) ([]*Placemark, error) {
+	d := xml.NewDecoder(in)
+	var placemarks []*Placemark
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return placemarks, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid KML")
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "Placemark" {
+			p, err := r.readPlacemark(d, start)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid Placemark %d", len(placemarks))
+			}
+			placemarks = append(placemarks, p)
+		}
+	}
+}
+
+func (r *Reader) readPlacemark(d *xml.Decoder, start xml.StartElement) (*Placemark, error) {
+	p := &Placemark{ID: attr(start, "id")}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return p, nil
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				err = d.DecodeElement(&p.Name, &t)
+			case "description":
+				err = d.DecodeElement(&p.Description, &t)
+			case "ExtendedData":
+				p.Data, err = readExtendedData(d, t)
+			case "Point", "LineString", "LinearRing", "Polygon", "MultiGeometry":
+				p.Geometry, err = r.readGeometry(d, t)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+}
+
+func readExtendedData(d *xml.Decoder, start xml.StartElement) (map[string]string, error) {
+	var e struct {
+		Data []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"Data"`
+		SchemaData []struct {
+			SimpleData []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"SimpleData"`
+		} `xml:"SchemaData"`
+	}
+	if err := d.DecodeElement(&e, &start); err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	for _, v := range e.Data {
+		data[v.Name] = v.Value
+	}
+	for _, sd := range e.SchemaData {
+		for _, v := range sd.SimpleData {
+			data[v.Name] = v.Value
+		}
+	}
+	return data, nil
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (r *Reader) readGeometry(d *xml.Decoder, start xml.StartElement) (*geom.Geometry, error) {
+	dec := &decoder{d: d}
+	g, err := dec.geometry(start)
+	if err != nil {
+		return nil, err
+	}
+	setLayout(g, geom.NewLayout(dec.hasZ, false))
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, nil
+}
+
+func setLayout(g *geom.Geometry, layout geom.Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setLayout(col, layout)
+	}
+}
+
+// decoder decodes a geometry, recording whether any coordinate has an
+// altitude so that the whole geometry can be given a consistent layout.
+type decoder struct {
+	d    *xml.Decoder
+	hasZ bool
+}
+
+type coordinatesElement struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+func (dec *decoder) coordinates(s string) (geom.Coordinates, error) {
+	cs, hasZ, err := parseCoordinates(s)
+	dec.hasZ = dec.hasZ || hasZ
+	return cs, err
+}
+
+func (dec *decoder) geometry(start xml.StartElement) (*geom.Geometry, error) {
+	switch start.Name.Local {
+	case "Point":
+		var e coordinatesElement
+		if err := dec.d.DecodeElement(&e, &start); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cs, err := dec.coordinates(e.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		switch len(cs) {
+		case 0:
+			return geom.NewEmptyPoint()
+		case 1:
+			return geom.NewPoint(cs[0])
+		}
+		return nil, errors.Errorf("KML Point has %d coordinates", len(cs))
+	case "LineString", "LinearRing":
+		var e coordinatesElement
+		if err := dec.d.DecodeElement(&e, &start); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cs, err := dec.coordinates(e.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return geom.NewLineString(cs)
+	case "Polygon":
+		return dec.polygon(start)
+	case "MultiGeometry":
+		return dec.multiGeometry()
+	}
+	return nil, errors.Errorf("unsupported KML geometry: %s", start.Name.Local)
+}
+
+func (dec *decoder) polygon(start xml.StartElement) (*geom.Geometry, error) {
+	var e struct {
+		Outer struct {
+			Ring coordinatesElement `xml:"LinearRing"`
+		} `xml:"outerBoundaryIs"`
+		// KML allows a single ring per innerBoundaryIs, but several are
+		// common in the wild
+		Inner []struct {
+			Rings []coordinatesElement `xml:"LinearRing"`
+		} `xml:"innerBoundaryIs"`
+	}
+	if err := dec.d.DecodeElement(&e, &start); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	shell, err := dec.coordinates(e.Outer.Ring.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+	var holes geom.MultiLine
+	for _, inner := range e.Inner {
+		for _, ring := range inner.Rings {
+			hole, err := dec.coordinates(ring.Coordinates)
+			if err != nil {
+				return nil, err
+			}
+			holes = append(holes, hole)
+		}
+	}
+	if len(shell) == 0 && len(holes) > 0 {
+		return nil, errors.New("KML Polygon has inner boundaries but no outer boundary")
+	}
+	for _, ring := range append(geom.MultiLine{shell}, holes...) {
+		if len(ring) > 0 && (len(ring) < 4 || !ring[0].Equals2D(ring[len(ring)-1])) {
+			return nil, errors.New("KML Polygon rings must be closed and have at least 4 coordinates")
+		}
+	}
+	return geom.NewPolygon(shell, holes)
+}
+
+func (dec *decoder) multiGeometry() (*geom.Geometry, error) {
+	var collection []*geom.Geometry
+	for {
+		tok, err := dec.d.Token()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			g, err := dec.geometry(t)
+			if err != nil {
+				return nil, err
+			}
+			collection = append(collection, g)
+		case xml.EndElement:
+			return newMulti(collection)
+		}
+	}
+}
+
+// newMulti returns the multi geometry matching the type of the geometries,
+// or a GeometryCollection if they are empty or of different types.
+func newMulti(collection []*geom.Geometry) (*geom.Geometry, error) {
+	types := map[geom.Type]bool{}
+	for _, g := range collection {
+		types[g.Type] = true
+	}
+	if len(types) == 1 {
+		switch collection[0].Type {
+		case geom.TypePoint:
+			return geom.NewMultiPoint(collection)
+		case geom.TypeLineString:
+			return geom.NewMultiLineString(collection)
+		case geom.TypePolygon:
+			return geom.NewMultiPolygon(collection)
+		}
+	}
+	return geom.NewCollection(collection)
+}
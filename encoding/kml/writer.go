@@ -0,0 +1,188 @@
+package kml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer encodes geometries as KML.
+type Writer struct {
+	// Precision is the number of decimal places written, or -1 to write the
+	// fewest digits which represent each value exactly. Trailing zeros are
+	// trimmed.
+	Precision int
+	// AltitudeMode, if set, is written for geometries with Z, e.g.
+	// "absolute" or "relativeToGround". KML defaults to "clampToGround",
+	// which ignores altitudes.
+	AltitudeMode string
+}
+
+func NewWriter() *Writer {
+	return &Writer{Precision: -1}
+}
+
+// Marshal encodes a geometry as a KML geometry element using the default
+// Writer.
+func Marshal(g *geom.Geometry) ([]byte, error) {
+	return NewWriter().Marshal(g)
+}
+
+// Marshal encodes a geometry as a KML geometry element. Multi geometries and
+// collections are written as MultiGeometry. M values are dropped.
+func (w *Writer) Marshal(g *geom.Geometry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.writeGeometry(&buf, g, g.Layout.HasZ()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteDocument writes a KML document holding the Placemarks.
+func (w *Writer) WriteDocument(out io.Writer, placemarks []*Placemark) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<kml xmlns="` + namespace + `"><Document>`)
+	for i, p := range placemarks {
+		buf.WriteString("<Placemark")
+		if p.ID != "" {
+			buf.WriteString(` id="`)
+			escape(&buf, p.ID)
+			buf.WriteByte('"')
+		}
+		buf.WriteByte('>')
+		if p.Name != "" {
+			element(&buf, "name", p.Name)
+		}
+		if p.Description != "" {
+			element(&buf, "description", p.Description)
+		}
+		if len(p.Data) > 0 {
+			w.writeData(&buf, p.Data)
+		}
+		if p.Geometry != nil {
+			if err := w.writeGeometry(&buf, p.Geometry, p.Geometry.Layout.HasZ()); err != nil {
+				return errors.Wrapf(err, "invalid geometry in Placemark %d", i)
+			}
+		}
+		buf.WriteString("</Placemark>")
+	}
+	buf.WriteString("</Document></kml>\n")
+
+	_, err := out.Write(buf.Bytes())
+	return errors.WithStack(err)
+}
+
+func (w *Writer) writeData(buf *bytes.Buffer, data map[string]string) {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.WriteString("<ExtendedData>")
+	for _, name := range names {
+		buf.WriteString(`<Data name="`)
+		escape(buf, name)
+		buf.WriteString(`">`)
+		element(buf, "value", data[name])
+		buf.WriteString("</Data>")
+	}
+	buf.WriteString("</ExtendedData>")
+}
+
+func escape(buf *bytes.Buffer, s string) {
+	// writing to a bytes.Buffer cannot fail
+	_ = xml.EscapeText(buf, []byte(s))
+}
+
+func element(buf *bytes.Buffer, name, text string) {
+	buf.WriteString("<" + name + ">")
+	escape(buf, text)
+	buf.WriteString("</" + name + ">")
+}
+
+func (w *Writer) writeGeometry(buf *bytes.Buffer, g *geom.Geometry, hasZ bool) error {
+	switch g.Type {
+	case geom.TypePoint:
+		buf.WriteString("<Point>")
+		w.writeAltitudeMode(buf, hasZ)
+		if !g.IsEmpty() {
+			w.writeCoordinates(buf, geom.Coordinates{g.Coord}, hasZ)
+		}
+		buf.WriteString("</Point>")
+	case geom.TypeLineString:
+		buf.WriteString("<LineString>")
+		w.writeAltitudeMode(buf, hasZ)
+		w.writeCoordinates(buf, g.Line, hasZ)
+		buf.WriteString("</LineString>")
+	case geom.TypePolygon:
+		buf.WriteString("<Polygon>")
+		w.writeAltitudeMode(buf, hasZ)
+		if !g.IsEmpty() {
+			w.writeRing(buf, "outerBoundaryIs", g.Line, hasZ)
+			for _, hole := range g.MultiLine {
+				w.writeRing(buf, "innerBoundaryIs", hole, hasZ)
+			}
+		}
+		buf.WriteString("</Polygon>")
+	case geom.TypeMultiPoint, geom.TypeMultiLineString, geom.TypeMultiPolygon, geom.TypeCollection:
+		buf.WriteString("<MultiGeometry>")
+		for _, col := range g.Collection {
+			if err := w.writeGeometry(buf, col, hasZ); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</MultiGeometry>")
+	default:
+		return errors.Errorf("unsupported geometry type for KML: %v", g.Type)
+	}
+	return nil
+}
+
+func (w *Writer) writeAltitudeMode(buf *bytes.Buffer, hasZ bool) {
+	if hasZ && w.AltitudeMode != "" {
+		element(buf, "altitudeMode", w.AltitudeMode)
+	}
+}
+
+func (w *Writer) writeRing(buf *bytes.Buffer, boundary string, ring geom.Coordinates, hasZ bool) {
+	buf.WriteString("<" + boundary + "><LinearRing>")
+	w.writeCoordinates(buf, ring, hasZ)
+	buf.WriteString("</LinearRing></" + boundary + ">")
+}
+
+func (w *Writer) writeCoordinates(buf *bytes.Buffer, cs geom.Coordinates, hasZ bool) {
+	buf.WriteString("<coordinates>")
+	for i, c := range cs {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(w.formatFloat(c.X))
+		buf.WriteByte(',')
+		buf.WriteString(w.formatFloat(c.Y))
+		if hasZ {
+			buf.WriteByte(',')
+			buf.WriteString(w.formatFloat(c.Z))
+		}
+	}
+	buf.WriteString("</coordinates>")
+}
+
+func (w *Writer) formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', w.Precision, 64)
+	if w.Precision > 0 {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
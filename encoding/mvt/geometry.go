@@ -0,0 +1,343 @@
+package mvt
+
+import (
+	"math"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+const (
+	geomTypePoint      = 1
+	geomTypeLineString = 2
+	geomTypePolygon    = 3
+
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+
+	earthRadius = 6378137.0
+	// maxLatitude is the latitude at which Web Mercator becomes square.
+	maxLatitude = 85.0511287798066
+)
+
+// tilePoint is a point in integer tile coordinates.
+type tilePoint [2]int32
+
+// encodeGeometry transforms, clips and simplifies a geometry, returning its
+// MVT geometry type and command stream. The command stream is empty if
+// nothing of the geometry remains.
+func (t *Tile) encodeGeometry(g *geom.Geometry) (int, []uint32) {
+	ce := &commandEncoder{}
+	switch g.Type {
+	case geom.TypePoint, geom.TypeMultiPoint:
+		var points []tilePoint
+		for _, p := range pointsOf(g) {
+			if tp, ok := t.clipPoint(p); ok {
+				points = append(points, tp)
+			}
+		}
+		if len(points) > 0 {
+			ce.command(cmdMoveTo, len(points))
+			for _, p := range points {
+				ce.point(p)
+			}
+		}
+		return geomTypePoint, ce.cmds
+	case geom.TypeLineString, geom.TypeMultiLineString:
+		for _, line := range linesOf(g) {
+			for _, part := range t.clipLine(t.simplify(t.transform(line))) {
+				ce.command(cmdMoveTo, 1)
+				ce.point(part[0])
+				ce.command(cmdLineTo, len(part)-1)
+				for _, p := range part[1:] {
+					ce.point(p)
+				}
+			}
+		}
+		return geomTypeLineString, ce.cmds
+	}
+
+	polygons := []*geom.Geometry{g}
+	if g.Type == geom.TypeMultiPolygon {
+		polygons = g.Collection
+	}
+	for _, poly := range polygons {
+		if poly.IsEmpty() {
+			continue
+		}
+		shell := t.clipRing(poly.Line, true)
+		if shell == nil {
+			continue
+		}
+		ce.ring(shell)
+		for _, hole := range poly.MultiLine {
+			if ring := t.clipRing(hole, false); ring != nil {
+				ce.ring(ring)
+			}
+		}
+	}
+	return geomTypePolygon, ce.cmds
+}
+
+// pointsOf returns the coordinates of the non-empty points of a Point or
+// MultiPoint.
+func pointsOf(g *geom.Geometry) coord.Coordinates {
+	parts := g.Collection
+	if g.Type == geom.TypePoint {
+		parts = []*geom.Geometry{g}
+	}
+	var points coord.Coordinates
+	for _, p := range parts {
+		if !p.IsEmpty() {
+			points = append(points, p.Coord)
+		}
+	}
+	return points
+}
+
+func linesOf(g *geom.Geometry) []coord.Coordinates {
+	if g.Type == geom.TypeLineString {
+		return []coord.Coordinates{g.Line}
+	}
+	var lines []coord.Coordinates
+	for _, line := range g.Collection {
+		lines = append(lines, line.Line)
+	}
+	return lines
+}
+
+// project converts a coordinate to floating point tile coordinates, with
+// the y axis pointing down.
+func (t *Tile) project(c geom.Coordinate) coord.Coordinate {
+	x, y := c.X, c.Y
+	if t.LonLat {
+		lat := math.Max(-maxLatitude, math.Min(maxLatitude, c.Y))
+		x = earthRadius * c.X * math.Pi / 180
+		y = earthRadius * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	}
+
+	worldSize := 2 * math.Pi * earthRadius
+	tileSize := worldSize / math.Ldexp(1, t.Z)
+	extent := float64(t.Extent)
+	return coord.Coordinate{
+		X: (x + worldSize/2 - float64(t.X)*tileSize) / tileSize * extent,
+		Y: (worldSize/2 - y - float64(t.Y)*tileSize) / tileSize * extent,
+	}
+}
+
+func (t *Tile) transform(cs coord.Coordinates) coord.Coordinates {
+	out := make(coord.Coordinates, len(cs))
+	for i, c := range cs {
+		out[i] = t.project(c)
+	}
+	return out
+}
+
+func (t *Tile) simplify(cs coord.Coordinates) coord.Coordinates {
+	return cs.Simplify(t.Simplify)
+}
+
+func (t *Tile) bounds() (float64, float64) {
+	return float64(-t.Buffer), float64(t.Extent + t.Buffer)
+}
+
+func round(c coord.Coordinate) tilePoint {
+	return tilePoint{int32(math.Round(c.X)), int32(math.Round(c.Y))}
+}
+
+// roundLine rounds a line to tile coordinates, removing repeated points.
+func roundLine(cs coord.Coordinates) []tilePoint {
+	var out []tilePoint
+	for _, c := range cs {
+		p := round(c)
+		if len(out) == 0 || out[len(out)-1] != p {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (t *Tile) clipPoint(c geom.Coordinate) (tilePoint, bool) {
+	p := t.project(c)
+	min, max := t.bounds()
+	if p.X < min || p.X > max || p.Y < min || p.Y > max {
+		return tilePoint{}, false
+	}
+	return round(p), true
+}
+
+// clipLine clips a line to the buffered tile, which may split it into
+// several parts.
+func (t *Tile) clipLine(line coord.Coordinates) [][]tilePoint {
+	min, max := t.bounds()
+	var parts []coord.Coordinates
+	var current coord.Coordinates
+	for i := 1; i < len(line); i++ {
+		a, b, ok := clipSegment(line[i-1], line[i], min, max)
+		if !ok {
+			continue
+		}
+		if len(current) > 0 && current[len(current)-1] == a {
+			current = append(current, b)
+			continue
+		}
+		if len(current) > 0 {
+			parts = append(parts, current)
+		}
+		current = coord.Coordinates{a, b}
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+
+	var result [][]tilePoint
+	for _, part := range parts {
+		if rounded := roundLine(part); len(rounded) >= 2 {
+			result = append(result, rounded)
+		}
+	}
+	return result
+}
+
+// clipSegment clips a segment to a square using the Liang-Barsky algorithm.
+func clipSegment(a, b coord.Coordinate, min, max float64) (coord.Coordinate, coord.Coordinate, bool) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	t0, t1 := 0.0, 1.0
+	for _, edge := range [4][2]float64{
+		{-dx, a.X - min},
+		{dx, max - a.X},
+		{-dy, a.Y - min},
+		{dy, max - a.Y},
+	} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return a, b, false
+			}
+			if r > t0 {
+				t0 = r
+			}
+		} else {
+			if r < t0 {
+				return a, b, false
+			}
+			if r < t1 {
+				t1 = r
+			}
+		}
+	}
+
+	clippedA, clippedB := a, b
+	if t0 > 0 {
+		clippedA = coord.Coordinate{X: a.X + t0*dx, Y: a.Y + t0*dy}
+	}
+	if t1 < 1 {
+		clippedB = coord.Coordinate{X: a.X + t1*dx, Y: a.Y + t1*dy}
+	}
+	return clippedA, clippedB, true
+}
+
+// clipRing transforms, simplifies and clips a polygon ring, returning it
+// closed and in tile coordinates, wound as required by the MVT
+// specification: shells with a positive area and holes with a negative area
+// (in tile coordinates, where y points down). Returns nil if the ring
+// collapses.
+func (t *Tile) clipRing(ring coord.Coordinates, shell bool) []tilePoint {
+	cs := t.simplify(t.transform(ring))
+	if len(cs) < 4 {
+		return nil
+	}
+
+	min, max := t.bounds()
+	pts := cs[:len(cs)-1]
+	pts = clipPolygonEdge(pts, func(c coord.Coordinate) float64 { return c.X - min })
+	pts = clipPolygonEdge(pts, func(c coord.Coordinate) float64 { return max - c.X })
+	pts = clipPolygonEdge(pts, func(c coord.Coordinate) float64 { return c.Y - min })
+	pts = clipPolygonEdge(pts, func(c coord.Coordinate) float64 { return max - c.Y })
+
+	rounded := roundLine(pts)
+	for len(rounded) > 1 && rounded[0] == rounded[len(rounded)-1] {
+		rounded = rounded[:len(rounded)-1]
+	}
+	if len(rounded) < 3 {
+		return nil
+	}
+
+	area := ringArea(rounded)
+	if area == 0 {
+		return nil
+	}
+	if (area > 0) != shell {
+		for i, j := 0, len(rounded)-1; i < j; i, j = i+1, j-1 {
+			rounded[i], rounded[j] = rounded[j], rounded[i]
+		}
+	}
+	return rounded
+}
+
+// clipPolygonEdge clips an open ring against a single edge using the
+// Sutherland-Hodgman algorithm. inside returns a non-negative value for
+// points inside the edge.
+func clipPolygonEdge(pts coord.Coordinates, inside func(coord.Coordinate) float64) coord.Coordinates {
+	var out coord.Coordinates
+	for i, cur := range pts {
+		prev := pts[(i+len(pts)-1)%len(pts)]
+		dCur, dPrev := inside(cur), inside(prev)
+		if (dCur >= 0) != (dPrev >= 0) {
+			r := dPrev / (dPrev - dCur)
+			out = append(out, coord.Coordinate{
+				X: prev.X + r*(cur.X-prev.X),
+				Y: prev.Y + r*(cur.Y-prev.Y),
+			})
+		}
+		if dCur >= 0 {
+			out = append(out, cur)
+		}
+	}
+	return out
+}
+
+// ringArea computes twice the signed area of an open ring.
+func ringArea(ring []tilePoint) int64 {
+	var sum int64
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		sum += int64(p[0])*int64(q[1]) - int64(q[0])*int64(p[1])
+	}
+	return sum
+}
+
+// commandEncoder builds a geometry command stream. Coordinates are encoded
+// relative to the cursor, which carries across all parts of a feature.
+type commandEncoder struct {
+	cmds   []uint32
+	cursor tilePoint
+}
+
+func (ce *commandEncoder) command(id, count int) {
+	ce.cmds = append(ce.cmds, uint32(id&0x7|count<<3))
+}
+
+func (ce *commandEncoder) point(p tilePoint) {
+	ce.cmds = append(ce.cmds, zigzag32(p[0]-ce.cursor[0]), zigzag32(p[1]-ce.cursor[1]))
+	ce.cursor = p
+}
+
+// ring writes an open ring, which is closed by the ClosePath command.
+func (ce *commandEncoder) ring(ring []tilePoint) {
+	ce.command(cmdMoveTo, 1)
+	ce.point(ring[0])
+	ce.command(cmdLineTo, len(ring)-1)
+	for _, p := range ring[1:] {
+		ce.point(p)
+	}
+	ce.command(cmdClosePath, 1)
+}
@@ -0,0 +1,77 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Protocol buffer wire types and the field numbers of vector_tile.proto (v2.1).
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+
+	tileLayers = 3
+
+	layerVersion  = 15
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+
+	featureID       = 1
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueFloat  = 2
+	valueDouble = 3
+	valueInt    = 4
+	valueUint   = 5
+	valueSint   = 6
+	valueBool   = 7
+)
+
+// protoBuffer appends protocol buffer encoded fields.
+type protoBuffer []byte
+
+func (pb protoBuffer) key(field, wireType int) protoBuffer {
+	return binary.AppendUvarint(pb, uint64(field<<3|wireType))
+}
+
+func (pb protoBuffer) varint(field int, v uint64) protoBuffer {
+	return binary.AppendUvarint(pb.key(field, wireVarint), v)
+}
+
+func (pb protoBuffer) bytes(field int, b []byte) protoBuffer {
+	pb = binary.AppendUvarint(pb.key(field, wireBytes), uint64(len(b)))
+	return append(pb, b...)
+}
+
+func (pb protoBuffer) fixed32(field int, v float32) protoBuffer {
+	return binary.LittleEndian.AppendUint32(pb.key(field, wireFixed32), math.Float32bits(v))
+}
+
+func (pb protoBuffer) fixed64(field int, v float64) protoBuffer {
+	return binary.LittleEndian.AppendUint64(pb.key(field, wireFixed64), math.Float64bits(v))
+}
+
+// packed writes a packed repeated uint32 field.
+func (pb protoBuffer) packed(field int, vs []uint32) protoBuffer {
+	var b []byte
+	for _, v := range vs {
+		b = binary.AppendUvarint(b, uint64(v))
+	}
+	return pb.bytes(field, b)
+}
+
+func zigzag32(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
@@ -0,0 +1,261 @@
+// Package mvt encodes geometries as Mapbox Vector Tiles (version 2.1).
+package mvt
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+const (
+	DefaultExtent = 4096
+	DefaultBuffer = 64
+)
+
+// Feature is a geometry with properties to be added to a tile layer.
+type Feature struct {
+	// ID is the identifier of the feature, or 0 for none.
+	ID         uint64
+	Geometry   *geom.Geometry
+	Properties map[string]interface{}
+}
+
+// Tile builds a vector tile. Geometries are transformed to tile
+// coordinates, clipped, simplified and encoded as they are added.
+type Tile struct {
+	Z, X, Y int
+
+	// Extent is the size of the tile in tile coordinates.
+	Extent int
+	// Buffer is the distance in tile coordinates beyond the tile edges to
+	// which geometries are clipped.
+	Buffer int
+	// Simplify is the Douglas-Peucker tolerance in tile coordinates used to
+	// simplify lines and rings. Zero disables simplification.
+	Simplify float64
+	// LonLat indicates that geometries have WGS84 longitude/latitude
+	// coordinates. Otherwise Web Mercator (EPSG:3857) metres are expected.
+	LonLat bool
+
+	layers []*layer
+}
+
+func NewTile(z, x, y int) *Tile {
+	return &Tile{
+		Z:        z,
+		X:        x,
+		Y:        y,
+		Extent:   DefaultExtent,
+		Buffer:   DefaultBuffer,
+		Simplify: 1,
+	}
+}
+
+type layer struct {
+	name     string
+	features []protoBuffer
+
+	keys       []string
+	keyIndex   map[string]uint32
+	values     []value
+	valueIndex map[value]uint32
+}
+
+// value is a tagged property value, comparable so that values can be
+// deduplicated within a layer.
+type value struct {
+	field int
+	s     string
+	f     float64
+	i     int64
+	u     uint64
+	b     bool
+}
+
+func (t *Tile) layer(name string) *layer {
+	for _, l := range t.layers {
+		if l.name == name {
+			return l
+		}
+	}
+	l := &layer{
+		name:       name,
+		keyIndex:   map[string]uint32{},
+		valueIndex: map[value]uint32{},
+	}
+	t.layers = append(t.layers, l)
+	return l
+}
+
+// AddFeature adds a feature to the named layer, creating the layer if
+// required. Features lying entirely outside the buffered tile are skipped.
+// A GeometryCollection is added as one feature per component.
+func (t *Tile) AddFeature(layerName string, f *Feature) error {
+	l := t.layer(layerName)
+	if f.Geometry == nil || f.Geometry.IsEmpty() {
+		return nil
+	}
+
+	if f.Geometry.Type == geom.TypeCollection {
+		for _, col := range f.Geometry.Collection {
+			part := *f
+			part.Geometry = col
+			if err := t.AddFeature(layerName, &part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	geomType, commands := t.encodeGeometry(f.Geometry)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	tags, err := l.tags(f.Properties)
+	if err != nil {
+		return errors.Wrapf(err, "invalid properties for feature %d", f.ID)
+	}
+
+	var pb protoBuffer
+	if f.ID != 0 {
+		pb = pb.varint(featureID, f.ID)
+	}
+	if len(tags) > 0 {
+		pb = pb.packed(featureTags, tags)
+	}
+	pb = pb.varint(featureType, uint64(geomType))
+	pb = pb.packed(featureGeometry, commands)
+	l.features = append(l.features, pb)
+	return nil
+}
+
+// tags returns the key and value indexes of the properties, in key order.
+func (l *layer) tags(properties map[string]interface{}) ([]uint32, error) {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags []uint32
+	for _, k := range keys {
+		if properties[k] == nil {
+			continue
+		}
+		v, err := newValue(properties[k])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for property %q", k)
+		}
+
+		ki, ok := l.keyIndex[k]
+		if !ok {
+			ki = uint32(len(l.keys))
+			l.keys = append(l.keys, k)
+			l.keyIndex[k] = ki
+		}
+		vi, ok := l.valueIndex[v]
+		if !ok {
+			vi = uint32(len(l.values))
+			l.values = append(l.values, v)
+			l.valueIndex[v] = vi
+		}
+		tags = append(tags, ki, vi)
+	}
+	return tags, nil
+}
+
+func newValue(p interface{}) (value, error) {
+	switch v := p.(type) {
+	case string:
+		return value{field: valueString, s: v}, nil
+	case float32:
+		return value{field: valueFloat, f: float64(v)}, nil
+	case float64:
+		return value{field: valueDouble, f: v}, nil
+	case int:
+		return newIntValue(int64(v)), nil
+	case int8:
+		return newIntValue(int64(v)), nil
+	case int16:
+		return newIntValue(int64(v)), nil
+	case int32:
+		return newIntValue(int64(v)), nil
+	case int64:
+		return newIntValue(v), nil
+	case uint:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint8:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint16:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint32:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint64:
+		return value{field: valueUint, u: v}, nil
+	case bool:
+		return value{field: valueBool, b: v}, nil
+	}
+	return value{}, errors.Errorf("unsupported property type %T", p)
+}
+
+func newIntValue(v int64) value {
+	if v < 0 {
+		return value{field: valueSint, i: v}
+	}
+	return value{field: valueInt, i: v}
+}
+
+func (v value) encode() protoBuffer {
+	var pb protoBuffer
+	switch v.field {
+	case valueString:
+		return pb.bytes(valueString, []byte(v.s))
+	case valueFloat:
+		return pb.fixed32(valueFloat, float32(v.f))
+	case valueDouble:
+		return pb.fixed64(valueDouble, v.f)
+	case valueInt:
+		return pb.varint(valueInt, uint64(v.i))
+	case valueUint:
+		return pb.varint(valueUint, v.u)
+	case valueSint:
+		return pb.varint(valueSint, zigzag64(v.i))
+	}
+	b := uint64(0)
+	if v.b {
+		b = 1
+	}
+	return pb.varint(valueBool, b)
+}
+
+// Marshal encodes the tile in the protocol buffer format. Layers without
+// features are omitted.
+func (t *Tile) Marshal() ([]byte, error) {
+	if t.Extent <= 0 {
+		return nil, errors.Errorf("invalid tile extent: %d", t.Extent)
+	}
+
+	var tile protoBuffer
+	for _, l := range t.layers {
+		if len(l.features) == 0 {
+			continue
+		}
+		var pb protoBuffer
+		pb = pb.varint(layerVersion, 2)
+		pb = pb.bytes(layerName, []byte(l.name))
+		for _, f := range l.features {
+			pb = pb.bytes(layerFeatures, f)
+		}
+		for _, k := range l.keys {
+			pb = pb.bytes(layerKeys, []byte(k))
+		}
+		for _, v := range l.values {
+			pb = pb.bytes(layerValues, v.encode())
+		}
+		pb = pb.varint(layerExtent, uint64(t.Extent))
+		tile = tile.bytes(tileLayers, pb)
+	}
+	return tile, nil
+}
@@ -0,0 +1,226 @@
+package mvt
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// fromTile parses a WKT geometry given in tile coordinates of tile 0/0/0 and
+// converts it to Web Mercator.
+func fromTile(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toMercator(g)
+	return g
+}
+
+func toMercator(g *geom.Geometry) {
+	worldSize := 2 * math.Pi * earthRadius
+	convert := func(c *geom.Coordinate) {
+		c.X = c.X/DefaultExtent*worldSize - worldSize/2
+		c.Y = worldSize/2 - c.Y/DefaultExtent*worldSize
+	}
+	convert(&g.Coord)
+	for i := range g.Line {
+		convert(&g.Line[i])
+	}
+	for _, ring := range g.MultiLine {
+		for i := range ring {
+			convert(&ring[i])
+		}
+	}
+	for _, col := range g.Collection {
+		toMercator(col)
+	}
+}
+
+func TestEncodeGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		geomType int
+		cmds     []uint32
+	}{
+		// examples from section 4.3.5 of the specification
+		{
+			name:     "point",
+			text:     "POINT (25 17)",
+			geomType: geomTypePoint,
+			cmds:     []uint32{9, 50, 34},
+		},
+		{
+			name:     "multipoint",
+			text:     "MULTIPOINT ((5 7), (3 2))",
+			geomType: geomTypePoint,
+			cmds:     []uint32{17, 10, 14, 3, 9},
+		},
+		{
+			name:     "empty point",
+			text:     "POINT EMPTY",
+			geomType: geomTypePoint,
+		},
+		{
+			name:     "multipoint with an empty point",
+			text:     "MULTIPOINT ((5 7), EMPTY, (3 2))",
+			geomType: geomTypePoint,
+			cmds:     []uint32{17, 10, 14, 3, 9},
+		},
+		{
+			name:     "linestring",
+			text:     "LINESTRING (2 2, 2 10, 10 10)",
+			geomType: geomTypeLineString,
+			cmds:     []uint32{9, 4, 4, 18, 0, 16, 16, 0},
+		},
+		{
+			name:     "multilinestring",
+			text:     "MULTILINESTRING ((2 2, 2 10, 10 10), (1 1, 3 5))",
+			geomType: geomTypeLineString,
+			cmds:     []uint32{9, 4, 4, 18, 0, 16, 16, 0, 9, 17, 17, 10, 4, 8},
+		},
+		{
+			name:     "polygon",
+			text:     "POLYGON ((3 6, 8 12, 20 34, 3 6))",
+			geomType: geomTypePolygon,
+			cmds:     []uint32{9, 6, 12, 18, 10, 12, 24, 44, 15},
+		},
+		{
+			name:     "multipolygon",
+			text:     "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0)), ((11 11, 20 11, 20 20, 11 20, 11 11), (13 13, 13 17, 17 17, 17 13, 13 13)))",
+			geomType: geomTypePolygon,
+			cmds:     []uint32{9, 0, 0, 26, 20, 0, 0, 20, 19, 0, 15, 9, 22, 2, 26, 18, 0, 0, 18, 17, 0, 15, 9, 4, 13, 26, 0, 8, 8, 0, 0, 7, 15},
+		},
+		{
+			name:     "rings are wound as the specification requires",
+			text:     "POLYGON ((3 6, 20 34, 8 12, 3 6))",
+			geomType: geomTypePolygon,
+			cmds:     []uint32{9, 16, 24, 18, 24, 44, 33, 55, 15},
+		},
+		{
+			name:     "point outside the buffer",
+			text:     "MULTIPOINT ((-65 0), (4161 0), (1 2))",
+			geomType: geomTypePoint,
+			cmds:     []uint32{9, 2, 4},
+		},
+		{
+			name:     "line clipped to the buffer",
+			text:     "LINESTRING (-100 10, 10 10, 10 5000)",
+			geomType: geomTypeLineString,
+			cmds:     []uint32{9, 127, 20, 18, 148, 0, 0, 8300},
+		},
+		{
+			name:     "line leaving and reentering",
+			text:     "LINESTRING (10 10, -100 10, -100 20, 10 20)",
+			geomType: geomTypeLineString,
+			cmds:     []uint32{9, 20, 20, 10, 147, 0, 9, 0, 20, 10, 148, 0},
+		},
+		{
+			name:     "polygon clipped to the buffer",
+			text:     "POLYGON ((-100 -100, 100 -100, 100 100, -100 100, -100 -100))",
+			geomType: geomTypePolygon,
+			cmds:     []uint32{9, 127, 127, 26, 328, 0, 0, 328, 327, 0, 15},
+		},
+		{
+			name:     "collapsed polygon",
+			text:     "POLYGON ((0 0, 0.2 0, 0.2 0.2, 0 0))",
+			geomType: geomTypePolygon,
+		},
+		{
+			name:     "polygon outside the buffer",
+			text:     "POLYGON ((-200 0, -100 0, -100 10, -200 0))",
+			geomType: geomTypePolygon,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tile := NewTile(0, 0, 0)
+			tile.Simplify = 0
+			geomType, cmds := tile.encodeGeometry(fromTile(t, test.text))
+			if geomType != test.geomType {
+				t.Errorf("type = %d, want %d", geomType, test.geomType)
+			}
+			if !reflect.DeepEqual(cmds, test.cmds) {
+				t.Errorf("commands = %v, want %v", cmds, test.cmds)
+			}
+		})
+	}
+}
+
+func TestLonLat(t *testing.T) {
+	tile := NewTile(1, 1, 0)
+	tile.LonLat = true
+	p, err := geom.NewMultiPoint([]*geom.Geometry{
+		mustPoint(t, 0, 0),
+		mustPoint(t, 90, 90),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (0, 0) is the bottom left corner of the tile and latitudes are
+	// clamped to the top edge of the Web Mercator square
+	_, cmds := tile.encodeGeometry(p)
+	if want := []uint32{17, 0, 8192, 4096, 8191}; !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands = %v, want %v", cmds, want)
+	}
+}
+
+func mustPoint(t *testing.T, x, y float64) *geom.Geometry {
+	t.Helper()
+	p, err := geom.NewPoint(geom.Coordinate{X: x, Y: y})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestMarshal(t *testing.T) {
+	tile := NewTile(0, 0, 0)
+	features := []*Feature{
+		{ID: 1, Geometry: fromTile(t, "POINT (25 17)"), Properties: map[string]interface{}{"name": "a", "n": -1}},
+		{ID: 2, Geometry: fromTile(t, "POINT (1 2)"), Properties: map[string]interface{}{"name": "a", "ok": true, "none": nil}},
+		{Geometry: fromTile(t, "POINT (-500 0)")},
+	}
+	for _, f := range features {
+		if err := tile.AddFeature("points", f); err != nil {
+			t.Fatalf("AddFeature() error = %v", err)
+		}
+	}
+	if err := tile.AddFeature("empty", &Feature{}); err != nil {
+		t.Fatalf("AddFeature() error = %v", err)
+	}
+	data, err := tile.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := "1a49" + // layer
+		"7802" + // version 2
+		"0a06706f696e7473" + // name "points"
+		"120f" + "0801" + "120400000101" + "1801" + "2203093222" + // feature 1: n=-1, name="a"
+		"120f" + "0802" + "120401010202" + "1801" + "2203090204" + // feature 2: name="a", ok=true
+		"1a016e" + "1a046e616d65" + "1a026f6b" + // keys "n", "name", "ok"
+		"22023001" + "22030a0161" + "22023801" + // values -1, "a", true
+		"288020" // extent 4096
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestAddFeatureErrors(t *testing.T) {
+	tile := NewTile(0, 0, 0)
+	f := &Feature{Geometry: fromTile(t, "POINT (1 2)"), Properties: map[string]interface{}{"a": []int{1}}}
+	if err := tile.AddFeature("l", f); err == nil {
+		t.Error("AddFeature() with a slice property, want an error")
+	}
+	tile.Extent = 0
+	if _, err := tile.Marshal(); err == nil {
+		t.Error("Marshal() with a zero extent, want an error")
+	}
+}
@@ -0,0 +1,128 @@
+// Package polyline implements the Google encoded polyline algorithm and its
+// "flexible polyline" variant, which adds a header and an optional third
+// dimension.
+//
+// Both formats store coordinates in latitude, longitude order, so Y is
+// written before X.
+package polyline
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPrecision is the precision of Google polylines. Precision 6 is
+	// also common, e.g. in OSRM and Valhalla.
+	DefaultPrecision = 5
+
+	flexibleVersion = 1
+	maxPrecision    = 15
+)
+
+// ThirdDimension is the meaning of the third dimension of a flexible
+// polyline. All of them are read and written as Z.
+type ThirdDimension int
+
+const (
+	ThirdDimensionAbsent    ThirdDimension = 0
+	ThirdDimensionLevel     ThirdDimension = 1
+	ThirdDimensionAltitude  ThirdDimension = 2
+	ThirdDimensionElevation ThirdDimension = 3
+	ThirdDimensionCustom1   ThirdDimension = 6
+	ThirdDimensionCustom2   ThirdDimension = 7
+)
+
+func (d ThirdDimension) valid() bool {
+	return d >= ThirdDimensionAbsent && d <= ThirdDimensionElevation ||
+		d == ThirdDimensionCustom1 || d == ThirdDimensionCustom2
+}
+
+// flexibleAlphabet maps 6 bit values to characters in flexible polylines.
+const flexibleAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// alphabet maps between 6 bit values and characters.
+type alphabet interface {
+	char(v uint64) byte
+	value(c byte) (uint64, bool)
+}
+
+// googleAlphabet offsets values by 63 into the printable ASCII range.
+type googleAlphabet struct{}
+
+func (googleAlphabet) char(v uint64) byte {
+	return byte(v + 63)
+}
+
+func (googleAlphabet) value(c byte) (uint64, bool) {
+	if c < 63 || c > 63+0x3f {
+		return 0, false
+	}
+	return uint64(c - 63), true
+}
+
+type urlAlphabet struct{}
+
+func (urlAlphabet) char(v uint64) byte {
+	return flexibleAlphabet[v]
+}
+
+func (urlAlphabet) value(c byte) (uint64, bool) {
+	i := strings.IndexByte(flexibleAlphabet, c)
+	return uint64(i), i >= 0
+}
+
+// appendUvarint appends v in 5 bit chunks, least significant first, with
+// the 0x20 bit set on all but the last.
+func appendUvarint(dst []byte, v uint64, a alphabet) []byte {
+	for v >= 0x20 {
+		dst = append(dst, a.char(0x20|v&0x1f))
+		v >>= 5
+	}
+	return append(dst, a.char(v))
+}
+
+func appendVarint(dst []byte, v int64, a alphabet) []byte {
+	return appendUvarint(dst, uint64(v<<1^v>>63), a)
+}
+
+type decoder struct {
+	s   string
+	pos int
+	a   alphabet
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); ; shift += 5 {
+		if d.pos >= len(d.s) {
+			return 0, errors.New("polyline is truncated")
+		}
+		if shift > 60 {
+			return 0, errors.Errorf("polyline value overflows at offset %d", d.pos)
+		}
+		chunk, ok := d.a.value(d.s[d.pos])
+		if !ok {
+			return 0, errors.Errorf("invalid polyline character %q at offset %d", d.s[d.pos], d.pos)
+		}
+		d.pos++
+		v |= (chunk & 0x1f) << shift
+		if chunk&0x20 == 0 {
+			return v, nil
+		}
+	}
+}
+
+func (d *decoder) varint() (int64, error) {
+	v, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func scale(precision int) float64 {
+	return math.Pow10(precision)
+}
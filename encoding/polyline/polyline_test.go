@@ -0,0 +1,155 @@
+package polyline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	// examples from the Google polyline documentation and the HERE flexible
+	// polyline README
+	here := coord.Coordinates{
+		{X: 8.6982122, Y: 50.1022829, Z: 10},
+		{X: 8.6956695, Y: 50.1020076, Z: 20},
+		{X: 8.6914960, Y: 50.1006313, Z: 30},
+		{X: 8.6875156, Y: 50.0987800, Z: 40},
+	}
+	tests := []struct {
+		name    string
+		w       *Writer
+		r       *Reader
+		cs      coord.Coordinates
+		third   ThirdDimension
+		encoded string
+	}{
+		{
+			name:    "google",
+			w:       NewWriter(),
+			r:       NewReader(),
+			cs:      coord.Coordinates{{X: -120.2, Y: 38.5}, {X: -120.95, Y: 40.7}, {X: -126.453, Y: 43.252}},
+			encoded: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			name:    "google precision 6",
+			w:       &Writer{Precision: 6},
+			r:       &Reader{Precision: 6},
+			cs:      coord.Coordinates{{X: -120.2, Y: 38.5}, {X: -120.95, Y: 40.7}},
+			encoded: "_izlhA~rlgdF_{geC~ywl@",
+		},
+		{
+			name:    "flexible",
+			w:       &Writer{Precision: 5, Flexible: true},
+			r:       &Reader{Flexible: true},
+			cs:      here,
+			encoded: "BFoz5xJ67i1B1B7PzIhaxL7Y",
+		},
+		{
+			name:    "flexible with altitude",
+			w:       &Writer{Precision: 5, Flexible: true},
+			r:       &Reader{Flexible: true},
+			cs:      here,
+			third:   ThirdDimensionAltitude,
+			encoded: "BlBoz5xJ67i1BU1B7PUzIhaUxL7YU",
+		},
+		{
+			name:    "empty",
+			w:       NewWriter(),
+			r:       NewReader(),
+			encoded: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded, err := test.w.Encode(test.cs, test.third)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if encoded != test.encoded {
+				t.Errorf("Encode() = %q, want %q", encoded, test.encoded)
+			}
+
+			cs, third, err := test.r.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if third != test.third {
+				t.Errorf("Decode() third dimension = %v, want %v", third, test.third)
+			}
+			if len(cs) != len(test.cs) {
+				t.Fatalf("Decode() = %v, want %v", cs, test.cs)
+			}
+			tolerance := math.Pow10(-test.w.Precision) / 2
+			for i, c := range cs {
+				want := test.cs[i]
+				if third == ThirdDimensionAbsent {
+					want.Z = 0
+				}
+				if math.Abs(c.X-want.X) > tolerance || math.Abs(c.Y-want.Y) > tolerance || c.Z != want.Z {
+					t.Errorf("Decode()[%d] = %v, want %v", i, c, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	g, err := Unmarshal("_p~iF~ps|U_ulLnnqC_mqNvxq`@")
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if g.Layout.HasZ() || len(g.Line) != 3 {
+		t.Errorf("Unmarshal() = %v %v", g.Layout, g.Line)
+	}
+	s, err := Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if s != "_p~iF~ps|U_ulLnnqC_mqNvxq`@" {
+		t.Errorf("Marshal() = %q", s)
+	}
+
+	g, err = (&Reader{Flexible: true}).Read("BlBoz5xJ67i1BU1B7PUzIhaUxL7YU")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !g.Layout.HasZ() {
+		t.Errorf("Read() layout = %v, want Z", g.Layout)
+	}
+
+	p, err := geom.NewPoint(geom.Coordinate{X: 1, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Marshal(p); err == nil {
+		t.Error("Marshal() of a point, want an error")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Reader
+		s    string
+	}{
+		{"truncated", NewReader(), "_p~iF"},
+		{"unterminated", NewReader(), "_p~iF~"},
+		{"invalid character", NewReader(), "_p~iF ps|U"},
+		{"invalid precision", &Reader{Precision: 16}, "_p~iF~ps|U"},
+		{"overflow", NewReader(), "~~~~~~~~~~~~~~?"},
+		{"flexible version", &Reader{Flexible: true}, "CFoz5xJ67i1B"},
+		{"flexible truncated", &Reader{Flexible: true}, "BFoz5xJ"},
+		{"flexible third dimension", &Reader{Flexible: true}, "BlCoz5xJ67i1B"},
+		{"flexible invalid character", &Reader{Flexible: true}, "BF~z5xJ67i1B"},
+		{"flexible missing header", &Reader{Flexible: true}, "B"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cs, _, err := test.r.Decode(test.s); err == nil {
+				t.Errorf("Decode() = %v, want an error", cs)
+			}
+		})
+	}
+}
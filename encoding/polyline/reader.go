@@ -0,0 +1,98 @@
+package polyline
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader decodes polylines.
+type Reader struct {
+	// Precision is the number of decimal places of Google polylines.
+	// Flexible polylines record their own precision.
+	Precision int
+
+	// Flexible reads flexible polylines instead of Google polylines.
+	Flexible bool
+}
+
+func NewReader() *Reader {
+	return &Reader{Precision: DefaultPrecision}
+}
+
+// Unmarshal decodes a Google polyline as a LineString using the default
+// Reader.
+func Unmarshal(s string) (*geom.Geometry, error) {
+	return NewReader().Read(s)
+}
+
+// Read decodes a polyline as a LineString, which has Z if the polyline has
+// a third dimension.
+func (r *Reader) Read(s string) (*geom.Geometry, error) {
+	cs, third, err := r.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	g, err := geom.NewLineString(cs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	g.Layout = geom.NewLayout(third != ThirdDimensionAbsent, false)
+	return g, nil
+}
+
+// Decode decodes a polyline, returning its coordinates and the meaning of
+// their Z values.
+func (r *Reader) Decode(s string) (coord.Coordinates, ThirdDimension, error) {
+	d := &decoder{s: s, a: googleAlphabet{}}
+	precision, precisionZ := r.Precision, 0
+	third := ThirdDimensionAbsent
+	if r.Flexible {
+		d.a = urlAlphabet{}
+		version, err := d.uvarint()
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "invalid flexible polyline header")
+		}
+		if version != flexibleVersion {
+			return nil, 0, errors.Errorf("unsupported flexible polyline version: %d", version)
+		}
+		header, err := d.uvarint()
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "invalid flexible polyline header")
+		}
+		precision = int(header & 0x0f)
+		third = ThirdDimension(header >> 4 & 0x07)
+		precisionZ = int(header >> 7 & 0x0f)
+		if !third.valid() {
+			return nil, 0, errors.Errorf("invalid polyline third dimension: %d", third)
+		}
+	} else if precision < 0 || precision > maxPrecision {
+		return nil, 0, errors.Errorf("polyline precision must be between 0 and %d, found %d", maxPrecision, precision)
+	}
+
+	dims := 2
+	if third != ThirdDimensionAbsent {
+		dims = 3
+	}
+	xyScale, zScale := scale(precision), scale(precisionZ)
+	var cs coord.Coordinates
+	var last [3]int64
+	for d.pos < len(d.s) {
+		for i := 0; i < dims; i++ {
+			delta, err := d.varint()
+			if err != nil {
+				return nil, 0, err
+			}
+			last[i] += delta
+		}
+		c := coord.Coordinate{
+			X: float64(last[1]) / xyScale,
+			Y: float64(last[0]) / xyScale,
+		}
+		if dims == 3 {
+			c.Z = float64(last[2]) / zScale
+		}
+		cs = append(cs, c)
+	}
+	return cs, third, nil
+}
@@ -0,0 +1,93 @@
+package polyline
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer encodes lines as polylines.
+type Writer struct {
+	// Precision is the number of decimal places kept for X and Y.
+	Precision int
+
+	// Flexible writes flexible polylines instead of Google polylines.
+	// Google polylines only hold X and Y, so Z is dropped without it.
+	Flexible bool
+	// ThirdDimension is written in the header of flexible polylines for
+	// geometries with Z.
+	ThirdDimension ThirdDimension
+	// PrecisionZ is the number of decimal places kept for Z.
+	PrecisionZ int
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		Precision:      DefaultPrecision,
+		ThirdDimension: ThirdDimensionAltitude,
+	}
+}
+
+// Marshal encodes a LineString as a Google polyline using the default
+// Writer.
+func Marshal(g *geom.Geometry) (string, error) {
+	return NewWriter().Marshal(g)
+}
+
+// Marshal encodes a LineString as a polyline.
+func (w *Writer) Marshal(g *geom.Geometry) (string, error) {
+	if g.Type != geom.TypeLineString {
+		return "", errors.Errorf("only LineString can be encoded as a polyline, found %v", g.Type)
+	}
+	third := ThirdDimensionAbsent
+	if g.Layout.HasZ() {
+		third = w.ThirdDimension
+	}
+	return w.Encode(g.Line, third)
+}
+
+// Encode encodes coordinates as a polyline. Z is written if third is not
+// ThirdDimensionAbsent and the Writer is Flexible.
+func (w *Writer) Encode(cs coord.Coordinates, third ThirdDimension) (string, error) {
+	if w.Precision < 0 || w.Precision > maxPrecision {
+		return "", errors.Errorf("polyline precision must be between 0 and %d, found %d", maxPrecision, w.Precision)
+	}
+
+	var a alphabet = googleAlphabet{}
+	var dst []byte
+	hasZ := false
+	if w.Flexible {
+		if !third.valid() {
+			return "", errors.Errorf("invalid polyline third dimension: %d", third)
+		}
+		if w.PrecisionZ < 0 || w.PrecisionZ > maxPrecision {
+			return "", errors.Errorf("polyline Z precision must be between 0 and %d, found %d", maxPrecision, w.PrecisionZ)
+		}
+		a = urlAlphabet{}
+		hasZ = third != ThirdDimensionAbsent
+		header := uint64(w.Precision) | uint64(third)<<4
+		if hasZ {
+			header |= uint64(w.PrecisionZ) << 7
+		}
+		dst = appendUvarint(dst, flexibleVersion, a)
+		dst = appendUvarint(dst, header, a)
+	}
+
+	xyScale, zScale := scale(w.Precision), scale(w.PrecisionZ)
+	dims := 2
+	if hasZ {
+		dims = 3
+	}
+	var last [3]int64
+	for _, c := range cs {
+		ords := [3]float64{c.Y * xyScale, c.X * xyScale, c.Z * zScale}
+		for i := 0; i < dims; i++ {
+			v := int64(math.Round(ords[i]))
+			dst = appendVarint(dst, v-last[i], a)
+			last[i] = v
+		}
+	}
+	return string(dst), nil
+}
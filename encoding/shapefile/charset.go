@@ -0,0 +1,176 @@
+package shapefile
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Charset converts DBF text to and from UTF-8. Charsets other than the
+// predefined ones can be supported by providing the conversion functions.
+type Charset struct {
+	// Name is the code page written to the .cpg file.
+	Name string
+	// LDID is the DBF language driver ID, or 0 if there is none.
+	LDID byte
+
+	Decode func([]byte) string
+	Encode func(string) ([]byte, error)
+}
+
+var (
+	CharsetUTF8 = &Charset{
+		Name: "UTF-8",
+		Decode: func(b []byte) string {
+			return strings.ToValidUTF8(string(b), "\uFFFD")
+		},
+		Encode: func(s string) ([]byte, error) {
+			return []byte(s), nil
+		},
+	}
+	CharsetLatin1      = newSingleByteCharset("ISO-8859-1", 0, latin1High())
+	CharsetWindows1252 = newSingleByteCharset("1252", 0x57, windows1252High())
+	CharsetCP437       = newSingleByteCharset("437", 0x01, []rune(cp437High))
+	CharsetCP850       = newSingleByteCharset("850", 0x02, []rune(cp850High))
+)
+
+// ldidCharsets maps DBF language driver IDs to charsets.
+var ldidCharsets = map[byte]*Charset{
+	0x01: CharsetCP437,
+	0x02: CharsetCP850,
+	0x03: CharsetWindows1252,
+	0x57: CharsetWindows1252,
+	0x58: CharsetWindows1252,
+	0x59: CharsetWindows1252,
+}
+
+// cpgCharsets maps normalised .cpg file contents to charsets.
+var cpgCharsets = map[string]*Charset{
+	"UTF8":         CharsetUTF8,
+	"88591":        CharsetLatin1,
+	"ISO88591":     CharsetLatin1,
+	"LATIN1":       CharsetLatin1,
+	"1252":         CharsetWindows1252,
+	"CP1252":       CharsetWindows1252,
+	"WINDOWS1252":  CharsetWindows1252,
+	"ANSI1252":     CharsetWindows1252,
+	"437":          CharsetCP437,
+	"CP437":        CharsetCP437,
+	"IBM437":       CharsetCP437,
+	"OEM437":       CharsetCP437,
+	"850":          CharsetCP850,
+	"CP850":        CharsetCP850,
+	"IBM850":       CharsetCP850,
+	"OEM850":       CharsetCP850,
+	"WINDOWSUTF8":  CharsetUTF8,
+	"UNICODEUTF8":  CharsetUTF8,
+	"UTF8UNICODE":  CharsetUTF8,
+	"CODEPAGEUTF8": CharsetUTF8,
+}
+
+// charsetFromCPG returns the charset named by the content of a .cpg file.
+func charsetFromCPG(cpg string) (*Charset, error) {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			return r
+		}
+		return -1
+	}, cpg)
+	if cs, ok := cpgCharsets[name]; ok {
+		return cs, nil
+	}
+	return nil, errors.Errorf("unsupported code page %q", strings.TrimSpace(cpg))
+}
+
+// autoCharset decodes text as UTF-8 if it is valid and as Windows-1252
+// otherwise. It is used when a DBF file does not declare its code page.
+var autoCharset = &Charset{
+	Name: CharsetUTF8.Name,
+	Decode: func(b []byte) string {
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return CharsetWindows1252.Decode(b)
+	},
+	Encode: CharsetUTF8.Encode,
+}
+
+// newSingleByteCharset creates a charset that maps bytes below 0x80 to
+// ASCII and the others to high.
+func newSingleByteCharset(name string, ldid byte, high []rune) *Charset {
+	if len(high) != 0x80 {
+		panic("single byte charset must map 128 characters")
+	}
+	encoding := make(map[rune]byte, len(high))
+	for i, r := range high {
+		encoding[r] = byte(0x80 + i)
+	}
+	return &Charset{
+		Name: name,
+		LDID: ldid,
+		Decode: func(b []byte) string {
+			var sb strings.Builder
+			for _, c := range b {
+				if c < 0x80 {
+					sb.WriteByte(c)
+				} else {
+					sb.WriteRune(high[c-0x80])
+				}
+			}
+			return sb.String()
+		},
+		Encode: func(s string) ([]byte, error) {
+			b := make([]byte, 0, len(s))
+			for _, r := range s {
+				if r < 0x80 {
+					b = append(b, byte(r))
+					continue
+				}
+				c, ok := encoding[r]
+				if !ok {
+					return nil, errors.Errorf("character %q cannot be encoded in code page %s", r, name)
+				}
+				b = append(b, c)
+			}
+			return b, nil
+		},
+	}
+}
+
+func latin1High() []rune {
+	high := make([]rune, 0x80)
+	for i := range high {
+		high[i] = rune(0x80 + i)
+	}
+	return high
+}
+
+func windows1252High() []rune {
+	high := latin1High()
+	// undefined bytes 0x81, 0x8d, 0x8f, 0x90 and 0x9d keep their C1 control
+	// code points
+	copy(high, []rune("€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ"))
+	return high
+}
+
+const cp437High = "ÇüéâäàåçêëèïîìÄÅ" +
+	"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" +
+	"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩" +
+	"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0"
+
+const cp850High = "ÇüéâäàåçêëèïîìÄÅ" +
+	"ÉæÆôöòûùÿÖÜø£Ø×ƒ" +
+	"áíóúñÑªº¿®¬½¼¡«»" +
+	"░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+	"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤" +
+	"ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀" +
+	"ÓßÔÒõÕµþÞÚÛÙýÝ¯´" +
+	"\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0"
@@ -0,0 +1,325 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FieldType is the type of a DBF field.
+type FieldType byte
+
+const (
+	FieldCharacter FieldType = 'C'
+	FieldNumeric   FieldType = 'N'
+	FieldFloat     FieldType = 'F'
+	FieldLogical   FieldType = 'L'
+	FieldDate      FieldType = 'D'
+)
+
+// Field describes a DBF attribute. Attribute values are read as string
+// (Character), int64 or float64 (Numeric and Float, int64 if there are no
+// decimals), bool (Logical) and time.Time (Date). Blank values are nil.
+type Field struct {
+	Name     string
+	Type     FieldType
+	Length   int
+	Decimals int
+}
+
+const (
+	dbfVersion       = 0x03
+	dbfHeaderSize    = 32
+	dbfFieldSize     = 32
+	dbfHeaderEnd     = 0x0d
+	dbfEOF           = 0x1a
+	dbfDeleted       = '*'
+	dbfMaxNameLength = 10
+)
+
+type dbfReader struct {
+	r          io.Reader
+	charset    *Charset
+	fields     []Field
+	numRecords int
+	read       int
+	record     []byte
+}
+
+// newDBFReader reads the DBF header. charset overrides the language driver
+// ID of the file if set.
+func newDBFReader(r io.Reader, charset *Charset) (*dbfReader, error) {
+	header := make([]byte, dbfHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "failed to read DBF header")
+	}
+	numRecords := binary.LittleEndian.Uint32(header[4:])
+	headerLen := int(binary.LittleEndian.Uint16(header[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(header[10:]))
+	if headerLen < dbfHeaderSize+1 || recordLen < 1 {
+		return nil, errors.Errorf("invalid DBF header: header length %d, record length %d", headerLen, recordLen)
+	}
+	if charset == nil {
+		if charset = ldidCharsets[header[29]]; charset == nil {
+			charset = autoCharset
+		}
+	}
+
+	descriptors := make([]byte, headerLen-dbfHeaderSize)
+	if _, err := io.ReadFull(r, descriptors); err != nil {
+		return nil, errors.Wrap(err, "failed to read DBF field descriptors")
+	}
+	var fields []Field
+	size := 1
+	for i := 0; i+dbfFieldSize <= len(descriptors) && descriptors[i] != dbfHeaderEnd; i += dbfFieldSize {
+		desc := descriptors[i : i+dbfFieldSize]
+		name := desc[:11]
+		if n := bytes.IndexByte(name, 0); n >= 0 {
+			name = name[:n]
+		}
+		f := Field{
+			Name:     strings.TrimSpace(charset.Decode(name)),
+			Type:     FieldType(desc[11]),
+			Length:   int(desc[16]),
+			Decimals: int(desc[17]),
+		}
+		fields = append(fields, f)
+		size += f.Length
+	}
+	if size > recordLen {
+		return nil, errors.Errorf("DBF fields need %d bytes but records have %d", size, recordLen)
+	}
+
+	return &dbfReader{
+		r:          r,
+		charset:    charset,
+		fields:     fields,
+		numRecords: int(numRecords),
+		record:     make([]byte, recordLen),
+	}, nil
+}
+
+// next reads the next record, returning io.EOF after the last one.
+func (d *dbfReader) next() (attrs map[string]interface{}, deleted bool, err error) {
+	if d.read >= d.numRecords {
+		return nil, false, io.EOF
+	}
+	if _, err := io.ReadFull(d.r, d.record); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to read DBF record %d", d.read)
+	}
+	d.read++
+
+	attrs = make(map[string]interface{}, len(d.fields))
+	pos := 1
+	for _, f := range d.fields {
+		v, err := d.parse(f, d.record[pos:pos+f.Length])
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "invalid value for field %s in DBF record %d", f.Name, d.read-1)
+		}
+		attrs[f.Name] = v
+		pos += f.Length
+	}
+	return attrs, d.record[0] == dbfDeleted, nil
+}
+
+func (d *dbfReader) parse(f Field, raw []byte) (interface{}, error) {
+	if f.Type == FieldCharacter {
+		return d.charset.Decode(bytes.TrimRight(raw, " \x00")), nil
+	}
+
+	s := strings.TrimSpace(string(bytes.TrimRight(raw, "\x00")))
+	switch f.Type {
+	case FieldNumeric, FieldFloat:
+		if s == "" || strings.Trim(s, "*") == "" {
+			return nil, nil
+		}
+		if f.Type == FieldNumeric && f.Decimals == 0 {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number %q", s)
+		}
+		return v, nil
+	case FieldLogical:
+		switch s {
+		case "T", "t", "Y", "y":
+			return true, nil
+		case "F", "f", "N", "n":
+			return false, nil
+		}
+		return nil, nil
+	case FieldDate:
+		if strings.Trim(s, "0") == "" {
+			return nil, nil
+		}
+		t, err := time.Parse("20060102", s)
+		if err != nil {
+			return nil, errors.Errorf("invalid date %q", s)
+		}
+		return t, nil
+	}
+	return d.charset.Decode(bytes.TrimSpace(raw)), nil
+}
+
+// writeDBF writes the attributes of the records as a DBF file.
+func writeDBF(w io.Writer, fields []Field, records []*Record, charset *Charset) error {
+	recordLen := 1
+	for i, f := range fields {
+		if err := validateField(f); err != nil {
+			return errors.Wrapf(err, "invalid field %d", i)
+		}
+		recordLen += f.Length
+	}
+	if recordLen > math.MaxUint16 {
+		return errors.Errorf("DBF record length %d is too long", recordLen)
+	}
+	headerLen := dbfHeaderSize + dbfFieldSize*len(fields) + 1
+	if headerLen > math.MaxUint16 {
+		return errors.Errorf("too many DBF fields: %d", len(fields))
+	}
+
+	buf := make([]byte, headerLen, headerLen+recordLen*len(records)+1)
+	now := time.Now()
+	buf[0] = dbfVersion
+	buf[1], buf[2], buf[3] = byte(now.Year()-1900), byte(now.Month()), byte(now.Day())
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(records)))
+	binary.LittleEndian.PutUint16(buf[8:], uint16(headerLen))
+	binary.LittleEndian.PutUint16(buf[10:], uint16(recordLen))
+	buf[29] = charset.LDID
+	for i, f := range fields {
+		desc := buf[dbfHeaderSize+i*dbfFieldSize:]
+		copy(desc[:dbfMaxNameLength], f.Name)
+		desc[11] = byte(f.Type)
+		desc[16] = byte(f.Length)
+		desc[17] = byte(f.Decimals)
+	}
+	buf[headerLen-1] = dbfHeaderEnd
+
+	for i, rec := range records {
+		buf = append(buf, ' ')
+		for _, f := range fields {
+			value, err := formatValue(f, rec.Attributes[f.Name], charset)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value for field %s in record %d", f.Name, i)
+			}
+			buf = append(buf, value...)
+		}
+	}
+	buf = append(buf, dbfEOF)
+
+	_, err := w.Write(buf)
+	return errors.WithStack(err)
+}
+
+func validateField(f Field) error {
+	if f.Name == "" || len(f.Name) > dbfMaxNameLength {
+		return errors.Errorf("DBF field names must have 1 to %d bytes, found %q", dbfMaxNameLength, f.Name)
+	}
+	for _, r := range f.Name {
+		if r >= 0x80 {
+			return errors.Errorf("DBF field names must be ASCII, found %q", f.Name)
+		}
+	}
+	switch f.Type {
+	case FieldCharacter, FieldNumeric, FieldFloat:
+		if f.Length < 1 || f.Length > 254 {
+			return errors.Errorf("length of %c field %s must be between 1 and 254, found %d", f.Type, f.Name, f.Length)
+		}
+	case FieldLogical:
+		if f.Length != 1 {
+			return errors.Errorf("length of logical field %s must be 1, found %d", f.Name, f.Length)
+		}
+	case FieldDate:
+		if f.Length != 8 {
+			return errors.Errorf("length of date field %s must be 8, found %d", f.Name, f.Length)
+		}
+	default:
+		return errors.Errorf("unsupported DBF field type %q", f.Type)
+	}
+	if f.Decimals < 0 || f.Decimals > 15 || f.Decimals > 0 && f.Decimals >= f.Length-1 {
+		return errors.Errorf("invalid decimal count %d for field %s", f.Decimals, f.Name)
+	}
+	return nil
+}
+
+// formatValue formats a value to the length of the field.
+func formatValue(f Field, v interface{}, charset *Charset) ([]byte, error) {
+	var s []byte
+	switch f.Type {
+	case FieldCharacter:
+		if v == nil {
+			break
+		}
+		str, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("expected string, found %T", v)
+		}
+		var err error
+		if s, err = charset.Encode(str); err != nil {
+			return nil, err
+		}
+		if len(s) > f.Length {
+			return nil, errors.Errorf("%d byte string is longer than the field", len(s))
+		}
+		return append(s, bytes.Repeat([]byte{' '}, f.Length-len(s))...), nil
+	case FieldNumeric, FieldFloat:
+		if v == nil {
+			break
+		}
+		switch n := v.(type) {
+		case int:
+			s = strconv.AppendInt(nil, int64(n), 10)
+		case int32:
+			s = strconv.AppendInt(nil, int64(n), 10)
+		case int64:
+			s = strconv.AppendInt(nil, n, 10)
+		case uint32:
+			s = strconv.AppendUint(nil, uint64(n), 10)
+		case uint64:
+			s = strconv.AppendUint(nil, n, 10)
+		case float32:
+			s = strconv.AppendFloat(nil, float64(n), 'f', f.Decimals, 32)
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				break
+			}
+			s = strconv.AppendFloat(nil, n, 'f', f.Decimals, 64)
+		default:
+			return nil, errors.Errorf("expected number, found %T", v)
+		}
+	case FieldLogical:
+		switch v {
+		case true:
+			s = []byte{'T'}
+		case false:
+			s = []byte{'F'}
+		case nil:
+			s = []byte{'?'}
+		default:
+			return nil, errors.Errorf("expected bool, found %T", v)
+		}
+	case FieldDate:
+		if v == nil {
+			break
+		}
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, errors.Errorf("expected time.Time, found %T", v)
+		}
+		s = []byte(t.Format("20060102"))
+	}
+
+	if len(s) > f.Length {
+		return nil, errors.Errorf("%q is longer than the field", s)
+	}
+	return append(bytes.Repeat([]byte{' '}, f.Length-len(s)), s...), nil
+}
@@ -0,0 +1,437 @@
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader reads shapefiles.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	// Otherwise rings keep the shapefile orientation: clockwise shells and
+	// counter-clockwise holes.
+	Orientation geom.Orientation
+	// Charset, if set, overrides the code page of the DBF file given by its
+	// .cpg file or language driver ID. Text is read as UTF-8, falling back
+	// to Windows-1252, if neither is present.
+	Charset *Charset
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// ReadFile reads a shapefile using the default Reader.
+func ReadFile(path string) (*Shapefile, error) {
+	return NewReader().ReadFile(path)
+}
+
+// ReadFile reads the .shp file at path and the .dbf, .prj and .cpg files
+// next to it. Only the .shp file is required.
+func (r *Reader) ReadFile(path string) (*Shapefile, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	shp, err := os.Open(base + ".shp")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer shp.Close()
+
+	reader := *r
+	if reader.Charset == nil {
+		cpg, err := readOptional(base + ".cpg")
+		if err != nil {
+			return nil, err
+		}
+		if cpg != nil {
+			if reader.Charset, err = charsetFromCPG(string(cpg)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var dbf io.Reader
+	dbfFile, err := os.Open(base + ".dbf")
+	switch {
+	case err == nil:
+		defer dbfFile.Close()
+		dbf = dbfFile
+	case !os.IsNotExist(err):
+		return nil, errors.WithStack(err)
+	}
+
+	sf, err := reader.Read(shp, dbf)
+	if err != nil {
+		return nil, err
+	}
+
+	prj, err := readOptional(base + ".prj")
+	if err != nil {
+		return nil, err
+	}
+	sf.Projection = strings.TrimSpace(string(prj))
+	return sf, nil
+}
+
+func readOptional(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, errors.WithStack(err)
+}
+
+// Read reads the shapes of a .shp file and the attributes of a .dbf file,
+// which may be nil. Records deleted in the DBF file are skipped.
+func (r *Reader) Read(shp, dbf io.Reader) (*Shapefile, error) {
+	br := bufio.NewReader(shp)
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, errors.Wrap(err, "failed to read shapefile header")
+	}
+	if code := binary.BigEndian.Uint32(header); code != fileCode {
+		return nil, errors.Errorf("invalid shapefile file code: %d", code)
+	}
+	sf := &Shapefile{ShapeType: ShapeType(binary.LittleEndian.Uint32(header[32:]))}
+	if _, ok := shapeTypeNames[sf.ShapeType]; !ok {
+		return nil, errors.Errorf("unsupported shape type: %d", sf.ShapeType)
+	}
+
+	var attrs *dbfReader
+	if dbf != nil {
+		var err error
+		if attrs, err = newDBFReader(bufio.NewReader(dbf), r.Charset); err != nil {
+			return nil, err
+		}
+		sf.Fields = attrs.fields
+	}
+
+	for i := 0; ; i++ {
+		g, err := r.readRecord(br, sf.ShapeType)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid shapefile record %d", i)
+		}
+
+		rec := &Record{Geometry: g}
+		if attrs != nil {
+			var deleted bool
+			rec.Attributes, deleted, err = attrs.next()
+			if err == io.EOF {
+				return nil, errors.Errorf("DBF file has fewer records than the %d+ shapes", i+1)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if deleted {
+				continue
+			}
+		}
+		sf.Records = append(sf.Records, rec)
+	}
+	return sf, nil
+}
+
+// readRecord reads the next shape, returning io.EOF after the last one.
+func (r *Reader) readRecord(br *bufio.Reader, shapeType ShapeType) (*geom.Geometry, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated record header")
+		}
+		return nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[4:])) * 2
+	content, err := io.ReadAll(io.LimitReader(br, size))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if int64(len(content)) != size {
+		return nil, errors.New("truncated record content")
+	}
+
+	g, err := decodeShape(content, shapeType)
+	if err != nil || g == nil {
+		return nil, err
+	}
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, nil
+}
+
+// shapeDecoder reads little endian values from a record, recording an error
+// rather than reading past its end.
+type shapeDecoder struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (d *shapeDecoder) next(n int) []byte {
+	if d.err != nil || d.pos+n > len(d.b) {
+		d.err = errors.New("truncated record content")
+		return make([]byte, n)
+	}
+	d.pos += n
+	return d.b[d.pos-n : d.pos]
+}
+
+func (d *shapeDecoder) int32() int32 {
+	return int32(binary.LittleEndian.Uint32(d.next(4)))
+}
+
+func (d *shapeDecoder) float() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(d.next(8)))
+}
+
+// m reads an M value, returning NaN for "no data".
+func (d *shapeDecoder) m() float64 {
+	m := d.float()
+	if m < noDataM {
+		return math.NaN()
+	}
+	return m
+}
+
+// count reads a count of elements that each take at least size bytes.
+func (d *shapeDecoder) count(size int) int {
+	n := int(d.int32())
+	if d.err == nil && (n < 0 || n > (len(d.b)-d.pos)/size) {
+		d.err = errors.Errorf("invalid element count: %d", n)
+		return 0
+	}
+	return n
+}
+
+func (d *shapeDecoder) remaining() int {
+	return len(d.b) - d.pos
+}
+
+// coords reads n points followed by the Z and M sections of the shape
+// type, returning whether there are M values. The sections start with
+// their range, except for points.
+func (d *shapeDecoder) coords(t ShapeType, n int) (coord.Coordinates, bool) {
+	rangeSize := 16
+	if t.base() == ShapePoint {
+		rangeSize = 0
+	}
+	cs := make(coord.Coordinates, n)
+	for i := range cs {
+		cs[i].X, cs[i].Y = d.float(), d.float()
+	}
+	if t.HasZ() {
+		d.next(rangeSize)
+		for i := range cs {
+			cs[i].Z = d.float()
+		}
+	}
+	// M is optional for Z types
+	if !t.HasM() && (!t.HasZ() || d.remaining() < rangeSize+8*n) {
+		return cs, false
+	}
+	d.next(rangeSize)
+	hasM := t.HasM()
+	for i := range cs {
+		cs[i].M = d.m()
+		hasM = hasM || !math.IsNaN(cs[i].M)
+	}
+	return cs, hasM
+}
+
+// decodeShape decodes the content of a record, returning nil for a null
+// shape.
+func decodeShape(b []byte, fileType ShapeType) (*geom.Geometry, error) {
+	d := &shapeDecoder{b: b}
+	t := ShapeType(d.int32())
+	if d.err != nil || t == ShapeNull {
+		return nil, d.err
+	}
+	if t != fileType {
+		return nil, errors.Errorf("shape type %v does not match the file shape type %v", t, fileType)
+	}
+
+	var g *geom.Geometry
+	var hasM bool
+	var err error
+	switch t.base() {
+	case ShapePoint:
+		var cs coord.Coordinates
+		cs, hasM = d.coords(t, 1)
+		g, err = geom.NewPoint(cs[0])
+	case ShapeMultiPoint:
+		d.next(32)
+		var cs coord.Coordinates
+		cs, hasM = d.coords(t, d.count(16))
+		points := make([]*geom.Geometry, len(cs))
+		for i, c := range cs {
+			if points[i], err = geom.NewPoint(c); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		g, err = geom.NewMultiPoint(points)
+	case ShapePolyLine, ShapePolygon:
+		d.next(32)
+		numParts := d.count(4)
+		numPoints := int(d.int32())
+		parts := make([]int, numParts)
+		for i := range parts {
+			parts[i] = int(d.int32())
+		}
+		if d.err == nil && (numPoints < 0 || numPoints > d.remaining()/16) {
+			return nil, errors.Errorf("invalid point count: %d", numPoints)
+		}
+		var cs coord.Coordinates
+		cs, hasM = d.coords(t, numPoints)
+		if d.err != nil {
+			return nil, d.err
+		}
+
+		lines := make([]coord.Coordinates, numParts)
+		for i, start := range parts {
+			end := numPoints
+			if i+1 < numParts {
+				end = parts[i+1]
+			}
+			if start < 0 || start > end || end > numPoints {
+				return nil, errors.Errorf("invalid part offsets: %v", parts)
+			}
+			lines[i] = cs[start:end:end]
+		}
+		if t.base() == ShapePolyLine {
+			g, err = newLines(lines)
+		} else {
+			g, err = newPolygons(lines)
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	setLayout(g, geom.NewLayout(t.HasZ(), hasM))
+	return g, nil
+}
+
+func setLayout(g *geom.Geometry, layout geom.Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setLayout(col, layout)
+	}
+}
+
+// newLines returns a LineString for a single part and a MultiLineString
+// otherwise.
+func newLines(lines []coord.Coordinates) (*geom.Geometry, error) {
+	if len(lines) == 1 {
+		return geom.NewLineString(lines[0])
+	}
+	lineStrings := make([]*geom.Geometry, len(lines))
+	for i, line := range lines {
+		var err error
+		if lineStrings[i], err = geom.NewLineString(line); err != nil {
+			return nil, err
+		}
+	}
+	return geom.NewMultiLineString(lineStrings)
+}
+
+// newPolygons assembles rings into polygons. Clockwise rings are shells and
+// counter-clockwise rings are holes, assigned to the smallest shell
+// containing them. Holes outside every shell are reversed and become shells
+// themselves, once all holes have been assigned. Returns a Polygon for a single shell and a
+// MultiPolygon otherwise.
+func newPolygons(rings []coord.Coordinates) (*geom.Geometry, error) {
+	type shell struct {
+		index int
+		ring  coord.Coordinates
+		env   *coord.Envelope
+		area  float64
+		holes coord.MultiLine
+	}
+	var shells []*shell
+	var holes []int
+	for i, ring := range rings {
+		if len(ring) < 4 {
+			continue
+		}
+		if area := coord.SignedArea(ring); area < 0 {
+			shells = append(shells, &shell{index: i, ring: ring, env: ring.Envelope(), area: -area})
+		} else {
+			holes = append(holes, i)
+		}
+	}
+
+	var orphans []*shell
+	for _, i := range holes {
+		hole := rings[i]
+		env := hole.Envelope()
+		var container *shell
+		for _, s := range shells {
+			if (container == nil || s.area < container.area) && contains(s.env, env) && ringContains(s.ring, hole) {
+				container = s
+			}
+		}
+		if container == nil {
+			// reversed to be clockwise like the other shells
+			orphans = append(orphans, &shell{index: i, ring: hole.Reverse(), env: env, area: coord.SignedArea(hole)})
+			continue
+		}
+		container.holes = append(container.holes, hole)
+	}
+	shells = append(shells, orphans...)
+
+	// keep the file order of shells
+	sort.Slice(shells, func(i, j int) bool {
+		return shells[i].index < shells[j].index
+	})
+
+	polygons := make([]*geom.Geometry, len(shells))
+	for i, s := range shells {
+		var err error
+		if polygons[i], err = geom.NewPolygon(s.ring, s.holes); err != nil {
+			return nil, err
+		}
+	}
+	switch len(polygons) {
+	case 0:
+		return geom.NewPolygon(nil, nil)
+	case 1:
+		return polygons[0], nil
+	}
+	return geom.NewMultiPolygon(polygons)
+}
+
+func contains(e, other *coord.Envelope) bool {
+	return other.MinX >= e.MinX && other.MaxX <= e.MaxX && other.MinY >= e.MinY && other.MaxY <= e.MaxY
+}
+
+// ringContains tests whether the first vertex of inner that is not on the
+// boundary of outer lies inside it.
+func ringContains(outer, inner coord.Coordinates) bool {
+	for _, c := range inner {
+		switch coord.PointInRing(c, outer) {
+		case coord.LocationInterior:
+			return true
+		case coord.LocationExterior:
+			return false
+		}
+	}
+	// all vertices are on the boundary
+	return true
+}
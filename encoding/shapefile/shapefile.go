@@ -0,0 +1,130 @@
+// Package shapefile reads and writes ESRI Shapefiles: the geometries in the
+// .shp file (indexed by the .shx file), their attributes in the .dbf file
+// and the projection in the .prj file, which is passed through as is.
+package shapefile
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+type ShapeType int32
+
+const (
+	ShapeNull        ShapeType = 0
+	ShapePoint       ShapeType = 1
+	ShapePolyLine    ShapeType = 3
+	ShapePolygon     ShapeType = 5
+	ShapeMultiPoint  ShapeType = 8
+	ShapePointZ      ShapeType = 11
+	ShapePolyLineZ   ShapeType = 13
+	ShapePolygonZ    ShapeType = 15
+	ShapeMultiPointZ ShapeType = 18
+	ShapePointM      ShapeType = 21
+	ShapePolyLineM   ShapeType = 23
+	ShapePolygonM    ShapeType = 25
+	ShapeMultiPointM ShapeType = 28
+)
+
+var shapeTypeNames = map[ShapeType]string{
+	ShapeNull:        "Null",
+	ShapePoint:       "Point",
+	ShapePolyLine:    "PolyLine",
+	ShapePolygon:     "Polygon",
+	ShapeMultiPoint:  "MultiPoint",
+	ShapePointZ:      "PointZ",
+	ShapePolyLineZ:   "PolyLineZ",
+	ShapePolygonZ:    "PolygonZ",
+	ShapeMultiPointZ: "MultiPointZ",
+	ShapePointM:      "PointM",
+	ShapePolyLineM:   "PolyLineM",
+	ShapePolygonM:    "PolygonM",
+	ShapeMultiPointM: "MultiPointM",
+}
+
+func (t ShapeType) String() string {
+	if name, ok := shapeTypeNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// HasZ reports whether shapes of this type have Z values. They may also
+// have M values.
+func (t ShapeType) HasZ() bool {
+	return t >= ShapePointZ && t <= ShapeMultiPointZ
+}
+
+// HasM reports whether shapes of this type have M values. They are
+// optional for Z types.
+func (t ShapeType) HasM() bool {
+	return t >= ShapePointM && t <= ShapeMultiPointM
+}
+
+// base returns the 2D shape type.
+func (t ShapeType) base() ShapeType {
+	switch {
+	case t.HasZ():
+		return t - 10
+	case t.HasM():
+		return t - 20
+	}
+	return t
+}
+
+func (t ShapeType) withLayout(layout geom.Layout) ShapeType {
+	switch {
+	case t == ShapeNull:
+		return t
+	case layout.HasZ():
+		return t + 10
+	case layout.HasM():
+		return t + 20
+	}
+	return t
+}
+
+// shapeTypeOf returns the shape type that holds the geometry.
+func shapeTypeOf(g *geom.Geometry) (ShapeType, error) {
+	var t ShapeType
+	switch g.Type {
+	case geom.TypePoint:
+		t = ShapePoint
+	case geom.TypeMultiPoint:
+		t = ShapeMultiPoint
+	case geom.TypeLineString, geom.TypeMultiLineString:
+		t = ShapePolyLine
+	case geom.TypePolygon, geom.TypeMultiPolygon:
+		t = ShapePolygon
+	default:
+		return 0, errors.Errorf("unsupported geometry type for shapefile: %v", g.Type)
+	}
+	return t.withLayout(g.Layout), nil
+}
+
+// Shapefile is the content of a shapefile.
+type Shapefile struct {
+	// ShapeType is the type of all non-null shapes. When writing, it is
+	// derived from the first geometry if not set.
+	ShapeType ShapeType
+	Fields    []Field
+	Records   []*Record
+	// Projection is the WKT content of the .prj file, if any.
+	Projection string
+}
+
+// Record is a shape and its attributes, keyed by field name. A null shape
+// has a nil Geometry.
+type Record struct {
+	Geometry   *geom.Geometry
+	Attributes map[string]interface{}
+}
+
+const (
+	fileCode    = 9994
+	fileVersion = 1000
+	headerSize  = 100
+
+	// noDataM is the threshold below which M values mean "no data".
+	noDataM = -1e38
+)
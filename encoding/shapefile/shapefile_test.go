@@ -0,0 +1,181 @@
+package shapefile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func ring(xys ...float64) coord.Coordinates {
+	var cs coord.Coordinates
+	for i := 0; i < len(xys); i += 2 {
+		cs = append(cs, coord.Coordinate{X: xys[i], Y: xys[i+1]})
+	}
+	return cs
+}
+
+func TestNewPolygons(t *testing.T) {
+	// shells are clockwise and holes counter-clockwise
+	outer := ring(0, 0, 0, 10, 10, 10, 10, 0, 0, 0)
+	inner := ring(2, 2, 2, 8, 8, 8, 8, 2, 2, 2)
+	hole := ring(4, 4, 6, 4, 6, 6, 4, 6, 4, 4)
+	orphan := ring(20, 0, 30, 0, 30, 10, 20, 10, 20, 0)
+	otherOrphan := ring(40, 0, 50, 0, 50, 10, 40, 10, 40, 0)
+	tests := []struct {
+		name  string
+		rings []coord.Coordinates
+		want  string
+	}{
+		{
+			name:  "shell",
+			rings: []coord.Coordinates{outer},
+			want:  "POLYGON ((0 0, 0 10, 10 10, 10 0, 0 0))",
+		},
+		{
+			name:  "hole in the smallest containing shell",
+			rings: []coord.Coordinates{outer, inner, hole},
+			want:  "MULTIPOLYGON (((0 0, 0 10, 10 10, 10 0, 0 0)), ((2 2, 2 8, 8 8, 8 2, 2 2), (4 4, 6 4, 6 6, 4 6, 4 4)))",
+		},
+		{
+			name:  "hole outside every shell",
+			rings: []coord.Coordinates{outer, orphan},
+			want:  "MULTIPOLYGON (((0 0, 0 10, 10 10, 10 0, 0 0)), ((20 0, 20 10, 30 10, 30 0, 20 0)))",
+		},
+		{
+			name:  "two holes outside every shell",
+			rings: []coord.Coordinates{orphan, otherOrphan},
+			want:  "MULTIPOLYGON (((20 0, 20 10, 30 10, 30 0, 20 0)), ((40 0, 40 10, 50 10, 50 0, 40 0)))",
+		},
+		{
+			name:  "orphan before a hole",
+			rings: []coord.Coordinates{orphan, outer, hole},
+			want:  "MULTIPOLYGON (((20 0, 20 10, 30 10, 30 0, 20 0)), ((0 0, 0 10, 10 10, 10 0, 0 0), (4 4, 6 4, 6 6, 4 6, 4 4)))",
+		},
+		{
+			name:  "collapsed ring",
+			rings: []coord.Coordinates{ring(0, 0, 1, 1, 0, 0), outer},
+			want:  "POLYGON ((0 0, 0 10, 10 10, 10 0, 0 0))",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := newPolygons(test.rings)
+			if err != nil {
+				t.Fatalf("newPolygons() error = %v", err)
+			}
+			if got, _ := wkt.Marshal(g); got != test.want {
+				t.Errorf("newPolygons() = %s, want %s", got, test.want)
+			}
+			polygons := []*geom.Geometry{g}
+			if g.Type == geom.TypeMultiPolygon {
+				polygons = g.Collection
+			}
+			for _, p := range polygons {
+				if coord.SignedArea(p.Line) >= 0 {
+					t.Errorf("newPolygons() shell %v is not clockwise", p.Line)
+				}
+			}
+		})
+	}
+}
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		shapeType ShapeType
+		geoms     []string
+	}{
+		{"points", ShapePoint, []string{"POINT (1 2)", "POINT (-3.5 4)"}},
+		{"points z", ShapePointZ, []string{"POINT Z (1 2 3)"}},
+		{"multipoints m", ShapeMultiPointM, []string{"MULTIPOINT M ((1 2 3), (4 5 6))"}},
+		{"polylines", ShapePolyLine, []string{"LINESTRING (0 0, 1 1)", "MULTILINESTRING ((0 0, 1 1), (2 2, 3 3))"}},
+		{"polygons", ShapePolygon, []string{
+			"POLYGON ((0 0, 0 10, 10 10, 10 0, 0 0), (2 2, 8 2, 8 8, 2 8, 2 2))",
+			"MULTIPOLYGON (((0 0, 0 1, 1 1, 1 0, 0 0)), ((5 5, 5 6, 6 6, 6 5, 5 5)))",
+		}},
+		{"polygons z", ShapePolygonZ, []string{"POLYGON Z ((0 0 1, 0 10 2, 10 10 3, 0 0 1))"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sf := &Shapefile{
+				Fields: []Field{
+					{Name: "NAME", Type: FieldCharacter, Length: 10},
+					{Name: "COUNT", Type: FieldNumeric, Length: 6},
+					{Name: "RATIO", Type: FieldFloat, Length: 8, Decimals: 3},
+					{Name: "OK", Type: FieldLogical, Length: 1},
+					{Name: "DAY", Type: FieldDate, Length: 8},
+				},
+			}
+			for i, text := range test.geoms {
+				sf.Records = append(sf.Records, &Record{
+					Geometry: mustUnmarshal(t, text),
+					Attributes: map[string]interface{}{
+						"NAME":  "ÄÖ",
+						"COUNT": int64(i),
+						"RATIO": 0.125,
+						"OK":    true,
+						"DAY":   time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+					},
+				})
+			}
+			sf.Records = append(sf.Records, &Record{Attributes: map[string]interface{}{}})
+
+			var shp, shx, dbf bytes.Buffer
+			if err := NewWriter().Write(&shp, &shx, &dbf, sf); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if shx.Len() != headerSize+8*len(sf.Records) {
+				t.Errorf("index size = %d, want %d", shx.Len(), headerSize+8*len(sf.Records))
+			}
+
+			got, err := NewReader().Read(&shp, &dbf)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if got.ShapeType != test.shapeType {
+				t.Errorf("ShapeType = %v, want %v", got.ShapeType, test.shapeType)
+			}
+			if !reflect.DeepEqual(got.Fields, sf.Fields) {
+				t.Errorf("Fields = %v, want %v", got.Fields, sf.Fields)
+			}
+			if len(got.Records) != len(sf.Records) {
+				t.Fatalf("got %d records, want %d", len(got.Records), len(sf.Records))
+			}
+			for i, text := range test.geoms {
+				rec := got.Records[i]
+				if text, _ := wkt.Marshal(mustUnmarshal(t, text)); mustMarshal(t, rec.Geometry) != text {
+					t.Errorf("record %d geometry = %s, want %s", i, mustMarshal(t, rec.Geometry), text)
+				}
+				if !reflect.DeepEqual(rec.Attributes, sf.Records[i].Attributes) {
+					t.Errorf("record %d attributes = %v, want %v", i, rec.Attributes, sf.Records[i].Attributes)
+				}
+			}
+			if last := got.Records[len(got.Records)-1]; last.Geometry != nil {
+				t.Errorf("null shape = %v, want nil", last.Geometry)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, g *geom.Geometry) string {
+	t.Helper()
+	text, err := wkt.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return text
+}
@@ -0,0 +1,333 @@
+package shapefile
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer writes shapefiles.
+type Writer struct {
+	// Charset is the code page of DBF text.
+	Charset *Charset
+}
+
+func NewWriter() *Writer {
+	return &Writer{Charset: CharsetUTF8}
+}
+
+// WriteFile writes a shapefile using the default Writer.
+func WriteFile(path string, sf *Shapefile) error {
+	return NewWriter().WriteFile(path, sf)
+}
+
+// WriteFile writes the .shp, .shx, .dbf and .cpg files of a shapefile, and
+// the .prj file if it has a projection.
+func (w *Writer) WriteFile(path string, sf *Shapefile) error {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	create := func(ext string) (*os.File, error) {
+		f, err := os.Create(base + ext)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		files = append(files, f)
+		return f, nil
+	}
+
+	shp, err := create(".shp")
+	if err != nil {
+		return err
+	}
+	shx, err := create(".shx")
+	if err != nil {
+		return err
+	}
+	dbf, err := create(".dbf")
+	if err != nil {
+		return err
+	}
+	if err := w.Write(shp, shx, dbf, sf); err != nil {
+		return err
+	}
+
+	if name := w.charset().Name; name != "" {
+		if err := os.WriteFile(base+".cpg", []byte(name), 0666); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if sf.Projection != "" {
+		if err := os.WriteFile(base+".prj", []byte(sf.Projection), 0666); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	files = nil
+	return nil
+}
+
+// Write writes the shapes of a shapefile to shp, its index to shx and its
+// attributes to dbf. If the shape type of the shapefile is not set, it is
+// derived from its first geometry. Polygons are written with clockwise
+// shells and counter-clockwise holes.
+func (w *Writer) Write(shp, shx, dbf io.Writer, sf *Shapefile) error {
+	shapeType := sf.ShapeType
+	if shapeType == ShapeNull {
+		for _, rec := range sf.Records {
+			if rec.Geometry != nil && !rec.Geometry.IsEmpty() {
+				var err error
+				if shapeType, err = shapeTypeOf(rec.Geometry); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	b := newBounds()
+	shapes := make([][]byte, len(sf.Records))
+	for i, rec := range sf.Records {
+		var err error
+		if shapes[i], err = encodeShape(rec.Geometry, shapeType, b); err != nil {
+			return errors.Wrapf(err, "invalid geometry in record %d", i)
+		}
+	}
+
+	// file lengths and offsets are in 16 bit words
+	length := headerSize / 2
+	index := make([]byte, 0, 8*len(shapes))
+	for _, shape := range shapes {
+		index = binary.BigEndian.AppendUint32(index, uint32(length))
+		index = binary.BigEndian.AppendUint32(index, uint32(len(shape)/2))
+		length += 4 + len(shape)/2
+	}
+	if length > math.MaxInt32 {
+		return errors.New("shapefile is larger than 4GB")
+	}
+
+	buf := appendHeader(make([]byte, 0, length*2), shapeType, length, b)
+	for i, shape := range shapes {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(i+1))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(shape)/2))
+		buf = append(buf, shape...)
+	}
+	if _, err := shp.Write(buf); err != nil {
+		return errors.WithStack(err)
+	}
+
+	header := appendHeader(nil, shapeType, headerSize/2+len(index)/2, b)
+	if _, err := shx.Write(append(header, index...)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return writeDBF(dbf, sf.Fields, sf.Records, w.charset())
+}
+
+func (w *Writer) charset() *Charset {
+	if w.Charset == nil {
+		return CharsetUTF8
+	}
+	return w.Charset
+}
+
+func appendHeader(dst []byte, shapeType ShapeType, length int, b *bounds) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, fileCode)
+	dst = append(dst, make([]byte, 20)...)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(length))
+	dst = binary.LittleEndian.AppendUint32(dst, fileVersion)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(shapeType))
+	for _, v := range b.header() {
+		dst = appendFloat(dst, v)
+	}
+	return dst
+}
+
+func appendFloat(dst []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(v))
+}
+
+// bounds accumulates the ranges of the ordinates of the shapes.
+type bounds struct {
+	min, max [4]float64
+}
+
+func newBounds() *bounds {
+	b := &bounds{}
+	for i := range b.min {
+		b.min[i], b.max[i] = math.Inf(1), math.Inf(-1)
+	}
+	return b
+}
+
+func (b *bounds) expand(c coord.Coordinate) {
+	for i, v := range [4]float64{c.X, c.Y, c.Z, c.M} {
+		if !math.IsNaN(v) {
+			b.min[i], b.max[i] = math.Min(b.min[i], v), math.Max(b.max[i], v)
+		}
+	}
+}
+
+// rangeOf returns the range of an ordinate, or zeros if it is unset.
+func (b *bounds) rangeOf(i int) (float64, float64) {
+	if b.min[i] > b.max[i] {
+		return 0, 0
+	}
+	return b.min[i], b.max[i]
+}
+
+// header returns Xmin, Ymin, Xmax, Ymax, Zmin, Zmax, Mmin and Mmax.
+func (b *bounds) header() [8]float64 {
+	minX, maxX := b.rangeOf(0)
+	minY, maxY := b.rangeOf(1)
+	minZ, maxZ := b.rangeOf(2)
+	minM, maxM := b.rangeOf(3)
+	return [8]float64{minX, minY, maxX, maxY, minZ, maxZ, minM, maxM}
+}
+
+// encodeShape encodes the content of a record, expanding the file bounds
+// by its coordinates. Nil and empty geometries are written as null shapes.
+func encodeShape(g *geom.Geometry, shapeType ShapeType, fileBounds *bounds) ([]byte, error) {
+	if g == nil || g.IsEmpty() {
+		return binary.LittleEndian.AppendUint32(nil, uint32(ShapeNull)), nil
+	}
+	t, err := shapeTypeOf(g)
+	if err != nil {
+		return nil, err
+	}
+	if t != shapeType {
+		return nil, errors.Errorf("%v %v does not match the file shape type %v", g.Layout, g.Type, shapeType)
+	}
+
+	var parts []coord.Coordinates
+	switch g.Type {
+	case geom.TypePoint:
+		parts = []coord.Coordinates{{g.Coord}}
+	case geom.TypeMultiPoint:
+		var points coord.Coordinates
+		for _, p := range g.Collection {
+			if !p.IsEmpty() {
+				points = append(points, p.Coord)
+			}
+		}
+		parts = []coord.Coordinates{points}
+	case geom.TypeLineString:
+		parts = []coord.Coordinates{g.Line}
+	case geom.TypeMultiLineString:
+		for _, line := range g.Collection {
+			if len(line.Line) > 0 {
+				parts = append(parts, line.Line)
+			}
+		}
+	case geom.TypePolygon:
+		parts = polygonRings(g)
+	case geom.TypeMultiPolygon:
+		for _, poly := range g.Collection {
+			parts = append(parts, polygonRings(poly)...)
+		}
+	}
+
+	var cs coord.Coordinates
+	for _, part := range parts {
+		cs = append(cs, part...)
+	}
+	b := newBounds()
+	for i := range cs {
+		if !g.Layout.HasM() {
+			cs[i].M = math.NaN()
+		}
+		b.expand(cs[i])
+		fileBounds.expand(cs[i])
+	}
+
+	dst := binary.LittleEndian.AppendUint32(nil, uint32(t))
+	if t.base() == ShapePoint {
+		return appendOrdinates(dst, t, cs, b, false), nil
+	}
+
+	header := b.header()
+	for _, v := range header[:4] {
+		dst = appendFloat(dst, v)
+	}
+	if t.base() == ShapeMultiPoint {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(cs)))
+		return appendOrdinates(dst, t, cs, b, true), nil
+	}
+
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(parts)))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(cs)))
+	start := 0
+	for _, part := range parts {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(start))
+		start += len(part)
+	}
+	return appendOrdinates(dst, t, cs, b, true), nil
+}
+
+// polygonRings returns the rings of a polygon with a clockwise shell and
+// counter-clockwise holes.
+func polygonRings(g *geom.Geometry) []coord.Coordinates {
+	if len(g.Line) == 0 {
+		return nil
+	}
+	rings := []coord.Coordinates{orientRing(g.Line, false)}
+	for _, hole := range g.MultiLine {
+		rings = append(rings, orientRing(hole, true))
+	}
+	return rings
+}
+
+func orientRing(ring coord.Coordinates, ccw bool) coord.Coordinates {
+	if coord.SignedArea(ring) > 0 != ccw {
+		return ring.Reverse()
+	}
+	return ring
+}
+
+// appendOrdinates appends X and Y followed by the Z and M sections of the
+// shape type. Multi-point shapes have the range of each section.
+func appendOrdinates(dst []byte, t ShapeType, cs coord.Coordinates, b *bounds, ranges bool) []byte {
+	for _, c := range cs {
+		dst = appendFloat(appendFloat(dst, c.X), c.Y)
+	}
+	if t.HasZ() {
+		if ranges {
+			min, max := b.rangeOf(2)
+			dst = appendFloat(appendFloat(dst, min), max)
+		}
+		for _, c := range cs {
+			dst = appendFloat(dst, c.Z)
+		}
+	}
+	if t.HasZ() || t.HasM() {
+		if ranges {
+			min, max := b.rangeOf(3)
+			dst = appendFloat(appendFloat(dst, min), max)
+		}
+		for _, c := range cs {
+			m := c.M
+			if math.IsNaN(m) {
+				m = noDataM * 10
+			}
+			dst = appendFloat(dst, m)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,122 @@
+// Package sqlgeom scans geometries from and writes geometries to SQL
+// databases through database/sql, as used with PostGIS and SpatiaLite.
+package sqlgeom
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/encoding/wkb"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Format selects how geometries are written to a database.
+type Format int
+
+const (
+	// FormatEWKB writes EWKB bytes.
+	FormatEWKB Format = iota
+	// FormatHexEWKB writes EWKB as a hex string, which PostGIS accepts as
+	// text input for geometry parameters.
+	FormatHexEWKB
+	// FormatEWKT writes EWKT text.
+	FormatEWKT
+)
+
+// Geometry wraps a geometry to implement sql.Scanner and driver.Valuer.
+// A nil geometry is a SQL NULL.
+//
+// Scan accepts EWKB or WKB bytes, hex encoded EWKB (optionally with the
+// "\x" prefix of PostgreSQL bytea text) and EWKT or WKT, as []byte or
+// string. The SRID of EWKB and EWKT values is kept. SpatiaLite's internal
+// BLOB format is not supported: select geometries with AsEWKB or
+// AsBinary instead.
+type Geometry struct {
+	*geom.Geometry
+	// Format is the format written by Value.
+	Format Format
+}
+
+// Scan implements sql.Scanner.
+func (g *Geometry) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		g.Geometry = nil
+	case []byte:
+		g.Geometry, err = decode(v)
+	case string:
+		g.Geometry, err = decodeText(v)
+	default:
+		return errors.Errorf("cannot scan %T into a geometry", src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer.
+func (g Geometry) Value() (driver.Value, error) {
+	if g.Geometry == nil {
+		return nil, nil
+	}
+	switch g.Format {
+	case FormatEWKB, FormatHexEWKB:
+		w := wkb.NewWriter()
+		w.Flavor = wkb.FlavorEWKB
+		b, err := w.Marshal(g.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		if g.Format == FormatHexEWKB {
+			return strings.ToUpper(hex.EncodeToString(b)), nil
+		}
+		return b, nil
+	case FormatEWKT:
+		w := wkt.NewWriter()
+		w.EWKT = true
+		s, err := w.Write(g.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, errors.Errorf("invalid geometry format: %d", g.Format)
+}
+
+// decode decodes binary EWKB, which always starts with a byte order of 0 or
+// 1, or else text returned as bytes.
+func decode(b []byte) (*geom.Geometry, error) {
+	if len(b) > 0 && (b[0] == 0 || b[0] == 1) {
+		return wkb.Unmarshal(b)
+	}
+	return decodeText(string(b))
+}
+
+func decodeText(s string) (*geom.Geometry, error) {
+	s = strings.TrimSpace(s)
+	h := strings.TrimPrefix(s, `\x`)
+	if isHex(h) {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return wkb.Unmarshal(b)
+	}
+	return wkt.Unmarshal(s)
+}
+
+// isHex reports whether s is a non-empty, even length string of hex digits,
+// which cannot be WKT.
+func isHex(s string) bool {
+	if s == "" || len(s)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("0123456789abcdefABCDEF", s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package sqlgeom
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+var (
+	_ sql.Scanner   = (*Geometry)(nil)
+	_ driver.Valuer = Geometry{}
+)
+
+// pointEWKB is what PostGIS returns for SELECT 'SRID=4326;POINT(1 2)'::geometry.
+const pointEWKB = "0101000020E6100000000000000000F03F0000000000000040"
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+		want string
+	}{
+		{"EWKB bytes", mustDecodeHex(t, pointEWKB), "SRID=4326;POINT (1 2)"},
+		{"WKB bytes", mustDecodeHex(t, "0101000000000000000000F03F0000000000000040"), "POINT (1 2)"},
+		{"big endian WKB", mustDecodeHex(t, "00000000013FF00000000000004000000000000000"), "POINT (1 2)"},
+		{"hex EWKB string", pointEWKB, "SRID=4326;POINT (1 2)"},
+		{"lower case hex", "0101000020e6100000000000000000f03f0000000000000040", "SRID=4326;POINT (1 2)"},
+		{"hex EWKB bytes", []byte(pointEWKB), "SRID=4326;POINT (1 2)"},
+		{"bytea text", `\x` + pointEWKB, "SRID=4326;POINT (1 2)"},
+		{"EWKT string", "SRID=3857;LINESTRING (1 2, 3 4)", "SRID=3857;LINESTRING (1 2, 3 4)"},
+		{"WKT bytes", []byte(" POLYGON ((0 0, 1 0, 1 1, 0 0)) "), "POLYGON ((0 0, 1 0, 1 1, 0 0))"},
+		{"Z EWKB", mustDecodeHex(t, "01010000A0E6100000000000000000F03F00000000000000400000000000000840"), "SRID=4326;POINT (1 2 3)"},
+	}
+	ewkt := &wkt.Writer{Precision: -1, TrimZeros: true, EWKT: true}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var g Geometry
+			if err := g.Scan(test.src); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if got, _ := ewkt.Write(g.Geometry); got != test.want {
+				t.Errorf("Scan() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanNull(t *testing.T) {
+	g := Geometry{Geometry: mustUnmarshal(t, "POINT (1 2)")}
+	if err := g.Scan(nil); err != nil || g.Geometry != nil {
+		t.Errorf("Scan(nil) = %v, %v, want nil", g.Geometry, err)
+	}
+	if v, err := g.Value(); v != nil || err != nil {
+		t.Errorf("Value() = %v, %v, want nil", v, err)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+	}{
+		{"unsupported type", 42},
+		{"truncated WKB", mustDecodeHex(t, "0101000020E6100000")},
+		{"truncated hex", pointEWKB[:20]},
+		{"invalid WKT", "POINT (1"},
+		{"empty", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var g Geometry
+			if err := g.Scan(test.src); err == nil {
+				t.Errorf("Scan() = %v, want an error", g.Geometry)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	p := mustUnmarshal(t, "SRID=4326;POINT (1 2)")
+	tests := []struct {
+		format Format
+		want   driver.Value
+	}{
+		{FormatEWKB, mustDecodeHex(t, pointEWKB)},
+		{FormatHexEWKB, pointEWKB},
+		{FormatEWKT, "SRID=4326;POINT (1 2)"},
+	}
+	for _, test := range tests {
+		v, err := Geometry{Geometry: p, Format: test.format}.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		if b, ok := test.want.([]byte); ok {
+			if got, _ := v.([]byte); !bytes.Equal(got, b) {
+				t.Errorf("format %d: Value() = %X, want %X", test.format, v, b)
+			}
+		} else if v != test.want {
+			t.Errorf("format %d: Value() = %v, want %v", test.format, v, test.want)
+		}
+		if !driver.IsValue(v) {
+			t.Errorf("format %d: Value() = %T, not a driver.Value", test.format, v)
+		}
+
+		// every value scans back to the same geometry and SRID
+		var g Geometry
+		if err := g.Scan(v); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if g.SRID != 4326 || g.Layout != p.Layout || g.Coord != p.Coord {
+			t.Errorf("format %d: Scan(Value()) = %+v", test.format, g.Geometry)
+		}
+	}
+
+	if _, err := (Geometry{Geometry: p, Format: Format(99)}).Value(); err == nil {
+		t.Error("Value() with an invalid format, want an error")
+	}
+}
@@ -0,0 +1,323 @@
+package twkb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Reader decodes TWKB geometries.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unmarshal decodes a TWKB geometry using the default Reader.
+func Unmarshal(data []byte) (*geom.Geometry, error) {
+	return NewReader().Read(data)
+}
+
+// Read decodes a TWKB geometry, ignoring any id list.
+func (r *Reader) Read(data []byte) (*geom.Geometry, error) {
+	g, _, err := r.ReadWithIDs(data)
+	return g, err
+}
+
+// ReadWithIDs decodes a TWKB geometry, also returning the ids of its
+// components if the geometry has an id list.
+func (r *Reader) ReadWithIDs(data []byte) (*geom.Geometry, []int64, error) {
+	d := &decoder{data: data}
+	g, ids, err := d.readGeometry()
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, nil, errors.Errorf("unexpected %d bytes after TWKB geometry", len(d.data)-d.pos)
+	}
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, ids, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+
+	layout geom.Layout
+	scales [4]float64
+	last   [4]int64
+}
+
+var errTruncated = errors.New("TWKB data is truncated")
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readVarint() (int64, error) {
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) readCount() (int, error) {
+	n, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	// every element takes at least one byte, which also bounds allocations
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, errors.Errorf("invalid TWKB element count: %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) readGeometry() (*geom.Geometry, []int64, error) {
+	header, err := d.readByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := geomType(header & 0x0f)
+	if err != nil {
+		return nil, nil, err
+	}
+	precision := unzigzag(header >> 4)
+
+	meta, err := d.readByte()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	layout := geom.LayoutXY
+	precisionZ, precisionM := 0, 0
+	if meta&flagExtendedPrecision != 0 {
+		ext, err := d.readByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		layout = geom.NewLayout(ext&extendedFlagZ != 0, ext&extendedFlagM != 0)
+		precisionZ = int(ext>>2) & 0x07
+		precisionM = int(ext>>5) & 0x07
+	}
+
+	if meta&flagEmpty != 0 {
+		g, err := emptyGeometry(t)
+		if err != nil {
+			return nil, nil, err
+		}
+		g.Layout = layout
+		return g, nil, nil
+	}
+
+	if meta&flagSize != 0 {
+		size, err := d.readUvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+		if size > uint64(len(d.data)-d.pos) {
+			return nil, nil, errTruncated
+		}
+	}
+
+	// the coordinate state is per geometry, so nested collection members
+	// are decoded with their own
+	parent := *d
+	d.layout = layout
+	d.scales = [4]float64{math.Pow10(precision), math.Pow10(precision), math.Pow10(precisionZ), math.Pow10(precisionM)}
+	d.last = [4]int64{}
+	defer func() {
+		d.layout, d.scales, d.last = parent.layout, parent.scales, parent.last
+	}()
+
+	if meta&flagBBox != 0 {
+		for i := 0; i < 2*layout.Stride(); i++ {
+			if _, err := d.readVarint(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var g *geom.Geometry
+	var ids []int64
+	switch t {
+	case geom.TypePoint, geom.TypeLineString, geom.TypePolygon:
+		if meta&flagIDList != 0 {
+			return nil, nil, errors.Errorf("unexpected TWKB id list for %v", t)
+		}
+		if g, err = d.readBody(t); err != nil {
+			return nil, nil, err
+		}
+	default:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, nil, err
+		}
+		if meta&flagIDList != 0 {
+			ids = make([]int64, n)
+			for i := range ids {
+				if ids[i], err = d.readVarint(); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		collection := make([]*geom.Geometry, 0, n)
+		for i := 0; i < n; i++ {
+			var col *geom.Geometry
+			switch t {
+			case geom.TypeMultiPoint:
+				col, err = d.readBody(geom.TypePoint)
+			case geom.TypeMultiLineString:
+				col, err = d.readBody(geom.TypeLineString)
+			case geom.TypeMultiPolygon:
+				col, err = d.readBody(geom.TypePolygon)
+			default:
+				col, _, err = d.readGeometry()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			collection = append(collection, col)
+		}
+		if g, err = newCollection(t, collection); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	setLayout(g, layout)
+	return g, ids, nil
+}
+
+func setLayout(g *geom.Geometry, layout geom.Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setLayout(col, layout)
+	}
+}
+
+func emptyGeometry(t geom.Type) (*geom.Geometry, error) {
+	switch t {
+	case geom.TypePoint:
+		return geom.NewEmptyPoint()
+	case geom.TypeLineString:
+		return geom.NewLineString(nil)
+	case geom.TypePolygon:
+		return geom.NewPolygon(nil, nil)
+	}
+	return newCollection(t, nil)
+}
+
+func newCollection(t geom.Type, collection []*geom.Geometry) (*geom.Geometry, error) {
+	switch t {
+	case geom.TypeMultiPoint:
+		return geom.NewMultiPoint(collection)
+	case geom.TypeMultiLineString:
+		return geom.NewMultiLineString(collection)
+	case geom.TypeMultiPolygon:
+		return geom.NewMultiPolygon(collection)
+	}
+	return geom.NewCollection(collection)
+}
+
+func (d *decoder) readCoord() (geom.Coordinate, error) {
+	var ords [4]float64
+	for i := 0; i < d.layout.Stride(); i++ {
+		delta, err := d.readVarint()
+		if err != nil {
+			return geom.Coordinate{}, err
+		}
+		d.last[i] += delta
+		scale := d.scales[i]
+		if i == 2 && d.layout == geom.LayoutXYM {
+			scale = d.scales[3]
+		}
+		ords[i] = float64(d.last[i]) / scale
+	}
+
+	c := geom.Coordinate{X: ords[0], Y: ords[1]}
+	switch d.layout {
+	case geom.LayoutXYZ:
+		c.Z = ords[2]
+	case geom.LayoutXYM:
+		c.M = ords[2]
+	case geom.LayoutXYZM:
+		c.Z, c.M = ords[2], ords[3]
+	}
+	return c, nil
+}
+
+func (d *decoder) readCoords() (geom.Coordinates, error) {
+	n, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+	coords := make(geom.Coordinates, 0, n)
+	for i := 0; i < n; i++ {
+		c, err := d.readCoord()
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, c)
+	}
+	return coords, nil
+}
+
+// readBody reads the coordinates of a single geometry, without a header.
+func (d *decoder) readBody(t geom.Type) (*geom.Geometry, error) {
+	switch t {
+	case geom.TypePoint:
+		c, err := d.readCoord()
+		if err != nil {
+			return nil, err
+		}
+		return geom.NewPoint(c)
+	case geom.TypeLineString:
+		line, err := d.readCoords()
+		if err != nil {
+			return nil, err
+		}
+		return geom.NewLineString(line)
+	}
+
+	n, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+	rings := make(geom.MultiLine, 0, n)
+	for i := 0; i < n; i++ {
+		ring, err := d.readCoords()
+		if err != nil {
+			return nil, err
+		}
+		rings = append(rings, ring)
+	}
+	if len(rings) == 0 {
+		return geom.NewPolygon(nil, nil)
+	}
+	return geom.NewPolygon(rings[0], rings[1:])
+}
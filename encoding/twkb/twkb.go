@@ -0,0 +1,51 @@
+// Package twkb implements the Tiny Well-Known Binary format: a compact
+// encoding of geometries using fixed precision, delta-encoded varint
+// coordinates.
+package twkb
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+const (
+	flagBBox              = 0x01
+	flagSize              = 0x02
+	flagIDList            = 0x04
+	flagExtendedPrecision = 0x08
+	flagEmpty             = 0x10
+
+	extendedFlagZ = 0x01
+	extendedFlagM = 0x02
+)
+
+var twkbTypes = map[geom.Type]byte{
+	geom.TypePoint:           1,
+	geom.TypeLineString:      2,
+	geom.TypePolygon:         3,
+	geom.TypeMultiPoint:      4,
+	geom.TypeMultiLineString: 5,
+	geom.TypeMultiPolygon:    6,
+	geom.TypeCollection:      7,
+}
+
+func geomType(twkbType byte) (geom.Type, error) {
+	for t, tt := range twkbTypes {
+		if tt == twkbType {
+			return t, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported TWKB geometry type: %d", twkbType)
+}
+
+// zigzag encodes a signed precision so that small magnitudes have small codes.
+func zigzag(v int) byte {
+	if v < 0 {
+		return byte(-2*v - 1)
+	}
+	return byte(2 * v)
+}
+
+func unzigzag(v byte) int {
+	return int(v>>1) ^ -int(v&1)
+}
@@ -0,0 +1,125 @@
+package twkb
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		w    *Writer
+		text string
+		want string // the text read back, if different
+		hex  string
+	}{
+		// examples from the TWKB specification and PostGIS ST_AsTWKB
+		{name: "point", w: NewWriter(), text: "POINT (1 2)", hex: "01000204"},
+		{name: "linestring", w: NewWriter(), text: "LINESTRING (1 2, 3 4)", hex: "02000202040404"},
+		{name: "postgis linestring", w: NewWriter(), text: "LINESTRING (1 1, 5 5)", hex: "02000202020808"},
+		{name: "precision", w: &Writer{Precision: 2}, text: "POINT (1.23 4.56)", hex: "4100f6019007"},
+		{name: "rounded", w: &Writer{Precision: 1}, text: "POINT (1.26 -4.54)", want: "POINT (1.3 -4.5)", hex: "21001a59"},
+		{name: "negative precision", w: &Writer{Precision: -2}, text: "POINT (1234 5678)", want: "POINT (1200 5700)", hex: "31001872"},
+		{name: "bbox", w: &Writer{BBox: true}, text: "LINESTRING (1 2, 3 4)", hex: "0201020404040202040404"},
+		{name: "size", w: &Writer{Size: true}, text: "POINT (1 2)", hex: "0102020204"},
+		{name: "z", w: &Writer{PrecisionZ: 1}, text: "POINT Z (1 2 3.5)", hex: "010805020446"},
+		{name: "m", w: NewWriter(), text: "POINT M (1 2 3)", hex: "010802020406"},
+		{name: "empty point", w: NewWriter(), text: "POINT EMPTY", hex: "0110"},
+		{name: "empty collection", w: NewWriter(), text: "GEOMETRYCOLLECTION EMPTY", hex: "0710"},
+		{name: "polygon with hole", w: NewWriter(), text: "POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0), (1 1, 1 2, 2 2, 2 1, 1 1))", hex: "03000205000008000008070000070502020002020000010100"},
+		{name: "multipoint", w: NewWriter(), text: "MULTIPOINT ((1 2), (3 4))", hex: "04000202040404"},
+		{name: "collection", w: NewWriter(), text: "GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (1 2, 3 4))", hex: "0700020100020402000202040404"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := wkt.Unmarshal(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := test.w.Marshal(g)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if got := hex.EncodeToString(b); got != test.hex {
+				t.Errorf("Marshal() = %s, want %s", got, test.hex)
+			}
+
+			g, err = Unmarshal(b)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			want := test.want
+			if want == "" {
+				want = test.text
+			}
+			if got, _ := wkt.Marshal(g); got != want {
+				t.Errorf("Unmarshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestIDs(t *testing.T) {
+	g, err := wkt.Unmarshal("MULTIPOINT ((1 2), (3 4))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewWriter().MarshalWithIDs(g, []int64{5, -6})
+	if err != nil {
+		t.Fatalf("MarshalWithIDs() error = %v", err)
+	}
+	if got, want := hex.EncodeToString(b), "0404020a0b02040404"; got != want {
+		t.Errorf("MarshalWithIDs() = %s, want %s", got, want)
+	}
+	_, ids, err := NewReader().ReadWithIDs(b)
+	if err != nil {
+		t.Fatalf("ReadWithIDs() error = %v", err)
+	}
+	if want := []int64{5, -6}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ReadWithIDs() ids = %v, want %v", ids, want)
+	}
+
+	if _, err := NewWriter().MarshalWithIDs(g, []int64{1}); err == nil {
+		t.Error("MarshalWithIDs() with too few ids, want an error")
+	}
+	p, _ := wkt.Unmarshal("POINT (1 2)")
+	if _, err := NewWriter().MarshalWithIDs(p, []int64{1}); err == nil {
+		t.Error("MarshalWithIDs() of a point, want an error")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"unsupported type", "08000204"},
+		{"truncated", "010002"},
+		{"truncated varint", "010002ff"},
+		{"trailing bytes", "0100020400"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := hex.DecodeString(test.hex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g, err := Unmarshal(b); err == nil {
+				t.Errorf("Unmarshal() = %v, want an error", g)
+			}
+		})
+	}
+}
+
+func TestWriterPrecisionErrors(t *testing.T) {
+	g, _ := wkt.Unmarshal("POINT (1 2)")
+	for _, w := range []*Writer{{Precision: 8}, {Precision: -9}, {PrecisionZ: 8}, {PrecisionM: -1}} {
+		if _, err := w.Marshal(g); err == nil {
+			t.Errorf("Marshal() with %+v, want an error", w)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package twkb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer encodes geometries as TWKB.
+type Writer struct {
+	// Precision is the number of decimal places kept for X and Y, from -8
+	// to 7. Negative values round to tens, hundreds etc.
+	Precision int
+	// PrecisionZ and PrecisionM are the decimal places kept for Z and M,
+	// from 0 to 7.
+	PrecisionZ, PrecisionM int
+
+	// BBox includes the bounding box of the geometry in the header.
+	BBox bool
+	// Size includes the size of the encoded geometry in the header, so
+	// that readers can skip it.
+	Size bool
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Marshal encodes a geometry as TWKB using the default Writer.
+func Marshal(g *geom.Geometry) ([]byte, error) {
+	return NewWriter().Marshal(g)
+}
+
+// Marshal encodes a geometry as TWKB.
+func (w *Writer) Marshal(g *geom.Geometry) ([]byte, error) {
+	return w.MarshalWithIDs(g, nil)
+}
+
+// MarshalWithIDs encodes a multi geometry or collection as TWKB, including
+// an id for each of its components.
+func (w *Writer) MarshalWithIDs(g *geom.Geometry, ids []int64) ([]byte, error) {
+	if w.Precision < -8 || w.Precision > 7 {
+		return nil, errors.Errorf("TWKB precision must be between -8 and 7, found %d", w.Precision)
+	}
+	if w.PrecisionZ < 0 || w.PrecisionZ > 7 || w.PrecisionM < 0 || w.PrecisionM > 7 {
+		return nil, errors.Errorf("TWKB Z and M precision must be between 0 and 7")
+	}
+	if ids != nil {
+		if !g.IsMulti() {
+			return nil, errors.Errorf("TWKB id lists are only supported for multi geometries, found %v", g.Type)
+		}
+		if len(ids) != len(g.Collection) {
+			return nil, errors.Errorf("expected %d ids, found %d", len(g.Collection), len(ids))
+		}
+	}
+	return w.appendGeometry(nil, g, g.Layout, ids)
+}
+
+type encoder struct {
+	layout geom.Layout
+	scales [4]float64
+	last   [4]int64
+	buf    []byte
+}
+
+func (w *Writer) appendGeometry(dst []byte, g *geom.Geometry, layout geom.Layout, ids []int64) ([]byte, error) {
+	twkbType, ok := twkbTypes[g.Type]
+	if !ok {
+		return nil, errors.Errorf("unsupported geometry type for TWKB: %v", g.Type)
+	}
+	dst = append(dst, twkbType|zigzag(w.Precision)<<4)
+
+	var meta byte
+	if layout != geom.LayoutXY {
+		meta |= flagExtendedPrecision
+	}
+	empty := g.IsEmpty()
+	if empty {
+		meta |= flagEmpty
+	} else {
+		if w.BBox {
+			meta |= flagBBox
+		}
+		if w.Size {
+			meta |= flagSize
+		}
+		if ids != nil {
+			meta |= flagIDList
+		}
+	}
+	dst = append(dst, meta)
+
+	if meta&flagExtendedPrecision != 0 {
+		var ext byte
+		if layout.HasZ() {
+			ext |= extendedFlagZ | byte(w.PrecisionZ)<<2
+		}
+		if layout.HasM() {
+			ext |= extendedFlagM | byte(w.PrecisionM)<<5
+		}
+		dst = append(dst, ext)
+	}
+	if empty {
+		return dst, nil
+	}
+
+	e := &encoder{layout: layout}
+	e.scales[0] = math.Pow10(w.Precision)
+	e.scales[1] = e.scales[0]
+	e.scales[2] = math.Pow10(w.PrecisionZ)
+	e.scales[3] = math.Pow10(w.PrecisionM)
+
+	if w.BBox {
+		e.appendBBox(g)
+	}
+
+	if g.IsMulti() {
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(g.Collection)))
+		for _, id := range ids {
+			e.buf = binary.AppendVarint(e.buf, id)
+		}
+	}
+
+	switch g.Type {
+	case geom.TypeCollection:
+		for _, col := range g.Collection {
+			var err error
+			if e.buf, err = w.appendGeometry(e.buf, col, layout, nil); err != nil {
+				return nil, err
+			}
+		}
+	case geom.TypeMultiPoint, geom.TypeMultiLineString, geom.TypeMultiPolygon:
+		for _, col := range g.Collection {
+			if err := e.appendBody(col); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if err := e.appendBody(g); err != nil {
+			return nil, err
+		}
+	}
+
+	if w.Size {
+		dst = binary.AppendUvarint(dst, uint64(len(e.buf)))
+	}
+	return append(dst, e.buf...), nil
+}
+
+func (e *encoder) ordinates(c geom.Coordinate) [4]float64 {
+	ords := [4]float64{c.X, c.Y}
+	n := 2
+	if e.layout.HasZ() {
+		ords[n] = c.Z
+		n++
+	}
+	if e.layout.HasM() {
+		ords[n] = c.M
+	}
+	return ords
+}
+
+// scale returns the ordinate at index i scaled to an integer. The third
+// ordinate is M rather than Z for XYM geometries.
+func (e *encoder) scale(v float64, i int) int64 {
+	s := e.scales[i]
+	if i == 2 && e.layout == geom.LayoutXYM {
+		s = e.scales[3]
+	}
+	return int64(math.Round(v * s))
+}
+
+func (e *encoder) appendBBox(g *geom.Geometry) {
+	var min, max [4]int64
+	first := true
+	forEachCoord(g, func(c geom.Coordinate) {
+		ords := e.ordinates(c)
+		for i := 0; i < e.layout.Stride(); i++ {
+			v := e.scale(ords[i], i)
+			if first || v < min[i] {
+				min[i] = v
+			}
+			if first || v > max[i] {
+				max[i] = v
+			}
+		}
+		first = false
+	})
+	for i := 0; i < e.layout.Stride(); i++ {
+		e.buf = binary.AppendVarint(e.buf, min[i])
+		e.buf = binary.AppendVarint(e.buf, max[i]-min[i])
+	}
+}
+
+func forEachCoord(g *geom.Geometry, fn func(geom.Coordinate)) {
+	switch g.Type {
+	case geom.TypePoint:
+		if !g.IsEmpty() {
+			fn(g.Coord)
+		}
+	case geom.TypeLineString, geom.TypePolygon:
+		for _, c := range g.Line {
+			fn(c)
+		}
+		for _, hole := range g.MultiLine {
+			for _, c := range hole {
+				fn(c)
+			}
+		}
+	default:
+		for _, col := range g.Collection {
+			forEachCoord(col, fn)
+		}
+	}
+}
+
+func (e *encoder) appendCoord(c geom.Coordinate) {
+	ords := e.ordinates(c)
+	for i := 0; i < e.layout.Stride(); i++ {
+		v := e.scale(ords[i], i)
+		e.buf = binary.AppendVarint(e.buf, v-e.last[i])
+		e.last[i] = v
+	}
+}
+
+func (e *encoder) appendCoords(coords geom.Coordinates) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(coords)))
+	for _, c := range coords {
+		e.appendCoord(c)
+	}
+}
+
+// appendBody writes the coordinates of a single geometry, without a header.
+func (e *encoder) appendBody(g *geom.Geometry) error {
+	switch g.Type {
+	case geom.TypePoint:
+		if g.IsEmpty() {
+			return errors.New("TWKB cannot encode an empty Point in a MultiPoint")
+		}
+		e.appendCoord(g.Coord)
+	case geom.TypeLineString:
+		e.appendCoords(g.Line)
+	case geom.TypePolygon:
+		if g.IsEmpty() {
+			e.buf = binary.AppendUvarint(e.buf, 0)
+			break
+		}
+		e.buf = binary.AppendUvarint(e.buf, uint64(1+len(g.MultiLine)))
+		e.appendCoords(g.Line)
+		for _, hole := range g.MultiLine {
+			e.appendCoords(hole)
+		}
+	default:
+		return errors.Errorf("unexpected geometry type in TWKB body: %v", g.Type)
+	}
+	return nil
+}
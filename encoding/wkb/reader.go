@@ -0,0 +1,275 @@
+package wkb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// maxPrealloc limits the capacity allocated up front from counts read from
+// the input, so that corrupt input cannot cause huge allocations.
+const maxPrealloc = 1 << 16
+
+// Reader decodes WKB and EWKB geometries. Both byte orders, ISO and EWKB
+// dimension flags and EWKB SRIDs are accepted.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unmarshal decodes a geometry from WKB or EWKB bytes using the default Reader.
+func Unmarshal(data []byte) (*geom.Geometry, error) {
+	return NewReader().Read(bytes.NewReader(data))
+}
+
+// Read decodes a single geometry from the input.
+func (r *Reader) Read(in io.Reader) (*geom.Geometry, error) {
+	d := &decoder{in: in}
+	g, err := d.readGeometry(true)
+	if err != nil {
+		return nil, err
+	}
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, nil
+}
+
+type decoder struct {
+	in    io.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if _, err := io.ReadFull(d.in, d.buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, errors.Wrap(err, "failed to read WKB")
+	}
+	return d.buf[:n], nil
+}
+
+func (d *decoder) readUint32() (uint32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *decoder) readFloat64() (float64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(d.order.Uint64(b)), nil
+}
+
+// readHeader reads the byte order, type, layout and SRID of a geometry.
+func (d *decoder) readHeader() (geom.Type, geom.Layout, int, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	switch b[0] {
+	case byteOrderBigEndian:
+		d.order = binary.BigEndian
+	case byteOrderLittleEndian:
+		d.order = binary.LittleEndian
+	default:
+		return 0, 0, 0, errors.Errorf("invalid WKB byte order: %d", b[0])
+	}
+
+	wkbType, err := d.readUint32()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	hasZ := wkbType&ewkbFlagZ != 0
+	hasM := wkbType&ewkbFlagM != 0
+	srid := 0
+	if wkbType&ewkbFlagSRID != 0 {
+		s, err := d.readUint32()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		srid = int(int32(s))
+	}
+	wkbType &^= ewkbFlags
+
+	switch wkbType / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	case 0:
+	default:
+		return 0, 0, 0, errors.Errorf("unsupported WKB geometry type: %d", wkbType)
+	}
+
+	t, err := geomType(wkbType % 1000)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return t, geom.NewLayout(hasZ, hasM), srid, nil
+}
+
+func (d *decoder) readGeometry(top bool) (*geom.Geometry, error) {
+	t, layout, srid, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	var g *geom.Geometry
+	switch t {
+	case geom.TypePoint:
+		c, err := d.readCoord(layout)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(c.X) && math.IsNaN(c.Y) {
+			g, err = geom.NewEmptyPoint()
+		} else {
+			g, err = geom.NewPoint(c)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case geom.TypeLineString:
+		line, err := d.readCoords(layout)
+		if err != nil {
+			return nil, err
+		}
+		if g, err = geom.NewLineString(line); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case geom.TypePolygon:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		rings := make(geom.MultiLine, 0, prealloc(n))
+		for i := 0; i < n; i++ {
+			ring, err := d.readCoords(layout)
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, ring)
+		}
+		if len(rings) == 0 {
+			g, err = geom.NewPolygon(nil, nil)
+		} else {
+			g, err = geom.NewPolygon(rings[0], rings[1:])
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		collection := make([]*geom.Geometry, 0, prealloc(n))
+		for i := 0; i < n; i++ {
+			col, err := d.readGeometry(false)
+			if err != nil {
+				return nil, err
+			}
+			if col.Layout != layout {
+				return nil, errors.Errorf("mixed coordinate dimensions in %v: expected %v, found %v", t, layout, col.Layout)
+			}
+			collection = append(collection, col)
+		}
+		if g, err = newCollection(t, collection); err != nil {
+			return nil, err
+		}
+	}
+
+	g.Layout = layout
+	if top {
+		g.SRID = srid
+	}
+	return g, nil
+}
+
+func newCollection(t geom.Type, collection []*geom.Geometry) (*geom.Geometry, error) {
+	switch t {
+	case geom.TypeMultiPoint:
+		return geom.NewMultiPoint(collection)
+	case geom.TypeMultiLineString:
+		return geom.NewMultiLineString(collection)
+	case geom.TypeMultiPolygon:
+		return geom.NewMultiPolygon(collection)
+	}
+	return geom.NewCollection(collection)
+}
+
+func (d *decoder) readCount() (int, error) {
+	n, err := d.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, errors.Errorf("invalid WKB element count: %d", n)
+	}
+	return int(n), nil
+}
+
+func prealloc(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return n
+}
+
+func (d *decoder) readCoord(layout geom.Layout) (geom.Coordinate, error) {
+	var ords [4]float64
+	for i := 0; i < layout.Stride(); i++ {
+		v, err := d.readFloat64()
+		if err != nil {
+			return geom.Coordinate{}, err
+		}
+		ords[i] = v
+	}
+
+	c := geom.Coordinate{X: ords[0], Y: ords[1]}
+	switch layout {
+	case geom.LayoutXYZ:
+		c.Z = ords[2]
+	case geom.LayoutXYM:
+		c.M = ords[2]
+	case geom.LayoutXYZM:
+		c.Z, c.M = ords[2], ords[3]
+	}
+	return c, nil
+}
+
+func (d *decoder) readCoords(layout geom.Layout) (geom.Coordinates, error) {
+	n, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+	coords := make(geom.Coordinates, 0, prealloc(n))
+	for i := 0; i < n; i++ {
+		c, err := d.readCoord(layout)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, c)
+	}
+	return coords, nil
+}
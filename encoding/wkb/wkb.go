@@ -0,0 +1,53 @@
+// Package wkb implements reading and writing geometries as Well-Known Binary,
+// in both the ISO flavor and the PostGIS extended (EWKB) flavor.
+package wkb
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Flavor selects how the dimensions and SRID of a geometry are encoded.
+type Flavor int
+
+const (
+	// FlavorISO encodes dimensions by adding 1000 (Z), 2000 (M) or 3000 (ZM)
+	// to the geometry type. ISO WKB has no SRID.
+	FlavorISO Flavor = iota
+	// FlavorEWKB encodes dimensions and the SRID as high bit flags of the
+	// geometry type, as used by PostGIS.
+	FlavorEWKB
+)
+
+const (
+	byteOrderBigEndian    = 0
+	byteOrderLittleEndian = 1
+
+	ewkbFlagZ    = 0x80000000
+	ewkbFlagM    = 0x40000000
+	ewkbFlagSRID = 0x20000000
+	ewkbFlags    = ewkbFlagZ | ewkbFlagM | ewkbFlagSRID
+
+	// quietNaN is the NaN written for the coordinates of an empty point, as
+	// written by PostGIS and GEOS. math.NaN has a different payload.
+	quietNaN = 0x7FF8000000000000
+)
+
+var wkbTypes = map[geom.Type]uint32{
+	geom.TypePoint:           1,
+	geom.TypeLineString:      2,
+	geom.TypePolygon:         3,
+	geom.TypeMultiPoint:      4,
+	geom.TypeMultiLineString: 5,
+	geom.TypeMultiPolygon:    6,
+	geom.TypeCollection:      7,
+}
+
+func geomType(wkbType uint32) (geom.Type, error) {
+	for t, wt := range wkbTypes {
+		if wt == wkbType {
+			return t, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported WKB geometry type: %d", wkbType)
+}
@@ -0,0 +1,136 @@
+package wkb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+)
+
+// bigEndian is a big-endian ByteOrder other than binary.BigEndian.
+type bigEndian struct {
+	binary.ByteOrder
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		w    *Writer
+		text string
+		hex  string
+	}{
+		{
+			name: "point",
+			w:    NewWriter(),
+			text: "POINT (1 2)",
+			hex:  "0101000000000000000000F03F0000000000000040",
+		},
+		{
+			name: "linestring",
+			w:    NewWriter(),
+			text: "LINESTRING (30 10, 10 30, 40 40)",
+			hex:  "0102000000030000000000000000003E40000000000000244000000000000024400000000000003E4000000000000044400000000000004440",
+		},
+		{
+			name: "big endian polygon",
+			w:    &Writer{ByteOrder: binary.BigEndian},
+			text: "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))",
+			hex:  "00000000030000000100000005000000000000000000000000000000003FF000000000000000000000000000003FF00000000000003FF000000000000000000000000000003FF000000000000000000000000000000000000000000000",
+		},
+		{
+			name: "other big endian byte order",
+			w:    &Writer{ByteOrder: bigEndian{binary.BigEndian}},
+			text: "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))",
+			hex:  "00000000030000000100000005000000000000000000000000000000003FF000000000000000000000000000003FF00000000000003FF000000000000000000000000000003FF000000000000000000000000000000000000000000000",
+		},
+		{
+			name: "multipoint",
+			w:    NewWriter(),
+			text: "MULTIPOINT ((1 2), (3 4))",
+			hex:  "0104000000020000000101000000000000000000F03F0000000000000040010100000000000000000008400000000000001040",
+		},
+		{
+			name: "iso point z",
+			w:    NewWriter(),
+			text: "POINT Z (1 2 3)",
+			hex:  "01E9030000000000000000F03F00000000000000400000000000000840",
+		},
+		{
+			name: "ewkb point z",
+			w:    &Writer{Flavor: FlavorEWKB},
+			text: "POINT Z (1 2 3)",
+			hex:  "0101000080000000000000F03F00000000000000400000000000000840",
+		},
+		{
+			name: "ewkb point zm with srid",
+			w:    &Writer{Flavor: FlavorEWKB},
+			text: "SRID=4326;POINT ZM (1 2 3 4)",
+			hex:  "01010000E0E6100000000000000000F03F000000000000004000000000000008400000000000001040",
+		},
+		{
+			name: "empty point",
+			w:    NewWriter(),
+			text: "POINT EMPTY",
+			hex:  "0101000000000000000000F87F000000000000F87F",
+		},
+		{
+			name: "empty collection",
+			w:    NewWriter(),
+			text: "GEOMETRYCOLLECTION EMPTY",
+			hex:  "010700000000000000",
+		},
+	}
+	ewkt := &wkt.Writer{Precision: -1, TrimZeros: true, EWKT: true}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in, err := wkt.Unmarshal(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := test.w.Marshal(in)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if got := strings.ToUpper(hex.EncodeToString(b)); got != test.hex {
+				t.Errorf("Marshal() = %s, want %s", got, test.hex)
+			}
+
+			g, err := Unmarshal(b)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			want, _ := ewkt.Write(in)
+			if got, _ := ewkt.Write(g); got != want {
+				t.Errorf("Unmarshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"invalid byte order", "0201000000000000000000F03F0000000000000040"},
+		{"unsupported type", "0108000000"},
+		{"unsupported dimensions", "01A10F0000"},
+		{"truncated coordinate", "0101000000000000000000F03F00000000"},
+		{"truncated collection", "01040000000200000001010000000000000000000000000000000000F03F"},
+		{"mixed dimensions", "0104000000010000000101000080000000000000F03F00000000000000400000000000000840"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := hex.DecodeString(test.hex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g, err := Unmarshal(b); err == nil {
+				t.Errorf("Unmarshal() = %v, want an error", g)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package wkb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer encodes geometries as WKB or EWKB.
+type Writer struct {
+	// ByteOrder is the byte order of the output, binary.LittleEndian by default.
+	ByteOrder binary.ByteOrder
+
+	// Flavor selects ISO WKB or PostGIS EWKB output. The SRID of a geometry
+	// is only written by FlavorEWKB, and only if it is non-zero.
+	Flavor Flavor
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		ByteOrder: binary.LittleEndian,
+		Flavor:    FlavorISO,
+	}
+}
+
+// Marshal encodes a geometry as ISO WKB using the default Writer.
+func Marshal(g *geom.Geometry) ([]byte, error) {
+	return NewWriter().Marshal(g)
+}
+
+// Marshal encodes a geometry to a byte slice.
+func (w *Writer) Marshal(g *geom.Geometry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.Write(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write encodes a geometry to the output.
+func (w *Writer) Write(out io.Writer, g *geom.Geometry) error {
+	e := &encoder{
+		order:  w.ByteOrder,
+		flavor: w.Flavor,
+	}
+	if e.order == nil {
+		e.order = binary.LittleEndian
+	}
+	e.marker = byteOrderMarker(e.order)
+	if err := e.writeGeometry(g, g.Layout, true); err != nil {
+		return err
+	}
+	if _, err := out.Write(e.buf); err != nil {
+		return errors.Wrap(err, "failed to write WKB")
+	}
+	return nil
+}
+
+type encoder struct {
+	order  binary.ByteOrder
+	marker byte
+	flavor Flavor
+	buf    []byte
+}
+
+// byteOrderMarker returns the WKB byte order marker of any ByteOrder, by
+// looking at where it puts the low byte of an integer.
+func byteOrderMarker(order binary.ByteOrder) byte {
+	var b [2]byte
+	order.PutUint16(b[:], 1)
+	if b[1] == 1 {
+		return byteOrderBigEndian
+	}
+	return byteOrderLittleEndian
+}
+
+func (e *encoder) writeUint32(v uint32) {
+	var b [4]byte
+	e.order.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) writeFloat64(v float64) {
+	var b [8]byte
+	e.order.PutUint64(b[:], math.Float64bits(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) writeHeader(g *geom.Geometry, layout geom.Layout, top bool) error {
+	wkbType, ok := wkbTypes[g.Type]
+	if !ok {
+		return errors.Errorf("unsupported geometry type for WKB: %v", g.Type)
+	}
+
+	e.buf = append(e.buf, e.marker)
+
+	writeSRID := false
+	switch e.flavor {
+	case FlavorEWKB:
+		if layout.HasZ() {
+			wkbType |= ewkbFlagZ
+		}
+		if layout.HasM() {
+			wkbType |= ewkbFlagM
+		}
+		if top && g.SRID != 0 {
+			wkbType |= ewkbFlagSRID
+			writeSRID = true
+		}
+	default:
+		switch layout {
+		case geom.LayoutXYZ:
+			wkbType += 1000
+		case geom.LayoutXYM:
+			wkbType += 2000
+		case geom.LayoutXYZM:
+			wkbType += 3000
+		}
+	}
+
+	e.writeUint32(wkbType)
+	if writeSRID {
+		e.writeUint32(uint32(int32(g.SRID)))
+	}
+	return nil
+}
+
+func (e *encoder) writeGeometry(g *geom.Geometry, layout geom.Layout, top bool) error {
+	if err := e.writeHeader(g, layout, top); err != nil {
+		return err
+	}
+
+	switch g.Type {
+	case geom.TypePoint:
+		if g.IsEmpty() {
+			nan := math.Float64frombits(quietNaN)
+			e.writeCoord(geom.Coordinate{X: nan, Y: nan, Z: nan, M: nan}, layout)
+		} else {
+			e.writeCoord(g.Coord, layout)
+		}
+	case geom.TypeLineString:
+		e.writeCoords(g.Line, layout)
+	case geom.TypePolygon:
+		if g.IsEmpty() {
+			e.writeUint32(0)
+			break
+		}
+		e.writeUint32(uint32(1 + len(g.MultiLine)))
+		e.writeCoords(g.Line, layout)
+		for _, hole := range g.MultiLine {
+			e.writeCoords(hole, layout)
+		}
+	default:
+		e.writeUint32(uint32(len(g.Collection)))
+		for _, col := range g.Collection {
+			if err := e.writeGeometry(col, layout, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *encoder) writeCoord(c geom.Coordinate, layout geom.Layout) {
+	e.writeFloat64(c.X)
+	e.writeFloat64(c.Y)
+	if layout.HasZ() {
+		e.writeFloat64(c.Z)
+	}
+	if layout.HasM() {
+		e.writeFloat64(c.M)
+	}
+}
+
+func (e *encoder) writeCoords(coords geom.Coordinates, layout geom.Layout) {
+	e.writeUint32(uint32(len(coords)))
+	for _, c := range coords {
+		e.writeCoord(c, layout)
+	}
+}
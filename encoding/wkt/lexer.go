@@ -0,0 +1,190 @@
+package wkt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenWord
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenSemicolon
+	tokenEquals
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case tokenEOF:
+		return "end of input"
+	case tokenWord:
+		return "word"
+	case tokenNumber:
+		return "number"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenComma:
+		return "','"
+	case tokenSemicolon:
+		return "';'"
+	case tokenEquals:
+		return "'='"
+	}
+	return "unknown token"
+}
+
+// Position is a location in the input text.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line and Column start at 1. Column counts bytes.
+	Line, Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// SyntaxError is returned when the input is not valid WKT.
+type SyntaxError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("wkt: syntax error at %v: %s", e.Pos, e.Msg)
+}
+
+type token struct {
+	Type  tokenType
+	Text  string
+	Value float64
+	Pos   Position
+}
+
+func (t token) String() string {
+	switch t.Type {
+	case tokenWord, tokenNumber:
+		return strconv.Quote(t.Text)
+	}
+	return t.Type.String()
+}
+
+type lexer struct {
+	input string
+	pos   Position
+
+	peeked *token
+}
+
+func newLexer(input string) *lexer {
+	return &lexer{
+		input: input,
+		pos:   Position{Line: 1, Column: 1},
+	}
+}
+
+func (l *lexer) advance(n int) {
+	s := l.input[l.pos.Offset : l.pos.Offset+n]
+	if lines := strings.Count(s, "\n"); lines > 0 {
+		l.pos.Line += lines
+		l.pos.Column = n - strings.LastIndexByte(s, '\n')
+	} else {
+		l.pos.Column += n
+	}
+	l.pos.Offset += n
+}
+
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		tok, err := l.scan()
+		if err != nil {
+			return tok, err
+		}
+		l.peeked = &tok
+	}
+	return *l.peeked, nil
+}
+
+func (l *lexer) next() (token, error) {
+	tok, err := l.peek()
+	l.peeked = nil
+	return tok, err
+}
+
+func (l *lexer) scan() (token, error) {
+	rest := l.input[l.pos.Offset:]
+	trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	l.advance(len(rest) - len(trimmed))
+
+	tok := token{Pos: l.pos}
+	if len(trimmed) == 0 {
+		tok.Type = tokenEOF
+		return tok, nil
+	}
+
+	switch c := trimmed[0]; {
+	case c == '(':
+		tok.Type = tokenLParen
+	case c == ')':
+		tok.Type = tokenRParen
+	case c == ',':
+		tok.Type = tokenComma
+	case c == ';':
+		tok.Type = tokenSemicolon
+	case c == '=':
+		tok.Type = tokenEquals
+	case isLetter(c):
+		n := 1
+		for n < len(trimmed) && (isLetter(trimmed[n]) || isDigit(trimmed[n])) {
+			n++
+		}
+		tok.Type = tokenWord
+		tok.Text = trimmed[:n]
+		l.advance(n)
+		return tok, nil
+	case isDigit(c) || c == '-' || c == '+' || c == '.':
+		n := 1
+		for n < len(trimmed) && isNumberChar(trimmed[n], trimmed[n-1]) {
+			n++
+		}
+		tok.Type = tokenNumber
+		tok.Text = trimmed[:n]
+		v, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return tok, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("invalid number %q", tok.Text)}
+		}
+		tok.Value = v
+		l.advance(n)
+		return tok, nil
+	default:
+		return tok, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+	}
+	tok.Text = trimmed[:1]
+	l.advance(1)
+	return tok, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumberChar(c, prev byte) bool {
+	if isDigit(c) || c == '.' || c == 'e' || c == 'E' {
+		return true
+	}
+	return (c == '-' || c == '+') && (prev == 'e' || prev == 'E')
+}
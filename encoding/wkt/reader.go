@@ -0,0 +1,420 @@
+// Package wkt implements reading and writing geometries as Well-Known Text,
+// including the PostGIS EWKT extensions for SRIDs and M coordinates.
+package wkt
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+var typeNames = []struct {
+	Name string
+	Type geom.Type
+}{
+	{"POINT", geom.TypePoint},
+	{"LINESTRING", geom.TypeLineString},
+	{"POLYGON", geom.TypePolygon},
+	{"MULTIPOINT", geom.TypeMultiPoint},
+	{"MULTILINESTRING", geom.TypeMultiLineString},
+	{"MULTIPOLYGON", geom.TypeMultiPolygon},
+	{"GEOMETRYCOLLECTION", geom.TypeCollection},
+}
+
+var layoutNames = map[string]geom.Layout{
+	"":   geom.LayoutXY,
+	"Z":  geom.LayoutXYZ,
+	"M":  geom.LayoutXYM,
+	"ZM": geom.LayoutXYZM,
+}
+
+// Reader parses WKT and EWKT text into geometries.
+type Reader struct {
+	// Orientation, if set, is enforced on the rings of all polygons read.
+	Orientation geom.Orientation
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unmarshal parses a geometry from WKT or EWKT text using the default Reader.
+func Unmarshal(text string) (*geom.Geometry, error) {
+	return NewReader().Read(text)
+}
+
+// Read parses a geometry from WKT or EWKT text. Keywords are case
+// insensitive. An EWKT "SRID=n;" prefix sets the SRID of the geometry.
+// Syntax errors are returned as a *SyntaxError giving the position of the
+// offending token.
+func (r *Reader) Read(text string) (*geom.Geometry, error) {
+	p := &parser{lex: newLexer(text)}
+	g, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if r.Orientation != geom.OrientationNone {
+		if g, err = g.Orient(r.Orientation); err != nil {
+			return nil, errors.Wrap(err, "failed to orient geometry")
+		}
+	}
+	return g, nil
+}
+
+type parser struct {
+	lex *lexer
+
+	layout    geom.Layout
+	layoutSet bool
+}
+
+func (p *parser) errorf(pos Position, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(typ tokenType) (token, error) {
+	tok, err := p.lex.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.Type != typ {
+		return tok, p.errorf(tok.Pos, "expected %v, found %v", typ, tok)
+	}
+	return tok, nil
+}
+
+// isWord consumes the next token and returns true if it is the given keyword.
+func (p *parser) isWord(word string) (bool, error) {
+	tok, err := p.lex.peek()
+	if err != nil {
+		return false, err
+	}
+	if tok.Type == tokenWord && strings.EqualFold(tok.Text, word) {
+		p.lex.next()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *parser) parse() (*geom.Geometry, error) {
+	srid := 0
+	if ok, err := p.isWord("SRID"); err != nil {
+		return nil, err
+	} else if ok {
+		if _, err := p.expect(tokenEquals); err != nil {
+			return nil, err
+		}
+		tok, err := p.expect(tokenNumber)
+		if err != nil {
+			return nil, err
+		}
+		if tok.Value != math.Trunc(tok.Value) {
+			return nil, p.errorf(tok.Pos, "SRID must be an integer, found %v", tok)
+		}
+		srid = int(tok.Value)
+		if _, err := p.expect(tokenSemicolon); err != nil {
+			return nil, err
+		}
+	}
+
+	g, err := p.parseGeometry()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenEOF); err != nil {
+		return nil, err
+	}
+
+	setLayout(g, p.layout)
+	g.SRID = srid
+	return g, nil
+}
+
+func setLayout(g *geom.Geometry, layout geom.Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setLayout(col, layout)
+	}
+}
+
+// setLayout records the layout of the geometry, which must be consistent
+// across all of its components.
+func (p *parser) setLayout(layout geom.Layout, pos Position) error {
+	if p.layoutSet && p.layout != layout {
+		return p.errorf(pos, "mixed coordinate dimensions: expected %v, found %v", p.layout, layout)
+	}
+	p.layout = layout
+	p.layoutSet = true
+	return nil
+}
+
+func (p *parser) parseGeometry() (*geom.Geometry, error) {
+	tok, err := p.expect(tokenWord)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, layout, ok := parseTypeName(tok.Text)
+	if !ok {
+		return nil, p.errorf(tok.Pos, "unknown geometry type %v", tok)
+	}
+	hasLayout := layout != geom.LayoutXY
+
+	// ISO style dimension, e.g. "POINT Z (1 2 3)"
+	if next, err := p.lex.peek(); err != nil {
+		return nil, err
+	} else if next.Type == tokenWord && !strings.EqualFold(next.Text, "EMPTY") {
+		l, ok := layoutNames[strings.ToUpper(next.Text)]
+		if !ok || hasLayout {
+			return nil, p.errorf(next.Pos, "expected dimension, '(' or EMPTY, found %v", next)
+		}
+		p.lex.next()
+		layout = l
+		hasLayout = true
+	}
+	if hasLayout {
+		if err := p.setLayout(layout, tok.Pos); err != nil {
+			return nil, err
+		}
+	}
+
+	switch typ {
+	case geom.TypePoint:
+		return p.parsePoint()
+	case geom.TypeLineString:
+		return p.parseLineString()
+	case geom.TypePolygon:
+		return p.parsePolygon()
+	case geom.TypeMultiPoint:
+		return p.parseMultiPoint()
+	case geom.TypeMultiLineString:
+		return p.parseMultiLineString()
+	case geom.TypeMultiPolygon:
+		return p.parseMultiPolygon()
+	}
+	return p.parseCollection()
+}
+
+// parseTypeName parses a geometry type keyword, including any EWKT
+// dimension suffix such as POINTM.
+func parseTypeName(text string) (geom.Type, geom.Layout, bool) {
+	upper := strings.ToUpper(text)
+	for _, tn := range typeNames {
+		if !strings.HasPrefix(upper, tn.Name) {
+			continue
+		}
+		if layout, ok := layoutNames[upper[len(tn.Name):]]; ok {
+			return tn.Type, layout, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parseList parses a parenthesised, comma separated list, or EMPTY.
+func (p *parser) parseList(item func() error) error {
+	if empty, err := p.isWord("EMPTY"); err != nil || empty {
+		return err
+	}
+	if _, err := p.expect(tokenLParen); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		tok, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if tok.Type == tokenRParen {
+			return nil
+		}
+		if tok.Type != tokenComma {
+			return p.errorf(tok.Pos, "expected ',' or ')', found %v", tok)
+		}
+	}
+}
+
+func (p *parser) parseCoord() (geom.Coordinate, error) {
+	start, err := p.lex.peek()
+	if err != nil {
+		return geom.Coordinate{}, err
+	}
+
+	var ords []float64
+	for {
+		tok, err := p.lex.peek()
+		if err != nil {
+			return geom.Coordinate{}, err
+		}
+		if tok.Type != tokenNumber {
+			if len(ords) < 2 {
+				return geom.Coordinate{}, p.errorf(tok.Pos, "expected number, found %v", tok)
+			}
+			break
+		}
+		if len(ords) == 4 {
+			return geom.Coordinate{}, p.errorf(tok.Pos, "too many ordinates in coordinate")
+		}
+		p.lex.next()
+		ords = append(ords, tok.Value)
+	}
+
+	c := geom.Coordinate{X: ords[0], Y: ords[1]}
+	switch len(ords) {
+	case 2:
+		if err := p.setLayout(geom.LayoutXY, start.Pos); err != nil {
+			return c, err
+		}
+	case 3:
+		if p.layoutSet && p.layout == geom.LayoutXYM {
+			c.M = ords[2]
+		} else {
+			if err := p.setLayout(geom.LayoutXYZ, start.Pos); err != nil {
+				return c, err
+			}
+			c.Z = ords[2]
+		}
+	case 4:
+		if err := p.setLayout(geom.LayoutXYZM, start.Pos); err != nil {
+			return c, err
+		}
+		c.Z, c.M = ords[2], ords[3]
+	}
+	return c, nil
+}
+
+func (p *parser) parseCoords() (geom.Coordinates, error) {
+	var coords geom.Coordinates
+	err := p.parseList(func() error {
+		c, err := p.parseCoord()
+		coords = append(coords, c)
+		return err
+	})
+	return coords, err
+}
+
+func (p *parser) parsePoint() (*geom.Geometry, error) {
+	var point *geom.Geometry
+	err := p.parseList(func() error {
+		if point != nil {
+			tok, _ := p.lex.peek()
+			return p.errorf(tok.Pos, "Point must contain a single coordinate")
+		}
+		c, err := p.parseCoord()
+		if err != nil {
+			return err
+		}
+		point, err = geom.NewPoint(c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if point == nil {
+		return geom.NewEmptyPoint()
+	}
+	return point, nil
+}
+
+func (p *parser) parseLineString() (*geom.Geometry, error) {
+	coords, err := p.parseCoords()
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewLineString(coords)
+}
+
+func (p *parser) parsePolygon() (*geom.Geometry, error) {
+	var rings geom.MultiLine
+	err := p.parseList(func() error {
+		tok, err := p.lex.peek()
+		if err != nil {
+			return err
+		}
+		ring, err := p.parseCoords()
+		if err != nil {
+			return err
+		}
+		if len(ring) == 0 {
+			return p.errorf(tok.Pos, "Polygon rings may not be empty")
+		}
+		rings = append(rings, ring)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rings) == 0 {
+		return geom.NewPolygon(nil, nil)
+	}
+	return geom.NewPolygon(rings[0], rings[1:])
+}
+
+func (p *parser) parseMultiPoint() (*geom.Geometry, error) {
+	var points []*geom.Geometry
+	err := p.parseList(func() error {
+		var point *geom.Geometry
+		tok, err := p.lex.peek()
+		if err != nil {
+			return err
+		}
+		// both "MULTIPOINT (1 2, 3 4)" and "MULTIPOINT ((1 2), (3 4))" are accepted
+		if tok.Type == tokenNumber {
+			var c geom.Coordinate
+			if c, err = p.parseCoord(); err == nil {
+				point, err = geom.NewPoint(c)
+			}
+		} else {
+			point, err = p.parsePoint()
+		}
+		points = append(points, point)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewMultiPoint(points)
+}
+
+func (p *parser) parseMultiLineString() (*geom.Geometry, error) {
+	var lines []*geom.Geometry
+	err := p.parseList(func() error {
+		line, err := p.parseLineString()
+		lines = append(lines, line)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewMultiLineString(lines)
+}
+
+func (p *parser) parseMultiPolygon() (*geom.Geometry, error) {
+	var polygons []*geom.Geometry
+	err := p.parseList(func() error {
+		poly, err := p.parsePolygon()
+		polygons = append(polygons, poly)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewMultiPolygon(polygons)
+}
+
+func (p *parser) parseCollection() (*geom.Geometry, error) {
+	var collection []*geom.Geometry
+	err := p.parseList(func() error {
+		g, err := p.parseGeometry()
+		collection = append(collection, g)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewCollection(collection)
+}
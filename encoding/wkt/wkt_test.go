@@ -0,0 +1,159 @@
+package wkt
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestRoundTrip(t *testing.T) {
+	// examples from OGC 06-103r4 (Simple Feature Access Part 1) 7.2.6
+	tests := []struct {
+		text   string
+		typ    geom.Type
+		layout geom.Layout
+	}{
+		{"POINT (10 10)", geom.TypePoint, geom.LayoutXY},
+		{"LINESTRING (10 10, 20 20, 30 40)", geom.TypeLineString, geom.LayoutXY},
+		{"POLYGON ((10 10, 10 20, 20 20, 20 15, 10 10))", geom.TypePolygon, geom.LayoutXY},
+		{"POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2))", geom.TypePolygon, geom.LayoutXY},
+		{"MULTIPOINT ((10 10), (20 20))", geom.TypeMultiPoint, geom.LayoutXY},
+		{"MULTILINESTRING ((10 10, 20 20), (15 15, 30 15))", geom.TypeMultiLineString, geom.LayoutXY},
+		{"MULTIPOLYGON (((10 10, 10 20, 20 20, 20 15, 10 10)), ((60 60, 70 70, 80 60, 60 60)))", geom.TypeMultiPolygon, geom.LayoutXY},
+		{"GEOMETRYCOLLECTION (POINT (10 10), POINT (30 30), LINESTRING (15 15, 20 20))", geom.TypeCollection, geom.LayoutXY},
+		{"POINT Z (10 10 5)", geom.TypePoint, geom.LayoutXYZ},
+		{"POINT ZM (10 10 5 40)", geom.TypePoint, geom.LayoutXYZM},
+		{"POINT M (10 10 40)", geom.TypePoint, geom.LayoutXYM},
+		{"LINESTRING M (10 10 1, 20 20 2)", geom.TypeLineString, geom.LayoutXYM},
+		{"POINT EMPTY", geom.TypePoint, geom.LayoutXY},
+		{"LINESTRING EMPTY", geom.TypeLineString, geom.LayoutXY},
+		{"POLYGON EMPTY", geom.TypePolygon, geom.LayoutXY},
+		{"MULTIPOINT ((1 2), EMPTY)", geom.TypeMultiPoint, geom.LayoutXY},
+		{"GEOMETRYCOLLECTION EMPTY", geom.TypeCollection, geom.LayoutXY},
+		{"GEOMETRYCOLLECTION (POINT (1 2), GEOMETRYCOLLECTION (LINESTRING (1 2, 3 4)))", geom.TypeCollection, geom.LayoutXY},
+		{"POINT (-1.5 0.000001)", geom.TypePoint, geom.LayoutXY},
+		{"POINT (-123456789.125 0.0000001)", geom.TypePoint, geom.LayoutXY},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			g, err := Unmarshal(test.text)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if g.Type != test.typ || g.Layout != test.layout {
+				t.Errorf("Unmarshal() = %v %v, want %v %v", g.Type, g.Layout, test.typ, test.layout)
+			}
+			text, err := Marshal(g)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if text != test.text {
+				t.Errorf("Marshal() = %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestUnmarshalVariants(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		srid int
+	}{
+		{"point(1 2)", "POINT (1 2)", 0},
+		{"  Point ( 1   2 )  ", "POINT (1 2)", 0},
+		{"POINT(1.0 -2.50)", "POINT (1 -2.5)", 0},
+		{"POINT(1e3 -2.5E-1)", "POINT (1000 -0.25)", 0},
+		{"POINTZ(1 2 3)", "POINT Z (1 2 3)", 0},
+		{"POINT (1 2 3)", "POINT Z (1 2 3)", 0},
+		{"POINT (1 2 3 4)", "POINT ZM (1 2 3 4)", 0},
+		{"SRID=4326;POINT(1 2)", "POINT (1 2)", 4326},
+		{"srid=3857; LINESTRING(0 0,1 1)", "LINESTRING (0 0, 1 1)", 3857},
+		{"SRID=4326;POINTM(1 2 3)", "POINT M (1 2 3)", 4326},
+		{"MULTIPOINTM((1 2 3),(4 5 6))", "MULTIPOINT M ((1 2 3), (4 5 6))", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			g, err := Unmarshal(test.text)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if g.SRID != test.srid {
+				t.Errorf("SRID = %d, want %d", g.SRID, test.srid)
+			}
+			if text, _ := Marshal(g); text != test.want {
+				t.Errorf("Marshal() = %q, want %q", text, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		text   string
+		line   int
+		column int
+	}{
+		{"", 1, 1},
+		{"CIRCLE (1 2)", 1, 1},
+		{"POINT (1)", 1, 9},
+		{"POINT (1 2", 1, 11},
+		{"POINT (1 2, 3 4)", 1, 13},
+		{"POINT (1 2 3 4 5)", 1, 16},
+		{"LINESTRING (1 2, 3 4 5)", 1, 18},
+		{"POLYGON ((0 0, 1 1, 0 0)\n(1 1))", 2, 1},
+		{"POINT (1 2) POINT", 1, 13},
+		{"SRID=x;POINT (1 2)", 1, 6},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := Unmarshal(test.text)
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Unmarshal() error = %v, want a *SyntaxError", err)
+			}
+			if serr.Pos.Line != test.line || serr.Pos.Column != test.column {
+				t.Errorf("error at %v, want line %d, column %d: %v", serr.Pos, test.line, test.column, serr)
+			}
+		})
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	g, err := Unmarshal("SRID=4326;LINESTRING M (1.23456 2.5 3, 4 5.10 6)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		w    *Writer
+		want string
+	}{
+		{"default", NewWriter(), "LINESTRING M (1.23456 2.5 3, 4 5.1 6)"},
+		{"precision", &Writer{Precision: 2, TrimZeros: true}, "LINESTRING M (1.23 2.5 3, 4 5.1 6)"},
+		{"precision without trimming", &Writer{Precision: 2}, "LINESTRING M (1.23 2.50 3.00, 4.00 5.10 6.00)"},
+		{"ewkt", &Writer{Precision: -1, EWKT: true}, "SRID=4326;LINESTRINGM (1.23456 2.5 3, 4 5.1 6)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.w.Write(g)
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("Write() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReaderOrientation(t *testing.T) {
+	r := &Reader{Orientation: geom.OrientationCCW}
+	g, err := r.Read("POLYGON ((0 0, 0 10, 10 10, 10 0, 0 0))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := Marshal(g); text != "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))" {
+		t.Errorf("Read() = %q", text)
+	}
+}
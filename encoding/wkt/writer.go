@@ -0,0 +1,165 @@
+package wkt
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Writer formats geometries as WKT or EWKT text.
+type Writer struct {
+	// Precision is the number of decimal places written, or -1 to write the
+	// fewest digits which represent each value exactly.
+	Precision int
+
+	// TrimZeros removes trailing zeros (and a trailing decimal point) from
+	// values written with a fixed Precision.
+	TrimZeros bool
+
+	// EWKT writes the PostGIS extended format: an "SRID=n;" prefix for
+	// geometries with a non-zero SRID, and dimension suffixes of the form
+	// POINTM instead of ISO style POINT Z, POINT M and POINT ZM.
+	EWKT bool
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		Precision: -1,
+		TrimZeros: true,
+	}
+}
+
+// Marshal formats a geometry as WKT using the default Writer.
+func Marshal(g *geom.Geometry) (string, error) {
+	return NewWriter().Write(g)
+}
+
+// Write formats a geometry as WKT, or EWKT if enabled.
+func (w *Writer) Write(g *geom.Geometry) (string, error) {
+	var sb strings.Builder
+	if w.EWKT && g.SRID != 0 {
+		sb.WriteString("SRID=")
+		sb.WriteString(strconv.Itoa(g.SRID))
+		sb.WriteByte(';')
+	}
+	if err := w.writeGeometry(&sb, g, g.Layout); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (w *Writer) writeGeometry(sb *strings.Builder, g *geom.Geometry, layout geom.Layout) error {
+	name := ""
+	for _, tn := range typeNames {
+		if tn.Type == g.Type {
+			name = tn.Name
+		}
+	}
+	if name == "" {
+		return errors.Errorf("unsupported geometry type for WKT: %v", g.Type)
+	}
+
+	sb.WriteString(name)
+	if w.EWKT {
+		if layout == geom.LayoutXYM {
+			sb.WriteString("M")
+		}
+	} else if layout != geom.LayoutXY {
+		sb.WriteByte(' ')
+		sb.WriteString(layout.String()[2:])
+	}
+	sb.WriteByte(' ')
+
+	if g.IsEmpty() {
+		sb.WriteString("EMPTY")
+		return nil
+	}
+	return w.writeBody(sb, g, layout)
+}
+
+// writeBody writes the parenthesised coordinates of a non-empty geometry.
+func (w *Writer) writeBody(sb *strings.Builder, g *geom.Geometry, layout geom.Layout) error {
+	switch g.Type {
+	case geom.TypePoint:
+		sb.WriteByte('(')
+		w.writeCoord(sb, g.Coord, layout)
+		sb.WriteByte(')')
+	case geom.TypeLineString:
+		w.writeCoords(sb, g.Line, layout)
+	case geom.TypePolygon:
+		w.writePolygon(sb, g, layout)
+	case geom.TypeMultiPoint, geom.TypeMultiLineString, geom.TypeMultiPolygon, geom.TypeCollection:
+		sb.WriteByte('(')
+		for i, col := range g.Collection {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if g.Type == geom.TypeCollection {
+				if err := w.writeGeometry(sb, col, layout); err != nil {
+					return err
+				}
+				continue
+			}
+			if col.IsEmpty() {
+				sb.WriteString("EMPTY")
+				continue
+			}
+			if err := w.writeBody(sb, col, layout); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(')')
+	default:
+		return errors.Errorf("unsupported geometry type for WKT: %v", g.Type)
+	}
+	return nil
+}
+
+func (w *Writer) writePolygon(sb *strings.Builder, g *geom.Geometry, layout geom.Layout) {
+	sb.WriteByte('(')
+	w.writeCoords(sb, g.Line, layout)
+	for _, hole := range g.MultiLine {
+		sb.WriteString(", ")
+		w.writeCoords(sb, hole, layout)
+	}
+	sb.WriteByte(')')
+}
+
+func (w *Writer) writeCoords(sb *strings.Builder, coords geom.Coordinates, layout geom.Layout) {
+	sb.WriteByte('(')
+	for i, c := range coords {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		w.writeCoord(sb, c, layout)
+	}
+	sb.WriteByte(')')
+}
+
+func (w *Writer) writeCoord(sb *strings.Builder, c geom.Coordinate, layout geom.Layout) {
+	sb.WriteString(w.formatFloat(c.X))
+	sb.WriteByte(' ')
+	sb.WriteString(w.formatFloat(c.Y))
+	if layout.HasZ() {
+		sb.WriteByte(' ')
+		sb.WriteString(w.formatFloat(c.Z))
+	}
+	if layout.HasM() {
+		sb.WriteByte(' ')
+		sb.WriteString(w.formatFloat(c.M))
+	}
+}
+
+func (w *Writer) formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', w.Precision, 64)
+	if w.Precision > 0 && w.TrimZeros {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
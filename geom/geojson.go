@@ -0,0 +1,345 @@
+package geom
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+var geoJSONTypes = map[Type]string{
+	TypePoint:           "Point",
+	TypeMultiPoint:      "MultiPoint",
+	TypeLineString:      "LineString",
+	TypeMultiLineString: "MultiLineString",
+	TypePolygon:         "Polygon",
+	TypeMultiPolygon:    "MultiPolygon",
+	TypeCollection:      "GeometryCollection",
+}
+
+// MarshalJSON encodes the geometry as a GeoJSON geometry object (RFC 7946),
+// writing coordinates at full precision.
+func (g *Geometry) MarshalJSON() ([]byte, error) {
+	return g.AppendGeoJSON(nil, -1)
+}
+
+// AppendGeoJSON appends the GeoJSON encoding of the geometry to dst.
+// Coordinates are rounded to the given number of decimal places, or written
+// at full precision if precision is negative. Only the Z ordinate is
+// written, as GeoJSON does not support M.
+func (g *Geometry) AppendGeoJSON(dst []byte, precision int) ([]byte, error) {
+	name, ok := geoJSONTypes[g.Type]
+	if !ok {
+		return nil, errors.Errorf("unsupported geometry type for GeoJSON: %v", g.Type)
+	}
+	dst = append(dst, `{"type":"`...)
+	dst = append(dst, name...)
+	dst = append(dst, '"')
+
+	e := geoJSONEncoder{hasZ: g.Layout.HasZ(), precision: precision}
+	if g.Type == TypeCollection {
+		dst = append(dst, `,"geometries":[`...)
+		for i, col := range g.Collection {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if dst, err = col.AppendGeoJSON(dst, precision); err != nil {
+				return nil, err
+			}
+		}
+		dst = append(dst, ']', '}')
+		return dst, nil
+	}
+
+	dst = append(dst, `,"coordinates":`...)
+	dst = e.appendCoordinates(dst, g)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return append(dst, '}'), nil
+}
+
+type geoJSONEncoder struct {
+	hasZ      bool
+	precision int
+	err       error
+}
+
+func (e *geoJSONEncoder) appendCoordinates(dst []byte, g *Geometry) []byte {
+	switch g.Type {
+	case TypePoint:
+		if g.IsEmpty() {
+			return append(dst, '[', ']')
+		}
+		return e.appendPosition(dst, g.Coord)
+	case TypeLineString:
+		return e.appendPositions(dst, g.Line)
+	case TypePolygon:
+		dst = append(dst, '[')
+		if !g.IsEmpty() {
+			dst = e.appendPositions(dst, g.Line)
+			for _, hole := range g.MultiLine {
+				dst = append(dst, ',')
+				dst = e.appendPositions(dst, hole)
+			}
+		}
+		return append(dst, ']')
+	}
+
+	dst = append(dst, '[')
+	for i, col := range g.Collection {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = e.appendCoordinates(dst, col)
+	}
+	return append(dst, ']')
+}
+
+func (e *geoJSONEncoder) appendPositions(dst []byte, coords Coordinates) []byte {
+	dst = append(dst, '[')
+	for i, c := range coords {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = e.appendPosition(dst, c)
+	}
+	return append(dst, ']')
+}
+
+func (e *geoJSONEncoder) appendPosition(dst []byte, c Coordinate) []byte {
+	dst = append(dst, '[')
+	dst = e.appendFloat(dst, c.X)
+	dst = append(dst, ',')
+	dst = e.appendFloat(dst, c.Y)
+	if e.hasZ {
+		dst = append(dst, ',')
+		dst = e.appendFloat(dst, c.Z)
+	}
+	return append(dst, ']')
+}
+
+func (e *geoJSONEncoder) appendFloat(dst []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		e.err = errors.Errorf("unsupported value for GeoJSON coordinate: %v", f)
+		return dst
+	}
+	if e.precision >= 0 {
+		start := len(dst)
+		dst = strconv.AppendFloat(dst, f, 'f', e.precision, 64)
+		if e.precision > 0 {
+			for dst[len(dst)-1] == '0' {
+				dst = dst[:len(dst)-1]
+			}
+			if dst[len(dst)-1] == '.' {
+				dst = dst[:len(dst)-1]
+			}
+		}
+		if string(dst[start:]) == "-0" {
+			dst = append(dst[:start], '0')
+		}
+		return dst
+	}
+	// use exponents for very large and small values, as encoding/json does
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.AppendFloat(dst, f, 'e', -1, 64)
+	}
+	return strconv.AppendFloat(dst, f, 'f', -1, 64)
+}
+
+type geometryJSON struct {
+	Type        string            `json:"type"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Geometries  []json.RawMessage `json:"geometries"`
+}
+
+// UnmarshalJSON decodes a GeoJSON geometry object (RFC 7946). The structure
+// of the geometry is validated: positions must have at least two ordinates,
+// used consistently, LineStrings must have at least two positions and
+// Polygon rings must be closed with at least four positions.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var d geoJSONDecoder
+	decoded, err := d.decodeGeometry(data)
+	if err != nil {
+		return err
+	}
+	setGeoJSONLayout(decoded, NewLayout(d.hasZ, false))
+	*g = *decoded
+	return nil
+}
+
+func setGeoJSONLayout(g *Geometry, layout Layout) {
+	g.Layout = layout
+	for _, col := range g.Collection {
+		setGeoJSONLayout(col, layout)
+	}
+}
+
+type geoJSONDecoder struct {
+	hasZ    bool
+	dimsSet bool
+}
+
+func (d *geoJSONDecoder) decodeGeometry(data []byte) (*Geometry, error) {
+	var gj geometryJSON
+	if err := json.Unmarshal(data, &gj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON geometry")
+	}
+
+	if gj.Type == "GeometryCollection" {
+		if gj.Geometries == nil {
+			return nil, errors.New("GeoJSON GeometryCollection is missing geometries")
+		}
+		collection := make([]*Geometry, 0, len(gj.Geometries))
+		for _, raw := range gj.Geometries {
+			col, err := d.decodeGeometry(raw)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			collection = append(collection, col)
+		}
+		return NewCollection(collection)
+	}
+
+	if gj.Coordinates == nil {
+		return nil, errors.Errorf("GeoJSON %s is missing coordinates", gj.Type)
+	}
+
+	switch gj.Type {
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(gj.Coordinates, &pos); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON Point coordinates")
+		}
+		if len(pos) == 0 {
+			return NewEmptyPoint()
+		}
+		c, err := d.position(pos)
+		if err != nil {
+			return nil, err
+		}
+		return NewPoint(c)
+	case "MultiPoint":
+		var positions [][]float64
+		if err := json.Unmarshal(gj.Coordinates, &positions); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON MultiPoint coordinates")
+		}
+		points := make([]*Geometry, 0, len(positions))
+		for _, pos := range positions {
+			c, err := d.position(pos)
+			if err != nil {
+				return nil, err
+			}
+			point, err := NewPoint(c)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			points = append(points, point)
+		}
+		return NewMultiPoint(points)
+	case "LineString":
+		var positions [][]float64
+		if err := json.Unmarshal(gj.Coordinates, &positions); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON LineString coordinates")
+		}
+		return d.lineString(positions)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(gj.Coordinates, &lines); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON MultiLineString coordinates")
+		}
+		collection := make([]*Geometry, 0, len(lines))
+		for _, positions := range lines {
+			line, err := d.lineString(positions)
+			if err != nil {
+				return nil, err
+			}
+			collection = append(collection, line)
+		}
+		return NewMultiLineString(collection)
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(gj.Coordinates, &rings); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON Polygon coordinates")
+		}
+		return d.polygon(rings)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(gj.Coordinates, &polygons); err != nil {
+			return nil, errors.Wrap(err, "invalid GeoJSON MultiPolygon coordinates")
+		}
+		collection := make([]*Geometry, 0, len(polygons))
+		for _, rings := range polygons {
+			poly, err := d.polygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			collection = append(collection, poly)
+		}
+		return NewMultiPolygon(collection)
+	}
+	return nil, errors.Errorf("unsupported GeoJSON geometry type: %q", gj.Type)
+}
+
+func (d *geoJSONDecoder) position(pos []float64) (Coordinate, error) {
+	if len(pos) < 2 {
+		return Coordinate{}, errors.Errorf("GeoJSON position must have at least 2 elements, found %d", len(pos))
+	}
+	hasZ := len(pos) > 2
+	if d.dimsSet && hasZ != d.hasZ {
+		return Coordinate{}, errors.New("GeoJSON positions have mixed dimensions")
+	}
+	d.hasZ, d.dimsSet = hasZ, true
+
+	c := Coordinate{X: pos[0], Y: pos[1]}
+	if hasZ {
+		c.Z = pos[2]
+	}
+	return c, nil
+}
+
+func (d *geoJSONDecoder) positions(positions [][]float64) (Coordinates, error) {
+	coords := make(Coordinates, 0, len(positions))
+	for _, pos := range positions {
+		c, err := d.position(pos)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, c)
+	}
+	return coords, nil
+}
+
+func (d *geoJSONDecoder) lineString(positions [][]float64) (*Geometry, error) {
+	if len(positions) == 1 {
+		return nil, errors.New("GeoJSON LineString must have at least 2 positions")
+	}
+	coords, err := d.positions(positions)
+	if err != nil {
+		return nil, err
+	}
+	return NewLineString(coords)
+}
+
+func (d *geoJSONDecoder) polygon(rings [][][]float64) (*Geometry, error) {
+	var lines MultiLine
+	for _, positions := range rings {
+		ring, err := d.positions(positions)
+		if err != nil {
+			return nil, err
+		}
+		if len(ring) < 4 {
+			return nil, errors.Errorf("GeoJSON Polygon ring must have at least 4 positions, found %d", len(ring))
+		}
+		if !ring[0].Equals2D(ring[len(ring)-1]) {
+			return nil, errors.Errorf("GeoJSON Polygon ring is not closed: %v", ring)
+		}
+		lines = append(lines, ring)
+	}
+	if len(lines) == 0 {
+		return NewPolygon(nil, nil)
+	}
+	return NewPolygon(lines[0], lines[1:])
+}
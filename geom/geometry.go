@@ -2,6 +2,7 @@ package geom
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/pkg/errors"
 	"github.com/simoncochrane/geoz/coord"
@@ -40,6 +41,64 @@ func (t Type) String() string {
 	return "Unknown"
 }
 
+// Layout describes which ordinates are present in the coordinates of a geometry.
+type Layout int
+
+const (
+	LayoutXY Layout = iota
+	LayoutXYZ
+	LayoutXYM
+	LayoutXYZM
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutXY:
+		return "XY"
+	case LayoutXYZ:
+		return "XYZ"
+	case LayoutXYM:
+		return "XYM"
+	case LayoutXYZM:
+		return "XYZM"
+	}
+	return "Unknown"
+}
+
+// HasZ returns true if the layout includes a z-coordinate.
+func (l Layout) HasZ() bool {
+	return l == LayoutXYZ || l == LayoutXYZM
+}
+
+// HasM returns true if the layout includes an m-coordinate.
+func (l Layout) HasM() bool {
+	return l == LayoutXYM || l == LayoutXYZM
+}
+
+// Stride returns the number of ordinates in each coordinate.
+func (l Layout) Stride() int {
+	switch l {
+	case LayoutXYZ, LayoutXYM:
+		return 3
+	case LayoutXYZM:
+		return 4
+	}
+	return 2
+}
+
+// NewLayout returns the layout with the given ordinates present.
+func NewLayout(hasZ, hasM bool) Layout {
+	switch {
+	case hasZ && hasM:
+		return LayoutXYZM
+	case hasZ:
+		return LayoutXYZ
+	case hasM:
+		return LayoutXYM
+	}
+	return LayoutXY
+}
+
 type Coordinate = coord.Coordinate
 type Coordinates = coord.Coordinates
 type MultiLine = coord.MultiLine
@@ -51,6 +110,11 @@ type Geometry struct {
 	MultiLine  MultiLine
 	Collection []*Geometry
 
+	// Layout determines which of the Z and M ordinates of the coordinates are used.
+	Layout Layout
+	// SRID is the spatial reference system identifier, or 0 if unknown.
+	SRID int
+
 	envelope *coord.Envelope
 }
 
@@ -61,6 +125,12 @@ func NewPoint(point Coordinate) (*Geometry, error) {
 	}, nil
 }
 
+// NewEmptyPoint returns a Point with no coordinate.
+// The coordinate of an empty Point has NaN X and Y values.
+func NewEmptyPoint() (*Geometry, error) {
+	return NewPoint(Coordinate{X: math.NaN(), Y: math.NaN()})
+}
+
 func NewMultiPoint(points []*Geometry) (*Geometry, error) {
 	for _, point := range points {
 		if point.Type != TypePoint {
@@ -123,10 +193,7 @@ func NewCollection(collection []*Geometry) (*Geometry, error) {
 // IsMulti returns true if the geometry is a multi or collection-based geometry.
 func (g *Geometry) IsMulti() bool {
 	switch g.Type {
-	case TypeMultiPoint:
-	case TypeMultiLineString:
-	case TypeMultiPolygon:
-	case TypeCollection:
+	case TypeMultiPoint, TypeMultiLineString, TypeMultiPolygon, TypeCollection:
 		return true
 	}
 	return false
@@ -136,8 +203,7 @@ func (g *Geometry) IsMulti() bool {
 func (g *Geometry) IsRings() bool {
 	switch g.Type {
 	//case TypeLinearRing:
-	case TypePolygon:
-	case TypeMultiPolygon:
+	case TypePolygon, TypeMultiPolygon:
 		return true
 	}
 	return false
@@ -155,8 +221,7 @@ func (g *Geometry) Dimension() int {
 		return 1
 	case TypePolygon:
 		return 2
-	case TypeMultiPolygon:
-	case TypeCollection:
+	case TypeMultiPolygon, TypeCollection:
 		dim := -1
 		for _, col := range g.Collection {
 			if d := col.Dimension(); d > dim {
@@ -187,8 +252,7 @@ func (g *Geometry) BoundaryDimension() int {
 		return 0
 	case TypePolygon:
 		return 1
-	case TypeMultiPolygon:
-	case TypeCollection:
+	case TypeMultiPolygon, TypeCollection:
 		dim := -1
 		for _, col := range g.Collection {
 			if d := col.BoundaryDimension(); d > dim {
@@ -219,14 +283,10 @@ func (g *Geometry) IsClosed() bool {
 func (g *Geometry) IsEmpty() bool {
 	switch g.Type {
 	case TypePoint:
-		return false
-	case TypeLineString:
-	case TypePolygon:
+		return math.IsNaN(g.Coord.X) && math.IsNaN(g.Coord.Y)
+	case TypeLineString, TypePolygon:
 		return len(g.Line) == 0
-	case TypeMultiPoint:
-	case TypeMultiLineString:
-	case TypeMultiPolygon:
-	case TypeCollection:
+	case TypeMultiPoint, TypeMultiLineString, TypeMultiPolygon, TypeCollection:
 		for _, c := range g.Collection {
 			if !c.IsEmpty() {
 				return false
@@ -242,15 +302,23 @@ func (g *Geometry) Envelope() *coord.Envelope {
 	if g.envelope != nil {
 		return g.envelope
 	}
+	if g.IsEmpty() {
+		return nil
+	}
 
 	var env *coord.Envelope
 
 	if g.IsMulti() {
-		for i, col := range g.Collection {
-			if i == 0 {
-				env = col.Envelope()
+		for _, col := range g.Collection {
+			colEnv := col.Envelope()
+			if colEnv == nil {
+				continue
+			}
+			if env == nil {
+				env = &coord.Envelope{}
+				*env = *colEnv
 			} else {
-				env.ExpandEnvelope(col.Envelope())
+				env.ExpandEnvelope(colEnv)
 			}
 		}
 	}
@@ -258,8 +326,7 @@ func (g *Geometry) Envelope() *coord.Envelope {
 	switch g.Type {
 	case TypePoint:
 		env = g.Coord.Envelope()
-	case TypeLineString:
-	case TypePolygon:
+	case TypeLineString, TypePolygon:
 		env = g.Line.Envelope()
 	}
 
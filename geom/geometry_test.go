@@ -0,0 +1,97 @@
+package geom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+func xys(xys ...float64) Coordinates {
+	var cs Coordinates
+	for i := 0; i < len(xys); i += 2 {
+		cs = append(cs, Coordinate{X: xys[i], Y: xys[i+1]})
+	}
+	return cs
+}
+
+func TestGeometryProperties(t *testing.T) {
+	point := &Geometry{Type: TypePoint, Coord: Coordinate{X: 1, Y: 2}}
+	line := &Geometry{Type: TypeLineString, Line: xys(0, 0, 4, 4)}
+	closedLine := &Geometry{Type: TypeLineString, Line: xys(0, 0, 4, 0, 4, 4, 0, 0)}
+	polygon := &Geometry{Type: TypePolygon, Line: xys(0, 0, 4, 0, 4, 4, 0, 0)}
+	emptyLine := &Geometry{Type: TypeLineString}
+	emptyPolygon := &Geometry{Type: TypePolygon}
+
+	tests := []struct {
+		name              string
+		g                 *Geometry
+		multi, rings      bool
+		dimension         int
+		boundaryDimension int
+		empty             bool
+	}{
+		{"point", point, false, false, 0, -1, false},
+		{"line", line, false, false, 1, 0, false},
+		{"closed line", closedLine, false, false, 1, -1, false},
+		{"empty line", emptyLine, false, false, 1, 0, true},
+		{"polygon", polygon, false, true, 2, 1, false},
+		{"empty polygon", emptyPolygon, false, true, 2, 1, true},
+		{"multipoint", &Geometry{Type: TypeMultiPoint, Collection: []*Geometry{point}}, true, false, 0, -1, false},
+		{"multilinestring", &Geometry{Type: TypeMultiLineString, Collection: []*Geometry{line}}, true, false, 1, 0, false},
+		{"empty multilinestring", &Geometry{Type: TypeMultiLineString, Collection: []*Geometry{emptyLine}}, true, false, 1, 0, true},
+		{"multipolygon", &Geometry{Type: TypeMultiPolygon, Collection: []*Geometry{polygon}}, true, true, 2, 1, false},
+		{"empty multipolygon", &Geometry{Type: TypeMultiPolygon}, true, true, -1, -1, true},
+		{"collection", &Geometry{Type: TypeCollection, Collection: []*Geometry{point, line}}, true, false, 1, 0, false},
+		{"collection of empty", &Geometry{Type: TypeCollection, Collection: []*Geometry{emptyPolygon}}, true, false, 2, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.g.IsMulti(); got != test.multi {
+				t.Errorf("IsMulti() = %v, want %v", got, test.multi)
+			}
+			if got := test.g.IsRings(); got != test.rings {
+				t.Errorf("IsRings() = %v, want %v", got, test.rings)
+			}
+			if got := test.g.Dimension(); got != test.dimension {
+				t.Errorf("Dimension() = %v, want %v", got, test.dimension)
+			}
+			if got := test.g.BoundaryDimension(); got != test.boundaryDimension {
+				t.Errorf("BoundaryDimension() = %v, want %v", got, test.boundaryDimension)
+			}
+			if got := test.g.IsEmpty(); got != test.empty {
+				t.Errorf("IsEmpty() = %v, want %v", got, test.empty)
+			}
+		})
+	}
+}
+
+func TestGeometryEnvelope(t *testing.T) {
+	p0 := &Geometry{Type: TypePoint, Coord: Coordinate{X: 1, Y: 2}}
+	p1 := &Geometry{Type: TypePoint, Coord: Coordinate{X: 5, Y: -1}}
+	line := &Geometry{Type: TypeLineString, Line: xys(3, 3, -2, 6)}
+	emptyLine := &Geometry{Type: TypeLineString}
+
+	tests := []struct {
+		name string
+		g    *Geometry
+		want *coord.Envelope
+	}{
+		{"point", p0, &coord.Envelope{MinX: 1, MaxX: 1, MinY: 2, MaxY: 2}},
+		{"line", line, &coord.Envelope{MinX: -2, MaxX: 3, MinY: 3, MaxY: 6}},
+		{"empty line", emptyLine, nil},
+		{"multipoint", &Geometry{Type: TypeMultiPoint, Collection: []*Geometry{p0, p1}}, &coord.Envelope{MinX: 1, MaxX: 5, MinY: -1, MaxY: 2}},
+		{"collection skips empty", &Geometry{Type: TypeCollection, Collection: []*Geometry{emptyLine, p1, line}}, &coord.Envelope{MinX: -2, MaxX: 5, MinY: -1, MaxY: 6}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.g.Envelope(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Envelope() = %v, want %v", got, test.want)
+			}
+		})
+	}
+	// the envelope of a collection must not change that of its first part
+	if got, want := p0.Envelope(), (&coord.Envelope{MinX: 1, MaxX: 1, MinY: 2, MaxY: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("first part Envelope() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package geom
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// Orientation describes the winding order of polygon rings.
+type Orientation int
+
+const (
+	// OrientationNone leaves rings as they are.
+	OrientationNone Orientation = iota
+	// OrientationCCW orients shells counter clockwise and holes clockwise.
+	OrientationCCW
+	// OrientationCW orients shells clockwise and holes counter clockwise.
+	OrientationCW
+)
+
+// OrientationRHR is the right-hand rule as defined by RFC 7946 (GeoJSON):
+// shells are counter clockwise and holes are clockwise.
+// Note that this is the opposite of PostGIS ST_ForceRHR.
+const OrientationRHR = OrientationCCW
+
+func (o Orientation) String() string {
+	switch o {
+	case OrientationNone:
+		return "None"
+	case OrientationCCW:
+		return "CCW"
+	case OrientationCW:
+		return "CW"
+	}
+	return "Unknown"
+}
+
+// ForceCCW returns a copy of the geometry with polygon shells oriented
+// counter clockwise and holes oriented clockwise.
+func (g *Geometry) ForceCCW() (*Geometry, error) {
+	return g.Orient(OrientationCCW)
+}
+
+// ForceCW returns a copy of the geometry with polygon shells oriented
+// clockwise and holes oriented counter clockwise.
+func (g *Geometry) ForceCW() (*Geometry, error) {
+	return g.Orient(OrientationCW)
+}
+
+// ForceRHR returns a copy of the geometry with polygons following the
+// RFC 7946 right-hand rule. See OrientationRHR.
+func (g *Geometry) ForceRHR() (*Geometry, error) {
+	return g.Orient(OrientationRHR)
+}
+
+// Orient returns a copy of the geometry with the rings of all polygons
+// (including those in multi geometries and collections) oriented as given.
+// Geometries without rings are returned unchanged.
+func (g *Geometry) Orient(o Orientation) (*Geometry, error) {
+	if o == OrientationNone {
+		return g, nil
+	}
+
+	switch g.Type {
+	case TypePolygon:
+		if g.IsEmpty() {
+			return g, nil
+		}
+		shell, err := orientRing(g.Line, o == OrientationCCW)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to orient polygon shell")
+		}
+		var holes MultiLine
+		for _, hole := range g.MultiLine {
+			h, err := orientRing(hole, o != OrientationCCW)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to orient polygon hole")
+			}
+			holes = append(holes, h)
+		}
+		oriented := &Geometry{}
+		*oriented = *g
+		oriented.Line = shell
+		oriented.MultiLine = holes
+		return oriented, nil
+	case TypeMultiPolygon, TypeCollection:
+		collection := make([]*Geometry, len(g.Collection))
+		for i, col := range g.Collection {
+			oriented, err := col.Orient(o)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			collection[i] = oriented
+		}
+		oriented := &Geometry{}
+		*oriented = *g
+		oriented.Collection = collection
+		return oriented, nil
+	}
+	return g, nil
+}
+
+// orientRing returns the ring with the given orientation, reversing it if required.
+// Degenerate rings, with fewer than 3 distinct points, have no orientation and
+// are returned unchanged.
+func orientRing(ring Coordinates, ccw bool) (Coordinates, error) {
+	if isDegenerate(ring) {
+		return ring, nil
+	}
+	isCCW, err := coord.IsCCW(ring)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isCCW == ccw {
+		return ring, nil
+	}
+	return ring.Reverse(), nil
+}
+
+// isDegenerate returns whether a ring has fewer than 3 distinct points.
+func isDegenerate(ring Coordinates) bool {
+	var distinct []Coordinate
+	for _, c := range ring {
+		seen := false
+		for _, d := range distinct {
+			if c.Equals2D(d) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			if distinct = append(distinct, c); len(distinct) == 3 {
+				return false
+			}
+		}
+	}
+	return true
+}
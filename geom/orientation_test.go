@@ -0,0 +1,115 @@
+package geom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ring(xys ...float64) Coordinates {
+	var cs Coordinates
+	for i := 0; i < len(xys); i += 2 {
+		cs = append(cs, Coordinate{X: xys[i], Y: xys[i+1]})
+	}
+	return cs
+}
+
+func mustPolygon(t *testing.T, shell Coordinates, holes ...Coordinates) *Geometry {
+	t.Helper()
+	p, err := NewPolygon(shell, holes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOrient(t *testing.T) {
+	ccwShell := ring(0, 0, 10, 0, 10, 10, 0, 10, 0, 0)
+	cwShell := ccwShell.Reverse()
+	cwHole := ring(2, 2, 2, 8, 8, 8, 8, 2, 2, 2)
+	ccwHole := cwHole.Reverse()
+	// collapsed rings, with fewer than 3 distinct points
+	collapsedHole := ring(4, 4, 6, 6, 4, 4)
+	collapsedShell := ring(1, 1, 1, 1, 1, 1, 1, 1)
+
+	tests := []struct {
+		name      string
+		shell     Coordinates
+		holes     []Coordinates
+		o         Orientation
+		wantShell Coordinates
+		wantHoles []Coordinates
+	}{
+		{"ccw keeps ccw", ccwShell, []Coordinates{cwHole}, OrientationCCW, ccwShell, []Coordinates{cwHole}},
+		{"ccw reverses cw", cwShell, []Coordinates{ccwHole}, OrientationCCW, ccwShell, []Coordinates{cwHole}},
+		{"cw reverses ccw", ccwShell, []Coordinates{cwHole}, OrientationCW, cwShell, []Coordinates{ccwHole}},
+		{"rhr is ccw", cwShell, nil, OrientationRHR, ccwShell, nil},
+		{"none leaves rings", cwShell, []Coordinates{cwHole}, OrientationNone, cwShell, []Coordinates{cwHole}},
+		{"collapsed hole is unchanged", cwShell, []Coordinates{collapsedHole, ccwHole}, OrientationCCW, ccwShell, []Coordinates{collapsedHole, cwHole}},
+		{"collapsed shell is unchanged", collapsedShell, nil, OrientationCW, collapsedShell, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := mustPolygon(t, test.shell, test.holes...).Orient(test.o)
+			if err != nil {
+				t.Fatalf("Orient() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Line, test.wantShell) {
+				t.Errorf("shell = %v, want %v", got.Line, test.wantShell)
+			}
+			if len(got.MultiLine) != len(test.wantHoles) {
+				t.Fatalf("got %d holes, want %d", len(got.MultiLine), len(test.wantHoles))
+			}
+			for i, hole := range got.MultiLine {
+				if !reflect.DeepEqual(hole, test.wantHoles[i]) {
+					t.Errorf("hole %d = %v, want %v", i, hole, test.wantHoles[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOrientCollections(t *testing.T) {
+	cw := mustPolygon(t, ring(0, 0, 0, 10, 10, 10, 10, 0, 0, 0))
+	collapsed := mustPolygon(t, ring(20, 0, 30, 0, 20, 0))
+	mp, err := NewMultiPolygon([]*Geometry{cw, collapsed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := NewLineString(ring(0, 0, 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc, err := NewCollection([]*Geometry{mp, line})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, force := range []func(*Geometry) (*Geometry, error){(*Geometry).ForceCCW, (*Geometry).ForceRHR} {
+		got, err := force(gc)
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		polygons := got.Collection[0].Collection
+		if want := cw.Line.Reverse(); !reflect.DeepEqual(polygons[0].Line, want) {
+			t.Errorf("polygon = %v, want %v", polygons[0].Line, want)
+		}
+		if !reflect.DeepEqual(polygons[1].Line, collapsed.Line) {
+			t.Errorf("collapsed polygon = %v, want %v", polygons[1].Line, collapsed.Line)
+		}
+		if got.Collection[1] != line {
+			t.Errorf("line was copied or changed")
+		}
+	}
+	// the input is not modified
+	if !reflect.DeepEqual(cw.Line, ring(0, 0, 0, 10, 10, 10, 10, 0, 0, 0)) {
+		t.Errorf("input polygon was modified: %v", cw.Line)
+	}
+
+	got, err := gc.ForceCW()
+	if err != nil {
+		t.Fatalf("ForceCW() error = %v", err)
+	}
+	if polygon := got.Collection[0].Collection[0]; !reflect.DeepEqual(polygon.Line, cw.Line) {
+		t.Errorf("ForceCW() polygon = %v, want %v", polygon.Line, cw.Line)
+	}
+}
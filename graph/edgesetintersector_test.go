@@ -0,0 +1,268 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/flatgeobuf"
+)
+
+func line(xys ...float64) coord.Coordinates {
+	var cs coord.Coordinates
+	for i := 0; i < len(xys); i += 2 {
+		cs = append(cs, coord.Coordinate{X: xys[i], Y: xys[i+1]})
+	}
+	return cs
+}
+
+func newEdges(lines ...coord.Coordinates) []*Edge {
+	edges := make([]*Edge, len(lines))
+	for i, l := range lines {
+		edges[i] = NewEdge(l)
+	}
+	return edges
+}
+
+// intersectionPoints returns the distinct intersection points recorded on
+// edges, sorted by X and then Y.
+func intersectionPoints(edgeSets ...[]*Edge) []coord.Coordinate {
+	seen := map[coord.Coordinate]bool{}
+	var pts []coord.Coordinate
+	for _, edges := range edgeSets {
+		for _, e := range edges {
+			for ei := range e.eiList.nodeMap {
+				if !seen[ei.Coordinate] {
+					seen[ei.Coordinate] = true
+					pts = append(pts, ei.Coordinate)
+				}
+			}
+		}
+	}
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+	return pts
+}
+
+func TestSimpleMCSweepLineIntersector(t *testing.T) {
+	t.Run("edge intersections", func(t *testing.T) {
+		// a zigzag crossing a line four times, and an edge far from both
+		edges0 := newEdges(line(0, 0, 2, 2, 4, 0, 6, 2, 8, 0))
+		edges1 := newEdges(line(0, 1, 8, 1), line(20, 20, 21, 21))
+		si := NewSegmentIntersector(coord.NewRobustLineIntersector(), true, false, false)
+		if err := NewSimpleMCSweepLineIntersector().ComputeEdgeIntersections(edges0, edges1, si); err != nil {
+			t.Fatalf("ComputeEdgeIntersections() error = %v", err)
+		}
+		if si.numIntersections != 4 {
+			t.Errorf("found %d intersections, want 4", si.numIntersections)
+		}
+		want := line(1, 1, 3, 1, 5, 1, 7, 1)
+		if got := intersectionPoints(edges0, edges1); !equalCoords(got, want) {
+			t.Errorf("intersection points = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("self intersections", func(t *testing.T) {
+		// the segments of each edge touch, but only the crossing of the two
+		// edges is counted
+		edges := newEdges(line(0, 0, 4, 4, 8, 0), line(0, 4, 4, 0, 8, 4))
+		si := NewSegmentIntersector(coord.NewRobustLineIntersector(), true, false, false)
+		if err := NewSimpleMCSweepLineIntersector().ComputeSelfIntersections(edges, si, false); err != nil {
+			t.Fatalf("ComputeSelfIntersections() error = %v", err)
+		}
+		if si.numIntersections != 2 {
+			t.Errorf("found %d intersections, want 2", si.numIntersections)
+		}
+		want := line(2, 2, 6, 2)
+		if got := intersectionPoints(edges); !equalCoords(got, want) {
+			t.Errorf("intersection points = %v, want %v", got, want)
+		}
+	})
+}
+
+func equalCoords(a, b []coord.Coordinate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equals2D(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// landmarkRings returns the rings of the polygons of the FlatGeobuf
+// reference file, which are buildings and parks in Manhattan.
+func landmarkRings(t testing.TB) []coord.Coordinates {
+	t.Helper()
+	f, err := os.Open("../encoding/flatgeobuf/testdata/poly_landmarks.fgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, features, err := flatgeobuf.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rings []coord.Coordinates
+	for _, f := range features {
+		rings = append(rings, f.Geometry.Line)
+		rings = append(rings, f.Geometry.MultiLine...)
+	}
+	return rings
+}
+
+// takeSegments returns the first lines with n segments in all, cutting the
+// last one short.
+func takeSegments(lines []coord.Coordinates, n int) []coord.Coordinates {
+	var taken []coord.Coordinates
+	for _, l := range lines {
+		if n <= 0 {
+			break
+		}
+		if len(l)-1 > n {
+			l = l[:n+1]
+		}
+		taken = append(taken, l)
+		n -= len(l) - 1
+	}
+	return taken
+}
+
+// translate returns lines moved by (dx, dy).
+func translate(lines []coord.Coordinates, dx, dy float64) []coord.Coordinates {
+	moved := make([]coord.Coordinates, len(lines))
+	for i, l := range lines {
+		moved[i] = make(coord.Coordinates, len(l))
+		for j, c := range l {
+			moved[i][j] = coord.Coordinate{X: c.X + dx, Y: c.Y + dy}
+		}
+	}
+	return moved
+}
+
+// edgeSetIntersectors are the strategies, each returning a new intersector.
+var edgeSetIntersectors = []struct {
+	name string
+	new  func() EdgeSetIntersector
+}{
+	{"Simple", func() EdgeSetIntersector { return NewSimpleEdgeSetIntersector() }},
+	{"SimpleMCSweepLine", func() EdgeSetIntersector { return NewSimpleMCSweepLineIntersector() }},
+	{"MCIndex", func() EdgeSetIntersector { return NewMCIndexEdgeSetIntersector() }},
+}
+
+// intersectionResult is what a strategy finds. The number of intersections
+// is not compared, since it counts the trivial intersections of adjacent
+// segments as often as each strategy tests them.
+type intersectionResult struct {
+	hasProper, hasProperInterior bool
+	points                       []coord.Coordinate
+}
+
+func (r intersectionResult) String() string {
+	return fmt.Sprintf("%d points, proper %v, proper interior %v", len(r.points), r.hasProper, r.hasProperInterior)
+}
+
+func (r intersectionResult) equals(other intersectionResult) bool {
+	return r.hasProper == other.hasProper &&
+		r.hasProperInterior == other.hasProperInterior &&
+		equalCoords(r.points, other.points)
+}
+
+func computeSelfIntersections(t *testing.T, esi EdgeSetIntersector, lines []coord.Coordinates, computeAllSegments bool) intersectionResult {
+	t.Helper()
+	edges := newEdges(lines...)
+	si := NewSegmentIntersector(coord.NewRobustLineIntersector(), true, false, false)
+	if err := esi.ComputeSelfIntersections(edges, si, computeAllSegments); err != nil {
+		t.Fatalf("ComputeSelfIntersections() error = %v", err)
+	}
+	return intersectionResult{si.hasProper, si.hasProperInterior, intersectionPoints(edges)}
+}
+
+func computeEdgeIntersections(t *testing.T, esi EdgeSetIntersector, lines0, lines1 []coord.Coordinates) intersectionResult {
+	t.Helper()
+	edges0, edges1 := newEdges(lines0...), newEdges(lines1...)
+	si := NewSegmentIntersector(coord.NewRobustLineIntersector(), true, false, false)
+	if err := esi.ComputeEdgeIntersections(edges0, edges1, si); err != nil {
+		t.Fatalf("ComputeEdgeIntersections() error = %v", err)
+	}
+	return intersectionResult{si.hasProper, si.hasProperInterior, intersectionPoints(edges0, edges1)}
+}
+
+func TestEdgeSetIntersectorsAgree(t *testing.T) {
+	// the other strategies find the same intersections as the sweep line,
+	// in the landmarks and in the landmarks overlaid with a shifted copy
+	rings := landmarkRings(t)
+	for _, n := range []int{16, 200, 1000, 1 << 30} {
+		lines := takeSegments(rings, n)
+		shifted := translate(lines, 0.0003, 0.0002)
+		both := append(append([]coord.Coordinates{}, lines...), shifted...)
+
+		for _, esi := range edgeSetIntersectors {
+			t.Run(fmt.Sprintf("%s/%d", esi.name, numSegments(newEdges(lines...))), func(t *testing.T) {
+				for _, computeAllSegments := range []bool{false, true} {
+					want := computeSelfIntersections(t, NewSimpleMCSweepLineIntersector(), both, computeAllSegments)
+					if got := computeSelfIntersections(t, esi.new(), both, computeAllSegments); !got.equals(want) {
+						t.Errorf("self intersections, all segments %v: %v, want %v", computeAllSegments, got, want)
+					}
+				}
+				want := computeEdgeIntersections(t, NewSimpleMCSweepLineIntersector(), lines, shifted)
+				if len(want.points) == 0 {
+					t.Fatal("shifted landmarks do not intersect")
+				}
+				if got := computeEdgeIntersections(t, esi.new(), lines, shifted); !got.equals(want) {
+					t.Errorf("edge intersections: %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+// benchmarkSegments are the total numbers of segments of the benchmarks.
+var benchmarkSegments = []int{4, 8, 16, 32, 64, 128, 256, 1024, 4096}
+
+// BenchmarkComputeSelfIntersections nodes the rings of the landmarks, as
+// when building the graph of a polygonal geometry.
+func BenchmarkComputeSelfIntersections(b *testing.B) {
+	rings := landmarkRings(b)
+	// the landmarks have about 2000 segments, so add a shifted copy
+	rings = append(rings[:len(rings):len(rings)], translate(rings, 0.0003, 0.0002)...)
+	for _, n := range benchmarkSegments {
+		lines := takeSegments(rings, n)
+		for _, esi := range edgeSetIntersectors {
+			b.Run(fmt.Sprintf("%s/%d", esi.name, n), func(b *testing.B) {
+				li := coord.NewRobustLineIntersector()
+				for i := 0; i < b.N; i++ {
+					si := NewSegmentIntersector(li, true, false, false)
+					esi.new().ComputeSelfIntersections(newEdges(lines...), si, true)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkComputeEdgeIntersections intersects the rings of the landmarks
+// with a shifted copy, as when relating two polygonal geometries.
+func BenchmarkComputeEdgeIntersections(b *testing.B) {
+	rings := landmarkRings(b)
+	for _, n := range benchmarkSegments {
+		lines0 := takeSegments(rings, n/2)
+		lines1 := translate(lines0, 0.0003, 0.0002)
+		for _, esi := range edgeSetIntersectors {
+			b.Run(fmt.Sprintf("%s/%d", esi.name, n), func(b *testing.B) {
+				li := coord.NewRobustLineIntersector()
+				for i := 0; i < b.N; i++ {
+					si := NewSegmentIntersector(li, true, false, false)
+					esi.new().ComputeEdgeIntersections(newEdges(lines0...), newEdges(lines1...), si)
+				}
+			})
+		}
+	}
+}
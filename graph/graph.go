@@ -1,6 +1,9 @@
 package graph
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/simoncochrane/geoz/coord"
 	"github.com/simoncochrane/geoz/geom"
@@ -33,6 +36,27 @@ func (tl TopologyLocation) IsNil() bool {
 	return true
 }
 
+// String returns the location symbols of the topology location: the on
+// location, or the left, on and right locations of an area edge.
+func (tl TopologyLocation) String() string {
+	if tl[PositionLeft] == coord.LocationNone && tl[PositionRight] == coord.LocationNone {
+		return locationSymbol(tl[PositionOn])
+	}
+	return locationSymbol(tl[PositionLeft]) + locationSymbol(tl[PositionOn]) + locationSymbol(tl[PositionRight])
+}
+
+func locationSymbol(loc coord.Location) string {
+	switch loc {
+	case coord.LocationInterior:
+		return "i"
+	case coord.LocationBoundary:
+		return "b"
+	case coord.LocationExterior:
+		return "e"
+	}
+	return "-"
+}
+
 // Label describes the relationship of a component of a topological graph to
 // its neighbours.
 type Label [2]TopologyLocation
@@ -49,6 +73,17 @@ func (l Label) LocationAt(index int, pos Position) coord.Location {
 	return l[index][pos]
 }
 
+// String returns the locations for both geometries, e.g. "A:ibe B:i".
+func (l Label) String() string {
+	var parts []string
+	for i, name := range []string{"A", "B"} {
+		if !l[i].IsNil() {
+			parts = append(parts, name+":"+l[i].String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func (l *Label) SetLocation(index int, loc coord.Location) {
 	l[index][PositionOn] = loc
 }
@@ -59,6 +94,13 @@ func (l *Label) SetLocations(index int, on, left, right coord.Location) {
 	l[index][PositionRight] = right
 }
 
+// SetAllLocations sets the on, left and right locations for the index.
+func (l *Label) SetAllLocations(index int, loc coord.Location) {
+	l[index][PositionOn] = loc
+	l[index][PositionLeft] = loc
+	l[index][PositionRight] = loc
+}
+
 func (l *Label) IsNil(index int) bool {
 	return l[index].IsNil()
 }
@@ -121,6 +163,10 @@ type Graph struct {
 
 	UseBoundaryDeterminationRule bool
 
+	// NewEdgeSetIntersector, if set, creates the strategy used to compute
+	// edge intersections. Otherwise it is chosen by ChooseEdgeSetIntersector.
+	NewEdgeSetIntersector func() EdgeSetIntersector
+
 	boundaryNodeRule BoundaryNodeRule
 
 	// the index of this geometry as an argument to a spatial function (used for labelling)
@@ -131,6 +177,7 @@ type Graph struct {
 
 func NewGraph(parent *geom.Geometry, index int, useBoundaryDeterminationRule bool) (*Graph, error) {
 	g := &Graph{
+		nodes:            map[coord.Coordinate]*Node{},
 		geometry:         parent,
 		argIndex:         index,
 		lineEdgeMap:      coord.NewCoordinatesMap(),
@@ -227,6 +274,31 @@ func On(location coord.Location) TopologyLocation {
 	return tl
 }
 
+// Nodes returns the nodes of the graph, ordered by X and then Y.
+func (gr *Graph) Nodes() []*Node {
+	return sortedNodes(gr.nodes)
+}
+
+// Edges returns the edges of the graph.
+func (gr *Graph) Edges() []*Edge {
+	return gr.edges
+}
+
+func sortedNodes(nodes map[coord.Coordinate]*Node) []*Node {
+	sorted := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		sorted = append(sorted, node)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].Point, sorted[j].Point
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+	return sorted
+}
+
 func (gr *Graph) BoundaryNodes() []*Node {
 	if gr.boundaryNodes != nil {
 		return gr.boundaryNodes
@@ -311,7 +383,7 @@ func (gr *Graph) insertPolygonRing(index int, points coord.Coordinates, cwLeft,
 // NOTE: computeAllSegments is only valid for ring geometries.
 func (gr *Graph) computeSelfNodes(li coord.LineIntersector, computeAllSegments, isDoneIfProperInt bool) *SegmentIntersector {
 	segmentIntersector := NewSegmentIntersector(li, true, false, isDoneIfProperInt)
-	edgeSetIntersector := NewSimpleMCSweepLineIntersector()
+	edgeSetIntersector := gr.edgeSetIntersector(numSegments(gr.edges))
 
 	edgeSetIntersector.ComputeSelfIntersections(gr.edges, segmentIntersector, computeAllSegments)
 
@@ -353,11 +425,18 @@ func (gr *Graph) IsBoundaryNode(argIndex int, point coord.Coordinate) bool {
 	return false
 }
 
+func (gr *Graph) edgeSetIntersector(numSegments int) EdgeSetIntersector {
+	if gr.NewEdgeSetIntersector != nil {
+		return gr.NewEdgeSetIntersector()
+	}
+	return ChooseEdgeSetIntersector(numSegments)
+}
+
 func (gr *Graph) computeEdgeIntersections(other *Graph, li coord.LineIntersector, includeProper bool) *SegmentIntersector {
 	si := NewSegmentIntersector(li, includeProper, true, false)
 	si.SetBoundaryNodes(gr.BoundaryNodes(), other.BoundaryNodes())
 
-	esi := NewSimpleMCSweepLineIntersector()
+	esi := gr.edgeSetIntersector(numSegments(gr.edges, other.edges))
 	esi.ComputeEdgeIntersections(gr.edges, other.edges, si)
 
 	return si
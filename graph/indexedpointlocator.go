@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/index/intervaltree"
+)
+
+// IndexedPointInAreaLocator locates points in a polygonal geometry, using an
+// index of the Y extents of the ring segments so that each point is only
+// tested against the segments its rightward ray may cross. Building the
+// index takes O(n log n) for n segments, after which Locate takes
+// O(log n) plus the number of segments found.
+type IndexedPointInAreaLocator struct {
+	index *intervaltree.Tree
+}
+
+// NewIndexedPointInAreaLocator indexes the rings of a polygon,
+// multipolygon, or collection of them.
+func NewIndexedPointInAreaLocator(geometry *geom.Geometry) (*IndexedPointInAreaLocator, error) {
+	var items []intervaltree.Item
+	if err := addRingSegments(geometry, &items); err != nil {
+		return nil, err
+	}
+	return &IndexedPointInAreaLocator{index: intervaltree.New(items)}, nil
+}
+
+// segment is the value of an index item.
+type segment struct {
+	p1, p2 coord.Coordinate
+}
+
+func addRingSegments(geometry *geom.Geometry, items *[]intervaltree.Item) error {
+	switch geometry.Type {
+	case geom.TypePolygon:
+		if geometry.IsEmpty() {
+			return nil
+		}
+		addSegments(geometry.Line, items)
+		for _, hole := range geometry.MultiLine {
+			addSegments(hole, items)
+		}
+	case geom.TypeMultiPolygon, geom.TypeCollection:
+		for _, g := range geometry.Collection {
+			if err := addRingSegments(g, items); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("cannot locate points in non-areal geometry type %v", geometry.Type)
+	}
+	return nil
+}
+
+func addSegments(ring coord.Coordinates, items *[]intervaltree.Item) {
+	for i := 1; i < len(ring); i++ {
+		p1, p2 := ring[i-1], ring[i]
+		*items = append(*items, intervaltree.Item{
+			Min:   math.Min(p1.Y, p2.Y),
+			Max:   math.Max(p1.Y, p2.Y),
+			Value: segment{p1, p2},
+		})
+	}
+}
+
+// Locate returns the location of a point in the geometry. Points inside
+// holes are exterior.
+func (l *IndexedPointInAreaLocator) Locate(point coord.Coordinate) coord.Location {
+	counter := coord.NewRayCrossingCounter(point)
+	l.index.Visit(point.Y, point.Y, func(item *intervaltree.Item) bool {
+		s := item.Value.(segment)
+		counter.CountSegment(s.p1, s.p2)
+		return !counter.IsOnSegment()
+	})
+	return counter.Location()
+}
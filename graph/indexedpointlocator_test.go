@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// locateInPolygons locates a point in the polygons of a geometry from
+// coord.PointInRing on each of their rings.
+func locateInPolygons(point coord.Coordinate, g *geom.Geometry) coord.Location {
+	if g.Type != geom.TypePolygon {
+		var loc coord.Location = coord.LocationExterior
+		for _, part := range g.Collection {
+			switch locateInPolygons(point, part) {
+			case coord.LocationBoundary:
+				return coord.LocationBoundary
+			case coord.LocationInterior:
+				loc = coord.LocationInterior
+			}
+		}
+		return loc
+	}
+	if g.IsEmpty() {
+		return coord.LocationExterior
+	}
+	if loc := coord.PointInRing(point, g.Line); loc != coord.LocationInterior {
+		return loc
+	}
+	for _, hole := range g.MultiLine {
+		switch coord.PointInRing(point, hole) {
+		case coord.LocationInterior:
+			return coord.LocationExterior
+		case coord.LocationBoundary:
+			return coord.LocationBoundary
+		}
+	}
+	return coord.LocationInterior
+}
+
+func TestIndexedPointInAreaLocator(t *testing.T) {
+	polygon := "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2), (6 5, 8 8, 8 5, 6 5))"
+	multiPolygon := "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2)), ((4 4, 6 4, 5 6, 4 4)), ((20 0, 25 5, 20 10, 15 5, 20 0)))"
+	tests := []struct {
+		name  string
+		wkt   string
+		point coord.Coordinate
+		want  coord.Location
+	}{
+		{"interior", polygon, coord.Coordinate{X: 1, Y: 1}, coord.LocationInterior},
+		{"exterior", polygon, coord.Coordinate{X: 11, Y: 5}, coord.LocationExterior},
+		{"exterior level with an edge", polygon, coord.Coordinate{X: -1, Y: 10}, coord.LocationExterior},
+		{"in a hole", polygon, coord.Coordinate{X: 3, Y: 3}, coord.LocationExterior},
+		{"in a triangular hole", polygon, coord.Coordinate{X: 7.5, Y: 6}, coord.LocationExterior},
+		{"between holes", polygon, coord.Coordinate{X: 5, Y: 3}, coord.LocationInterior},
+		{"beside a hole edge", polygon, coord.Coordinate{X: 6.5, Y: 7}, coord.LocationInterior},
+		{"on the shell", polygon, coord.Coordinate{X: 5, Y: 0}, coord.LocationBoundary},
+		{"on a vertical shell edge", polygon, coord.Coordinate{X: 10, Y: 3}, coord.LocationBoundary},
+		{"on a shell vertex", polygon, coord.Coordinate{X: 10, Y: 10}, coord.LocationBoundary},
+		{"on a hole edge", polygon, coord.Coordinate{X: 3, Y: 4}, coord.LocationBoundary},
+		{"on a sloping hole edge", polygon, coord.Coordinate{X: 7, Y: 6.5}, coord.LocationBoundary},
+		{"on a hole vertex", polygon, coord.Coordinate{X: 2, Y: 2}, coord.LocationBoundary},
+		{"on a triangular hole vertex", polygon, coord.Coordinate{X: 8, Y: 8}, coord.LocationBoundary},
+		{"island in a hole", multiPolygon, coord.Coordinate{X: 5, Y: 5}, coord.LocationInterior},
+		{"hole around an island", multiPolygon, coord.Coordinate{X: 3, Y: 5}, coord.LocationExterior},
+		{"on an island vertex", multiPolygon, coord.Coordinate{X: 5, Y: 6}, coord.LocationBoundary},
+		{"second part", multiPolygon, coord.Coordinate{X: 20, Y: 5}, coord.LocationInterior},
+		{"on a second part vertex", multiPolygon, coord.Coordinate{X: 15, Y: 5}, coord.LocationBoundary},
+		{"between parts", multiPolygon, coord.Coordinate{X: 12, Y: 5}, coord.LocationExterior},
+		{"empty polygon", "POLYGON EMPTY", coord.Coordinate{X: 1, Y: 1}, coord.LocationExterior},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := mustUnmarshal(t, test.wkt)
+			locator, err := NewIndexedPointInAreaLocator(g)
+			if err != nil {
+				t.Fatalf("NewIndexedPointInAreaLocator() error = %v", err)
+			}
+			if want := locateInPolygons(test.point, g); want != test.want {
+				t.Fatalf("PointInRing locates %v at %v, test wants %v", test.point, want, test.want)
+			}
+			if got := locator.Locate(test.point); got != test.want {
+				t.Errorf("Locate(%v) = %v, want %v", test.point, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIndexedPointInAreaLocatorGrid(t *testing.T) {
+	for _, text := range []string{
+		"POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2), (6 5, 8 8, 8 5, 6 5))",
+		"MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2)), ((4 4, 6 4, 5 6, 4 4)))",
+		"POLYGON ((0 0, 5 8, 10 0, 10 10, 5 2, 0 10, 0 0))",
+	} {
+		g := mustUnmarshal(t, text)
+		locator, err := NewIndexedPointInAreaLocator(g)
+		if err != nil {
+			t.Fatalf("NewIndexedPointInAreaLocator() error = %v", err)
+		}
+		// every vertex, edge and cell of a half unit grid
+		for x := -1.0; x <= 11; x += 0.5 {
+			for y := -1.0; y <= 11; y += 0.5 {
+				p := coord.Coordinate{X: x, Y: y}
+				if got, want := locator.Locate(p), locateInPolygons(p, g); got != want {
+					t.Errorf("%s: Locate(%v) = %v, want %v", text, p, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexedPointInAreaLocatorNonAreal(t *testing.T) {
+	for _, text := range []string{"POINT (1 1)", "LINESTRING (0 0, 1 1)", "GEOMETRYCOLLECTION (POLYGON ((0 0, 1 0, 1 1, 0 0)), POINT (1 1))"} {
+		if _, err := NewIndexedPointInAreaLocator(mustUnmarshal(t, text)); err == nil {
+			t.Errorf("NewIndexedPointInAreaLocator(%s) error = nil, want an error", text)
+		}
+	}
+}
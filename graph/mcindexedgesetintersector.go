@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/index/strtree"
+)
+
+// MCIndexEdgeSetIntersector indexes the monotone chains of edges in an
+// R-tree and intersects each chain with those whose envelopes it overlaps.
+// Unlike a sweep line, its cost does not grow with the number of chains
+// spanning each X, so it suits large edge sets.
+type MCIndexEdgeSetIntersector struct{}
+
+func NewMCIndexEdgeSetIntersector() *MCIndexEdgeSetIntersector {
+	return &MCIndexEdgeSetIntersector{}
+}
+
+func (mei *MCIndexEdgeSetIntersector) ComputeSelfIntersections(edges []*Edge,
+	si *SegmentIntersector, computeAllSegments bool) error {
+
+	chains, err := monotoneChains(edges)
+	if err != nil {
+		return errors.Wrap(err, "failed to add edges")
+	}
+	tree := indexChains(chains)
+	for i, mc := range chains {
+		env := chainEnvelope(mc)
+		tree.Visit(env, func(item *strtree.Item) bool {
+			// test each pair of chains once, including each chain with
+			// itself
+			j := item.Value.(int)
+			other := chains[j]
+			if j < i || !computeAllSegments && other.Edge.Edge == mc.Edge.Edge {
+				return true
+			}
+			mc.ComputeIntersections(other, si)
+			return !si.Done()
+		})
+		if si.Done() {
+			break
+		}
+	}
+	return nil
+}
+
+func (mei *MCIndexEdgeSetIntersector) ComputeEdgeIntersections(edges0, edges1 []*Edge, si *SegmentIntersector) error {
+	chains0, err := monotoneChains(edges0)
+	if err != nil {
+		return errors.Wrap(err, "failed to add edges0")
+	}
+	chains1, err := monotoneChains(edges1)
+	if err != nil {
+		return errors.Wrap(err, "failed to add edges1")
+	}
+	tree := indexChains(chains1)
+	for _, mc := range chains0 {
+		tree.Visit(chainEnvelope(mc), func(item *strtree.Item) bool {
+			mc.ComputeIntersections(chains1[item.Value.(int)], si)
+			return !si.Done()
+		})
+		if si.Done() {
+			break
+		}
+	}
+	return nil
+}
+
+func monotoneChains(edges []*Edge) ([]*MonotoneChain, error) {
+	var chains []*MonotoneChain
+	for _, edge := range edges {
+		mce, err := edge.MonotoneChainEdge()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// the last start index is the end of the last chain
+		for i := 0; i < len(mce.StartIndexes)-1; i++ {
+			chains = append(chains, mce.MonotoneChain(i))
+		}
+	}
+	return chains, nil
+}
+
+func indexChains(chains []*MonotoneChain) *strtree.Tree {
+	items := make([]strtree.Item, len(chains))
+	for i, mc := range chains {
+		items[i] = strtree.Item{Envelope: *chainEnvelope(mc), Value: i}
+	}
+	return strtree.New(items)
+}
+
+// chainEnvelope returns the envelope of a monotone chain, which is that of
+// its end points.
+func chainEnvelope(mc *MonotoneChain) *coord.Envelope {
+	mce := mc.Edge
+	return coord.NewEnvelopeFromCoords(
+		mce.Points[mce.StartIndexes[mc.Index]],
+		mce.Points[mce.StartIndexes[mc.Index+1]])
+}
@@ -60,6 +60,7 @@ func findChainEnd(pts coord.Coordinates, start int) (int, error) {
 		if quad != chainQuad {
 			break
 		}
+		last++
 	}
 	return last - 1, nil
 }
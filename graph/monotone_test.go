@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+func TestChainStartIndexes(t *testing.T) {
+	tests := []struct {
+		name string
+		pts  coord.Coordinates
+		want []int
+	}{
+		{
+			name: "segment",
+			pts:  coord.Coordinates{{X: 0, Y: 0}, {X: 1, Y: 1}},
+			want: []int{0, 1},
+		},
+		{
+			name: "one chain",
+			pts:  coord.Coordinates{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 3}, {X: 5, Y: 4}},
+			want: []int{0, 3},
+		},
+		{
+			name: "zigzag",
+			pts:  coord.Coordinates{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 1}, {X: 4, Y: 0}, {X: 3, Y: -1}},
+			want: []int{0, 2, 4, 5},
+		},
+		{
+			// horizontal and vertical segments are in the quadrant to their
+			// north east or south east
+			name: "ring",
+			pts:  coord.Coordinates{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}},
+			want: []int{0, 2, 3, 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// a chain end which is never found loops forever
+			done := make(chan struct{})
+			var got []int
+			var err error
+			go func() {
+				got, err = chainStartIndexes(test.pts)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("chainStartIndexes() did not return")
+			}
+			if err != nil {
+				t.Fatalf("chainStartIndexes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("chainStartIndexes() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package graph
+
+import "github.com/simoncochrane/geoz/coord"
+
+// OverlayOp is a set-theoretic operation combining two geometries.
+type OverlayOp int
+
+const (
+	OverlayIntersection OverlayOp = iota + 1
+	OverlayUnion
+	OverlayDifference
+	OverlaySymDifference
+)
+
+func (op OverlayOp) String() string {
+	switch op {
+	case OverlayIntersection:
+		return "Intersection"
+	case OverlayUnion:
+		return "Union"
+	case OverlayDifference:
+		return "Difference"
+	case OverlaySymDifference:
+		return "SymDifference"
+	}
+	return "Unknown"
+}
+
+// IsResultOfOp returns whether a point with the given locations in the two
+// geometries is in the result of an overlay. Boundaries count as part of
+// their geometry.
+func IsResultOfOp(loc0, loc1 coord.Location, op OverlayOp) bool {
+	in0 := loc0 == coord.LocationInterior || loc0 == coord.LocationBoundary
+	in1 := loc1 == coord.LocationInterior || loc1 == coord.LocationBoundary
+	switch op {
+	case OverlayIntersection:
+		return in0 && in1
+	case OverlayUnion:
+		return in0 || in1
+	case OverlayDifference:
+		return in0 && !in1
+	case OverlaySymDifference:
+		return in0 != in1
+	}
+	return false
+}
+
+// IsResultOfOp returns whether the label's locations at a position are in
+// the result of an overlay.
+func (l Label) IsResultOfOp(pos Position, op OverlayOp) bool {
+	return IsResultOfOp(l[0][pos], l[1][pos], op)
+}
@@ -0,0 +1,292 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// DirectedEdge is one direction of an edge of a PlanarGraph. The face of a
+// directed edge is the face lying on its left-hand side.
+type DirectedEdge struct {
+	Orig, Dest coord.Coordinate
+	Sym        *DirectedEdge
+	// Label holds the locations on the edge and on its left and right in
+	// each geometry.
+	Label *Label
+
+	// InResultArea is whether the face on the left is in the result of an
+	// overlay, and InResultLine whether the edge is in it as a line.
+	InResultArea bool
+	InResultLine bool
+
+	angle float64
+	face  *EdgeRing
+}
+
+// EdgeRing is a closed ring of directed edges, traced with its face on the
+// left. Rings with a positive area are the boundaries of bounded faces;
+// rings with a negative area are the outer boundaries of connected
+// components.
+type EdgeRing struct {
+	Edges  []*DirectedEdge
+	Coords coord.Coordinates
+	Area   float64
+}
+
+// PlanarGraph is a graph of noded segments, which only meet at their end
+// points, with the outgoing directed edges of every node sorted counter
+// clockwise by angle.
+type PlanarGraph struct {
+	stars map[coord.XY][]*DirectedEdge
+	nodes coord.Coordinates
+	edges []*DirectedEdge
+}
+
+func NewPlanarGraph() *PlanarGraph {
+	return &PlanarGraph{
+		stars: map[coord.XY][]*DirectedEdge{},
+	}
+}
+
+// AddEdge adds an edge between two distinct points, returning its direction
+// from p0 to p1.
+func (pg *PlanarGraph) AddEdge(p0, p1 coord.Coordinate) *DirectedEdge {
+	e0 := &DirectedEdge{Orig: p0, Dest: p1, Label: NewLabel()}
+	e1 := &DirectedEdge{Orig: p1, Dest: p0, Label: NewLabel()}
+	e0.Sym, e1.Sym = e1, e0
+	e0.angle = math.Atan2(p1.Y-p0.Y, p1.X-p0.X)
+	e1.angle = math.Atan2(p0.Y-p1.Y, p0.X-p1.X)
+
+	pg.edges = append(pg.edges, e0, e1)
+	pg.addToStar(e0)
+	pg.addToStar(e1)
+	return e0
+}
+
+func (pg *PlanarGraph) addToStar(e *DirectedEdge) {
+	key := e.Orig.XY()
+	star, ok := pg.stars[key]
+	if !ok {
+		pg.nodes = append(pg.nodes, e.Orig)
+	}
+	i := sort.Search(len(star), func(i int) bool { return star[i].angle > e.angle })
+	star = append(star, nil)
+	copy(star[i+1:], star[i:])
+	star[i] = e
+	pg.stars[key] = star
+}
+
+// Edges returns the directed edges of the graph, each followed by its Sym.
+func (pg *PlanarGraph) Edges() []*DirectedEdge {
+	return pg.edges
+}
+
+// Nodes returns the nodes of the graph, in the order they were added.
+func (pg *PlanarGraph) Nodes() coord.Coordinates {
+	return pg.nodes
+}
+
+// Star returns the directed edges leaving the node at c, counter clockwise.
+func (pg *PlanarGraph) Star(c coord.Coordinate) []*DirectedEdge {
+	return pg.stars[c.XY()]
+}
+
+// next returns the directed edge following e around the face on its left,
+// considering only the directed edges accepted by include. This is the
+// first accepted edge clockwise from the reverse of e at its destination.
+func (pg *PlanarGraph) next(e *DirectedEdge, include func(*DirectedEdge) bool) *DirectedEdge {
+	star := pg.stars[e.Dest.XY()]
+	idx := 0
+	for i, out := range star {
+		if out == e.Sym {
+			idx = i
+			break
+		}
+	}
+	for i := 1; i <= len(star); i++ {
+		cand := star[(idx-i+len(star))%len(star)]
+		if include(cand) {
+			return cand
+		}
+	}
+	return nil
+}
+
+// Rings traces the rings formed by the directed edges accepted by include.
+func (pg *PlanarGraph) Rings(include func(*DirectedEdge) bool) []*EdgeRing {
+	visited := map[*DirectedEdge]bool{}
+	var rings []*EdgeRing
+	for _, start := range pg.edges {
+		if visited[start] || !include(start) {
+			continue
+		}
+		ring := &EdgeRing{}
+		for e := start; e != nil && !visited[e]; e = pg.next(e, include) {
+			visited[e] = true
+			ring.Edges = append(ring.Edges, e)
+			ring.Coords = append(ring.Coords, e.Orig)
+		}
+		ring.Coords = append(ring.Coords, ring.Coords[0])
+		ring.Area = coord.SignedArea(ring.Coords)
+		rings = append(rings, ring)
+	}
+	return rings
+}
+
+// MergeLines merges the edges accepted by include, in either direction,
+// into maximal lines, which only start and end at nodes that do not have
+// exactly two accepted edges. Cycles of such nodes are returned as closed
+// lines.
+func (pg *PlanarGraph) MergeLines(include func(*DirectedEdge) bool) []coord.Coordinates {
+	degree := func(c coord.Coordinate) int {
+		n := 0
+		for _, e := range pg.stars[c.XY()] {
+			if include(e) {
+				n++
+			}
+		}
+		return n
+	}
+
+	visited := map[*DirectedEdge]bool{}
+	walk := func(start *DirectedEdge) coord.Coordinates {
+		line := coord.Coordinates{start.Orig}
+		e := start
+		for {
+			visited[e], visited[e.Sym] = true, true
+			line = append(line, e.Dest)
+			if degree(e.Dest) != 2 {
+				break
+			}
+			prev := e
+			e = pg.next(e, func(cand *DirectedEdge) bool { return cand != prev.Sym && include(cand) })
+			if visited[e] {
+				break
+			}
+		}
+		return line
+	}
+
+	var lines []coord.Coordinates
+	for _, e := range pg.edges {
+		if !visited[e] && include(e) && degree(e.Orig) != 2 {
+			lines = append(lines, walk(e))
+		}
+	}
+	for _, e := range pg.edges {
+		if !visited[e] && include(e) {
+			lines = append(lines, walk(e))
+		}
+	}
+	return lines
+}
+
+// LabelFaces sets the left and right locations of each directed edge in
+// two geometries, from the faces on its sides. A bounded face is in a
+// geometry if inArea finds an interior point of it in the geometry's area.
+// The outer boundary ring of a connected component takes the locations of
+// the face it lies in. The on location of an edge becomes the boundary if
+// its sides differ, otherwise that of its sides, unless it is already set,
+// as for the edges of lines.
+func (pg *PlanarGraph) LabelFaces(inArea func(geomIndex int, point coord.Coordinate) bool) {
+	var bounded, outer []*EdgeRing
+	for _, face := range pg.Rings(func(*DirectedEdge) bool { return true }) {
+		for _, e := range face.Edges {
+			e.face = face
+		}
+		if face.Area > 0 {
+			bounded = append(bounded, face)
+		} else {
+			outer = append(outer, face)
+		}
+	}
+
+	holes := map[*EdgeRing][]coord.Coordinates{}
+	parents := map[*EdgeRing]*EdgeRing{}
+	index := newRingIndex(bounded)
+	for _, r := range outer {
+		if parent := index.containing(r.Coords[0]); parent != nil {
+			parents[r] = parent
+			holes[parent] = append(holes[parent], r.Coords)
+		}
+	}
+
+	exterior := [2]coord.Location{coord.LocationExterior, coord.LocationExterior}
+	locs := map[*EdgeRing][2]coord.Location{}
+	for _, face := range bounded {
+		loc := exterior
+		if point, ok := interiorPoint(face.Coords, holes[face]); ok {
+			for i := range loc {
+				if inArea(i, point) {
+					loc[i] = coord.LocationInterior
+				}
+			}
+		}
+		locs[face] = loc
+	}
+	for _, r := range outer {
+		if parent, ok := parents[r]; ok {
+			locs[r] = locs[parent]
+		} else {
+			locs[r] = exterior
+		}
+	}
+
+	for i := 0; i < len(pg.edges); i += 2 {
+		e := pg.edges[i]
+		for geomIndex := range exterior {
+			left, right := locs[e.face][geomIndex], locs[e.Sym.face][geomIndex]
+			on := e.Label[geomIndex][PositionOn]
+			if on == coord.LocationNone {
+				on = left
+				if left != right {
+					on = coord.LocationBoundary
+				}
+			}
+			e.Label.SetLocations(geomIndex, on, left, right)
+			e.Sym.Label.SetLocations(geomIndex, on, right, left)
+		}
+	}
+}
+
+// LabelResult marks the directed edges whose faces are in the result of an
+// overlay, and those in it as lines: selected by the operation and not
+// covered by a face of the result.
+func (pg *PlanarGraph) LabelResult(op OverlayOp) {
+	for _, e := range pg.edges {
+		e.InResultArea = e.Label.IsResultOfOp(PositionLeft, op)
+	}
+	for _, e := range pg.edges {
+		e.InResultLine = e.Label.IsResultOfOp(PositionOn, op) && !e.InResultArea && !e.Sym.InResultArea
+	}
+}
+
+// LinkResultEdges links the directed edges between faces in the result
+// and faces not in it into rings, with the result on their left.
+func (pg *PlanarGraph) LinkResultEdges() []*EdgeRing {
+	return pg.Rings(func(e *DirectedEdge) bool {
+		return e.InResultArea && !e.Sym.InResultArea
+	})
+}
+
+// BuildPolygons assembles the polygons of the result faces.
+func (pg *PlanarGraph) BuildPolygons() ([]*geom.Geometry, error) {
+	return buildPolygons(pg.LinkResultEdges())
+}
+
+// BuildLines merges the result lines at the nodes where they do not branch.
+func (pg *PlanarGraph) BuildLines() ([]*geom.Geometry, error) {
+	var lines []*geom.Geometry
+	for _, line := range pg.MergeLines(func(e *DirectedEdge) bool { return e.InResultLine }) {
+		ls, err := geom.NewLineString(line)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		lines = append(lines, ls)
+	}
+	return lines, nil
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// newPlanarGraph adds the segments of noded lines to a graph.
+func newPlanarGraph(lines ...coord.Coordinates) *PlanarGraph {
+	pg := NewPlanarGraph()
+	for _, l := range lines {
+		for i := 1; i < len(l); i++ {
+			pg.AddEdge(l[i-1], l[i])
+		}
+	}
+	return pg
+}
+
+// lineStrings returns lines as sorted strings, each starting at its lesser
+// end.
+func lineStrings(lines []coord.Coordinates) []string {
+	var texts []string
+	for _, l := range lines {
+		l = append(coord.Coordinates(nil), l...)
+		if last := l[len(l)-1]; last.X < l[0].X || last.X == l[0].X && last.Y < l[0].Y {
+			for i, j := 0, len(l)-1; i < j; i, j = i+1, j-1 {
+				l[i], l[j] = l[j], l[i]
+			}
+		}
+		texts = append(texts, fmt.Sprint(l))
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+func TestPlanarGraphStar(t *testing.T) {
+	pg := newPlanarGraph(line(0, 0, 1, 0), line(0, 0, 0, 1), line(0, 0, -1, -1), line(0, 0, 1, 1))
+	var dests []coord.Coordinate
+	for _, e := range pg.Star(coord.Coordinate{}) {
+		dests = append(dests, e.Dest)
+	}
+	if want := line(-1, -1, 1, 0, 1, 1, 0, 1); !equalCoords(dests, want) {
+		t.Errorf("Star() = %v, want %v", dests, want)
+	}
+	if n := len(pg.Nodes()); n != 5 {
+		t.Errorf("Nodes() = %d nodes, want 5", n)
+	}
+	if n := len(pg.Edges()); n != 8 {
+		t.Errorf("Edges() = %d edges, want 8", n)
+	}
+}
+
+func TestPlanarGraphRings(t *testing.T) {
+	// a square split by a diagonal has two bounded faces and an outer
+	// boundary
+	pg := newPlanarGraph(line(0, 0, 2, 0, 2, 2, 0, 2, 0, 0), line(0, 0, 2, 2))
+	var areas []float64
+	for _, r := range pg.Rings(func(*DirectedEdge) bool { return true }) {
+		areas = append(areas, r.Area)
+	}
+	sort.Float64s(areas)
+	if want := []float64{-4, 2, 2}; fmt.Sprint(areas) != fmt.Sprint(want) {
+		t.Errorf("ring areas = %v, want %v", areas, want)
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	// a branch at (1 0) ends the lines there; a cycle is a closed line
+	pg := newPlanarGraph(line(0, 0, 1, 0, 2, 0), line(1, 0, 1, 1, 1, 2), line(5, 5, 6, 5, 6, 6, 5, 5))
+	got := lineStrings(pg.MergeLines(func(*DirectedEdge) bool { return true }))
+	want := lineStrings([]coord.Coordinates{
+		line(0, 0, 1, 0), line(1, 0, 2, 0), line(1, 0, 1, 1, 1, 2), line(5, 5, 6, 5, 6, 6, 5, 5),
+	})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("MergeLines() = %v, want %v", got, want)
+	}
+
+	// without the vertical edges, the lines through (1 0) and (5 5) are
+	// merged
+	got = lineStrings(pg.MergeLines(func(e *DirectedEdge) bool { return e.Orig.X != e.Dest.X }))
+	want = lineStrings([]coord.Coordinates{line(0, 0, 1, 0, 2, 0), line(6, 5, 5, 5, 6, 6)})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("MergeLines() = %v, want %v", got, want)
+	}
+}
+
+// overlaidSquares returns the graph of the noded rings of two overlapping
+// squares, A from (0 0) to (2 2) and B from (1 1) to (3 3), labelled.
+func overlaidSquares() *PlanarGraph {
+	pg := newPlanarGraph(
+		line(0, 0, 2, 0, 2, 1, 2, 2, 1, 2, 0, 2, 0, 0),
+		line(1, 1, 2, 1), line(2, 1, 3, 1, 3, 3, 1, 3, 1, 2), line(1, 2, 1, 1),
+	)
+	a, b := coord.NewEnvelope(0, 2, 0, 2), coord.NewEnvelope(1, 3, 1, 3)
+	pg.LabelFaces(func(geomIndex int, p coord.Coordinate) bool {
+		return []*coord.Envelope{a, b}[geomIndex].ContainsCoord(p)
+	})
+	return pg
+}
+
+func TestLabelFaces(t *testing.T) {
+	pg := overlaidSquares()
+	labels := map[string]string{}
+	for _, e := range pg.Edges() {
+		labels[fmt.Sprint(e.Orig, e.Dest)] = e.Label.String()
+	}
+	for _, test := range []struct {
+		orig, dest coord.Coordinate
+		want       string
+	}{
+		// A's boundary outside B, inside B, and B's boundary inside A
+		{coord.Coordinate{X: 0, Y: 0}, coord.Coordinate{X: 2, Y: 0}, "A:ibe B:eee"},
+		{coord.Coordinate{X: 2, Y: 1}, coord.Coordinate{X: 2, Y: 2}, "A:ibe B:iii"},
+		{coord.Coordinate{X: 1, Y: 1}, coord.Coordinate{X: 2, Y: 1}, "A:iii B:ibe"},
+		{coord.Coordinate{X: 2, Y: 1}, coord.Coordinate{X: 1, Y: 1}, "A:iii B:ebi"},
+	} {
+		if got := labels[fmt.Sprint(test.orig, test.dest)]; got != test.want {
+			t.Errorf("label of %v-%v = %s, want %s", test.orig, test.dest, got, test.want)
+		}
+	}
+
+	// the locations on lines are kept
+	pg = newPlanarGraph(line(0, 0, 1, 0))
+	pg.Edges()[0].Label.SetLocation(0, coord.LocationInterior)
+	pg.Edges()[1].Label.SetLocation(0, coord.LocationInterior)
+	pg.LabelFaces(func(int, coord.Coordinate) bool { return false })
+	if got := pg.Edges()[0].Label.String(); got != "A:eie B:eee" {
+		t.Errorf("line label = %s, want A:eie B:eee", got)
+	}
+}
+
+func TestBuildPolygons(t *testing.T) {
+	tests := []struct {
+		op   OverlayOp
+		area float64
+		env  *coord.Envelope
+		n    int
+	}{
+		{OverlayIntersection, 1, coord.NewEnvelope(1, 2, 1, 2), 1},
+		{OverlayUnion, 7, coord.NewEnvelope(0, 3, 0, 3), 1},
+		{OverlayDifference, 3, coord.NewEnvelope(0, 2, 0, 2), 1},
+		{OverlaySymDifference, 6, coord.NewEnvelope(0, 3, 0, 3), 2},
+	}
+	for _, test := range tests {
+		t.Run(test.op.String(), func(t *testing.T) {
+			pg := overlaidSquares()
+			pg.LabelResult(test.op)
+			polygons, err := pg.BuildPolygons()
+			if err != nil {
+				t.Fatalf("BuildPolygons() error = %v", err)
+			}
+			if len(polygons) != test.n {
+				t.Fatalf("BuildPolygons() = %d polygons, want %d", len(polygons), test.n)
+			}
+			area := 0.0
+			env := polygons[0].Envelope()
+			for _, p := range polygons {
+				area += coord.SignedArea(p.Line)
+				env.ExpandEnvelope(p.Envelope())
+				if len(p.MultiLine) != 0 {
+					t.Errorf("polygon has %d holes", len(p.MultiLine))
+				}
+			}
+			if area != test.area || *env != *test.env {
+				t.Errorf("BuildPolygons() area %v in %v, want %v in %v", area, env, test.area, test.env)
+			}
+			lines, err := pg.BuildLines()
+			if err != nil || len(lines) != 0 {
+				t.Errorf("BuildLines() = %v, %v, want no lines", lines, err)
+			}
+		})
+	}
+}
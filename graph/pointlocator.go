@@ -102,26 +102,20 @@ func (pl *PointLocator) locateInPolygon(point coord.Coordinate, geometry *geom.G
 	shell := geometry.Line
 
 	shellLoc := pl.locateInPolygonRing(point, shell)
+	if shellLoc != coord.LocationInterior {
+		return shellLoc
+	}
 
-	/////
-
-	/*
-	   if (poly.isEmpty()) return Location.EXTERIOR;
-
-	   LinearRing shell = (LinearRing) poly.getExteriorRing();
-
-	   int shellLoc = locateInPolygonRing(p, shell);
-	   if (shellLoc == Location.EXTERIOR) return Location.EXTERIOR;
-	   if (shellLoc == Location.BOUNDARY) return Location.BOUNDARY;
-	   // now test if the point lies in or on the holes
-	   for (int i = 0; i < poly.getNumInteriorRing(); i++) {
-	     LinearRing hole = (LinearRing) poly.getInteriorRingN(i);
-	     int holeLoc = locateInPolygonRing(p, hole);
-	     if (holeLoc == Location.INTERIOR) return Location.EXTERIOR;
-	     if (holeLoc == Location.BOUNDARY) return Location.BOUNDARY;
-	   }
-	   return Location.INTERIOR;
-	*/
+	// now test if the point lies in or on the holes
+	for _, hole := range geometry.MultiLine {
+		switch pl.locateInPolygonRing(point, hole) {
+		case coord.LocationInterior:
+			return coord.LocationExterior
+		case coord.LocationBoundary:
+			return coord.LocationBoundary
+		}
+	}
+	return coord.LocationInterior
 }
 
 func (pl *PointLocator) locateInPolygonRing(point coord.Coordinate, ring coord.Coordinates) coord.Location {
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestPointLocatorLocate(t *testing.T) {
+	polygon := mustUnmarshal(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2), (6 6, 6 8, 8 8, 8 6, 6 6))")
+	line := mustUnmarshal(t, "LINESTRING (0 0, 5 5, 10 0)")
+	ring := mustUnmarshal(t, "LINESTRING (0 0, 5 5, 10 0, 0 0)")
+	point := mustUnmarshal(t, "POINT (1 2)")
+	tests := []struct {
+		name  string
+		g     *geom.Geometry
+		point coord.Coordinate
+		want  coord.Location
+	}{
+		{"polygon interior", polygon, coord.Coordinate{X: 1, Y: 1}, coord.LocationInterior},
+		{"polygon shell", polygon, coord.Coordinate{X: 10, Y: 5}, coord.LocationBoundary},
+		{"polygon exterior", polygon, coord.Coordinate{X: 11, Y: 5}, coord.LocationExterior},
+		{"first hole", polygon, coord.Coordinate{X: 3, Y: 3}, coord.LocationExterior},
+		{"second hole", polygon, coord.Coordinate{X: 7, Y: 7}, coord.LocationExterior},
+		{"hole boundary", polygon, coord.Coordinate{X: 6, Y: 7}, coord.LocationBoundary},
+		{"between holes", polygon, coord.Coordinate{X: 5, Y: 5}, coord.LocationInterior},
+		{"shell vertex", polygon, coord.Coordinate{X: 10, Y: 10}, coord.LocationBoundary},
+		{"hole vertex", polygon, coord.Coordinate{X: 4, Y: 2}, coord.LocationBoundary},
+		{"beside a hole", polygon, coord.Coordinate{X: 5, Y: 3}, coord.LocationInterior},
+		{"level with a hole edge", polygon, coord.Coordinate{X: 1, Y: 4}, coord.LocationInterior},
+		{"empty polygon", mustUnmarshal(t, "POLYGON EMPTY"), coord.Coordinate{X: 1, Y: 1}, coord.LocationExterior},
+		{"line interior", line, coord.Coordinate{X: 5, Y: 5}, coord.LocationInterior},
+		{"line end", line, coord.Coordinate{X: 10, Y: 0}, coord.LocationBoundary},
+		{"off the line", line, coord.Coordinate{X: 5, Y: 0}, coord.LocationExterior},
+		{"closed line start", ring, coord.Coordinate{X: 0, Y: 0}, coord.LocationInterior},
+		{"on the point", point, coord.Coordinate{X: 1, Y: 2}, coord.LocationInterior},
+		{"off the point", point, coord.Coordinate{X: 1, Y: 3}, coord.LocationExterior},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := NewPointLocator().Locate(test.point, test.g); got != test.want {
+				t.Errorf("Locate(%v) = %v, want %v", test.point, got, test.want)
+			}
+		})
+	}
+}
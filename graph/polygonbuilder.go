@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/index/strtree"
+)
+
+// ringIndex finds the smallest of a set of rings containing points or other
+// rings.
+type ringIndex struct {
+	tree *strtree.Tree
+}
+
+func newRingIndex(rings []*EdgeRing) *ringIndex {
+	items := make([]strtree.Item, len(rings))
+	for i, ring := range rings {
+		items[i] = strtree.Item{Envelope: *ring.Coords.Envelope(), Value: ring}
+	}
+	return &ringIndex{tree: strtree.New(items)}
+}
+
+// containing returns the ring with the smallest area which contains the
+// point in its interior, or nil if there is none.
+func (ri *ringIndex) containing(point coord.Coordinate) *EdgeRing {
+	var smallest *EdgeRing
+	env := coord.NewEnvelope(point.X, point.X, point.Y, point.Y)
+	for _, v := range ri.tree.Query(env) {
+		ring := v.(*EdgeRing)
+		if smallest != nil && ring.Area >= smallest.Area {
+			continue
+		}
+		if coord.PointInRing(point, ring.Coords) == coord.LocationInterior {
+			smallest = ring
+		}
+	}
+	return smallest
+}
+
+// enclosing returns the ring with the smallest area which encloses the
+// hole, or nil if there is none. Rings never cross, so the hole is inside a
+// ring if its first vertex not on the ring is in the ring's interior.
+// Interior points of the hole can't be used, as other rings may lie inside
+// it.
+func (ri *ringIndex) enclosing(hole *EdgeRing) *EdgeRing {
+	env := hole.Coords.Envelope()
+	var smallest *EdgeRing
+	for _, v := range ri.tree.Query(env) {
+		ring := v.(*EdgeRing)
+		if smallest != nil && ring.Area >= smallest.Area {
+			continue
+		}
+		ringEnv := ring.Coords.Envelope()
+		if !ringEnv.Contains(env.MinX, env.MinY) || !ringEnv.Contains(env.MaxX, env.MaxY) {
+			continue
+		}
+		for _, c := range hole.Coords {
+			loc := coord.PointInRing(c, ring.Coords)
+			if loc == coord.LocationBoundary {
+				continue
+			}
+			if loc == coord.LocationInterior {
+				smallest = ring
+			}
+			break
+		}
+	}
+	return smallest
+}
+
+// buildPolygons assembles polygons from rings traced with their interior on
+// the left: counter clockwise rings are shells and clockwise rings are holes.
+// Each hole is assigned to the smallest shell containing it.
+func buildPolygons(rings []*EdgeRing) ([]*geom.Geometry, error) {
+	var shells []*EdgeRing
+	var holeRings []*EdgeRing
+	for _, ring := range rings {
+		if ring.Area > 0 {
+			shells = append(shells, ring)
+		} else if ring.Area < 0 {
+			holeRings = append(holeRings, ring)
+		}
+	}
+
+	holes := map[*EdgeRing]coord.MultiLine{}
+	index := newRingIndex(shells)
+	for _, hole := range holeRings {
+		if shell := index.enclosing(hole); shell != nil {
+			holes[shell] = append(holes[shell], hole.Coords)
+		}
+	}
+
+	var polygons []*geom.Geometry
+	for _, shell := range shells {
+		poly, err := geom.NewPolygon(shell.Coords, holes[shell])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		polygons = append(polygons, poly)
+	}
+	return polygons, nil
+}
+
+// interiorPoint computes a point lying strictly in the interior of the area
+// defined by a shell and holes. A horizontal scan line is placed between
+// vertices near the middle of the shell, and the midpoint of the widest
+// interior section along it is chosen. Returns false if the area has no
+// interior.
+func interiorPoint(shell coord.Coordinates, holes []coord.Coordinates) (coord.Coordinate, bool) {
+	if len(shell) < 4 {
+		return coord.Coordinate{}, false
+	}
+	env := shell.Envelope()
+	centreY := (env.MinY + env.MaxY) / 2
+	hiY, loY := env.MaxY, env.MinY
+
+	rings := append([]coord.Coordinates{shell}, holes...)
+	for _, ring := range rings {
+		for _, c := range ring {
+			if c.Y <= centreY {
+				if c.Y > loY {
+					loY = c.Y
+				}
+			} else if c.Y < hiY {
+				hiY = c.Y
+			}
+		}
+	}
+	if hiY <= loY {
+		return coord.Coordinate{}, false
+	}
+	scanY := (hiY + loY) / 2
+
+	var xs []float64
+	for _, ring := range rings {
+		for i := 1; i < len(ring); i++ {
+			p1, p2 := ring[i-1], ring[i]
+			if (p1.Y > scanY) == (p2.Y > scanY) {
+				continue
+			}
+			xs = append(xs, p1.X+(scanY-p1.Y)*(p2.X-p1.X)/(p2.Y-p1.Y))
+		}
+	}
+	sort.Float64s(xs)
+
+	best := -1.0
+	var point coord.Coordinate
+	for i := 1; i < len(xs); i += 2 {
+		if width := xs[i] - xs[i-1]; width > best {
+			best = width
+			point = coord.Coordinate{X: (xs[i] + xs[i-1]) / 2, Y: scanY}
+		}
+	}
+	return point, best > 0
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// rect returns the ring of a rectangle, counter clockwise for a shell or
+// clockwise for a hole.
+func rect(minX, minY, maxX, maxY float64, ccw bool) *EdgeRing {
+	coords := line(minX, minY, maxX, minY, maxX, maxY, minX, maxY, minX, minY)
+	if !ccw {
+		coords = line(minX, minY, minX, maxY, maxX, maxY, maxX, minY, minX, minY)
+	}
+	return &EdgeRing{Coords: coords, Area: coord.SignedArea(coords)}
+}
+
+func TestRingIndexContaining(t *testing.T) {
+	outer, middle, inner := rect(0, 0, 10, 10, true), rect(2, 2, 8, 8, true), rect(4, 4, 6, 6, true)
+	apart := rect(20, 20, 30, 30, true)
+	index := newRingIndex([]*EdgeRing{apart, inner, outer, middle})
+	tests := []struct {
+		name  string
+		point coord.Coordinate
+		want  *EdgeRing
+	}{
+		{"innermost", coord.Coordinate{X: 5, Y: 5}, inner},
+		{"middle", coord.Coordinate{X: 3, Y: 3}, middle},
+		{"outer", coord.Coordinate{X: 1, Y: 9}, outer},
+		{"on the middle ring", coord.Coordinate{X: 2, Y: 5}, outer},
+		{"apart", coord.Coordinate{X: 25, Y: 25}, apart},
+		{"outside", coord.Coordinate{X: 15, Y: 15}, nil},
+		{"on the outer ring", coord.Coordinate{X: 10, Y: 0}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := index.containing(test.point); got != test.want {
+				t.Errorf("containing(%v) = %v, want %v", test.point, got, test.want)
+			}
+		})
+	}
+
+	if got := newRingIndex(nil).containing(coord.Coordinate{}); got != nil {
+		t.Errorf("containing() in an empty index = %v", got)
+	}
+}
+
+func TestRingIndexContainingMany(t *testing.T) {
+	// the smallest ring found through the index is the smallest found by
+	// testing every ring
+	r := rand.New(rand.NewSource(1))
+	var rings []*EdgeRing
+	for i := 0; i < 500; i++ {
+		x, y, size := r.Float64()*100, r.Float64()*100, 1+r.Float64()*20
+		rings = append(rings, rect(x, y, x+size, y+size, true))
+	}
+	index := newRingIndex(rings)
+	for i := 0; i < 1000; i++ {
+		p := coord.Coordinate{X: r.Float64() * 120, Y: r.Float64() * 120}
+		var want *EdgeRing
+		for _, ring := range rings {
+			if coord.PointInRing(p, ring.Coords) == coord.LocationInterior && (want == nil || ring.Area < want.Area) {
+				want = ring
+			}
+		}
+		if got := index.containing(p); got != want {
+			t.Fatalf("containing(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestRingIndexEnclosing(t *testing.T) {
+	outer, middle := rect(0, 0, 10, 10, true), rect(2, 2, 8, 8, true)
+	index := newRingIndex([]*EdgeRing{outer, middle})
+	tests := []struct {
+		name string
+		hole *EdgeRing
+		want *EdgeRing
+	}{
+		{"inside both", rect(3, 3, 7, 7, false), middle},
+		{"between", rect(0.5, 0.5, 1.5, 9.5, false), outer},
+		{"touching at a vertex", &EdgeRing{Coords: line(0, 0, 1, 1.5, 1.5, 1, 0, 0)}, outer},
+		{"sharing an edge", rect(2, 2, 5, 5, false), middle},
+		{"around the middle ring", rect(1, 1, 9, 9, false), outer},
+		{"crossing out", rect(5, 5, 15, 15, false), nil},
+		{"outside", rect(20, 20, 30, 30, false), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := index.enclosing(test.hole); got != test.want {
+				t.Errorf("enclosing() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildPolygonsNested(t *testing.T) {
+	// an island with a pond in a lake: each hole belongs to the smallest
+	// shell around it, and a hole outside every shell is dropped
+	rings := []*EdgeRing{
+		rect(4.5, 4.5, 5.5, 5.5, false),
+		rect(0, 0, 10, 10, true),
+		rect(4, 4, 6, 6, true),
+		rect(20, 20, 30, 30, false),
+		rect(2, 2, 8, 8, false),
+	}
+	polygons, err := buildPolygons(rings)
+	if err != nil {
+		t.Fatalf("buildPolygons() error = %v", err)
+	}
+	if len(polygons) != 2 {
+		t.Fatalf("buildPolygons() = %d polygons, want 2", len(polygons))
+	}
+	for i, want := range []struct {
+		shell, hole *EdgeRing
+	}{
+		{rings[1], rings[4]},
+		{rings[2], rings[0]},
+	} {
+		p := polygons[i]
+		if !equalCoords(p.Line, want.shell.Coords) || len(p.MultiLine) != 1 || !equalCoords(p.MultiLine[0], want.hole.Coords) {
+			t.Errorf("polygon %d = %v %v, want %v %v", i, p.Line, p.MultiLine, want.shell.Coords, want.hole.Coords)
+		}
+	}
+}
+
+func TestInteriorPoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		shell coord.Coordinates
+		holes []coord.Coordinates
+		ok    bool
+	}{
+		{"square", rect(0, 0, 10, 10, true).Coords, nil, true},
+		{"square with a hole in the middle", rect(0, 0, 10, 10, true).Coords, []coord.Coordinates{rect(2, 2, 8, 8, false).Coords}, true},
+		{"U shape", line(0, 0, 10, 0, 10, 10, 8, 10, 8, 2, 2, 2, 2, 10, 0, 10, 0, 0), nil, true},
+		{"flat", line(0, 0, 10, 0, 5, 0, 0, 0), nil, false},
+		{"too short", line(0, 0, 1, 1, 0, 0), nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, ok := interiorPoint(test.shell, test.holes)
+			if ok != test.ok {
+				t.Fatalf("interiorPoint() = %v, %v, want ok %v", p, ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if coord.PointInRing(p, test.shell) != coord.LocationInterior {
+				t.Errorf("interiorPoint() = %v, not inside the shell", p)
+			}
+			for _, hole := range test.holes {
+				if coord.PointInRing(p, hole) != coord.LocationExterior {
+					t.Errorf("interiorPoint() = %v, not outside the hole", p)
+				}
+			}
+		})
+	}
+}
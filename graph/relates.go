@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/simoncochrane/geoz/coord"
 	"github.com/simoncochrane/geoz/geom"
@@ -37,7 +39,7 @@ func NewIntersectionMatrix() IntersectionMatrix {
 	return im
 }
 
-func (im IntersectionMatrix) SetAll(dimVal int) {
+func (im *IntersectionMatrix) SetAll(dimVal int) {
 	for i := 0; i < len(im); i++ {
 		for j := 0; j < len(im[i]); j++ {
 			im[i][j] = dimVal
@@ -45,10 +47,44 @@ func (im IntersectionMatrix) SetAll(dimVal int) {
 	}
 }
 
-func (im IntersectionMatrix) Set(row, col, dim int) {
+func (im *IntersectionMatrix) Set(row, col, dim int) {
 	im[row][col] = dim
 }
 
+// SetAtLeast raises an entry to a dimension if it is lower.
+func (im *IntersectionMatrix) SetAtLeast(row, col, minDim int) {
+	if im[row][col] < minDim {
+		im[row][col] = minDim
+	}
+}
+
+// SetAtLeastPattern raises the entries to the dimensions of a pattern of 9
+// symbols in row major order, e.g. "212101212". F, T and * are lower than
+// any dimension and leave their entries unchanged.
+func (im *IntersectionMatrix) SetAtLeastPattern(pattern string) {
+	for i := 0; i < len(pattern) && i < 9; i++ {
+		if c := pattern[i]; c >= '0' && c <= '2' {
+			im.SetAtLeast(i/3, i%3, int(c-'0'))
+		}
+	}
+}
+
+// String returns the dimensions of the matrix in row major order, with F
+// for DimensionFalse, e.g. "212101212".
+func (im IntersectionMatrix) String() string {
+	var b strings.Builder
+	for _, row := range im {
+		for _, dim := range row {
+			if dim == DimensionFalse {
+				b.WriteByte('F')
+			} else {
+				b.WriteByte(byte('0' + dim))
+			}
+		}
+	}
+	return b.String()
+}
+
 func NewRelate(a, b *geom.Geometry) (*Relate, error) {
 	ga, err := NewGraph(a, 0, true)
 	if err != nil {
@@ -64,16 +100,29 @@ func NewRelate(a, b *geom.Geometry) (*Relate, error) {
 		graphs: [2]*Graph{
 			ga, gb,
 		},
+		nodes:           map[coord.Coordinate]*RelateNode{},
 		lineIntersector: coord.NewRobustLineIntersector(),
+		pointLocator:    NewPointLocator(),
 	}, nil
 }
 
+// Graphs returns the graphs of the two geometries.
+func (r *Relate) Graphs() [2]*Graph {
+	return r.graphs
+}
+
+// Nodes returns the nodes computed by IntersectionMatrix, ordered by X and
+// then Y.
+func (r *Relate) Nodes() []*Node {
+	return sortedNodes(r.nodes)
+}
+
 func (r *Relate) IntersectionMatrix() IntersectionMatrix {
 	im := NewIntersectionMatrix()
 	im.Set(coord.LocationExterior, coord.LocationExterior, 2)
 
 	if !r.graphs[0].geometry.Envelope().Intersects(r.graphs[1].geometry.Envelope()) {
-		r.computeDisjointIM(im)
+		r.computeDisjointIM(&im)
 		return im
 	}
 
@@ -94,11 +143,10 @@ func (r *Relate) IntersectionMatrix() IntersectionMatrix {
 	// complete the labelling for any nodes which only have a label for a single geometry
 	r.labelIsolatedNodes()
 
-	/*
-
-	    // If a proper intersection was found, we can set a lower bound on the IM.
-	    computeProperIntersectionIM(intersector, im);
+	// If a proper intersection was found, we can set a lower bound on the IM.
+	r.computeProperIntersectionIM(intersector, &im)
 
+	/*
 	    /**
 	     * Now process improper intersections
 	     * (eg where one or other of the geometries has a vertex at the intersection point)
@@ -130,9 +178,10 @@ func (r *Relate) IntersectionMatrix() IntersectionMatrix {
 	    updateIM(im);
 	    return im;
 	*/
+	return im
 }
 
-func (r *Relate) computeDisjointIM(im IntersectionMatrix) {
+func (r *Relate) computeDisjointIM(im *IntersectionMatrix) {
 	if !r.graphs[0].geometry.IsEmpty() {
 		im.Set(coord.LocationInterior, coord.LocationExterior, r.graphs[0].geometry.Dimension())
 		im.Set(coord.LocationBoundary, coord.LocationExterior, r.graphs[0].geometry.BoundaryDimension())
@@ -143,6 +192,43 @@ func (r *Relate) computeDisjointIM(im IntersectionMatrix) {
 	}
 }
 
+// computeProperIntersectionIM sets a lower bound on the IM from the proper
+// intersections found between the edges of the geometries. A proper
+// intersection of two areas means their interiors, boundaries and
+// exteriors all intersect; for an area and a line it means the line
+// crosses the area's boundary.
+func (r *Relate) computeProperIntersectionIM(intersector *SegmentIntersector, im *IntersectionMatrix) {
+	dimA := r.graphs[0].geometry.Dimension()
+	dimB := r.graphs[1].geometry.Dimension()
+	hasProper := intersector.hasProper
+	hasProperInterior := intersector.hasProperInterior
+
+	switch {
+	case dimA == 2 && dimB == 2:
+		if hasProper {
+			im.SetAtLeastPattern("212101212")
+		}
+	case dimA == 2 && dimB == 1:
+		if hasProper {
+			im.SetAtLeastPattern("FFF0FFFF2")
+		}
+		if hasProperInterior {
+			im.SetAtLeastPattern("1FFFFF1FF")
+		}
+	case dimA == 1 && dimB == 2:
+		if hasProper {
+			im.SetAtLeastPattern("F0FFFFFF2")
+		}
+		if hasProperInterior {
+			im.SetAtLeastPattern("1F1FFFFFF")
+		}
+	case dimA == 1 && dimB == 1:
+		if hasProperInterior {
+			im.SetAtLeastPattern("0FFFFFFFF")
+		}
+	}
+}
+
 func (r *Relate) computeIntersectionNodes(argIndex int) {
 	for _, edge := range r.graphs[argIndex].edges {
 		edgeLoc := edge.Label.LocationAt(argIndex, PositionOn)
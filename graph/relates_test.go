@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func mustUnmarshal(t testing.TB, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestNewGraphNodes(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"POINT (1 2)", []string{"(1 2) A:i"}},
+		{"LINESTRING (0 0, 1 1, 2 0)", []string{"(0 0) A:b", "(2 0) A:b"}},
+		{"MULTIPOINT ((1 2), (0 0))", []string{"(0 0) A:i", "(1 2) A:i"}},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			g, err := NewGraph(mustUnmarshal(t, test.text), 0, true)
+			if err != nil {
+				t.Fatalf("NewGraph() error = %v", err)
+			}
+			if got := nodeStrings(g.Nodes()); !equalStrings(got, test.want) {
+				t.Errorf("Nodes() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRelateNodes(t *testing.T) {
+	r, err := NewRelate(
+		mustUnmarshal(t, "POINT (1 1)"),
+		mustUnmarshal(t, "LINESTRING (0 0, 2 2)"))
+	if err != nil {
+		t.Fatalf("NewRelate() error = %v", err)
+	}
+	r.IntersectionMatrix()
+	// the point is on the interior of the line, whose end points are in the
+	// exterior of the point
+	want := []string{"(0 0) A:eee B:b", "(1 1) A:i B:iii", "(2 2) A:eee B:b"}
+	if got := nodeStrings(r.Nodes()); !equalStrings(got, want) {
+		t.Errorf("Nodes() = %v, want %v", got, want)
+	}
+}
+
+func TestRelateNodesInPolygonHoles(t *testing.T) {
+	polygon := "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2))"
+	tests := []struct {
+		point string
+		want  string
+	}{
+		{"POINT (3 3)", "A:i B:eee"},
+		{"POINT (2 3)", "A:i B:bbb"},
+		{"POINT (4 4)", "A:i B:bbb"},
+		{"POINT (5 5)", "A:i B:iii"},
+	}
+	for _, test := range tests {
+		t.Run(test.point, func(t *testing.T) {
+			point := mustUnmarshal(t, test.point)
+			r, err := NewRelate(point, mustUnmarshal(t, polygon))
+			if err != nil {
+				t.Fatalf("NewRelate() error = %v", err)
+			}
+			r.IntersectionMatrix()
+			for _, n := range r.Nodes() {
+				if n.Point.Equals2D(point.Coord) {
+					if got := n.Label.String(); got != test.want {
+						t.Errorf("node label = %s, want %s", got, test.want)
+					}
+					return
+				}
+			}
+			t.Errorf("no node at %v in %v", point.Coord, nodeStrings(r.Nodes()))
+		})
+	}
+}
+
+func TestIntersectionMatrixSetters(t *testing.T) {
+	im := NewIntersectionMatrix()
+	if got, want := im.String(), "FFFFFFFFF"; got != want {
+		t.Errorf("NewIntersectionMatrix() = %s, want %s", got, want)
+	}
+	im.Set(0, 1, 1)
+	im.SetAtLeast(0, 1, 0)
+	im.SetAtLeast(2, 2, 2)
+	if got, want := im.String(), "F1FFFFFF2"; got != want {
+		t.Errorf("after Set and SetAtLeast = %s, want %s", got, want)
+	}
+	im.SetAtLeastPattern("0T*1F0F02")
+	if got, want := im.String(), "01F1F0F02"; got != want {
+		t.Errorf("after SetAtLeastPattern = %s, want %s", got, want)
+	}
+	im.SetAll(2)
+	if got, want := im.String(), "222222222"; got != want {
+		t.Errorf("after SetAll = %s, want %s", got, want)
+	}
+}
+
+func TestIntersectionMatrix(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		// want is the exact matrix for disjoint envelopes, and otherwise a
+		// lower bound set by proper intersections
+		want  string
+		exact bool
+	}{
+		{"disjoint point and line", "POINT (0 0)", "LINESTRING (5 5, 6 6)", "FF0FFF102", true},
+		{"disjoint polygons", "POLYGON ((0 0, 1 0, 1 1, 0 0))", "POLYGON ((5 5, 6 5, 6 6, 5 5))", "FF2FF1212", true},
+		{"crossing lines", "LINESTRING (0 0, 2 2)", "LINESTRING (0 2, 2 0)", "0FFFFFFF2", false},
+		{"overlapping polygons", "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", "POLYGON ((1 1, 3 1, 3 3, 1 3, 1 1))", "212101212", false},
+		{"line across a polygon", "LINESTRING (-1 1, 3 1)", "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", "101FFFFF2", false},
+		{"polygon across a line", "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", "LINESTRING (-1 1, 3 1)", "1FF0FF1F2", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewRelate(mustUnmarshal(t, test.a), mustUnmarshal(t, test.b))
+			if err != nil {
+				t.Fatalf("NewRelate() error = %v", err)
+			}
+			im := r.IntersectionMatrix()
+			if test.exact && im.String() != test.want {
+				t.Errorf("IntersectionMatrix() = %s, want %s", im, test.want)
+			}
+			var atLeast IntersectionMatrix
+			atLeast.SetAll(DimensionFalse)
+			atLeast.SetAtLeastPattern(test.want)
+			for i := range im {
+				for j := range im[i] {
+					if im[i][j] < atLeast[i][j] {
+						t.Errorf("IntersectionMatrix() = %s, want at least %s", im, test.want)
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIntersectionMatrixNoProperIntersection(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"lines touching at end points", "LINESTRING (0 0, 2 2)", "LINESTRING (2 2, 4 0)"},
+		{"line ending on a line", "LINESTRING (0 0, 2 2)", "LINESTRING (2 2, 4 4, 6 2)"},
+		{"polygons sharing an edge", "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", "POLYGON ((2 0, 4 0, 4 2, 2 2, 2 0))"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewRelate(mustUnmarshal(t, test.a), mustUnmarshal(t, test.b))
+			if err != nil {
+				t.Fatalf("NewRelate() error = %v", err)
+			}
+			// only proper intersections set the interior entries so far
+			if im := r.IntersectionMatrix(); im[0][0] != DimensionFalse {
+				t.Errorf("IntersectionMatrix() = %s, want interiors disjoint", im)
+			}
+		})
+	}
+}
+
+func nodeStrings(nodes []*Node) []string {
+	var s []string
+	for _, n := range nodes {
+		text, _ := wkt.Marshal(&geom.Geometry{Type: geom.TypePoint, Coord: n.Point})
+		s = append(s, text[len("POINT "):]+" "+n.Label.String())
+	}
+	return s
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
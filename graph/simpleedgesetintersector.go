@@ -0,0 +1,59 @@
+package graph
+
+// SimpleEdgeSetIntersector tests every pair of segments. It has no set up
+// cost, which makes it the fastest strategy for a handful of segments.
+type SimpleEdgeSetIntersector struct{}
+
+func NewSimpleEdgeSetIntersector() *SimpleEdgeSetIntersector {
+	return &SimpleEdgeSetIntersector{}
+}
+
+func (sei *SimpleEdgeSetIntersector) ComputeSelfIntersections(edges []*Edge,
+	si *SegmentIntersector, computeAllSegments bool) error {
+
+	// test each pair of edges once
+	for i, e0 := range edges {
+		if computeAllSegments {
+			computeSelfSegmentIntersections(e0, si)
+		}
+		for _, e1 := range edges[i+1:] {
+			if si.Done() {
+				return nil
+			}
+			computeSegmentIntersections(e0, e1, si)
+		}
+	}
+	return nil
+}
+
+func (sei *SimpleEdgeSetIntersector) ComputeEdgeIntersections(edges0, edges1 []*Edge, si *SegmentIntersector) error {
+	for _, e0 := range edges0 {
+		for _, e1 := range edges1 {
+			computeSegmentIntersections(e0, e1, si)
+			if si.Done() {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// computeSegmentIntersections intersects every segment of e0 with every
+// segment of e1.
+func computeSegmentIntersections(e0, e1 *Edge, si *SegmentIntersector) {
+	for i := 0; i < len(e0.Coordinates)-1; i++ {
+		for j := 0; j < len(e1.Coordinates)-1; j++ {
+			si.AddIntersections(e0, i, e1, j)
+		}
+	}
+}
+
+// computeSelfSegmentIntersections intersects each pair of segments of an
+// edge once, including each segment with itself.
+func computeSelfSegmentIntersections(e *Edge, si *SegmentIntersector) {
+	for i := 0; i < len(e.Coordinates)-1; i++ {
+		for j := i; j < len(e.Coordinates)-1; j++ {
+			si.AddIntersections(e, i, e, j)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package chain indexes the segments of coordinate sequences as monotone
+// chains: runs of segments which all point into the same quadrant. The
+// envelope of any part of a monotone chain is the envelope of its end
+// points, which makes finding the segments within an envelope or the pairs
+// of segments of two chains which may intersect fast.
+package chain
+
+import (
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/index/strtree"
+)
+
+// Chain is a monotone chain of the segments of Points from Start to End.
+type Chain struct {
+	Points     coord.Coordinates
+	Start, End int
+	// Value is the value given when the chain was created, such as the
+	// geometry or edge the points belong to.
+	Value interface{}
+
+	env coord.Envelope
+}
+
+// Segment is the segment of a chain starting at a point of the chain.
+type Segment struct {
+	Chain *Chain
+	Index int
+}
+
+// Coordinates returns the end points of the segment.
+func (s Segment) Coordinates() (coord.Coordinate, coord.Coordinate) {
+	return s.Chain.Points[s.Index], s.Chain.Points[s.Index+1]
+}
+
+// Chains splits a coordinate sequence into monotone chains. Repeated points
+// are included in the chains around them.
+func Chains(pts coord.Coordinates, value interface{}) []*Chain {
+	var chains []*Chain
+	start := 0
+	for start < len(pts)-1 {
+		end := chainEnd(pts, start)
+		c := &Chain{Points: pts, Start: start, End: end, Value: value}
+		c.env = *coord.NewEnvelopeFromCoords(pts[start], pts[end])
+		chains = append(chains, c)
+		start = end
+	}
+	return chains
+}
+
+// chainEnd returns the index of the last point of the chain starting at
+// start.
+func chainEnd(pts coord.Coordinates, start int) int {
+	// skip repeated points to find the quadrant of the chain
+	first := start
+	for first < len(pts)-1 && pts[first].Equals2D(pts[first+1]) {
+		first++
+	}
+	if first >= len(pts)-1 {
+		return len(pts) - 1
+	}
+	chainQuad := quadrant(pts[first], pts[first+1])
+	last := first + 1
+	for last < len(pts)-1 {
+		// repeated points do not change the quadrant
+		if !pts[last].Equals2D(pts[last+1]) && quadrant(pts[last], pts[last+1]) != chainQuad {
+			break
+		}
+		last++
+	}
+	return last
+}
+
+// quadrant returns the quadrant, numbered anticlockwise from the north east,
+// of the direction from p0 to p1, which must differ.
+func quadrant(p0, p1 coord.Coordinate) int {
+	dx, dy := p1.X-p0.X, p1.Y-p0.Y
+	switch {
+	case dx >= 0 && dy >= 0:
+		return 0
+	case dx < 0 && dy >= 0:
+		return 1
+	case dx < 0:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Envelope returns the envelope of the chain.
+func (c *Chain) Envelope() *coord.Envelope {
+	return &c.env
+}
+
+// Select calls visit with each segment of the chain whose envelope
+// intersects env, until visit returns false. It returns false if visiting
+// was stopped.
+func (c *Chain) Select(env *coord.Envelope, visit func(s Segment) bool) bool {
+	return c.selectIn(env, c.Start, c.End, visit)
+}
+
+func (c *Chain) selectIn(env *coord.Envelope, start, end int, visit func(s Segment) bool) bool {
+	if !envelopeIntersects(c.Points[start], c.Points[end], env) {
+		return true
+	}
+	if end-start == 1 {
+		return visit(Segment{c, start})
+	}
+	mid := (start + end) / 2
+	return c.selectIn(env, start, mid, visit) && c.selectIn(env, mid, end, visit)
+}
+
+// Overlaps calls visit with each pair of segments of this chain and another
+// whose envelopes intersect, until visit returns false. It returns false if
+// visiting was stopped.
+func (c *Chain) Overlaps(other *Chain, visit func(a, b Segment) bool) bool {
+	return c.overlaps(c.Start, c.End, other, other.Start, other.End, visit)
+}
+
+func (c *Chain) overlaps(start0, end0 int, other *Chain, start1, end1 int, visit func(a, b Segment) bool) bool {
+	if !coord.EnvelopeIntersects(c.Points[start0], c.Points[end0], other.Points[start1], other.Points[end1]) {
+		return true
+	}
+	if end0-start0 == 1 && end1-start1 == 1 {
+		return visit(Segment{c, start0}, Segment{other, start1})
+	}
+
+	// split the longer of the chains in half and recurse
+	if end0-start0 >= end1-start1 {
+		mid := (start0 + end0) / 2
+		return c.overlaps(start0, mid, other, start1, end1, visit) &&
+			c.overlaps(mid, end0, other, start1, end1, visit)
+	}
+	mid := (start1 + end1) / 2
+	return c.overlaps(start0, end0, other, start1, mid, visit) &&
+		c.overlaps(start0, end0, other, mid, end1, visit)
+}
+
+func envelopeIntersects(p1, p2 coord.Coordinate, env *coord.Envelope) bool {
+	return !(env.MinX > p1.X && env.MinX > p2.X ||
+		env.MaxX < p1.X && env.MaxX < p2.X ||
+		env.MinY > p1.Y && env.MinY > p2.Y ||
+		env.MaxY < p1.Y && env.MaxY < p2.Y)
+}
+
+// Index is a spatial index of monotone chains.
+type Index struct {
+	chains []*Chain
+	tree   *strtree.Tree
+}
+
+// NewIndex indexes the chains of coordinate sequences, such as the lines
+// and rings of geometries. The value of the chains of each sequence is its
+// index in seqs.
+func NewIndex(seqs []coord.Coordinates) *Index {
+	var chains []*Chain
+	for i, pts := range seqs {
+		chains = append(chains, Chains(pts, i)...)
+	}
+	return NewChainIndex(chains)
+}
+
+// NewChainIndex indexes chains.
+func NewChainIndex(chains []*Chain) *Index {
+	items := make([]strtree.Item, len(chains))
+	for i, c := range chains {
+		items[i] = strtree.Item{Envelope: c.env, Value: i}
+	}
+	return &Index{chains: chains, tree: strtree.New(items)}
+}
+
+// Chains returns the indexed chains.
+func (idx *Index) Chains() []*Chain {
+	return idx.chains
+}
+
+// Query calls visit with each segment whose envelope intersects env, until
+// visit returns false. It returns false if the query was stopped.
+func (idx *Index) Query(env *coord.Envelope, visit func(s Segment) bool) bool {
+	return idx.tree.Visit(env, func(item *strtree.Item) bool {
+		return idx.chains[item.Value.(int)].Select(env, visit)
+	})
+}
+
+// Overlaps calls visit with each pair of a segment of this index and a
+// segment of another whose envelopes intersect, until visit returns false.
+// It returns false if visiting was stopped.
+func (idx *Index) Overlaps(other *Index, visit func(a, b Segment) bool) bool {
+	for _, c := range idx.chains {
+		ok := other.tree.Visit(&c.env, func(item *strtree.Item) bool {
+			return c.Overlaps(other.chains[item.Value.(int)], visit)
+		})
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SelfOverlaps calls visit once with each pair of distinct segments of the
+// index whose envelopes intersect, until visit returns false. Adjacent
+// segments of a sequence always overlap at their shared point. It returns
+// false if visiting was stopped.
+func (idx *Index) SelfOverlaps(visit func(a, b Segment) bool) bool {
+	for i, c := range idx.chains {
+		ok := idx.tree.Visit(&c.env, func(item *strtree.Item) bool {
+			j := item.Value.(int)
+			if j < i {
+				return true
+			}
+			other := idx.chains[j]
+			if j > i {
+				return c.Overlaps(other, visit)
+			}
+			// pair the segments of a chain with the later ones only
+			return c.Overlaps(c, func(a, b Segment) bool {
+				if a.Index >= b.Index {
+					return true
+				}
+				return visit(a, b)
+			})
+		})
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
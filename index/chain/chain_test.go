@@ -0,0 +1,289 @@
+package chain
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+func xys(xys ...float64) coord.Coordinates {
+	var cs coord.Coordinates
+	for i := 0; i < len(xys); i += 2 {
+		cs = append(cs, coord.Coordinate{X: xys[i], Y: xys[i+1]})
+	}
+	return cs
+}
+
+func TestChains(t *testing.T) {
+	tests := []struct {
+		name string
+		pts  coord.Coordinates
+		want [][2]int
+	}{
+		{"empty", nil, nil},
+		{"point", xys(1, 1), nil},
+		{"segment", xys(0, 0, 1, 1), [][2]int{{0, 1}}},
+		{"monotone", xys(0, 0, 1, 2, 3, 3, 4, 5), [][2]int{{0, 3}}},
+		{"zigzag", xys(0, 0, 1, 1, 2, 0, 3, 1, 4, 0), [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}}},
+		{"turns at the top", xys(0, 0, 1, 2, 2, 3, 3, 2, 4, 0), [][2]int{{0, 2}, {2, 4}}},
+		{"square", xys(0, 0, 2, 0, 2, 2, 0, 2, 0, 0), [][2]int{{0, 2}, {2, 3}, {3, 4}}},
+		{"horizontal then vertical", xys(0, 0, 1, 0, 2, 0, 2, 1, 2, 2), [][2]int{{0, 4}}},
+		{"repeated point inside", xys(0, 0, 1, 1, 1, 1, 2, 2), [][2]int{{0, 3}}},
+		{"repeated point at a turn", xys(0, 0, 1, 1, 1, 1, 2, 0), [][2]int{{0, 2}, {2, 3}}},
+		{"repeated start", xys(0, 0, 0, 0, 1, 1, 2, 0), [][2]int{{0, 2}, {2, 3}}},
+		{"repeated end", xys(0, 0, 1, 1, 2, 0, 2, 0), [][2]int{{0, 1}, {1, 3}}},
+		{"all repeated", xys(1, 1, 1, 1, 1, 1), [][2]int{{0, 2}}},
+		{"all quadrants", xys(0, 0, 1, 1, 0, 2, -1, 1, 0, 0), [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][2]int
+			for _, c := range Chains(test.pts, "value") {
+				got = append(got, [2]int{c.Start, c.End})
+				if c.Value != "value" {
+					t.Errorf("chain value = %v, want value", c.Value)
+				}
+				if env := coord.NewEnvelopeFromCoords(test.pts[c.Start], test.pts[c.End]); *c.Envelope() != *env {
+					t.Errorf("chain %d-%d envelope = %v, want %v", c.Start, c.End, *c.Envelope(), *env)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Chains() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// randomWalk returns n points of a walk on an integer grid which often
+// repeats points and continues in the same direction.
+func randomWalk(n int, r *rand.Rand) coord.Coordinates {
+	pts := make(coord.Coordinates, n)
+	dx, dy := 1.0, 1.0
+	for i := 1; i < n; i++ {
+		if r.Intn(3) == 0 {
+			dx, dy = float64(r.Intn(5)-2), float64(r.Intn(5)-2)
+		}
+		pts[i] = coord.Coordinate{X: pts[i-1].X + dx, Y: pts[i-1].Y + dy}
+	}
+	return pts
+}
+
+func TestChainsMonotone(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		pts := randomWalk(2+r.Intn(50), r)
+		chains := Chains(pts, nil)
+		if chains[0].Start != 0 || chains[len(chains)-1].End != len(pts)-1 {
+			t.Fatalf("%v: chains do not span the points", pts)
+		}
+		quads := make([]int, len(chains))
+		for j, c := range chains {
+			if j > 0 && c.Start != chains[j-1].End {
+				t.Fatalf("%v: chain %d starts at %d, the previous ends at %d", pts, j, c.Start, chains[j-1].End)
+			}
+			quads[j] = -1
+			for k := c.Start; k < c.End; k++ {
+				if pts[k].Equals2D(pts[k+1]) {
+					continue
+				}
+				q := quadrant(pts[k], pts[k+1])
+				if quads[j] == -1 {
+					quads[j] = q
+				} else if q != quads[j] {
+					t.Fatalf("%v: chain %d-%d is not monotone", pts, c.Start, c.End)
+				}
+			}
+			// a chain only ends where the quadrant changes
+			if j > 0 && quads[j] == quads[j-1] {
+				t.Fatalf("%v: chains %d and %d are in the same quadrant", pts, j-1, j)
+			}
+		}
+	}
+}
+
+// segmentKey identifies a segment by the index of its sequence and its
+// index in the sequence.
+type segmentKey struct {
+	seq, index int
+}
+
+func keyOf(s Segment) segmentKey {
+	return segmentKey{s.Chain.Value.(int), s.Index}
+}
+
+type segmentPair [2]segmentKey
+
+func sortPairs(pairs []segmentPair) []segmentPair {
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		for k := range a {
+			if a[k] != b[k] {
+				if a[k].seq != b[k].seq {
+					return a[k].seq < b[k].seq
+				}
+				return a[k].index < b[k].index
+			}
+		}
+		return false
+	})
+	return pairs
+}
+
+func lessKey(a, b segmentKey) bool {
+	return a.seq < b.seq || a.seq == b.seq && a.index < b.index
+}
+
+func segmentEnvelope(pts coord.Coordinates, i int) *coord.Envelope {
+	return coord.NewEnvelopeFromCoords(pts[i], pts[i+1])
+}
+
+func randomSeqs(n int, r *rand.Rand) []coord.Coordinates {
+	seqs := make([]coord.Coordinates, n)
+	for i := range seqs {
+		seqs[i] = randomWalk(2+r.Intn(40), r)
+		offset := coord.Coordinate{X: float64(r.Intn(40) - 20), Y: float64(r.Intn(40) - 20)}
+		for j := range seqs[i] {
+			seqs[i][j].X += offset.X
+			seqs[i][j].Y += offset.Y
+		}
+	}
+	return seqs
+}
+
+func TestSelectAndQuery(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		seqs := randomSeqs(1+r.Intn(10), r)
+		idx := NewIndex(seqs)
+		for j := 0; j < 50; j++ {
+			x, y := float64(r.Intn(60)-30), float64(r.Intn(60)-30)
+			env := coord.NewEnvelope(x, x+float64(r.Intn(10)), y, y+float64(r.Intn(10)))
+
+			var want []segmentKey
+			for s, pts := range seqs {
+				for k := 0; k < len(pts)-1; k++ {
+					if segmentEnvelope(pts, k).Intersects(env) {
+						want = append(want, segmentKey{s, k})
+					}
+				}
+			}
+
+			var selected []segmentKey
+			for _, c := range idx.Chains() {
+				c.Select(env, func(s Segment) bool {
+					selected = append(selected, keyOf(s))
+					return true
+				})
+			}
+			var queried []segmentKey
+			idx.Query(env, func(s Segment) bool {
+				queried = append(queried, keyOf(s))
+				return true
+			})
+			for _, got := range [][]segmentKey{selected, queried} {
+				sort.Slice(got, func(a, b int) bool { return lessKey(got[a], got[b]) })
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("query %v found %v, want %v", env, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		seqs0, seqs1 := randomSeqs(1+r.Intn(5), r), randomSeqs(1+r.Intn(5), r)
+
+		var want []segmentPair
+		for s0, pts0 := range seqs0 {
+			for k0 := 0; k0 < len(pts0)-1; k0++ {
+				for s1, pts1 := range seqs1 {
+					for k1 := 0; k1 < len(pts1)-1; k1++ {
+						if segmentEnvelope(pts0, k0).Intersects(segmentEnvelope(pts1, k1)) {
+							want = append(want, segmentPair{{s0, k0}, {s1, k1}})
+						}
+					}
+				}
+			}
+		}
+
+		var got []segmentPair
+		NewIndex(seqs0).Overlaps(NewIndex(seqs1), func(a, b Segment) bool {
+			got = append(got, segmentPair{keyOf(a), keyOf(b)})
+			return true
+		})
+		if !reflect.DeepEqual(sortPairs(got), sortPairs(want)) {
+			t.Fatalf("Overlaps() found %d pairs, want %d: %v, want %v", len(got), len(want), got, want)
+		}
+	}
+}
+
+func TestSelfOverlaps(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 50; i++ {
+		seqs := randomSeqs(1+r.Intn(5), r)
+
+		var segments []segmentKey
+		for s, pts := range seqs {
+			for k := 0; k < len(pts)-1; k++ {
+				segments = append(segments, segmentKey{s, k})
+			}
+		}
+		var want []segmentPair
+		for a, ka := range segments {
+			for _, kb := range segments[a+1:] {
+				if segmentEnvelope(seqs[ka.seq], ka.index).Intersects(segmentEnvelope(seqs[kb.seq], kb.index)) {
+					want = append(want, segmentPair{ka, kb})
+				}
+			}
+		}
+
+		var got []segmentPair
+		NewIndex(seqs).SelfOverlaps(func(a, b Segment) bool {
+			ka, kb := keyOf(a), keyOf(b)
+			if ka == kb {
+				t.Fatalf("SelfOverlaps() paired %v with itself", ka)
+			}
+			if lessKey(kb, ka) {
+				ka, kb = kb, ka
+			}
+			got = append(got, segmentPair{ka, kb})
+			return true
+		})
+		if !reflect.DeepEqual(sortPairs(got), sortPairs(want)) {
+			t.Fatalf("SelfOverlaps() found %d pairs, want %d", len(got), len(want))
+		}
+	}
+}
+
+func TestOverlapsStops(t *testing.T) {
+	seqs := []coord.Coordinates{xys(0, 0, 10, 0, 10, 10, 0, 10, 0, 0)}
+	idx := NewIndex(seqs)
+	for _, limit := range []int{1, 2, 3} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			count := 0
+			stop := func(a, b Segment) bool {
+				count++
+				return count < limit
+			}
+			if idx.SelfOverlaps(stop) {
+				t.Error("SelfOverlaps() = true after being stopped")
+			}
+			if count != limit {
+				t.Errorf("SelfOverlaps() visited %d pairs after being stopped at %d", count, limit)
+			}
+			count = 0
+			if idx.Overlaps(idx, stop) {
+				t.Error("Overlaps() = true after being stopped")
+			}
+			if count != limit {
+				t.Errorf("Overlaps() visited %d pairs after being stopped at %d", count, limit)
+			}
+		})
+	}
+}
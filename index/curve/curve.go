@@ -0,0 +1,210 @@
+// Package curve orders coordinates and geometries along space-filling
+// curves, which keep points that are close in the plane mostly close along
+// the curve. Ordering data this way clusters it for packed indexes and for
+// storage, and gives a deterministic processing order.
+package curve
+
+import (
+	"sort"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// Order is the number of bits of each axis of a position.
+const Order = 16
+
+const maxAxis = 1<<Order - 1
+
+// Encoder maps coordinates to positions along a curve.
+type Encoder interface {
+	Encode(c coord.Coordinate) uint32
+}
+
+// Hilbert encodes coordinates within an envelope along a Hilbert curve.
+type Hilbert struct {
+	grid
+}
+
+func NewHilbert(env *coord.Envelope) *Hilbert {
+	return &Hilbert{newGrid(env)}
+}
+
+// Encode returns the position of c along the curve. Coordinates outside the
+// envelope are clamped to it.
+func (h *Hilbert) Encode(c coord.Coordinate) uint32 {
+	return HilbertCode(h.cell(c))
+}
+
+// Morton encodes coordinates within an envelope along a Morton, or Z-order,
+// curve. It is cheaper to compute than a Hilbert curve but jumps further
+// between neighbouring positions.
+type Morton struct {
+	grid
+}
+
+func NewMorton(env *coord.Envelope) *Morton {
+	return &Morton{newGrid(env)}
+}
+
+// Encode returns the position of c along the curve. Coordinates outside the
+// envelope are clamped to it.
+func (m *Morton) Encode(c coord.Coordinate) uint32 {
+	return MortonCode(m.cell(c))
+}
+
+// grid maps coordinates within an envelope to a grid of 1<<Order cells
+// along each axis. Positions are scaled as the FlatGeobuf reference
+// implementation does, so that its index order can be reproduced.
+type grid struct {
+	env           coord.Envelope
+	width, height float64
+}
+
+func newGrid(env *coord.Envelope) grid {
+	return grid{env: *env, width: env.MaxX - env.MinX, height: env.MaxY - env.MinY}
+}
+
+func (g *grid) cell(c coord.Coordinate) (uint32, uint32) {
+	return scale(c.X, g.env.MinX, g.width), scale(c.Y, g.env.MinY, g.height)
+}
+
+func scale(v, min, size float64) uint32 {
+	if !(size > 0) {
+		return 0
+	}
+	f := maxAxis * (v - min) / size
+	switch {
+	case !(f > 0):
+		// also catches NaN
+		return 0
+	case f >= maxAxis:
+		return maxAxis
+	}
+	return uint32(f)
+}
+
+// MortonCode returns the position of x, y along a Morton curve of order 16,
+// so x and y must be less than 1<<16. The bits of x and y are interleaved,
+// with those of x in the even positions.
+func MortonCode(x, y uint32) uint32 {
+	return spread(x) | spread(y)<<1
+}
+
+// spread moves the lower 16 bits of v to the even bit positions.
+func spread(v uint32) uint32 {
+	v &= 0xFFFF
+	v = (v | (v << 8)) & 0x00FF00FF
+	v = (v | (v << 4)) & 0x0F0F0F0F
+	v = (v | (v << 2)) & 0x33333333
+	v = (v | (v << 1)) & 0x55555555
+	return v
+}
+
+// HilbertCode returns the position of x, y along a Hilbert curve of order
+// 16, so x and y must be less than 1<<16.
+// See http://threadlocalmutex.com/?p=126.
+func HilbertCode(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
+
+// SortHilbert sorts geometries by the Hilbert positions of the centres of
+// their envelopes within the envelope of them all.
+func SortHilbert(gs []*geom.Geometry) {
+	Sort(gs, func(env *coord.Envelope) Encoder { return NewHilbert(env) })
+}
+
+// SortMorton sorts geometries by the Morton positions of the centres of
+// their envelopes within the envelope of them all.
+func SortMorton(gs []*geom.Geometry) {
+	Sort(gs, func(env *coord.Envelope) Encoder { return NewMorton(env) })
+}
+
+// Sort sorts geometries by the positions of the centres of their envelopes
+// along a curve over the envelope of them all. The sort is stable, and
+// empty geometries come last.
+func Sort(gs []*geom.Geometry, newEncoder func(env *coord.Envelope) Encoder) {
+	var extent *coord.Envelope
+	for _, g := range gs {
+		env := g.Envelope()
+		if env == nil {
+			continue
+		}
+		if extent == nil {
+			extent = &coord.Envelope{}
+			*extent = *env
+		} else {
+			extent.ExpandEnvelope(env)
+		}
+	}
+	if extent == nil {
+		return
+	}
+
+	e := newEncoder(extent)
+	keys := make([]uint64, len(gs))
+	for i, g := range gs {
+		env := g.Envelope()
+		if env == nil {
+			// after any position
+			keys[i] = 1 << 32
+			continue
+		}
+		keys[i] = uint64(e.Encode(coord.Coordinate{X: (env.MinX + env.MaxX) / 2, Y: (env.MinY + env.MaxY) / 2}))
+	}
+	sort.Stable(&sorter{gs, keys})
+}
+
+type sorter struct {
+	gs   []*geom.Geometry
+	keys []uint64
+}
+
+func (s *sorter) Len() int           { return len(s.gs) }
+func (s *sorter) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s *sorter) Swap(i, j int) {
+	s.gs[i], s.gs[j] = s.gs[j], s.gs[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
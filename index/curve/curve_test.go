@@ -0,0 +1,243 @@
+package curve
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestMortonCode(t *testing.T) {
+	tests := []struct {
+		x, y uint32
+		want uint32
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{0, 1, 2},
+		{1, 1, 3},
+		{2, 0, 4},
+		{3, 3, 15},
+		{maxAxis, 0, 0x55555555},
+		{0, maxAxis, 0xAAAAAAAA},
+		{maxAxis, maxAxis, 0xFFFFFFFF},
+		// bits above the order are ignored
+		{1<<Order | 1, 0, 1},
+	}
+	for _, test := range tests {
+		if got := MortonCode(test.x, test.y); got != test.want {
+			t.Errorf("MortonCode(%d, %d) = %#x, want %#x", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestHilbertCode(t *testing.T) {
+	tests := []struct {
+		x, y uint32
+		want uint32
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{1, 1, 2},
+		{0, 1, 3},
+		{0, maxAxis, 0x55555555},
+		{maxAxis, maxAxis, 0xAAAAAAAA},
+		{maxAxis, 0, 0xFFFFFFFF},
+	}
+	for _, test := range tests {
+		if got := HilbertCode(test.x, test.y); got != test.want {
+			t.Errorf("HilbertCode(%d, %d) = %#x, want %#x", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+// TestHilbertCodeBlocks checks that the curve fills each square block at
+// the origin before leaving it, one neighbouring cell at a time.
+func TestHilbertCodeBlocks(t *testing.T) {
+	for k := uint(1); k <= 6; k++ {
+		size := uint32(1) << k
+		cells := make([][2]uint32, size*size)
+		seen := make([]bool, size*size)
+		for x := uint32(0); x < size; x++ {
+			for y := uint32(0); y < size; y++ {
+				code := HilbertCode(x, y)
+				if code >= size*size {
+					t.Fatalf("HilbertCode(%d, %d) = %d, outside the %d by %d block", x, y, code, size, size)
+				}
+				if seen[code] {
+					t.Fatalf("HilbertCode(%d, %d) = %d is repeated", x, y, code)
+				}
+				seen[code] = true
+				cells[code] = [2]uint32{x, y}
+			}
+		}
+		for i := 1; i < len(cells); i++ {
+			if d := manhattan(cells[i-1], cells[i]); d != 1 {
+				t.Fatalf("positions %d and %d are %d cells apart", i-1, i, d)
+			}
+		}
+	}
+}
+
+// TestHilbertCodeNeighbours checks across the whole grid that the next and
+// previous positions of a cell are at neighbouring cells.
+func TestHilbertCodeNeighbours(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		x, y := uint32(r.Intn(maxAxis+1)), uint32(r.Intn(maxAxis+1))
+		code := HilbertCode(x, y)
+		next, prev := code == math.MaxUint32, code == 0
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			nx, ny := int(x)+d[0], int(y)+d[1]
+			if nx < 0 || ny < 0 || nx > maxAxis || ny > maxAxis {
+				continue
+			}
+			switch HilbertCode(uint32(nx), uint32(ny)) {
+			case code + 1:
+				next = true
+			case code - 1:
+				prev = true
+			}
+		}
+		if !next || !prev {
+			t.Fatalf("HilbertCode(%d, %d) = %d: neighbouring positions found after %v, before %v", x, y, code, next, prev)
+		}
+	}
+}
+
+func manhattan(a, b [2]uint32) int {
+	dx, dy := int(a[0])-int(b[0]), int(a[1])-int(b[1])
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
+
+func TestEncoders(t *testing.T) {
+	env := coord.NewEnvelope(10, 20, -5, 5)
+	tests := []struct {
+		name         string
+		c            coord.Coordinate
+		hilbert, mor uint32
+	}{
+		{"min corner", coord.Coordinate{X: 10, Y: -5}, 0, 0},
+		{"max x", coord.Coordinate{X: 20, Y: -5}, 0xFFFFFFFF, 0x55555555},
+		{"max y", coord.Coordinate{X: 10, Y: 5}, 0x55555555, 0xAAAAAAAA},
+		{"max corner", coord.Coordinate{X: 20, Y: 5}, 0xAAAAAAAA, 0xFFFFFFFF},
+		{"clamped below", coord.Coordinate{X: 0, Y: -100}, 0, 0},
+		{"clamped above", coord.Coordinate{X: 100, Y: 100}, 0xAAAAAAAA, 0xFFFFFFFF},
+		{"NaN", coord.Coordinate{X: math.NaN(), Y: math.NaN()}, 0, 0},
+		{"centre", coord.Coordinate{X: 15, Y: 0}, HilbertCode(maxAxis/2, maxAxis/2), MortonCode(maxAxis/2, maxAxis/2)},
+	}
+	h, m := NewHilbert(env), NewMorton(env)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := h.Encode(test.c); got != test.hilbert {
+				t.Errorf("Hilbert Encode(%v) = %#x, want %#x", test.c, got, test.hilbert)
+			}
+			if got := m.Encode(test.c); got != test.mor {
+				t.Errorf("Morton Encode(%v) = %#x, want %#x", test.c, got, test.mor)
+			}
+		})
+	}
+
+	// a degenerate envelope maps its axis to zero
+	flat := NewMorton(coord.NewEnvelope(0, 10, 3, 3))
+	if got, want := flat.Encode(coord.Coordinate{X: 10, Y: 3}), MortonCode(maxAxis, 0); got != want {
+		t.Errorf("Encode() in a flat envelope = %#x, want %#x", got, want)
+	}
+}
+
+func point(t *testing.T, x, y float64) *geom.Geometry {
+	t.Helper()
+	p, err := geom.NewPoint(coord.Coordinate{X: x, Y: y})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSort(t *testing.T) {
+	bottomLeft, bottomRight := point(t, 0, 0), point(t, 10, 0)
+	topLeft, topRight := point(t, 0, 10), point(t, 10, 10)
+	// equal to topLeft, to check the sort is stable
+	topLeft2 := point(t, 0, 10)
+	empty, err := geom.NewLineString(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		sort func([]*geom.Geometry)
+		in   []*geom.Geometry
+		want []*geom.Geometry
+	}{
+		{
+			"hilbert",
+			SortHilbert,
+			[]*geom.Geometry{topRight, empty, topLeft, bottomRight, topLeft2, bottomLeft},
+			[]*geom.Geometry{bottomLeft, topLeft, topLeft2, topRight, bottomRight, empty},
+		},
+		{
+			"morton",
+			SortMorton,
+			[]*geom.Geometry{topRight, empty, topLeft, bottomRight, topLeft2, bottomLeft},
+			[]*geom.Geometry{bottomLeft, bottomRight, topLeft, topLeft2, topRight, empty},
+		},
+		{"only empty", SortHilbert, []*geom.Geometry{empty, empty}, []*geom.Geometry{empty, empty}},
+		{"none", SortMorton, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gs := append([]*geom.Geometry(nil), test.in...)
+			test.sort(gs)
+			if !reflect.DeepEqual(gs, test.want) {
+				t.Errorf("sorted = %v, want %v", gs, test.want)
+			}
+		})
+	}
+}
+
+// TestSortGrid sorts the cells of a grid and checks that consecutive cells
+// are neighbours along the Hilbert curve, and that the Morton order visits
+// each quadrant in turn.
+func TestSortGrid(t *testing.T) {
+	const n = 8
+	var gs []*geom.Geometry
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			gs = append(gs, point(t, float64(x), float64(y)))
+		}
+	}
+	rand.New(rand.NewSource(2)).Shuffle(len(gs), func(i, j int) { gs[i], gs[j] = gs[j], gs[i] })
+
+	SortHilbert(gs)
+	for i := 1; i < len(gs); i++ {
+		a, b := gs[i-1].Coord, gs[i].Coord
+		if d := math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y); d != 1 {
+			t.Fatalf("Hilbert order has %v then %v", a, b)
+		}
+	}
+
+	SortMorton(gs)
+	for i, g := range gs {
+		// each run of n*n/4 cells is one quadrant, in Z order
+		quad := 0
+		if g.Coord.X >= n/2 {
+			quad |= 1
+		}
+		if g.Coord.Y >= n/2 {
+			quad |= 2
+		}
+		if want := i / (n * n / 4); quad != want {
+			t.Fatalf("Morton order has %v at %d, in quadrant %d, want %d", g.Coord, i, quad, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Package intervaltree implements a static R-tree of one-dimensional
+// intervals, packed by sorting the intervals on their centres.
+package intervaltree
+
+import (
+	"sort"
+)
+
+// nodeCapacity is the number of children of each node.
+const nodeCapacity = 8
+
+// Item is a closed interval with an arbitrary value.
+type Item struct {
+	Min, Max float64
+	Value    interface{}
+}
+
+// Tree is a packed interval tree. The zero value is an empty tree.
+type Tree struct {
+	// items are the leaf entries in tree order.
+	items []Item
+	// nodes are stored a level at a time from the leaves up, so the root is
+	// the last node. The children of a leaf node are items, those of any
+	// other node are nodes of the level below.
+	nodes  []node
+	height int
+}
+
+// node is a node of the tree, with its children in [start, end).
+type node struct {
+	min, max   float64
+	start, end int
+}
+
+// New builds a tree from items. The items are copied, so the slice may be
+// reused.
+func New(items []Item) *Tree {
+	t := &Tree{items: make([]Item, len(items))}
+	copy(t.items, items)
+	if len(t.items) == 0 {
+		return t
+	}
+	sort.Slice(t.items, func(i, j int) bool {
+		return t.items[i].Min+t.items[i].Max < t.items[j].Min+t.items[j].Max
+	})
+
+	for i := 0; i < len(t.items); i += nodeCapacity {
+		n := node{min: t.items[i].Min, max: t.items[i].Max, start: i, end: minInt(i+nodeCapacity, len(t.items))}
+		for _, item := range t.items[i+1 : n.end] {
+			n.expand(item.Min, item.Max)
+		}
+		t.nodes = append(t.nodes, n)
+	}
+	t.height = 1
+
+	// the nodes of each level are already in order, so they are grouped
+	// into the level above as they are
+	start := 0
+	for len(t.nodes)-start > 1 {
+		end := len(t.nodes)
+		for i := start; i < end; i += nodeCapacity {
+			n := node{min: t.nodes[i].min, max: t.nodes[i].max, start: i, end: minInt(i+nodeCapacity, end)}
+			for j := i + 1; j < n.end; j++ {
+				n.expand(t.nodes[j].min, t.nodes[j].max)
+			}
+			t.nodes = append(t.nodes, n)
+		}
+		t.height++
+		start = end
+	}
+	return t
+}
+
+func (n *node) expand(min, max float64) {
+	if min < n.min {
+		n.min = min
+	}
+	if max > n.max {
+		n.max = max
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Len returns the number of items in the tree.
+func (t *Tree) Len() int {
+	return len(t.items)
+}
+
+// Query returns the values of the items intersecting [min, max].
+func (t *Tree) Query(min, max float64) []interface{} {
+	var values []interface{}
+	t.Visit(min, max, func(item *Item) bool {
+		values = append(values, item.Value)
+		return true
+	})
+	return values
+}
+
+// Visit calls visit with each item intersecting [min, max], until visit
+// returns false. It returns false if the query was stopped.
+func (t *Tree) Visit(min, max float64, visit func(item *Item) bool) bool {
+	if len(t.nodes) == 0 {
+		return true
+	}
+	return t.visit(len(t.nodes)-1, t.height-1, min, max, visit)
+}
+
+func (t *Tree) visit(i, level int, min, max float64, visit func(item *Item) bool) bool {
+	n := &t.nodes[i]
+	if n.min > max || n.max < min {
+		return true
+	}
+	for j := n.start; j < n.end; j++ {
+		if level > 0 {
+			if !t.visit(j, level-1, min, max, visit) {
+				return false
+			}
+			continue
+		}
+		item := &t.items[j]
+		if item.Min <= max && item.Max >= min && !visit(item) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package intervaltree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomItems returns n intervals in [0, 1000), whose values are their
+// indexes. Some are points.
+func randomItems(n int, seed int64) []Item {
+	r := rand.New(rand.NewSource(seed))
+	items := make([]Item, n)
+	for i := range items {
+		min := float64(r.Intn(1000))
+		items[i] = Item{Min: min, Max: min + float64(r.Intn(20)), Value: i}
+	}
+	return items
+}
+
+func bruteForce(items []Item, min, max float64) []int {
+	var values []int
+	for _, item := range items {
+		if item.Min <= max && item.Max >= min {
+			values = append(values, item.Value.(int))
+		}
+	}
+	sort.Ints(values)
+	return values
+}
+
+func sortedInts(values []interface{}) []int {
+	var ints []int
+	for _, v := range values {
+		ints = append(ints, v.(int))
+	}
+	sort.Ints(ints)
+	return ints
+}
+
+func TestQuery(t *testing.T) {
+	items := []Item{
+		{Min: 0, Max: 10, Value: 0},
+		{Min: 5, Max: 5, Value: 1},
+		{Min: 8, Max: 12, Value: 2},
+		{Min: 20, Max: 30, Value: 3},
+		{Min: -5, Max: -1, Value: 4},
+	}
+	tree := New(items)
+	tests := []struct {
+		name     string
+		min, max float64
+		want     []int
+	}{
+		{"point inside", 6, 6, []int{0}},
+		{"point on a point item", 5, 5, []int{0, 1}},
+		{"touching an end", 12, 12, []int{2}},
+		{"touching a start", 15, 20, []int{3}},
+		{"overlapping several", 9, 21, []int{0, 2, 3}},
+		{"containing all", -100, 100, []int{0, 1, 2, 3, 4}},
+		{"in a gap", 13, 19, nil},
+		{"below all", -10, -6, nil},
+		{"above all", 31, 40, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortedInts(tree.Query(test.min, test.max)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Query(%v, %v) = %v, want %v", test.min, test.max, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQueryRandom(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 8, 9, 64, 65, 1000, 10000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			items := randomItems(n, int64(n))
+			tree := New(items)
+			if tree.Len() != n {
+				t.Fatalf("Len() = %d, want %d", tree.Len(), n)
+			}
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 200; i++ {
+				min := float64(r.Intn(1000))
+				max := min + float64(r.Intn(30))
+				got := sortedInts(tree.Query(min, max))
+				if want := bruteForce(items, min, max); !reflect.DeepEqual(got, want) {
+					t.Fatalf("Query(%v, %v) = %v, want %v", min, max, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCopiesItems(t *testing.T) {
+	items := randomItems(50, 2)
+	tree := New(items)
+	for i := range items {
+		items[i] = Item{Min: -10, Max: -10, Value: -1}
+	}
+	if got := tree.Query(-10, -10); len(got) != 0 {
+		t.Errorf("Query() = %v after changing the input, want none", got)
+	}
+	if got := tree.Query(0, 1000); len(got) != 50 {
+		t.Errorf("Query() found %d items, want 50", len(got))
+	}
+}
+
+func TestVisitStops(t *testing.T) {
+	tree := New(randomItems(500, 3))
+	count := 0
+	if tree.Visit(0, 1000, func(*Item) bool {
+		count++
+		return count < 10
+	}) {
+		t.Error("Visit() = true after being stopped")
+	}
+	if count != 10 {
+		t.Errorf("Visit() visited %d items after being stopped at 10", count)
+	}
+}
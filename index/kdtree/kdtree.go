@@ -0,0 +1,230 @@
+// Package kdtree implements a 2-dimensional KD-tree of points, which can
+// merge points closer together than a snapping tolerance.
+package kdtree
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// Node is a distinct point of the tree.
+type Node struct {
+	Coordinate coord.Coordinate
+	// Value is the value of the first point inserted at the node.
+	Value interface{}
+	// Count is the number of points inserted at or snapped to the node.
+	Count int
+
+	left, right *Node
+}
+
+// Tree is a KD-tree. Nodes alternately split the plane by X and by Y. The
+// tree is not rebalanced, so inserting points in sorted order makes it
+// slow; shuffling them first avoids that.
+type Tree struct {
+	// Tolerance is the distance within which an inserted point is snapped
+	// to an existing node. With no tolerance only equal points are merged.
+	Tolerance float64
+
+	root *Node
+	size int
+}
+
+func New(tolerance float64) *Tree {
+	return &Tree{Tolerance: tolerance}
+}
+
+// Len returns the number of nodes in the tree.
+func (t *Tree) Len() int {
+	return t.size
+}
+
+// Insert adds a point, returning its node. If the point is within the
+// tolerance of existing nodes, it is snapped to the nearest of them and its
+// count increased instead.
+func (t *Tree) Insert(c coord.Coordinate, value interface{}) *Node {
+	if n := t.snap(c); n != nil {
+		n.Count++
+		return n
+	}
+
+	n := &Node{Coordinate: c, Value: value, Count: 1}
+	t.size++
+	if t.root == nil {
+		t.root = n
+		return n
+	}
+	parent, splitX := t.root, true
+	for {
+		var next **Node
+		if less(c, parent.Coordinate, splitX) {
+			next = &parent.left
+		} else {
+			next = &parent.right
+		}
+		if *next == nil {
+			*next = n
+			return n
+		}
+		parent, splitX = *next, !splitX
+	}
+}
+
+// snap returns the nearest node within the tolerance of c, or nil.
+func (t *Tree) snap(c coord.Coordinate) *Node {
+	if t.Tolerance <= 0 {
+		n := t.root
+		for splitX := true; n != nil; splitX = !splitX {
+			if n.Coordinate.Equals2D(c) {
+				return n
+			}
+			if less(c, n.Coordinate, splitX) {
+				n = n.left
+			} else {
+				n = n.right
+			}
+		}
+		return nil
+	}
+
+	env := coord.NewEnvelope(c.X-t.Tolerance, c.X+t.Tolerance, c.Y-t.Tolerance, c.Y+t.Tolerance)
+	var nearest *Node
+	best := t.Tolerance
+	t.Visit(env, func(n *Node) bool {
+		if d := n.Coordinate.Distance(c); d <= best {
+			nearest, best = n, d
+		}
+		return true
+	})
+	return nearest
+}
+
+// less returns whether a is on the low side of b along the split axis.
+func less(a, b coord.Coordinate, splitX bool) bool {
+	if splitX {
+		return a.X < b.X
+	}
+	return a.Y < b.Y
+}
+
+// Query returns the nodes within env.
+func (t *Tree) Query(env *coord.Envelope) []*Node {
+	var nodes []*Node
+	t.Visit(env, func(n *Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+// Visit calls visit with each node within env, until visit returns false.
+// It returns false if the query was stopped.
+func (t *Tree) Visit(env *coord.Envelope, visit func(n *Node) bool) bool {
+	if env == nil {
+		return true
+	}
+	return visitNode(t.root, env, true, visit)
+}
+
+func visitNode(n *Node, env *coord.Envelope, splitX bool, visit func(n *Node) bool) bool {
+	if n == nil {
+		return true
+	}
+	min, max, split := env.MinY, env.MaxY, n.Coordinate.Y
+	if splitX {
+		min, max, split = env.MinX, env.MaxX, n.Coordinate.X
+	}
+	if min < split && !visitNode(n.left, env, !splitX, visit) {
+		return false
+	}
+	if env.ContainsCoord(n.Coordinate) && !visit(n) {
+		return false
+	}
+	if max >= split && !visitNode(n.right, env, !splitX, visit) {
+		return false
+	}
+	return true
+}
+
+// Nearest returns the k nodes nearest to c, nearest first.
+func (t *Tree) Nearest(c coord.Coordinate, k int) []*Node {
+	if k <= 0 {
+		return nil
+	}
+	s := &search{target: c, k: k}
+	s.visit(t.root, true)
+	sort.Sort(s)
+	nodes := make([]*Node, len(s.nodes))
+	for i, n := range s.nodes {
+		nodes[i] = n.node
+	}
+	return nodes
+}
+
+// search is a k-nearest-neighbour search, holding the nearest nodes found
+// so far as a max-heap on distance.
+type search struct {
+	target coord.Coordinate
+	k      int
+	nodes  []candidate
+}
+
+type candidate struct {
+	node     *Node
+	distance float64
+}
+
+func (s *search) Len() int           { return len(s.nodes) }
+func (s *search) Less(i, j int) bool { return s.nodes[i].distance < s.nodes[j].distance }
+func (s *search) Swap(i, j int)      { s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i] }
+
+// maxHeap orders a search farthest first.
+type maxHeap struct{ *search }
+
+func (h maxHeap) Less(i, j int) bool { return h.nodes[i].distance > h.nodes[j].distance }
+func (h maxHeap) Push(x interface{}) { h.nodes = append(h.nodes, x.(candidate)) }
+func (h maxHeap) Pop() interface{} {
+	last := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	return last
+}
+
+// farthest returns the distance of the farthest node found, or infinity
+// until k have been found.
+func (s *search) farthest() float64 {
+	if len(s.nodes) < s.k {
+		return math.Inf(1)
+	}
+	return s.nodes[0].distance
+}
+
+func (s *search) visit(n *Node, splitX bool) {
+	if n == nil {
+		return
+	}
+	d := n.Coordinate.Distance(s.target)
+	if d < s.farthest() {
+		if len(s.nodes) == s.k {
+			heap.Pop(maxHeap{s})
+		}
+		heap.Push(maxHeap{s}, candidate{n, d})
+	}
+
+	// search the side of the split holding the target first, then the
+	// other side if it may hold nearer nodes
+	offset := s.target.Y - n.Coordinate.Y
+	if splitX {
+		offset = s.target.X - n.Coordinate.X
+	}
+	near, far := n.right, n.left
+	if offset < 0 {
+		near, far = n.left, n.right
+	}
+	s.visit(near, !splitX)
+	if math.Abs(offset) < s.farthest() {
+		s.visit(far, !splitX)
+	}
+}
@@ -0,0 +1,247 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// randomPoints returns n points on a 100 by 100 integer grid, so that many
+// share an X or a Y with the nodes splitting the tree, and some are equal.
+func randomPoints(n int, seed int64) []coord.Coordinate {
+	r := rand.New(rand.NewSource(seed))
+	points := make([]coord.Coordinate, n)
+	for i := range points {
+		points[i] = coord.Coordinate{X: float64(r.Intn(100)), Y: float64(r.Intn(100))}
+	}
+	return points
+}
+
+// distinct returns the points without repeats, in order of first
+// appearance.
+func distinct(points []coord.Coordinate) []coord.Coordinate {
+	seen := map[coord.Coordinate]bool{}
+	var out []coord.Coordinate
+	for _, p := range points {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func sortCoords(cs []coord.Coordinate) []coord.Coordinate {
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].X != cs[j].X {
+			return cs[i].X < cs[j].X
+		}
+		return cs[i].Y < cs[j].Y
+	})
+	return cs
+}
+
+func coordsOf(nodes []*Node) []coord.Coordinate {
+	var cs []coord.Coordinate
+	for _, n := range nodes {
+		cs = append(cs, n.Coordinate)
+	}
+	return cs
+}
+
+func TestQuery(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 5000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			points := randomPoints(n, int64(n))
+			tree := New(0)
+			for i, p := range points {
+				tree.Insert(p, i)
+			}
+			unique := distinct(points)
+			if tree.Len() != len(unique) {
+				t.Fatalf("Len() = %d, want %d", tree.Len(), len(unique))
+			}
+
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 200; i++ {
+				x, y := float64(r.Intn(100)), float64(r.Intn(100))
+				env := coord.NewEnvelope(x, x+float64(r.Intn(20)), y, y+float64(r.Intn(20)))
+				var want []coord.Coordinate
+				for _, p := range unique {
+					if env.ContainsCoord(p) {
+						want = append(want, p)
+					}
+				}
+				got := coordsOf(tree.Query(env))
+				if !reflect.DeepEqual(sortCoords(got), sortCoords(want)) {
+					t.Fatalf("Query(%v) = %v, want %v", env, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVisitStops(t *testing.T) {
+	tree := New(0)
+	for i, p := range randomPoints(100, 2) {
+		tree.Insert(p, i)
+	}
+	count := 0
+	if tree.Visit(coord.NewEnvelope(0, 100, 0, 100), func(*Node) bool {
+		count++
+		return count < 3
+	}) {
+		t.Error("Visit() = true after being stopped")
+	}
+	if count != 3 {
+		t.Errorf("Visit() visited %d nodes after being stopped at 3", count)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	points := distinct(randomPoints(2000, 3))
+	tree := New(0)
+	for i, p := range points {
+		tree.Insert(p, i)
+	}
+
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 100; i++ {
+		target := coord.Coordinate{X: r.Float64()*120 - 10, Y: r.Float64()*120 - 10}
+		var want []float64
+		for _, p := range points {
+			want = append(want, p.Distance(target))
+		}
+		sort.Float64s(want)
+
+		for _, k := range []int{1, 4, 30} {
+			nodes := tree.Nearest(target, k)
+			if len(nodes) != k {
+				t.Fatalf("Nearest(%v, %d) found %d nodes", target, k, len(nodes))
+			}
+			for j, n := range nodes {
+				if d := n.Coordinate.Distance(target); d != want[j] {
+					t.Fatalf("Nearest(%v, %d)[%d] is at %v, want %v", target, k, j, d, want[j])
+				}
+			}
+		}
+	}
+
+	if got := tree.Nearest(coord.Coordinate{}, 0); got != nil {
+		t.Errorf("Nearest() with k 0 = %v, want nil", got)
+	}
+	if got := New(0).Nearest(coord.Coordinate{}, 3); len(got) != 0 {
+		t.Errorf("Nearest() on an empty tree = %v, want none", got)
+	}
+	small := New(0)
+	small.Insert(coord.Coordinate{X: 1, Y: 1}, nil)
+	small.Insert(coord.Coordinate{X: 2, Y: 2}, nil)
+	if got := small.Nearest(coord.Coordinate{}, 5); len(got) != 2 {
+		t.Errorf("Nearest() with k above Len() found %d nodes, want 2", len(got))
+	}
+}
+
+func TestSnapping(t *testing.T) {
+	type insert struct {
+		x, y  float64
+		value string
+	}
+	type node struct {
+		X, Y  float64
+		Value interface{}
+		Count int
+	}
+	tests := []struct {
+		name      string
+		tolerance float64
+		inserts   []insert
+		want      []node
+	}{
+		{
+			"duplicates without tolerance",
+			0,
+			[]insert{{1, 1, "a"}, {2, 2, "b"}, {1, 1, "c"}, {1, 1, "d"}, {1, 1.001, "e"}},
+			[]node{{1, 1, "a", 3}, {1, 1.001, "e", 1}, {2, 2, "b", 1}},
+		},
+		{
+			"duplicates with tolerance",
+			0.5,
+			[]insert{{1, 1, "a"}, {1, 1, "b"}, {1, 1, "c"}},
+			[]node{{1, 1, "a", 3}},
+		},
+		{
+			"within tolerance",
+			0.5,
+			[]insert{{1, 1, "a"}, {1.3, 1.3, "b"}, {0.6, 1, "c"}, {1, 1.5, "d"}},
+			[]node{{1, 1, "a", 4}},
+		},
+		{
+			"beyond tolerance",
+			0.5,
+			[]insert{{1, 1, "a"}, {1.4, 1.4, "b"}, {0.4, 1, "c"}},
+			[]node{{0.4, 1, "c", 1}, {1, 1, "a", 1}, {1.4, 1.4, "b", 1}},
+		},
+		{
+			"snapped to the nearest node",
+			1,
+			[]insert{{0, 0, "a"}, {1.5, 0, "b"}, {0.8, 0, "c"}, {0.7, 0, "d"}},
+			[]node{{0, 0, "a", 2}, {1.5, 0, "b", 2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := New(test.tolerance)
+			for _, in := range test.inserts {
+				n := tree.Insert(coord.Coordinate{X: in.x, Y: in.y}, in.value)
+				if d := n.Coordinate.Distance(coord.Coordinate{X: in.x, Y: in.y}); d > test.tolerance {
+					t.Errorf("Insert(%v %v) returned a node %v away", in.x, in.y, d)
+				}
+			}
+			if tree.Len() != len(test.want) {
+				t.Errorf("Len() = %d, want %d", tree.Len(), len(test.want))
+			}
+			var got []node
+			for _, n := range tree.Query(coord.NewEnvelope(-10, 10, -10, 10)) {
+				got = append(got, node{n.Coordinate.X, n.Coordinate.Y, n.Value, n.Count})
+			}
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].X != got[j].X {
+					return got[i].X < got[j].X
+				}
+				return got[i].Y < got[j].Y
+			})
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("nodes = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSnappingRandom(t *testing.T) {
+	const tolerance = 2
+	points := randomPoints(3000, 5)
+	tree := New(tolerance)
+	counts := 0
+	for i, p := range points {
+		tree.Insert(p, i)
+	}
+	nodes := tree.Query(coord.NewEnvelope(-1, 101, -1, 101))
+	if len(nodes) != tree.Len() {
+		t.Fatalf("Query() found %d nodes, Len() = %d", len(nodes), tree.Len())
+	}
+	for i, a := range nodes {
+		counts += a.Count
+		for _, b := range nodes[i+1:] {
+			if d := a.Coordinate.Distance(b.Coordinate); d <= tolerance {
+				t.Fatalf("nodes %v and %v are %v apart, within the tolerance", a.Coordinate, b.Coordinate, d)
+			}
+		}
+	}
+	if counts != len(points) {
+		t.Errorf("node counts add up to %d, want %d", counts, len(points))
+	}
+}
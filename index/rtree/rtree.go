@@ -0,0 +1,453 @@
+// Package rtree implements a dynamic R*-tree, which unlike a packed tree
+// allows items to be added and removed at any time.
+//
+// The tree follows Beckmann et al., "The R*-tree: An Efficient and Robust
+// Access Method for Points and Rectangles": subtrees are chosen to minimise
+// overlap, overflowing nodes first have some of their entries reinserted,
+// and splits minimise margin, then overlap.
+package rtree
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// DefaultNodeCapacity is the maximum number of entries of each node when no
+// capacity is given.
+const DefaultNodeCapacity = 16
+
+// Item is an envelope with an arbitrary value, such as a polygon.
+type Item struct {
+	Envelope coord.Envelope
+	Value    interface{}
+}
+
+// Visitor is called with each item found by a query. Returning false stops
+// the query.
+type Visitor func(item Item) bool
+
+// Tree is an R*-tree. Its methods may be called concurrently; queries run
+// in parallel with each other but not with changes to the tree.
+type Tree struct {
+	mu         sync.RWMutex
+	root       *node
+	size       int
+	maxEntries int
+	minEntries int
+}
+
+// node is a node of the tree. Its level is its height above the leaves,
+// whose entries are items.
+type node struct {
+	level   int
+	entries []entry
+}
+
+type entry struct {
+	env   coord.Envelope
+	child *node
+	value interface{}
+}
+
+// New returns an empty tree with the default node capacity.
+func New() *Tree {
+	return NewWithCapacity(DefaultNodeCapacity)
+}
+
+// NewWithCapacity returns an empty tree with at most nodeCapacity entries
+// per node.
+func NewWithCapacity(nodeCapacity int) *Tree {
+	if nodeCapacity < 4 {
+		nodeCapacity = 4
+	}
+	return &Tree{
+		root:       &node{},
+		maxEntries: nodeCapacity,
+		// 40% is the minimum fill found to perform best
+		minEntries: int(math.Max(2, math.Floor(float64(nodeCapacity)*0.4))),
+	}
+}
+
+// Len returns the number of items in the tree.
+func (t *Tree) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+// Envelope returns the envelope of all items, or nil if the tree is empty.
+func (t *Tree) Envelope() *coord.Envelope {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.root.entries) == 0 {
+		return nil
+	}
+	env := t.root.envelope()
+	return &env
+}
+
+// Query returns the values of the items whose envelopes intersect env.
+func (t *Tree) Query(env *coord.Envelope) []interface{} {
+	var values []interface{}
+	t.Visit(env, func(item Item) bool {
+		values = append(values, item.Value)
+		return true
+	})
+	return values
+}
+
+// Visit calls visit with each item whose envelope intersects env, until
+// visit returns false. It returns false if the query was stopped. The tree
+// is locked while visiting, so visit must not change it.
+func (t *Tree) Visit(env *coord.Envelope, visit Visitor) bool {
+	if env == nil {
+		return true
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.root.visit(env, visit)
+}
+
+func (n *node) visit(env *coord.Envelope, visit Visitor) bool {
+	for i := range n.entries {
+		e := &n.entries[i]
+		if !e.env.Intersects(env) {
+			continue
+		}
+		if n.level == 0 {
+			if !visit(Item{e.env, e.value}) {
+				return false
+			}
+		} else if !e.child.visit(env, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Insert adds an item to the tree.
+func (t *Tree) Insert(item Item) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.insert(item)
+	t.size++
+}
+
+// Delete removes an item with the same envelope and value, returning
+// whether one was found. Values are compared with ==, so they must be
+// comparable.
+func (t *Tree) Delete(item Item) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delete(item)
+}
+
+// Update moves an item to a new envelope, returning whether it was found.
+func (t *Tree) Update(item Item, env *coord.Envelope) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.delete(item) {
+		return false
+	}
+	item.Envelope = *env
+	t.insert(item)
+	t.size++
+	return true
+}
+
+func (t *Tree) insert(item Item) {
+	ins := inserter{tree: t, reinserted: map[int]bool{}}
+	ins.insert(entry{env: item.Envelope, value: item.Value}, 0)
+	// entries removed for reinsertion are inserted again in the same pass,
+	// so each level overflows into a reinsertion at most once
+	for len(ins.pending) > 0 {
+		p := ins.pending[0]
+		ins.pending = ins.pending[1:]
+		ins.insert(p.entry, p.level)
+	}
+}
+
+// inserter holds the state of an insertion.
+type inserter struct {
+	tree *Tree
+	// reinserted records the levels whose overflow has been treated by
+	// reinsertion
+	reinserted map[int]bool
+	pending    []pendingEntry
+}
+
+type pendingEntry struct {
+	entry entry
+	level int
+}
+
+// insert adds an entry to a node at a level of the tree, growing the tree
+// if the root is split.
+func (ins *inserter) insert(e entry, level int) {
+	t := ins.tree
+	sibling := ins.insertAt(t.root, e, level)
+	if sibling != nil {
+		old := t.root
+		t.root = &node{
+			level: old.level + 1,
+			entries: []entry{
+				{env: old.envelope(), child: old},
+				{env: sibling.envelope(), child: sibling},
+			},
+		}
+	}
+}
+
+// insertAt adds an entry to the subtree of n, returning a new sibling of n
+// if n was split.
+func (ins *inserter) insertAt(n *node, e entry, level int) *node {
+	if n.level == level {
+		n.entries = append(n.entries, e)
+	} else {
+		i := n.chooseSubtree(&e.env)
+		child := n.entries[i].child
+		sibling := ins.insertAt(child, e, level)
+		n.entries[i].env = child.envelope()
+		if sibling != nil {
+			n.entries = append(n.entries, entry{env: sibling.envelope(), child: sibling})
+		}
+	}
+	if len(n.entries) <= ins.tree.maxEntries {
+		return nil
+	}
+	if n != ins.tree.root && !ins.reinserted[n.level] {
+		ins.reinserted[n.level] = true
+		ins.reinsert(n)
+		return nil
+	}
+	return ins.tree.split(n)
+}
+
+// reinsert removes the 30% of the entries of n whose centres are farthest
+// from the centre of n, queueing them to be inserted again closest first.
+func (ins *inserter) reinsert(n *node) {
+	env := n.envelope()
+	cx, cy := centre(&env)
+	sort.SliceStable(n.entries, func(i, j int) bool {
+		xi, yi := centre(&n.entries[i].env)
+		xj, yj := centre(&n.entries[j].env)
+		return math.Hypot(xi-cx, yi-cy) < math.Hypot(xj-cx, yj-cy)
+	})
+	p := len(n.entries) * 3 / 10
+	if p < 1 {
+		p = 1
+	}
+	keep := len(n.entries) - p
+	for _, e := range n.entries[keep:] {
+		ins.pending = append(ins.pending, pendingEntry{e, n.level})
+	}
+	n.entries = n.entries[:keep:keep]
+}
+
+// chooseSubtree returns the index of the entry of n to insert env into.
+// Above the leaves it is the entry needing the least overlap enlargement,
+// elsewhere the one needing the least area enlargement, with ties going to
+// the smallest area.
+func (n *node) chooseSubtree(env *coord.Envelope) int {
+	best := 0
+	bestOverlap, bestEnlargement, bestArea := math.Inf(1), math.Inf(1), math.Inf(1)
+	for i := range n.entries {
+		e := &n.entries[i]
+		a := area(&e.env)
+		enlarged := union(&e.env, env)
+		enlargement := area(&enlarged) - a
+
+		overlap := 0.0
+		if n.level == 1 {
+			for j := range n.entries {
+				if j != i {
+					overlap += overlapArea(&enlarged, &n.entries[j].env) - overlapArea(&e.env, &n.entries[j].env)
+				}
+			}
+		}
+		if overlap < bestOverlap ||
+			overlap == bestOverlap && (enlargement < bestEnlargement ||
+				enlargement == bestEnlargement && a < bestArea) {
+			best = i
+			bestOverlap, bestEnlargement, bestArea = overlap, enlargement, a
+		}
+	}
+	return best
+}
+
+// split moves some of the entries of an overflowing node to a new sibling,
+// which it returns. The split axis is the one whose distributions have the
+// least total margin, and the distribution on it the one with the least
+// overlap, then the least area.
+func (t *Tree) split(n *node) *node {
+	byMinX := func(a, b *coord.Envelope) bool { return a.MinX < b.MinX || a.MinX == b.MinX && a.MaxX < b.MaxX }
+	byMaxX := func(a, b *coord.Envelope) bool { return a.MaxX < b.MaxX || a.MaxX == b.MaxX && a.MinX < b.MinX }
+	byMinY := func(a, b *coord.Envelope) bool { return a.MinY < b.MinY || a.MinY == b.MinY && a.MaxY < b.MaxY }
+	byMaxY := func(a, b *coord.Envelope) bool { return a.MaxY < b.MaxY || a.MaxY == b.MaxY && a.MinY < b.MinY }
+
+	axes := [2][2]func(a, b *coord.Envelope) bool{{byMinX, byMaxX}, {byMinY, byMaxY}}
+	var bestAxis [2]func(a, b *coord.Envelope) bool
+	bestMargin := math.Inf(1)
+	for _, axis := range axes {
+		margin := 0.0
+		for _, less := range axis {
+			t.sortEntries(n.entries, less)
+			t.distributions(n.entries, func(k int, first, second *coord.Envelope) {
+				margin += perimeter(first) + perimeter(second)
+			})
+		}
+		if margin < bestMargin {
+			bestMargin = margin
+			bestAxis = axis
+		}
+	}
+
+	var bestLess func(a, b *coord.Envelope) bool
+	bestK := 0
+	bestOverlap, bestArea := math.Inf(1), math.Inf(1)
+	for _, less := range bestAxis {
+		t.sortEntries(n.entries, less)
+		t.distributions(n.entries, func(k int, first, second *coord.Envelope) {
+			overlap := overlapArea(first, second)
+			a := area(first) + area(second)
+			if overlap < bestOverlap || overlap == bestOverlap && a < bestArea {
+				bestOverlap, bestArea = overlap, a
+				bestLess, bestK = less, k
+			}
+		})
+	}
+
+	t.sortEntries(n.entries, bestLess)
+	sibling := &node{level: n.level, entries: make([]entry, len(n.entries)-bestK)}
+	copy(sibling.entries, n.entries[bestK:])
+	n.entries = append([]entry(nil), n.entries[:bestK]...)
+	return sibling
+}
+
+func (t *Tree) sortEntries(entries []entry, less func(a, b *coord.Envelope) bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(&entries[i].env, &entries[j].env)
+	})
+}
+
+// distributions calls f with each split of sorted entries into the first k
+// and the rest that leaves each group with at least the minimum entries.
+func (t *Tree) distributions(entries []entry, f func(k int, first, second *coord.Envelope)) {
+	for k := t.minEntries; k <= len(entries)-t.minEntries; k++ {
+		first := envelopeOf(entries[:k])
+		second := envelopeOf(entries[k:])
+		f(k, &first, &second)
+	}
+}
+
+func (t *Tree) delete(item Item) bool {
+	var orphans []entry
+	if !t.remove(t.root, &item, &orphans) {
+		return false
+	}
+	t.size--
+
+	// shorten the tree before reinserting, so the root is never an empty
+	// node above the leaves
+	for t.root.level > 0 && len(t.root.entries) <= 1 {
+		if len(t.root.entries) == 0 {
+			t.root = &node{}
+			break
+		}
+		t.root = t.root.entries[0].child
+	}
+	for _, e := range orphans {
+		t.insert(Item{e.env, e.value})
+	}
+	return true
+}
+
+// remove removes an item from the subtree of n, returning whether it was
+// found. Nodes left with too few entries are removed and their items added
+// to orphans.
+func (t *Tree) remove(n *node, item *Item, orphans *[]entry) bool {
+	if n.level == 0 {
+		for i := range n.entries {
+			e := &n.entries[i]
+			if e.env == item.Envelope && e.value == item.Value {
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	for i := range n.entries {
+		e := &n.entries[i]
+		if !contains(&e.env, &item.Envelope) || !t.remove(e.child, item, orphans) {
+			continue
+		}
+		if len(e.child.entries) < t.minEntries {
+			*orphans = e.child.appendItems(*orphans)
+			n.entries = append(n.entries[:i], n.entries[i+1:]...)
+		} else {
+			e.env = e.child.envelope()
+		}
+		return true
+	}
+	return false
+}
+
+// appendItems appends the item entries of the subtree of n.
+func (n *node) appendItems(entries []entry) []entry {
+	if n.level == 0 {
+		return append(entries, n.entries...)
+	}
+	for _, e := range n.entries {
+		entries = e.child.appendItems(entries)
+	}
+	return entries
+}
+
+func (n *node) envelope() coord.Envelope {
+	return envelopeOf(n.entries)
+}
+
+func envelopeOf(entries []entry) coord.Envelope {
+	if len(entries) == 0 {
+		return coord.Envelope{}
+	}
+	env := entries[0].env
+	for i := 1; i < len(entries); i++ {
+		env.ExpandEnvelope(&entries[i].env)
+	}
+	return env
+}
+
+func union(a, b *coord.Envelope) coord.Envelope {
+	env := *a
+	env.ExpandEnvelope(b)
+	return env
+}
+
+func area(e *coord.Envelope) float64 {
+	return (e.MaxX - e.MinX) * (e.MaxY - e.MinY)
+}
+
+func perimeter(e *coord.Envelope) float64 {
+	return 2 * ((e.MaxX - e.MinX) + (e.MaxY - e.MinY))
+}
+
+func overlapArea(a, b *coord.Envelope) float64 {
+	w := math.Min(a.MaxX, b.MaxX) - math.Max(a.MinX, b.MinX)
+	h := math.Min(a.MaxY, b.MaxY) - math.Max(a.MinY, b.MinY)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+func contains(a, b *coord.Envelope) bool {
+	return b.MinX >= a.MinX && b.MaxX <= a.MaxX && b.MinY >= a.MinY && b.MaxY <= a.MaxY
+}
+
+func centre(e *coord.Envelope) (float64, float64) {
+	return (e.MinX + e.MaxX) / 2, (e.MinY + e.MaxY) / 2
+}
@@ -0,0 +1,407 @@
+package rtree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/index/strtree"
+)
+
+// randomItems returns n items with small envelopes in a 1000 by 1000
+// square, whose values are their indexes.
+func randomItems(n int, seed int64) []Item {
+	r := rand.New(rand.NewSource(seed))
+	items := make([]Item, n)
+	for i := range items {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		items[i] = Item{
+			Envelope: *coord.NewEnvelope(x, x+r.Float64()*10, y, y+r.Float64()*10),
+			Value:    i,
+		}
+	}
+	return items
+}
+
+func queries(n int, seed int64) []*coord.Envelope {
+	r := rand.New(rand.NewSource(seed))
+	envs := make([]*coord.Envelope, n)
+	for i := range envs {
+		x, y, size := r.Float64()*1000, r.Float64()*1000, r.Float64()*100
+		envs[i] = coord.NewEnvelope(x, x+size, y, y+size)
+	}
+	return envs
+}
+
+// bruteForce returns the sorted values of the items intersecting env.
+func bruteForce(items []Item, env *coord.Envelope) []int {
+	var values []int
+	for _, item := range items {
+		if item.Envelope.Intersects(env) {
+			values = append(values, item.Value.(int))
+		}
+	}
+	sort.Ints(values)
+	return values
+}
+
+func query(tree *Tree, env *coord.Envelope) []int {
+	var values []int
+	for _, v := range tree.Query(env) {
+		values = append(values, v.(int))
+	}
+	sort.Ints(values)
+	return values
+}
+
+// checkTree checks the structure of a tree: leaves all at level 0, entry
+// envelopes equal to those of their children, node sizes within the
+// capacity, and the number of items.
+func checkTree(t *testing.T, tree *Tree) {
+	t.Helper()
+	count := 0
+	var check func(n *node, level int, isRoot bool)
+	check = func(n *node, level int, isRoot bool) {
+		if n.level != level {
+			t.Fatalf("node at level %d, want %d", n.level, level)
+		}
+		if len(n.entries) > tree.maxEntries || !isRoot && len(n.entries) < tree.minEntries {
+			t.Fatalf("node at level %d has %d entries, want %d to %d", level, len(n.entries), tree.minEntries, tree.maxEntries)
+		}
+		if isRoot && level > 0 && len(n.entries) < 2 {
+			t.Fatalf("root above the leaves has %d entries", len(n.entries))
+		}
+		for _, e := range n.entries {
+			if level == 0 {
+				count++
+				continue
+			}
+			if e.env != e.child.envelope() {
+				t.Fatalf("entry envelope %v, child envelope %v", e.env, e.child.envelope())
+			}
+			check(e.child, level-1, false)
+		}
+	}
+	check(tree.root, tree.root.level, true)
+	if count != tree.size || count != tree.Len() {
+		t.Fatalf("tree has %d items, Len() = %d", count, tree.Len())
+	}
+}
+
+func checkQueries(t *testing.T, tree *Tree, items []Item) {
+	t.Helper()
+	for _, env := range queries(50, 2) {
+		if got, want := query(tree, env), bruteForce(items, env); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Query(%v) = %v, want %v", env, got, want)
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	for _, capacity := range []int{4, 9, DefaultNodeCapacity} {
+		t.Run(fmt.Sprint(capacity), func(t *testing.T) {
+			tree := NewWithCapacity(capacity)
+			if tree.Envelope() != nil || tree.Len() != 0 || len(tree.Query(coord.NewEnvelope(0, 1000, 0, 1000))) != 0 {
+				t.Fatal("new tree is not empty")
+			}
+			items := randomItems(2000, 1)
+			for i, item := range items {
+				tree.Insert(item)
+				if i%500 == 0 {
+					checkTree(t, tree)
+				}
+			}
+			checkTree(t, tree)
+			checkQueries(t, tree, items)
+
+			want := items[0].Envelope
+			for _, item := range items {
+				want.ExpandEnvelope(&item.Envelope)
+			}
+			if got := tree.Envelope(); *got != want {
+				t.Errorf("Envelope() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInsertDuplicates(t *testing.T) {
+	// identical envelopes cannot be split apart, but must still fit
+	tree := NewWithCapacity(4)
+	var items []Item
+	for i := 0; i < 100; i++ {
+		item := Item{Envelope: *coord.NewEnvelope(1, 2, 1, 2), Value: i}
+		items = append(items, item)
+		tree.Insert(item)
+	}
+	checkTree(t, tree)
+	checkQueries(t, tree, items)
+	if n := len(tree.Query(coord.NewEnvelope(1.5, 1.5, 1.5, 1.5))); n != 100 {
+		t.Errorf("Query() found %d items, want 100", n)
+	}
+}
+
+func TestVisitStops(t *testing.T) {
+	tree := New()
+	for _, item := range randomItems(500, 1) {
+		tree.Insert(item)
+	}
+	n := 0
+	ok := tree.Visit(coord.NewEnvelope(0, 1000, 0, 1000), func(Item) bool {
+		n++
+		return n < 10
+	})
+	if ok || n != 10 {
+		t.Errorf("Visit() = %v after %d items, want false after 10", ok, n)
+	}
+	if !tree.Visit(nil, func(Item) bool { return false }) {
+		t.Error("Visit(nil) = false, want true")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tree := NewWithCapacity(6)
+	items := randomItems(1000, 1)
+	for _, item := range items {
+		tree.Insert(item)
+	}
+
+	if tree.Delete(Item{Envelope: items[0].Envelope, Value: -1}) {
+		t.Error("Delete() of an unknown value = true")
+	}
+	if tree.Delete(Item{Envelope: items[1].Envelope, Value: 0}) {
+		t.Error("Delete() with another envelope = true")
+	}
+
+	// delete every other item, checking the tree as it shrinks
+	var kept []Item
+	for i, item := range items {
+		if i%2 == 1 {
+			kept = append(kept, item)
+			continue
+		}
+		if !tree.Delete(item) {
+			t.Fatalf("Delete(%v) = false", item)
+		}
+		if i%100 == 0 {
+			checkTree(t, tree)
+		}
+	}
+	checkTree(t, tree)
+	checkQueries(t, tree, kept)
+	if tree.Delete(items[0]) {
+		t.Error("Delete() of a deleted item = true")
+	}
+
+	for _, item := range kept {
+		if !tree.Delete(item) {
+			t.Fatalf("Delete(%v) = false", item)
+		}
+	}
+	checkTree(t, tree)
+	if tree.Len() != 0 || tree.Envelope() != nil || tree.root.level != 0 {
+		t.Errorf("emptied tree has %d items, envelope %v, height %d", tree.Len(), tree.Envelope(), tree.root.level)
+	}
+
+	// the emptied tree is usable again
+	tree.Insert(items[0])
+	checkTree(t, tree)
+	checkQueries(t, tree, items[:1])
+}
+
+func TestUpdate(t *testing.T) {
+	tree := NewWithCapacity(5)
+	items := randomItems(1000, 1)
+	for _, item := range items {
+		tree.Insert(item)
+	}
+	// move a third of the items by 500 in both directions, wrapping around
+	moved := append([]Item(nil), items...)
+	for i := 0; i < len(moved); i += 3 {
+		env := moved[i].Envelope
+		dx := 500.0
+		if env.MinX >= 500 {
+			dx = -500
+		}
+		to := coord.NewEnvelope(env.MinX+dx, env.MaxX+dx, env.MinY, env.MaxY)
+		if !tree.Update(moved[i], to) {
+			t.Fatalf("Update(%v) = false", moved[i])
+		}
+		moved[i].Envelope = *to
+	}
+	checkTree(t, tree)
+	checkQueries(t, tree, moved)
+
+	if tree.Update(items[0], coord.NewEnvelope(0, 1, 0, 1)) {
+		t.Error("Update() of an item at its old envelope = true")
+	}
+	if tree.Len() != len(items) {
+		t.Errorf("Len() = %d, want %d", tree.Len(), len(items))
+	}
+}
+
+func TestReinsert(t *testing.T) {
+	// ten entries along the X axis, centred on 4.5: the 30% farthest from
+	// the centre are removed, and queued closest first
+	n := &node{}
+	for i := 0; i < 10; i++ {
+		x := float64(i)
+		n.entries = append(n.entries, entry{env: *coord.NewEnvelope(x, x, 0, 0), value: i})
+	}
+	ins := inserter{tree: NewWithCapacity(9), reinserted: map[int]bool{}}
+	ins.reinsert(n)
+
+	var kept, pending []int
+	for _, e := range n.entries {
+		kept = append(kept, e.value.(int))
+	}
+	for _, p := range ins.pending {
+		pending = append(pending, p.entry.value.(int))
+	}
+	sort.Ints(kept)
+	if want := []int{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	if want := []int{8, 0, 9}; !reflect.DeepEqual(pending, want) {
+		t.Errorf("queued %v, want %v", pending, want)
+	}
+}
+
+func TestForcedReinsert(t *testing.T) {
+	// count the overflows treated by reinsertion rather than splitting, by
+	// inserting clustered items into a tree of small nodes
+	tree := NewWithCapacity(4)
+	var items []Item
+	reinsertions := 0
+	for i := 0; i < 400; i++ {
+		cx, cy := float64(i%4)*100, float64(i/4%4)*100
+		item := Item{Envelope: *coord.NewEnvelope(cx+float64(i%7), cx+float64(i%7)+1, cy+float64(i%5), cy+float64(i%5)+1), Value: i}
+		items = append(items, item)
+
+		ins := inserter{tree: tree, reinserted: map[int]bool{}}
+		ins.insert(entry{env: item.Envelope, value: item.Value}, 0)
+		for len(ins.pending) > 0 {
+			p := ins.pending[0]
+			ins.pending = ins.pending[1:]
+			ins.insert(p.entry, p.level)
+		}
+		tree.size++
+		reinsertions += len(ins.reinserted)
+	}
+	if reinsertions == 0 {
+		t.Error("no overflow was treated by reinsertion")
+	}
+	checkTree(t, tree)
+	checkQueries(t, tree, items)
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	// run with -race: readers query while a writer changes the tree
+	tree := New()
+	items := randomItems(2000, 1)
+	for _, item := range items[:1000] {
+		tree.Insert(item)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			envs := queries(20, seed)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				env := envs[i%len(envs)]
+				tree.Visit(env, func(item Item) bool {
+					if !item.Envelope.Intersects(env) {
+						t.Errorf("Visit(%v) found %v", env, item.Envelope)
+					}
+					return true
+				})
+				tree.Len()
+				tree.Envelope()
+			}
+		}(int64(r))
+	}
+
+	for i, item := range items[1000:] {
+		tree.Insert(item)
+		old := items[i]
+		if i%2 == 0 {
+			tree.Delete(old)
+		} else {
+			tree.Update(old, coord.NewEnvelope(0, 1, 0, 1))
+		}
+	}
+	close(done)
+	wg.Wait()
+	checkTree(t, tree)
+	if tree.Len() != 1500 {
+		t.Errorf("Len() = %d, want 1500", tree.Len())
+	}
+}
+
+var benchmarkSizes = []int{1000, 10000, 100000}
+
+func strItems(items []Item) []strtree.Item {
+	s := make([]strtree.Item, len(items))
+	for i, item := range items {
+		s[i] = strtree.Item{Envelope: item.Envelope, Value: item.Value}
+	}
+	return s
+}
+
+// BenchmarkInsert compares inserting items one at a time with bulk loading
+// a packed tree.
+func BenchmarkInsert(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		items := randomItems(n, 1)
+		b.Run(fmt.Sprintf("rtree/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tree := New()
+				for _, item := range items {
+					tree.Insert(item)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("strtree/%d", n), func(b *testing.B) {
+			s := strItems(items)
+			for i := 0; i < b.N; i++ {
+				strtree.New(s)
+			}
+		})
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	envs := queries(1000, 2)
+	for _, n := range benchmarkSizes {
+		items := randomItems(n, 1)
+		b.Run(fmt.Sprintf("rtree/%d", n), func(b *testing.B) {
+			tree := New()
+			for _, item := range items {
+				tree.Insert(item)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tree.Visit(envs[i%len(envs)], func(Item) bool { return true })
+			}
+		})
+		b.Run(fmt.Sprintf("strtree/%d", n), func(b *testing.B) {
+			tree := strtree.New(strItems(items))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tree.Visit(envs[i%len(envs)], func(*strtree.Item) bool { return true })
+			}
+		})
+	}
+}
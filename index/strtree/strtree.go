@@ -0,0 +1,301 @@
+// Package strtree implements a static R-tree packed with the
+// Sort-Tile-Recursive algorithm. The tree is built once from all of its
+// items, which makes bulk loading fast and queries efficient, but items
+// cannot be added or removed afterwards.
+package strtree
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// DefaultNodeCapacity is the number of children of each node when no
+// capacity is given.
+const DefaultNodeCapacity = 10
+
+// Item is an envelope with an arbitrary value, such as an edge or polygon.
+type Item struct {
+	Envelope coord.Envelope
+	Value    interface{}
+}
+
+// Visitor is called with each item found by a query. Returning false stops
+// the query.
+type Visitor func(item *Item) bool
+
+// Tree is a packed R-tree. The zero value is an empty tree.
+type Tree struct {
+	// items are the leaf entries in tree order.
+	items []Item
+	// nodes are stored a level at a time from the leaves up, so the root is
+	// the last node. The children of a leaf node are items, those of any
+	// other node are nodes of the level below.
+	nodes []node
+	// height is the number of levels of nodes.
+	height int
+}
+
+// node is a node of the tree, with its children in [start, end).
+type node struct {
+	env        coord.Envelope
+	start, end int
+}
+
+// New builds a tree from items with the default node capacity.
+func New(items []Item) *Tree {
+	return NewWithCapacity(items, DefaultNodeCapacity)
+}
+
+// NewWithCapacity builds a tree from items with at most nodeCapacity
+// children per node. The items are copied, so the slice may be reused.
+func NewWithCapacity(items []Item, nodeCapacity int) *Tree {
+	if nodeCapacity < 2 {
+		nodeCapacity = 2
+	}
+	t := &Tree{items: make([]Item, len(items))}
+	copy(t.items, items)
+	if len(t.items) == 0 {
+		return t
+	}
+
+	// pack the items into leaves
+	strSort(len(t.items), nodeCapacity, func(i int) *coord.Envelope {
+		return &t.items[i].Envelope
+	}, func(i, j int) {
+		t.items[i], t.items[j] = t.items[j], t.items[i]
+	})
+	t.nodes = pack(t.nodes, 0, len(t.items), nodeCapacity, func(i int) *coord.Envelope {
+		return &t.items[i].Envelope
+	})
+	t.height = 1
+
+	// pack each level of nodes into the level above until there is a root
+	start := 0
+	for len(t.nodes)-start > 1 {
+		end := len(t.nodes)
+		level := t.nodes[start:end]
+		strSort(len(level), nodeCapacity, func(i int) *coord.Envelope {
+			return &level[i].env
+		}, func(i, j int) {
+			level[i], level[j] = level[j], level[i]
+		})
+		t.nodes = pack(t.nodes, start, end, nodeCapacity, func(i int) *coord.Envelope {
+			return &t.nodes[i].env
+		})
+		t.height++
+		start = end
+	}
+	return t
+}
+
+// strSort orders n entries so that consecutive runs of nodeCapacity entries
+// are spatially close: the entries are sorted into vertical slices by the X
+// of their centres, and each slice by the Y of their centres.
+func strSort(n, nodeCapacity int, env func(i int) *coord.Envelope, swap func(i, j int)) {
+	numNodes := (n + nodeCapacity - 1) / nodeCapacity
+	numSlices := int(math.Ceil(math.Sqrt(float64(numNodes))))
+	sliceSize := nodeCapacity * ((numNodes + numSlices - 1) / numSlices)
+
+	sort.Sort(&sorter{n: n, swap: swap, key: func(i int) float64 {
+		e := env(i)
+		return e.MinX + e.MaxX
+	}})
+	for start := 0; start < n; start += sliceSize {
+		end := start + sliceSize
+		if end > n {
+			end = n
+		}
+		sort.Sort(&sorter{n: end - start, swap: func(i, j int) {
+			swap(start+i, start+j)
+		}, key: func(i int) float64 {
+			e := env(start + i)
+			return e.MinY + e.MaxY
+		}})
+	}
+}
+
+// pack appends nodes holding consecutive runs of nodeCapacity children in
+// [start, end).
+func pack(nodes []node, start, end, nodeCapacity int, env func(i int) *coord.Envelope) []node {
+	for i := start; i < end; i += nodeCapacity {
+		n := node{start: i, end: i + nodeCapacity}
+		if n.end > end {
+			n.end = end
+		}
+		n.env = *env(i)
+		for j := i + 1; j < n.end; j++ {
+			n.env.ExpandEnvelope(env(j))
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+type sorter struct {
+	n    int
+	swap func(i, j int)
+	key  func(i int) float64
+}
+
+func (s *sorter) Len() int           { return s.n }
+func (s *sorter) Swap(i, j int)      { s.swap(i, j) }
+func (s *sorter) Less(i, j int) bool { return s.key(i) < s.key(j) }
+
+// Len returns the number of items in the tree.
+func (t *Tree) Len() int {
+	return len(t.items)
+}
+
+// Envelope returns the envelope of all items, or nil if the tree is empty.
+func (t *Tree) Envelope() *coord.Envelope {
+	if len(t.nodes) == 0 {
+		return nil
+	}
+	env := t.nodes[len(t.nodes)-1].env
+	return &env
+}
+
+// Items returns the items of the tree, in tree order.
+func (t *Tree) Items() []Item {
+	return t.items
+}
+
+// Query returns the values of the items whose envelopes intersect env.
+func (t *Tree) Query(env *coord.Envelope) []interface{} {
+	var values []interface{}
+	t.Visit(env, func(item *Item) bool {
+		values = append(values, item.Value)
+		return true
+	})
+	return values
+}
+
+// Visit calls visit with each item whose envelope intersects env, until
+// visit returns false. It returns false if the query was stopped.
+func (t *Tree) Visit(env *coord.Envelope, visit Visitor) bool {
+	if len(t.nodes) == 0 || env == nil {
+		return true
+	}
+	root := len(t.nodes) - 1
+	if !t.nodes[root].env.Intersects(env) {
+		return true
+	}
+
+	// the stack holds the nodes to visit with their level, counted from the
+	// leaves
+	type entry struct{ node, level int }
+	stack := []entry{{root, t.height - 1}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := &t.nodes[e.node]
+		if e.level == 0 {
+			for i := n.start; i < n.end; i++ {
+				if t.items[i].Envelope.Intersects(env) && !visit(&t.items[i]) {
+					return false
+				}
+			}
+			continue
+		}
+		// push in reverse so children are visited in order
+		for i := n.end - 1; i >= n.start; i-- {
+			if t.nodes[i].env.Intersects(env) {
+				stack = append(stack, entry{i, e.level - 1})
+			}
+		}
+	}
+	return true
+}
+
+// Neighbour is an item found by a nearest neighbour search.
+type Neighbour struct {
+	Item     *Item
+	Distance float64
+}
+
+// Nearest returns up to k items nearest to env, nearest first. distance
+// returns the exact distance to an item, which must be at least the
+// distance between env and the item's envelope, such as the distance
+// between geometries. Nodes are searched nearest first, and the search
+// ends when the nearest remaining node is no nearer than the k-th item
+// found.
+func (t *Tree) Nearest(env *coord.Envelope, k int, distance func(item *Item) float64) []Neighbour {
+	if len(t.nodes) == 0 || env == nil || k <= 0 {
+		return nil
+	}
+
+	root := len(t.nodes) - 1
+	queue := &nodeQueue{{root, t.height - 1, t.nodes[root].env.Distance(env)}}
+	found := &neighbourHeap{}
+	kth := func() float64 {
+		if found.Len() < k {
+			return math.Inf(1)
+		}
+		return (*found)[0].Distance
+	}
+	for queue.Len() > 0 {
+		e := heap.Pop(queue).(queueEntry)
+		if e.distance >= kth() {
+			break
+		}
+		n := &t.nodes[e.node]
+		for i := n.start; i < n.end; i++ {
+			if e.level > 0 {
+				if d := t.nodes[i].env.Distance(env); d < kth() {
+					heap.Push(queue, queueEntry{i, e.level - 1, d})
+				}
+				continue
+			}
+			item := &t.items[i]
+			if item.Envelope.Distance(env) >= kth() {
+				continue
+			}
+			if d := distance(item); d < kth() {
+				if found.Len() == k {
+					heap.Pop(found)
+				}
+				heap.Push(found, Neighbour{item, d})
+			}
+		}
+	}
+
+	neighbours := make([]Neighbour, found.Len())
+	for i := len(neighbours) - 1; i >= 0; i-- {
+		neighbours[i] = heap.Pop(found).(Neighbour)
+	}
+	return neighbours
+}
+
+type queueEntry struct {
+	node, level int
+	distance    float64
+}
+
+// nodeQueue is a min-heap of nodes by distance.
+type nodeQueue []queueEntry
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(queueEntry)) }
+func (q *nodeQueue) Pop() interface{} {
+	last := (*q)[len(*q)-1]
+	*q = (*q)[:len(*q)-1]
+	return last
+}
+
+// neighbourHeap is a max-heap of neighbours by distance.
+type neighbourHeap []Neighbour
+
+func (h neighbourHeap) Len() int            { return len(h) }
+func (h neighbourHeap) Less(i, j int) bool  { return h[i].Distance > h[j].Distance }
+func (h neighbourHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighbourHeap) Push(x interface{}) { *h = append(*h, x.(Neighbour)) }
+func (h *neighbourHeap) Pop() interface{} {
+	last := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return last
+}
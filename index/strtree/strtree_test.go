@@ -0,0 +1,263 @@
+package strtree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+)
+
+// randomItems returns n items with small envelopes in a 1000 by 1000
+// square, whose values are their indexes.
+func randomItems(n int, seed int64) []Item {
+	r := rand.New(rand.NewSource(seed))
+	items := make([]Item, n)
+	for i := range items {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		items[i] = Item{
+			Envelope: *coord.NewEnvelope(x, x+r.Float64()*10, y, y+r.Float64()*10),
+			Value:    i,
+		}
+	}
+	return items
+}
+
+func queries(n int, seed int64) []*coord.Envelope {
+	r := rand.New(rand.NewSource(seed))
+	envs := make([]*coord.Envelope, n)
+	for i := range envs {
+		x, y, size := r.Float64()*1000, r.Float64()*1000, r.Float64()*100
+		envs[i] = coord.NewEnvelope(x, x+size, y, y+size)
+	}
+	return envs
+}
+
+// bruteForce returns the sorted values of the items intersecting env.
+func bruteForce(items []Item, env *coord.Envelope) []int {
+	var values []int
+	for _, item := range items {
+		if item.Envelope.Intersects(env) {
+			values = append(values, item.Value.(int))
+		}
+	}
+	sort.Ints(values)
+	return values
+}
+
+func sortedInts(values []interface{}) []int {
+	var ints []int
+	for _, v := range values {
+		ints = append(ints, v.(int))
+	}
+	sort.Ints(ints)
+	return ints
+}
+
+// checkTree checks the packing of a tree: every item in exactly one leaf,
+// node envelopes equal to those of their children, and node sizes within
+// the capacity.
+func checkTree(t *testing.T, tree *Tree, n, nodeCapacity int) {
+	t.Helper()
+	if tree.Len() != n {
+		t.Fatalf("Len() = %d, want %d", tree.Len(), n)
+	}
+	if n == 0 {
+		if len(tree.nodes) != 0 || tree.Envelope() != nil {
+			t.Fatalf("empty tree has %d nodes and envelope %v", len(tree.nodes), tree.Envelope())
+		}
+		return
+	}
+
+	seen := make([]bool, n)
+	var check func(i, level int) coord.Envelope
+	check = func(i, level int) coord.Envelope {
+		nd := tree.nodes[i]
+		if size := nd.end - nd.start; size < 1 || size > nodeCapacity {
+			t.Fatalf("node %d has %d children, want 1 to %d", i, size, nodeCapacity)
+		}
+		var env coord.Envelope
+		for j := nd.start; j < nd.end; j++ {
+			var child coord.Envelope
+			if level == 0 {
+				v := tree.items[j].Value.(int)
+				if seen[v] {
+					t.Fatalf("item %d is in more than one leaf", v)
+				}
+				seen[v] = true
+				child = tree.items[j].Envelope
+			} else {
+				child = check(j, level-1)
+			}
+			if j == nd.start {
+				env = child
+			} else {
+				env.ExpandEnvelope(&child)
+			}
+		}
+		if env != nd.env {
+			t.Fatalf("node %d envelope = %v, want %v", i, nd.env, env)
+		}
+		return env
+	}
+	env := check(len(tree.nodes)-1, tree.height-1)
+	for v, ok := range seen {
+		if !ok {
+			t.Fatalf("item %d is in no leaf", v)
+		}
+	}
+	if got := tree.Envelope(); *got != env {
+		t.Fatalf("Envelope() = %v, want %v", *got, env)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 9, 10, 11, 100, 1000, 5000} {
+		for _, nodeCapacity := range []int{2, 4, 10, 16} {
+			t.Run(fmt.Sprintf("%d items capacity %d", n, nodeCapacity), func(t *testing.T) {
+				items := randomItems(n, int64(n))
+				tree := NewWithCapacity(items, nodeCapacity)
+				checkTree(t, tree, n, nodeCapacity)
+			})
+		}
+	}
+}
+
+func TestNewCopiesItems(t *testing.T) {
+	items := randomItems(100, 1)
+	tree := New(items)
+	for i := range items {
+		items[i] = Item{Envelope: *coord.NewEnvelope(-1, -1, -1, -1), Value: -1}
+	}
+	checkTree(t, tree, 100, DefaultNodeCapacity)
+	if got := tree.Query(coord.NewEnvelope(-1, -1, -1, -1)); len(got) != 0 {
+		t.Errorf("Query() = %v after changing the input, want none", got)
+	}
+}
+
+func TestNewDuplicates(t *testing.T) {
+	items := make([]Item, 50)
+	for i := range items {
+		items[i] = Item{Envelope: *coord.NewEnvelope(1, 2, 1, 2), Value: i}
+	}
+	tree := NewWithCapacity(items, 4)
+	checkTree(t, tree, len(items), 4)
+	if got := tree.Query(coord.NewEnvelope(0, 1, 0, 1)); len(got) != len(items) {
+		t.Errorf("Query() found %d items, want %d", len(got), len(items))
+	}
+}
+
+func TestQuery(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 10000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			items := randomItems(n, 2)
+			tree := New(items)
+			for _, env := range queries(200, 3) {
+				got := sortedInts(tree.Query(env))
+				if want := bruteForce(items, env); !reflect.DeepEqual(got, want) {
+					t.Fatalf("Query(%v) = %v, want %v", env, got, want)
+				}
+			}
+			if got := tree.Query(nil); len(got) != 0 {
+				t.Errorf("Query(nil) = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestVisit(t *testing.T) {
+	items := randomItems(1000, 4)
+	tree := NewWithCapacity(items, 4)
+	for _, env := range queries(50, 5) {
+		var visited []interface{}
+		if !tree.Visit(env, func(item *Item) bool {
+			visited = append(visited, item.Value)
+			return true
+		}) {
+			t.Fatalf("Visit(%v) = false without being stopped", env)
+		}
+		want := bruteForce(items, env)
+		if got := sortedInts(visited); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Visit(%v) visited %v, want %v", env, got, want)
+		}
+		if len(want) < 2 {
+			continue
+		}
+
+		count := 0
+		if tree.Visit(env, func(item *Item) bool {
+			count++
+			return count < 2
+		}) {
+			t.Errorf("Visit(%v) = true after being stopped", env)
+		}
+		if count != 2 {
+			t.Errorf("Visit(%v) visited %d items after being stopped at 2", env, count)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	items := randomItems(2000, 6)
+	tree := New(items)
+	// the distance to each item is that to the centre of its envelope,
+	// which is at least that to the envelope
+	centreDistance := func(p coord.Coordinate) func(item *Item) float64 {
+		return func(item *Item) float64 {
+			e := item.Envelope
+			return math.Hypot((e.MinX+e.MaxX)/2-p.X, (e.MinY+e.MaxY)/2-p.Y)
+		}
+	}
+
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 50; i++ {
+		p := coord.Coordinate{X: r.Float64()*1200 - 100, Y: r.Float64()*1200 - 100}
+		distance := centreDistance(p)
+		var want []float64
+		for j := range items {
+			want = append(want, distance(&items[j]))
+		}
+		sort.Float64s(want)
+
+		for _, k := range []int{1, 5, 50} {
+			neighbours := tree.Nearest(p.Envelope(), k, distance)
+			if len(neighbours) != k {
+				t.Fatalf("Nearest(%v, %d) found %d items", p, k, len(neighbours))
+			}
+			for j, nb := range neighbours {
+				if nb.Distance != want[j] {
+					t.Fatalf("Nearest(%v, %d)[%d].Distance = %v, want %v", p, k, j, nb.Distance, want[j])
+				}
+				if d := distance(nb.Item); d != nb.Distance {
+					t.Fatalf("Nearest(%v, %d)[%d] has distance %v, item is at %v", p, k, j, nb.Distance, d)
+				}
+			}
+		}
+	}
+
+	small := New(items[:3])
+	if got := small.Nearest(coord.NewEnvelope(0, 0, 0, 0), 10, centreDistance(coord.Coordinate{})); len(got) != 3 {
+		t.Errorf("Nearest() with k above Len() found %d items, want 3", len(got))
+	}
+	if got := tree.Nearest(coord.NewEnvelope(0, 0, 0, 0), 0, centreDistance(coord.Coordinate{})); got != nil {
+		t.Errorf("Nearest() with k 0 = %v, want nil", got)
+	}
+	if got := New(nil).Nearest(coord.NewEnvelope(0, 0, 0, 0), 1, centreDistance(coord.Coordinate{})); got != nil {
+		t.Errorf("Nearest() on an empty tree = %v, want nil", got)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		items := randomItems(n, 8)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				New(items)
+			}
+		})
+	}
+}
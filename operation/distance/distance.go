@@ -0,0 +1,148 @@
+// Package distance computes the distance between geometries and finds the
+// geometries of a set nearest to another.
+package distance
+
+import (
+	"math"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+	"github.com/simoncochrane/geoz/index/chain"
+)
+
+// Distance returns the shortest distance between two geometries, which is
+// zero if they intersect. A point inside a polygon, or a polygon inside
+// another, intersects it. Empty geometries are infinitely far from any
+// other.
+func Distance(a, b *geom.Geometry) float64 {
+	return newParts(a).distance(newParts(b))
+}
+
+// parts are the components of a geometry used to compute distances.
+type parts struct {
+	// seqs are the points, lines and polygon rings, with points as two
+	// equal coordinates so that every component is made of segments.
+	seqs     []coord.Coordinates
+	segments *chain.Index
+	polygons []polygon
+	env      *coord.Envelope
+}
+
+// polygon locates points in a polygon of a geometry.
+type polygon struct {
+	env     *coord.Envelope
+	locator *graph.IndexedPointInAreaLocator
+}
+
+func newParts(g *geom.Geometry) *parts {
+	p := &parts{env: g.Envelope()}
+	p.add(g)
+	p.segments = chain.NewIndex(p.seqs)
+	return p
+}
+
+func (p *parts) add(g *geom.Geometry) {
+	if g.IsEmpty() {
+		return
+	}
+	switch g.Type {
+	case geom.TypePoint:
+		p.seqs = append(p.seqs, coord.Coordinates{g.Coord, g.Coord})
+	case geom.TypeLineString:
+		if len(g.Line) == 1 {
+			p.seqs = append(p.seqs, coord.Coordinates{g.Line[0], g.Line[0]})
+		} else {
+			p.seqs = append(p.seqs, g.Line)
+		}
+	case geom.TypePolygon:
+		p.seqs = append(p.seqs, g.Line)
+		p.seqs = append(p.seqs, g.MultiLine...)
+		// only non-areal geometries are rejected
+		locator, _ := graph.NewIndexedPointInAreaLocator(g)
+		p.polygons = append(p.polygons, polygon{g.Envelope(), locator})
+	default:
+		for _, col := range g.Collection {
+			p.add(col)
+		}
+	}
+}
+
+func (p *parts) numSegments() int {
+	n := 0
+	for _, seq := range p.seqs {
+		n += len(seq) - 1
+	}
+	return n
+}
+
+func (p *parts) distance(other *parts) float64 {
+	if p.env == nil || other.env == nil {
+		return math.Inf(1)
+	}
+	if p.inside(other) || other.inside(p) {
+		return 0
+	}
+
+	// visit the segments of the smaller geometry, finding those of the
+	// other within the nearest distance so far
+	a, b := p, other
+	if a.numSegments() > b.numSegments() {
+		a, b = b, a
+	}
+	min := a.seqs[0][0].Distance(b.seqs[0][0])
+	li := coord.NewRobustLineIntersector()
+	for _, seq := range a.seqs {
+		for i := 1; i < len(seq); i++ {
+			p0, p1 := seq[i-1], seq[i]
+			b.segments.Query(nearby(p0, p1, min), func(s chain.Segment) bool {
+				q0, q1 := s.Coordinates()
+				min = math.Min(min, segmentDistance(li, p0, p1, q0, q1))
+				return min > 0
+			})
+			if min == 0 {
+				return 0
+			}
+		}
+	}
+	return min
+}
+
+// inside returns whether a component of p lies within or on a polygon of
+// other. A component which is not entirely inside or outside a polygon
+// crosses its boundary, so it is enough to test one point of each.
+func (p *parts) inside(other *parts) bool {
+	for _, polygon := range other.polygons {
+		for _, seq := range p.seqs {
+			if polygon.env.ContainsCoord(seq[0]) && polygon.locator.Locate(seq[0]) != coord.LocationExterior {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nearby returns the envelope of the points within d of a segment.
+func nearby(p0, p1 coord.Coordinate, d float64) *coord.Envelope {
+	env := coord.NewEnvelopeFromCoords(p0, p1)
+	env.MinX -= d
+	env.MaxX += d
+	env.MinY -= d
+	env.MaxY += d
+	return env
+}
+
+// segmentDistance returns the distance between two segments, either of
+// which may be a single point.
+func segmentDistance(li *coord.RobustLineIntersector, p0, p1, q0, q1 coord.Coordinate) float64 {
+	if !p0.Equals2D(p1) && !q0.Equals2D(q1) {
+		li.ComputeLineIntersection(p0, p1, q0, q1)
+		if li.HasIntersection() {
+			return 0
+		}
+	}
+	return math.Min(
+		math.Min(coord.DistancePointToSegment(p0, q0, q1), coord.DistancePointToSegment(p1, q0, q1)),
+		math.Min(coord.DistancePointToSegment(q0, p0, p1), coord.DistancePointToSegment(q1, p0, p1)),
+	)
+}
@@ -0,0 +1,172 @@
+package distance
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func mustUnmarshal(t testing.TB, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDistance(t *testing.T) {
+	const square = "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (4 4, 6 4, 6 6, 4 6, 4 4))"
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"points", "POINT (0 0)", "POINT (3 4)", 5},
+		{"same point", "POINT (1 1)", "POINT (1 1)", 0},
+		{"point and line", "POINT (5 5)", "LINESTRING (0 0, 10 0)", 5},
+		{"point on line", "POINT (5 0)", "LINESTRING (0 0, 10 0)", 0},
+		{"crossing lines", "LINESTRING (0 0, 10 10)", "LINESTRING (0 10, 10 0)", 0},
+		{"parallel lines", "LINESTRING (0 0, 10 0)", "LINESTRING (2 3, 8 3)", 3},
+		{"line end", "LINESTRING (0 0, 10 0)", "LINESTRING (13 4, 20 10)", 5},
+		{"single point line", "LINESTRING (3 4)", "POINT (0 0)", 5},
+		{"point in polygon", "POINT (2 2)", square, 0},
+		{"point on boundary", "POINT (0 5)", square, 0},
+		{"point in hole", "POINT (5 5.5)", square, 0.5},
+		{"point outside", "POINT (13 14)", square, 5},
+		{"line in polygon", "LINESTRING (1 1, 2 2)", square, 0},
+		{"line in hole", "LINESTRING (4.5 5, 5.5 5)", square, 0.5},
+		{"polygon in polygon", "POLYGON ((1 1, 2 1, 2 2, 1 1))", square, 0},
+		{"polygon containing polygon", square, "POLYGON ((1 1, 2 1, 2 2, 1 1))", 0},
+		{"polygon in hole", "POLYGON ((4.5 4.5, 5.5 4.5, 5.5 5.5, 4.5 4.5))", square, 0.5},
+		{"polygons", "POLYGON ((13 0, 20 0, 20 10, 13 0))", square, 3},
+		{"multipoint", "MULTIPOINT ((20 20), (11 5))", square, 1},
+		{"collection", "GEOMETRYCOLLECTION (POINT (20 20), LINESTRING (-2 -2, -2 12))", square, 2},
+		{"empty", "POINT EMPTY", square, math.Inf(1)},
+		{"empty collection", "POINT (0 0)", "GEOMETRYCOLLECTION EMPTY", math.Inf(1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := mustUnmarshal(t, test.a), mustUnmarshal(t, test.b)
+			if got := Distance(a, b); math.Abs(got-test.want) > 1e-9 && got != test.want {
+				t.Errorf("Distance() = %v, want %v", got, test.want)
+			}
+			if got := Distance(b, a); math.Abs(got-test.want) > 1e-9 && got != test.want {
+				t.Errorf("Distance() reversed = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// randomLine returns a line of n points in a random walk from (x, y).
+func randomLine(r *rand.Rand, x, y float64, n int) *geom.Geometry {
+	line := make(coord.Coordinates, n)
+	for i := range line {
+		line[i] = coord.Coordinate{X: x, Y: y}
+		x += r.Float64()*2 - 1
+		y += r.Float64()*2 - 1
+	}
+	g, _ := geom.NewLineString(line)
+	return g
+}
+
+// segmentsDistance returns the distance between two lines by comparing
+// every pair of segments.
+func segmentsDistance(a, b coord.Coordinates) float64 {
+	li := coord.NewRobustLineIntersector()
+	min := math.Inf(1)
+	for i := 1; i < len(a); i++ {
+		for j := 1; j < len(b); j++ {
+			min = math.Min(min, segmentDistance(li, a[i-1], a[i], b[j-1], b[j]))
+		}
+	}
+	return min
+}
+
+func TestDistanceLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		a := randomLine(r, 0, 0, 200)
+		b := randomLine(r, r.Float64()*20, r.Float64()*20, 200)
+		if got, want := Distance(a, b), segmentsDistance(a.Line, b.Line); got != want {
+			t.Fatalf("Distance() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	depots := []*geom.Geometry{
+		mustUnmarshal(t, "POINT (20 20)"),
+		mustUnmarshal(t, "POINT (12 5)"),
+		mustUnmarshal(t, "POINT EMPTY"),
+		mustUnmarshal(t, "POINT (5 -3)"),
+		mustUnmarshal(t, "POINT (-1 -1)"),
+		mustUnmarshal(t, "POINT (5 14)"),
+		mustUnmarshal(t, "POINT (2 3)"),
+	}
+	// the depot at (2 3) is inside the polygon, and the one at (12 5) is
+	// nearer its edge than the corner of its envelope
+	area := mustUnmarshal(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))")
+
+	tests := []struct {
+		name string
+		k    int
+		want []int
+	}{
+		{"three nearest depots", 3, []int{6, 4, 1}},
+		{"nearest", 1, []int{6}},
+		{"none", 0, nil},
+		{"k larger than the number of depots", 10, []int{6, 4, 1, 3, 5, 0}},
+	}
+	idx := NewIndex(depots)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			neighbours := idx.Nearest(area, test.k)
+			if len(neighbours) != len(test.want) {
+				t.Fatalf("Nearest() found %d, want %d", len(neighbours), len(test.want))
+			}
+			for i, n := range neighbours {
+				if n.Index != test.want[i] || n.Geometry != depots[n.Index] {
+					t.Errorf("Nearest()[%d] = depot %d, want %d", i, n.Index, test.want[i])
+				}
+				if d := Distance(area, depots[n.Index]); n.Distance != d {
+					t.Errorf("Nearest()[%d].Distance = %v, want %v", i, n.Distance, d)
+				}
+			}
+		})
+	}
+
+	if n := Nearest(depots, mustUnmarshal(t, "POLYGON EMPTY"), 3); len(n) != 0 {
+		t.Errorf("Nearest() of an empty geometry = %v", n)
+	}
+}
+
+func TestNearestLines(t *testing.T) {
+	// the nearest of many lines match those found by comparing with each
+	r := rand.New(rand.NewSource(2))
+	var lines []*geom.Geometry
+	for i := 0; i < 300; i++ {
+		lines = append(lines, randomLine(r, r.Float64()*100, r.Float64()*100, 10))
+	}
+	target := mustUnmarshal(t, "POLYGON ((40 40, 60 40, 50 55, 40 40))")
+	want := make([]float64, len(lines))
+	for i, line := range lines {
+		want[i] = Distance(target, line)
+	}
+	sort.Float64s(want)
+
+	neighbours := NewIndex(lines).Nearest(target, 20)
+	if len(neighbours) != 20 {
+		t.Fatalf("Nearest() found %d, want 20", len(neighbours))
+	}
+	for i, n := range neighbours {
+		if n.Distance != want[i] {
+			t.Errorf("Nearest()[%d].Distance = %v, want %v", i, n.Distance, want[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package distance
+
+import (
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/index/strtree"
+)
+
+// Index is a set of geometries indexed for nearest neighbour searches.
+type Index struct {
+	geometries []*geom.Geometry
+	parts      []*parts
+	tree       *strtree.Tree
+}
+
+// Neighbour is a geometry found by a nearest neighbour search.
+type Neighbour struct {
+	Geometry *geom.Geometry
+	// Index is the index of the geometry in the indexed set.
+	Index    int
+	Distance float64
+}
+
+// NewIndex indexes geometries. Empty geometries are never found.
+func NewIndex(geometries []*geom.Geometry) *Index {
+	idx := &Index{
+		geometries: geometries,
+		parts:      make([]*parts, len(geometries)),
+	}
+	var items []strtree.Item
+	for i, g := range geometries {
+		idx.parts[i] = newParts(g)
+		if env := g.Envelope(); env != nil {
+			items = append(items, strtree.Item{Envelope: *env, Value: i})
+		}
+	}
+	idx.tree = strtree.New(items)
+	return idx
+}
+
+// Nearest returns up to k of the indexed geometries nearest to g, nearest
+// first, using exact distances between the geometries. Geometries are
+// searched in order of the distance to their envelopes, stopping when no
+// remaining geometry can be nearer than the k-th found.
+func (idx *Index) Nearest(g *geom.Geometry, k int) []Neighbour {
+	target := newParts(g)
+	found := idx.tree.Nearest(g.Envelope(), k, func(item *strtree.Item) float64 {
+		return target.distance(idx.parts[item.Value.(int)])
+	})
+	neighbours := make([]Neighbour, len(found))
+	for i, n := range found {
+		j := n.Item.Value.(int)
+		neighbours[i] = Neighbour{Geometry: idx.geometries[j], Index: j, Distance: n.Distance}
+	}
+	return neighbours
+}
+
+// Nearest returns up to k of geometries nearest to g, nearest first. To
+// search the same geometries repeatedly, use an Index.
+func Nearest(geometries []*geom.Geometry, g *geom.Geometry, k int) []Neighbour {
+	return NewIndex(geometries).Nearest(g, k)
+}
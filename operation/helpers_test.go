@@ -0,0 +1,102 @@
+package operation
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func mustUnmarshal(t testing.TB, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", text, err)
+	}
+	return g
+}
+
+// normalized returns the WKT of a geometry in a normal form, so that
+// geometries with the same parts compare equal whatever the order of the
+// parts and the start points and directions of lines and rings.
+func normalized(t testing.TB, g *geom.Geometry) string {
+	t.Helper()
+	text, err := wkt.Marshal(normalize(t, g))
+	if err != nil {
+		t.Fatalf("failed to write WKT: %v", err)
+	}
+	return text
+}
+
+func normalize(t testing.TB, g *geom.Geometry) *geom.Geometry {
+	t.Helper()
+	if g.IsEmpty() {
+		return g
+	}
+	var n *geom.Geometry
+	var err error
+	switch g.Type {
+	case geom.TypePoint:
+		return g
+	case geom.TypeLineString:
+		line := g.Line
+		if less(line[len(line)-1], line[0]) {
+			line = line.Reverse()
+		}
+		n, err = geom.NewLineString(line)
+	case geom.TypePolygon:
+		var holes coord.MultiLine
+		for _, hole := range g.MultiLine {
+			holes = append(holes, normalizeRing(hole, false))
+		}
+		sort.Slice(holes, func(i, j int) bool { return less(holes[i][0], holes[j][0]) })
+		n, err = geom.NewPolygon(normalizeRing(g.Line, true), holes)
+	default:
+		parts := make([]*geom.Geometry, len(g.Collection))
+		keys := make(map[*geom.Geometry]string, len(parts))
+		for i, part := range g.Collection {
+			parts[i] = normalize(t, part)
+			keys[parts[i]], _ = wkt.Marshal(parts[i])
+		}
+		sort.Slice(parts, func(i, j int) bool { return keys[parts[i]] < keys[parts[j]] })
+		switch g.Type {
+		case geom.TypeMultiPoint:
+			n, err = geom.NewMultiPoint(parts)
+		case geom.TypeMultiLineString:
+			n, err = geom.NewMultiLineString(parts)
+		case geom.TypeMultiPolygon:
+			n, err = geom.NewMultiPolygon(parts)
+		default:
+			n, err = geom.NewCollection(parts)
+		}
+	}
+	if err != nil {
+		t.Fatalf("failed to normalize %v: %v", g.Type, err)
+	}
+	return n
+}
+
+// normalizeRing orients a closed ring and starts it at its least vertex.
+func normalizeRing(ring coord.Coordinates, ccw bool) coord.Coordinates {
+	pts := append(coord.Coordinates(nil), ring[:len(ring)-1]...)
+	if (coord.SignedArea(ring) > 0) != ccw {
+		pts = pts.Reverse()
+	}
+	start := 0
+	for i, c := range pts {
+		if less(c, pts[start]) {
+			start = i
+		}
+	}
+	pts = append(pts[start:], pts[:start]...)
+	return append(pts, pts[0])
+}
+
+func less(a, b coord.Coordinate) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	return a.Y < b.Y
+}
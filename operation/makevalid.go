@@ -0,0 +1,177 @@
+package operation
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+)
+
+// MakeValid repairs an invalid geometry, returning the valid geometry which
+// best represents it.
+//
+// Polygon rings are noded against each other and the resulting faces are
+// kept according to the even-odd rule within each polygon, and merged across
+// the polygons of a MultiPolygon. This splits bow-tie polygons into their
+// lobes and resolves self-overlapping rings. Collapsed components are
+// removed; if a geometry collapses entirely it is returned with a lower
+// dimension (e.g. a zero-area polygon becomes a LineString or Point). The
+// result keeps the SRID and layout of the geometry.
+func MakeValid(g *geom.Geometry) (*geom.Geometry, error) {
+	valid, err := makeValid(g)
+	if err != nil {
+		return nil, err
+	}
+	return withProperties(valid, g.SRID, g.Layout), nil
+}
+
+func makeValid(g *geom.Geometry) (*geom.Geometry, error) {
+	switch g.Type {
+	case geom.TypePoint, geom.TypeMultiPoint:
+		return g, nil
+	case geom.TypeLineString:
+		return makeValidLines([]*geom.Geometry{g}, false)
+	case geom.TypeMultiLineString:
+		return makeValidLines(g.Collection, true)
+	case geom.TypePolygon:
+		return makeValidPolygons([]*geom.Geometry{g}, false)
+	case geom.TypeMultiPolygon:
+		return makeValidPolygons(g.Collection, true)
+	case geom.TypeCollection:
+		var collection []*geom.Geometry
+		for _, col := range g.Collection {
+			valid, err := makeValid(col)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if !valid.IsEmpty() {
+				collection = append(collection, valid)
+			}
+		}
+		return geom.NewCollection(collection)
+	}
+	return nil, errors.Errorf("unsupported geometry type for MakeValid: %v", g.Type)
+}
+
+func makeValidLines(lines []*geom.Geometry, multi bool) (*geom.Geometry, error) {
+	var valid []*geom.Geometry
+	var collapsed []*geom.Geometry
+	for _, line := range lines {
+		if line.IsEmpty() {
+			continue
+		}
+		coords := line.Line.RemoveRepeated()
+		if len(coords) == 1 {
+			point, err := geom.NewPoint(coords[0])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			collapsed = append(collapsed, point)
+			continue
+		}
+		ls, err := geom.NewLineString(coords)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		valid = append(valid, ls)
+	}
+
+	switch {
+	case len(valid) == 0 && len(collapsed) == 1:
+		return collapsed[0], nil
+	case len(valid) == 0 && len(collapsed) > 1:
+		return geom.NewMultiPoint(collapsed)
+	case len(valid) == 1 && !multi:
+		return valid[0], nil
+	}
+	return geom.NewMultiLineString(valid)
+}
+
+func makeValidPolygons(polygons []*geom.Geometry, multi bool) (*geom.Geometry, error) {
+	// the closed rings of each polygon, used to determine which faces are kept
+	var polyRings [][]coord.Coordinates
+	var lines []coord.Coordinates
+	for _, poly := range polygons {
+		if poly.IsEmpty() {
+			continue
+		}
+		var rings []coord.Coordinates
+		for _, ring := range append([]coord.Coordinates{poly.Line}, poly.MultiLine...) {
+			ring = ring.RemoveRepeated()
+			if len(ring) == 0 {
+				continue
+			}
+			if !ring[0].Equals2D(ring[len(ring)-1]) {
+				ring = append(ring, ring[0])
+			}
+			rings = append(rings, ring)
+			lines = append(lines, ring)
+		}
+		polyRings = append(polyRings, rings)
+	}
+
+	if len(lines) == 0 {
+		if multi {
+			return geom.NewMultiPolygon(nil)
+		}
+		return geom.NewPolygon(nil, nil)
+	}
+
+	segments := NodeLines(lines)
+	if len(segments) == 0 {
+		return geom.NewPoint(lines[0][0])
+	}
+	pg := graph.NewPlanarGraph()
+	for _, s := range segments {
+		pg.AddEdge(s[0], s[1])
+	}
+
+	// a face is kept if an interior point of it lies inside an odd number
+	// of the rings of any polygon, and the union with nothing keeps exactly
+	// those faces
+	pg.LabelFaces(func(geomIndex int, point coord.Coordinate) bool {
+		return geomIndex == 0 && insideOddRings(point, polyRings)
+	})
+	pg.LabelResult(graph.OverlayUnion)
+	result, err := pg.BuildPolygons()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	switch {
+	case len(result) == 0:
+		// the polygon has collapsed, so return the linework instead
+		var collapsed []*geom.Geometry
+		for _, line := range pg.MergeLines(func(*graph.DirectedEdge) bool { return true }) {
+			ls, err := geom.NewLineString(line)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			collapsed = append(collapsed, ls)
+		}
+		if len(collapsed) == 1 {
+			return collapsed[0], nil
+		}
+		return geom.NewMultiLineString(collapsed)
+	case len(result) == 1 && !multi:
+		return result[0], nil
+	}
+	return geom.NewMultiPolygon(result)
+}
+
+// insideOddRings returns whether a point lies inside an odd number of the
+// rings of any polygon.
+func insideOddRings(point coord.Coordinate, polyRings [][]coord.Coordinates) bool {
+	for _, rings := range polyRings {
+		count := 0
+		for _, ring := range rings {
+			if coord.PointInRing(point, ring) == coord.LocationInterior {
+				count++
+			}
+		}
+		if count%2 == 1 {
+			return true
+		}
+	}
+	return false
+}
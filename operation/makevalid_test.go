@@ -0,0 +1,125 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestMakeValid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "valid polygon is unchanged",
+			in:   "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,2 8,8 8,8 2,2 2))",
+			want: "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,2 8,8 8,8 2,2 2))",
+		},
+		{
+			name: "bow-tie is split into its lobes",
+			in:   "POLYGON((0 0,10 10,10 0,0 10,0 0))",
+			want: "MULTIPOLYGON(((0 0,5 5,0 10,0 0)),((5 5,10 0,10 10,5 5)))",
+		},
+		{
+			name: "self-overlapping ring keeps the area covered once",
+			in:   "POLYGON((0 0,10 0,10 10,0 10,0 0,5 5,15 5,15 15,5 15,5 5,0 0))",
+			want: "MULTIPOLYGON(((0 0,10 0,10 5,5 5,5 10,0 10,0 0)),((5 10,10 10,10 5,15 5,15 15,5 15,5 10)))",
+		},
+		{
+			name: "hole crossing the shell",
+			in:   "POLYGON((0 0,10 0,10 10,0 10,0 0),(5 5,15 5,15 15,5 15,5 5))",
+			want: "MULTIPOLYGON(((0 0,10 0,10 5,5 5,5 10,0 10,0 0)),((5 10,10 10,10 5,15 5,15 15,5 15,5 10)))",
+		},
+		{
+			name: "ring without closing point",
+			in:   "POLYGON((0 0,10 0,10 10,0 10))",
+			want: "POLYGON((0 0,10 0,10 10,0 10,0 0))",
+		},
+		{
+			name: "overlapping polygons of a multipolygon are merged",
+			in:   "MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0)),((5 5,15 5,15 15,5 15,5 5)))",
+			want: "MULTIPOLYGON(((0 0,10 0,10 5,15 5,15 15,5 15,5 10,0 10,0 0)))",
+		},
+		{
+			name: "collapsed part of a multipolygon is removed",
+			in:   "MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0)),((20 0,30 0,25 0,20 0)))",
+			want: "MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0)))",
+		},
+		{
+			name: "polygon collapsed to a line",
+			in:   "POLYGON((0 0,10 0,5 0,0 0))",
+			want: "LINESTRING(0 0,5 0,10 0)",
+		},
+		{
+			name: "polygon collapsed to a point",
+			in:   "POLYGON((1 1,1 1,1 1,1 1))",
+			want: "POINT(1 1)",
+		},
+		{
+			name: "line with repeated points",
+			in:   "LINESTRING(0 0,0 0,1 1,1 1)",
+			want: "LINESTRING(0 0,1 1)",
+		},
+		{
+			name: "line collapsed to a point",
+			in:   "LINESTRING(1 1,1 1)",
+			want: "POINT(1 1)",
+		},
+		{
+			name: "collection with collapsed parts of lower dimension",
+			in:   "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,5 0,0 0)),POINT(1 1),POLYGON((0 0,10 10,10 0,0 10,0 0)))",
+			want: "GEOMETRYCOLLECTION(LINESTRING(0 0,5 0,10 0),MULTIPOLYGON(((0 0,5 5,0 10,0 0)),((5 5,10 0,10 10,5 5))),POINT(1 1))",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MakeValid(mustUnmarshal(t, test.in))
+			if err != nil {
+				t.Fatalf("MakeValid() error = %v", err)
+			}
+			if got, want := normalized(t, got), normalized(t, mustUnmarshal(t, test.want)); got != want {
+				t.Errorf("MakeValid() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMakeValidProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		wantSRID   int
+		wantLayout geom.Layout
+	}{
+		{"valid point", "SRID=4326;POINT Z(1 1 1)", 4326, geom.LayoutXYZ},
+		{"bow-tie", "SRID=4326;POLYGON Z((0 0 1,10 10 1,10 0 1,0 10 1,0 0 1))", 4326, geom.LayoutXYZ},
+		{"collapsed polygon", "SRID=3857;POLYGON M((0 0 1,10 0 1,5 0 1,0 0 1))", 3857, geom.LayoutXYM},
+		{"collection", "SRID=4326;GEOMETRYCOLLECTION ZM(POLYGON ZM((0 0 1 2,10 10 1 2,10 0 1 2,0 10 1 2,0 0 1 2)),POINT ZM(1 1 1 2))", 4326, geom.LayoutXYZM},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MakeValid(mustUnmarshal(t, test.in))
+			if err != nil {
+				t.Fatalf("MakeValid() error = %v", err)
+			}
+			if got.SRID != test.wantSRID {
+				t.Errorf("MakeValid() SRID = %d, want %d", got.SRID, test.wantSRID)
+			}
+			var check func(g *geom.Geometry)
+			check = func(g *geom.Geometry) {
+				if g.Layout != test.wantLayout {
+					t.Errorf("MakeValid() %v has layout %v, want %v", g.Type, g.Layout, test.wantLayout)
+				}
+				for _, part := range g.Collection {
+					if part.SRID != 0 {
+						t.Errorf("MakeValid() part %v has SRID %d, want 0", part.Type, part.SRID)
+					}
+					check(part)
+				}
+			}
+			check(got)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package operation
+
+import (
+	"sort"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/index/chain"
+)
+
+// Segment is a line segment between two coordinates.
+type Segment [2]coord.Coordinate
+
+// nodedSegment is a segment together with the points where it is intersected
+// by other segments.
+type nodedSegment struct {
+	Segment
+	nodes coord.Coordinates
+	// line is the index of the line the segment belongs to.
+	line int
+}
+
+// NodeLines computes the full noding of a set of lines: every line is split
+// at each point where it intersects itself or another line. The resulting
+// segments only meet at their endpoints. Duplicate segments (in either
+// direction) are returned once.
+func NodeLines(lines []coord.Coordinates) []Segment {
+	segments, _ := nodeLines(lines)
+	return segments
+}
+
+// nodeLines nodes lines as NodeLines does, also returning the indexes of the
+// lines each segment came from. Candidate pairs of segments are found with
+// a monotone chain index.
+func nodeLines(lines []coord.Coordinates) ([]Segment, [][]int) {
+	var segs []*nodedSegment
+	// segAt holds the segment starting at each point of each line, or nil
+	// for repeated points
+	segAt := make([][]*nodedSegment, len(lines))
+	for i, line := range lines {
+		segAt[i] = make([]*nodedSegment, len(line))
+		for j := 1; j < len(line); j++ {
+			if line[j-1].Equals2D(line[j]) {
+				continue
+			}
+			s := &nodedSegment{Segment: Segment{line[j-1], line[j]}, line: i}
+			segAt[i][j-1] = s
+			segs = append(segs, s)
+		}
+	}
+
+	li := coord.NewRobustLineIntersector()
+	chain.NewIndex(lines).SelfOverlaps(func(a, b chain.Segment) bool {
+		s0 := segAt[a.Chain.Value.(int)][a.Index]
+		s1 := segAt[b.Chain.Value.(int)][b.Index]
+		if s0 == nil || s1 == nil {
+			return true
+		}
+		li.ComputeLineIntersection(s0.Segment[0], s0.Segment[1], s1.Segment[0], s1.Segment[1])
+		for k := 0; k < li.NumIntersections(); k++ {
+			intPt := li.IntersectionAt(k)
+			s0.nodes = append(s0.nodes, intPt)
+			s1.nodes = append(s1.nodes, intPt)
+		}
+		return true
+	})
+
+	return splitSegments(segs)
+}
+
+// splitSegments splits each segment at its nodes, removing duplicates and
+// recording the lines each resulting segment came from.
+func splitSegments(segs []*nodedSegment) ([]Segment, [][]int) {
+	seen := map[[2]coord.XY]int{}
+	var result []Segment
+	var sources [][]int
+	for _, s := range segs {
+		start := s.Segment[0]
+		pts := append(coord.Coordinates{start, s.Segment[1]}, s.nodes...)
+		sort.SliceStable(pts, func(i, j int) bool {
+			return distanceSq(start, pts[i]) < distanceSq(start, pts[j])
+		})
+		pts = pts.RemoveRepeated()
+
+		for i := 1; i < len(pts); i++ {
+			k0, k1 := pts[i-1].XY(), pts[i].XY()
+			index, ok := seen[[2]coord.XY{k0, k1}]
+			if !ok {
+				index, ok = seen[[2]coord.XY{k1, k0}]
+			}
+			if !ok {
+				index = len(result)
+				seen[[2]coord.XY{k0, k1}] = index
+				result = append(result, Segment{pts[i-1], pts[i]})
+				sources = append(sources, nil)
+			}
+			if n := len(sources[index]); n == 0 || sources[index][n-1] != s.line {
+				sources[index] = append(sources[index], s.line)
+			}
+		}
+	}
+	return result, sources
+}
+
+func distanceSq(a, b coord.Coordinate) float64 {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return dx*dx + dy*dy
+}
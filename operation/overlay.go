@@ -0,0 +1,379 @@
+package operation
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+)
+
+// Intersection returns the points shared by two geometries.
+func Intersection(a, b *geom.Geometry) (*geom.Geometry, error) {
+	return Overlay(a, b, graph.OverlayIntersection)
+}
+
+// Union returns the points of either of two geometries.
+func Union(a, b *geom.Geometry) (*geom.Geometry, error) {
+	return Overlay(a, b, graph.OverlayUnion)
+}
+
+// Difference returns the points of a which are not in b.
+func Difference(a, b *geom.Geometry) (*geom.Geometry, error) {
+	return Overlay(a, b, graph.OverlayDifference)
+}
+
+// SymDifference returns the points which are in one of two geometries but
+// not both.
+func SymDifference(a, b *geom.Geometry) (*geom.Geometry, error) {
+	return Overlay(a, b, graph.OverlaySymDifference)
+}
+
+// Overlay combines two geometries of any types with a set-theoretic
+// operation. Polygonal inputs must be valid.
+//
+// The lines and rings of both geometries are noded together and the faces
+// of the resulting planar graph are located in each geometry. Each
+// half-edge is then labelled with the locations on it and on its left and
+// right in both geometries. The result is made of the faces selected by the
+// operation, the edges selected by it which no selected face covers, and
+// the nodes and input points selected by it which no other part of the
+// result covers. Polygons are assembled from the edges between selected and
+// unselected faces, and lines are merged at nodes where they do not branch.
+// A result with parts of several dimensions is a collection.
+//
+// The geometries must have the same SRID, which the result takes. The
+// result only has the Z and M ordinates which both geometries have.
+func Overlay(a, b *geom.Geometry, op graph.OverlayOp) (*geom.Geometry, error) {
+	srid, layout, err := properties(a, b)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	result, err := computeOverlay(a, b, op)
+	if err != nil {
+		return nil, err
+	}
+	return withProperties(result, srid, layout), nil
+}
+
+// computeOverlay returns the overlay of two geometries, ignoring their SRIDs
+// and layouts.
+func computeOverlay(a, b *geom.Geometry, op graph.OverlayOp) (*geom.Geometry, error) {
+	switch op {
+	case graph.OverlayIntersection, graph.OverlayUnion, graph.OverlayDifference, graph.OverlaySymDifference:
+	default:
+		return nil, errors.Errorf("unsupported overlay operation: %v", op)
+	}
+
+	var inputs [2]*overlayInput
+	for i, g := range []*geom.Geometry{a, b} {
+		in, err := newOverlayInput(g)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to prepare geometry %d for overlay", i)
+		}
+		inputs[i] = in
+	}
+
+	o := &overlay{op: op, inputs: inputs}
+	o.label()
+	polygons, err := o.pg.BuildPolygons()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lines, err := o.pg.BuildLines()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	points, err := o.buildPoints(lines)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return o.result(polygons, lines, points)
+}
+
+// overlayInput holds the components of an overlay argument.
+type overlayInput struct {
+	geometry *geom.Geometry
+	points   coord.Coordinates
+	lines    []coord.Coordinates
+	// rings are the rings of the polygons, closed and without repeated
+	// points.
+	rings []coord.Coordinates
+	// area locates points in the polygons, if there are any.
+	area *graph.IndexedPointInAreaLocator
+}
+
+func newOverlayInput(g *geom.Geometry) (*overlayInput, error) {
+	in := &overlayInput{geometry: g}
+	var polygons []*geom.Geometry
+	in.add(g, &polygons)
+	if len(polygons) > 0 {
+		mp, err := geom.NewMultiPolygon(polygons)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if in.area, err = graph.NewIndexedPointInAreaLocator(mp); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return in, nil
+}
+
+func (in *overlayInput) add(g *geom.Geometry, polygons *[]*geom.Geometry) {
+	if g.IsEmpty() {
+		return
+	}
+	switch g.Type {
+	case geom.TypePoint:
+		in.points = append(in.points, g.Coord)
+	case geom.TypeLineString:
+		in.lines = append(in.lines, g.Line)
+	case geom.TypePolygon:
+		for _, ring := range append(coord.MultiLine{g.Line}, g.MultiLine...) {
+			ring = ring.RemoveRepeated()
+			if len(ring) == 0 {
+				continue
+			}
+			if !ring[0].Equals2D(ring[len(ring)-1]) {
+				ring = append(ring, ring[0])
+			}
+			in.rings = append(in.rings, ring)
+		}
+		*polygons = append(*polygons, g)
+	default:
+		for _, col := range g.Collection {
+			in.add(col, polygons)
+		}
+	}
+}
+
+// locateArea returns the location of a point in the polygons.
+func (in *overlayInput) locateArea(c coord.Coordinate) coord.Location {
+	if in.area == nil {
+		return coord.LocationExterior
+	}
+	return in.area.Locate(c)
+}
+
+// locate returns the location of a point in the geometry.
+func (in *overlayInput) locate(c coord.Coordinate) coord.Location {
+	if loc := in.locateArea(c); loc != coord.LocationExterior {
+		return loc
+	}
+	for _, line := range in.lines {
+		if line.Envelope().ContainsCoord(c) && coord.PointOnLine(c, line) {
+			return coord.LocationInterior
+		}
+	}
+	for _, p := range in.points {
+		if p.Equals2D(c) {
+			return coord.LocationInterior
+		}
+	}
+	return coord.LocationExterior
+}
+
+// overlay holds the planar graph of the noded linework of both inputs.
+type overlay struct {
+	op     graph.OverlayOp
+	inputs [2]*overlayInput
+	pg     *graph.PlanarGraph
+}
+
+// label nodes the linework of the inputs into a planar graph, labels its
+// edges with their locations in each input and marks the result.
+func (o *overlay) label() {
+	var lines []coord.Coordinates
+	// owners records which input and whether a line or ring each noded
+	// line came from
+	type owner struct {
+		input  int
+		isLine bool
+	}
+	var owners []owner
+	for i, in := range o.inputs {
+		for _, line := range in.lines {
+			lines = append(lines, line)
+			owners = append(owners, owner{i, true})
+		}
+		for _, ring := range in.rings {
+			lines = append(lines, ring)
+			owners = append(owners, owner{i, false})
+		}
+	}
+
+	segments, sources := nodeLines(lines)
+	o.pg = graph.NewPlanarGraph()
+	for i, s := range segments {
+		e := o.pg.AddEdge(s[0], s[1])
+		for _, line := range sources[i] {
+			if owner := owners[line]; owner.isLine {
+				e.Label.SetLocation(owner.input, coord.LocationInterior)
+				e.Sym.Label.SetLocation(owner.input, coord.LocationInterior)
+			}
+		}
+	}
+	o.pg.LabelFaces(func(i int, point coord.Coordinate) bool {
+		return o.inputs[i].locateArea(point) == coord.LocationInterior
+	})
+	o.pg.LabelResult(o.op)
+}
+
+// buildPoints returns the nodes and input points selected by the operation
+// which are not covered by the result polygons or lines.
+func (o *overlay) buildPoints(lines []*geom.Geometry) ([]*geom.Geometry, error) {
+	var points []*geom.Geometry
+	added := map[coord.XY]bool{}
+	add := func(c coord.Coordinate) error {
+		added[c.XY()] = true
+		p, err := geom.NewPoint(c)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		points = append(points, p)
+		return nil
+	}
+
+	var inputPoints [2]map[coord.XY]bool
+	for i, in := range o.inputs {
+		inputPoints[i] = map[coord.XY]bool{}
+		for _, c := range in.points {
+			inputPoints[i][c.XY()] = true
+		}
+	}
+
+	// a node is in an input if any edge at it or any input point on it is,
+	// and is covered if any edge or face of the result at it is
+	for _, node := range o.pg.Nodes() {
+		key := node.XY()
+		var locs [2]coord.Location
+		for i := range locs {
+			locs[i] = coord.LocationExterior
+			if inputPoints[i][key] {
+				locs[i] = coord.LocationInterior
+			}
+		}
+		covered := false
+		for _, e := range o.pg.Star(node) {
+			for i := range locs {
+				if e.Label.LocationAt(i, graph.PositionOn) != coord.LocationExterior {
+					locs[i] = coord.LocationInterior
+				}
+			}
+			if e.InResultArea || e.InResultLine {
+				covered = true
+			}
+		}
+		if !covered && graph.IsResultOfOp(locs[0], locs[1], o.op) && !added[key] {
+			if err := add(node); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// input points which are not nodes are covered by a face of the result
+	// if the operation selects their locations in the input areas, as it
+	// selects the faces by the same locations
+	for _, in := range o.inputs {
+		for _, c := range in.points {
+			key := c.XY()
+			if added[key] || o.pg.Star(c) != nil {
+				continue
+			}
+			if !graph.IsResultOfOp(o.inputs[0].locate(c), o.inputs[1].locate(c), o.op) {
+				continue
+			}
+			if graph.IsResultOfOp(o.inputs[0].locateArea(c), o.inputs[1].locateArea(c), o.op) {
+				continue
+			}
+			onLine := false
+			for _, line := range lines {
+				if coord.PointOnLine(c, line.Line) {
+					onLine = true
+					break
+				}
+			}
+			if !onLine {
+				if err := add(c); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// result assembles the parts of the result. An empty result has the
+// dimension the operation would give it.
+func (o *overlay) result(polygons, lines, points []*geom.Geometry) (*geom.Geometry, error) {
+	var parts []*geom.Geometry
+	for _, p := range []struct {
+		parts []*geom.Geometry
+		multi func([]*geom.Geometry) (*geom.Geometry, error)
+	}{
+		{polygons, geom.NewMultiPolygon},
+		{lines, geom.NewMultiLineString},
+		{points, geom.NewMultiPoint},
+	} {
+		switch len(p.parts) {
+		case 0:
+		case 1:
+			parts = append(parts, p.parts[0])
+		default:
+			m, err := p.multi(p.parts)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			parts = append(parts, m)
+		}
+	}
+	switch {
+	case len(parts) == 1:
+		return parts[0], nil
+	case len(parts) > 1:
+		var collection []*geom.Geometry
+		for _, part := range parts {
+			if part.Type == geom.TypeMultiPolygon || part.Type == geom.TypeMultiLineString || part.Type == geom.TypeMultiPoint {
+				collection = append(collection, part.Collection...)
+			} else {
+				collection = append(collection, part)
+			}
+		}
+		return geom.NewCollection(collection)
+	}
+
+	dim0, dim1 := dimension(o.inputs[0].geometry), dimension(o.inputs[1].geometry)
+	dim := dim0
+	switch o.op {
+	case graph.OverlayIntersection:
+		if dim1 < dim {
+			dim = dim1
+		}
+	case graph.OverlayUnion, graph.OverlaySymDifference:
+		if dim1 > dim {
+			dim = dim1
+		}
+	}
+	switch dim {
+	case 0:
+		return geom.NewEmptyPoint()
+	case 1:
+		return geom.NewLineString(nil)
+	case 2:
+		return geom.NewPolygon(nil, nil)
+	}
+	return geom.NewCollection(nil)
+}
+
+// dimension returns the dimension of a geometry, which for an empty
+// geometry is that of its type, or -1 for an empty collection.
+func dimension(g *geom.Geometry) int {
+	switch g.Type {
+	case geom.TypeMultiPoint:
+		return 0
+	case geom.TypeMultiLineString:
+		return 1
+	case geom.TypeMultiPolygon:
+		return 2
+	}
+	return g.Dimension()
+}
@@ -0,0 +1,259 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+)
+
+const (
+	square        = "POLYGON((0 0,10 0,10 10,0 10,0 0))"
+	shiftedSquare = "POLYGON((5 5,15 5,15 15,5 15,5 5))"
+)
+
+func TestOverlay(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		op   graph.OverlayOp
+		want string
+	}{
+		// polygon/polygon
+		{
+			name: "intersection of overlapping polygons",
+			a:    square, b: shiftedSquare, op: graph.OverlayIntersection,
+			want: "POLYGON((5 5,10 5,10 10,5 10,5 5))",
+		},
+		{
+			name: "union of overlapping polygons",
+			a:    square, b: shiftedSquare, op: graph.OverlayUnion,
+			want: "POLYGON((0 0,10 0,10 5,15 5,15 15,5 15,5 10,0 10,0 0))",
+		},
+		{
+			name: "difference of overlapping polygons",
+			a:    square, b: shiftedSquare, op: graph.OverlayDifference,
+			want: "POLYGON((0 0,10 0,10 5,5 5,5 10,0 10,0 0))",
+		},
+		{
+			name: "symmetric difference of overlapping polygons",
+			a:    square, b: shiftedSquare, op: graph.OverlaySymDifference,
+			want: "MULTIPOLYGON(((0 0,10 0,10 5,5 5,5 10,0 10,0 0)),((10 5,15 5,15 15,5 15,5 10,10 10,10 5)))",
+		},
+		{
+			name: "union of adjacent polygons",
+			a:    square, b: "POLYGON((10 0,20 0,20 10,10 10,10 0))", op: graph.OverlayUnion,
+			want: "POLYGON((0 0,10 0,20 0,20 10,10 10,0 10,0 0))",
+		},
+		{
+			name: "intersection of adjacent polygons",
+			a:    square, b: "POLYGON((10 0,20 0,20 10,10 10,10 0))", op: graph.OverlayIntersection,
+			want: "LINESTRING(10 0,10 10)",
+		},
+		{
+			name: "union of disjoint polygons",
+			a:    square, b: "POLYGON((20 0,30 0,30 10,20 0))", op: graph.OverlayUnion,
+			want: "MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0)),((20 0,30 0,30 10,20 0)))",
+		},
+		{
+			name: "intersection of disjoint polygons",
+			a:    square, b: "POLYGON((20 0,30 0,30 10,20 0))", op: graph.OverlayIntersection,
+			want: "POLYGON EMPTY",
+		},
+		{
+			name: "intersection with a polygon with a hole",
+			a:    "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,4 2,4 4,2 4,2 2))", b: "POLYGON((1 1,5 1,5 5,1 5,1 1))", op: graph.OverlayIntersection,
+			want: "POLYGON((1 1,5 1,5 5,1 5,1 1),(2 2,4 2,4 4,2 4,2 2))",
+		},
+		{
+			name: "difference making a hole",
+			a:    square, b: "POLYGON((2 2,4 2,4 4,2 4,2 2))", op: graph.OverlayDifference,
+			want: "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,4 2,4 4,2 4,2 2))",
+		},
+		{
+			name: "union with an empty polygon",
+			a:    square, b: "POLYGON EMPTY", op: graph.OverlayUnion,
+			want: square,
+		},
+		{
+			name: "intersection with an empty polygon",
+			a:    square, b: "POLYGON EMPTY", op: graph.OverlayIntersection,
+			want: "POLYGON EMPTY",
+		},
+
+		// polygon/line
+		{
+			name: "line clipped to a polygon",
+			a:    "LINESTRING(-5 5,15 5)", b: square, op: graph.OverlayIntersection,
+			want: "LINESTRING(0 5,10 5)",
+		},
+		{
+			name: "line outside a polygon",
+			a:    "LINESTRING(-5 5,15 5)", b: square, op: graph.OverlayDifference,
+			want: "MULTILINESTRING((-5 5,0 5),(10 5,15 5))",
+		},
+		{
+			name: "polygon less a line",
+			a:    square, b: "LINESTRING(-5 5,15 5)", op: graph.OverlayDifference,
+			want: "POLYGON((0 0,10 0,10 5,10 10,0 10,0 5,0 0))",
+		},
+		{
+			name: "union of a polygon and a line",
+			a:    square, b: "LINESTRING(-5 5,15 5)", op: graph.OverlayUnion,
+			want: "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 5,10 10,0 10,0 5,0 0)),LINESTRING(-5 5,0 5),LINESTRING(10 5,15 5))",
+		},
+		{
+			name: "line along a polygon boundary",
+			a:    "LINESTRING(0 0,10 0)", b: square, op: graph.OverlayIntersection,
+			want: "LINESTRING(0 0,10 0)",
+		},
+
+		// line/line
+		{
+			name: "crossing lines",
+			a:    "LINESTRING(0 0,10 10)", b: "LINESTRING(0 10,10 0)", op: graph.OverlayIntersection,
+			want: "POINT(5 5)",
+		},
+		{
+			name: "overlapping lines",
+			a:    "LINESTRING(0 0,10 0)", b: "LINESTRING(5 0,15 0)", op: graph.OverlayIntersection,
+			want: "LINESTRING(5 0,10 0)",
+		},
+		{
+			name: "union of overlapping lines",
+			a:    "LINESTRING(0 0,10 0)", b: "LINESTRING(5 0,15 0)", op: graph.OverlayUnion,
+			want: "LINESTRING(0 0,5 0,10 0,15 0)",
+		},
+
+		// point/area and point/line
+		{
+			name: "point in a polygon",
+			a:    "POINT(5 5)", b: square, op: graph.OverlayIntersection,
+			want: "POINT(5 5)",
+		},
+		{
+			name: "point on a polygon boundary",
+			a:    "POINT(0 5)", b: square, op: graph.OverlayIntersection,
+			want: "POINT(0 5)",
+		},
+		{
+			name: "point outside a polygon",
+			a:    "POINT(20 20)", b: square, op: graph.OverlayIntersection,
+			want: "POINT EMPTY",
+		},
+		{
+			name: "point less a polygon",
+			a:    "MULTIPOINT((5 5),(20 20))", b: square, op: graph.OverlayDifference,
+			want: "POINT(20 20)",
+		},
+		{
+			name: "union of a polygon and points",
+			a:    square, b: "MULTIPOINT((5 5),(0 0),(20 20))", op: graph.OverlayUnion,
+			want: "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 10,0 10,0 0)),POINT(20 20))",
+		},
+		{
+			name: "union of a polygon with a hole and points",
+			a:    "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,8 2,8 8,2 8,2 2))", b: "MULTIPOINT((5 5),(1 1),(5 0),(2 5))", op: graph.OverlayUnion,
+			want: "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,8 2,8 8,2 8,2 2)),POINT(5 5))",
+		},
+		{
+			name: "points in the intersection of polygons",
+			a:    "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 10,0 10,0 0)),MULTIPOINT((7 7),(2 2),(12 12),(20 20)))", b: shiftedSquare, op: graph.OverlayIntersection,
+			want: "GEOMETRYCOLLECTION(POLYGON((5 5,10 5,10 10,5 10,5 5)),POINT(12 12))",
+		},
+		{
+			name: "symmetric difference of points and a polygon",
+			a:    "MULTIPOINT((2 2),(10 5),(20 20))", b: square, op: graph.OverlaySymDifference,
+			want: "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 10,0 10,0 0)),POINT(20 20))",
+		},
+		{
+			name: "point on a line",
+			a:    "LINESTRING(0 0,10 0)", b: "POINT(5 0)", op: graph.OverlayUnion,
+			want: "LINESTRING(0 0,10 0)",
+		},
+		{
+			name: "point at the end of a line",
+			a:    "POINT(10 0)", b: "LINESTRING(0 0,10 0)", op: graph.OverlayIntersection,
+			want: "POINT(10 0)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Overlay(mustUnmarshal(t, test.a), mustUnmarshal(t, test.b), test.op)
+			if err != nil {
+				t.Fatalf("Overlay() error = %v", err)
+			}
+			if got, want := normalized(t, got), normalized(t, mustUnmarshal(t, test.want)); got != want {
+				t.Errorf("Overlay() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestOverlayFunctions(t *testing.T) {
+	a, b := mustUnmarshal(t, square), mustUnmarshal(t, shiftedSquare)
+	tests := []struct {
+		name string
+		f    func(a, b *geom.Geometry) (*geom.Geometry, error)
+		op   graph.OverlayOp
+	}{
+		{"Intersection", Intersection, graph.OverlayIntersection},
+		{"Union", Union, graph.OverlayUnion},
+		{"Difference", Difference, graph.OverlayDifference},
+		{"SymDifference", SymDifference, graph.OverlaySymDifference},
+	}
+	for _, test := range tests {
+		got, err := test.f(a, b)
+		if err != nil {
+			t.Fatalf("%s() error = %v", test.name, err)
+		}
+		want, _ := Overlay(a, b, test.op)
+		if normalized(t, got) != normalized(t, want) {
+			t.Errorf("%s() = %s, want %s", test.name, normalized(t, got), normalized(t, want))
+		}
+	}
+
+	if _, err := Overlay(a, b, graph.OverlayOp(0)); err == nil {
+		t.Error("Overlay() with an invalid operation, want an error")
+	}
+}
+
+func TestOverlayProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       string
+		op         graph.OverlayOp
+		wantSRID   int
+		wantLayout geom.Layout
+	}{
+		{"SRID", "SRID=4326;" + square, "SRID=4326;" + shiftedSquare, graph.OverlayUnion, 4326, geom.LayoutXY},
+		{"SRID of an empty result", "SRID=3857;POINT(20 20)", "SRID=3857;" + square, graph.OverlayIntersection, 3857, geom.LayoutXY},
+		{"Z", "POLYGON Z((0 0 1,10 0 1,10 10 1,0 10 1,0 0 1))", "POLYGON Z((5 5 2,15 5 2,15 15 2,5 15 2,5 5 2))", graph.OverlaySymDifference, 0, geom.LayoutXYZ},
+		{"Z and M", "SRID=4326;LINESTRING ZM(0 0 1 2,10 0 1 2)", "SRID=4326;LINESTRING M(5 0 3,15 0 3)", graph.OverlayUnion, 4326, geom.LayoutXYM},
+		{"Z and XY", "LINESTRING Z(0 0 1,10 10 1)", "LINESTRING(0 10,10 0)", graph.OverlayIntersection, 0, geom.LayoutXY},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Overlay(mustUnmarshal(t, test.a), mustUnmarshal(t, test.b), test.op)
+			if err != nil {
+				t.Fatalf("Overlay() error = %v", err)
+			}
+			if got.SRID != test.wantSRID {
+				t.Errorf("Overlay() SRID = %d, want %d", got.SRID, test.wantSRID)
+			}
+			if got.Layout != test.wantLayout {
+				t.Errorf("Overlay() layout = %v, want %v", got.Layout, test.wantLayout)
+			}
+			for _, part := range got.Collection {
+				if part.SRID != 0 || part.Layout != test.wantLayout {
+					t.Errorf("Overlay() part has SRID %d and layout %v, want 0 and %v", part.SRID, part.Layout, test.wantLayout)
+				}
+			}
+		})
+	}
+
+	a, b := mustUnmarshal(t, "SRID=4326;"+square), mustUnmarshal(t, "SRID=3857;"+shiftedSquare)
+	if _, err := Overlay(a, b, graph.OverlayUnion); err == nil {
+		t.Error("Overlay() of geometries with different SRIDs, want an error")
+	}
+}
@@ -0,0 +1,39 @@
+package operation
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+// properties returns the SRID and layout of the result of an operation on
+// geometries: the SRID they share, and the ordinates all of them have.
+func properties(gs ...*geom.Geometry) (int, geom.Layout, error) {
+	if len(gs) == 0 {
+		return 0, geom.LayoutXY, nil
+	}
+	srid, hasZ, hasM := gs[0].SRID, true, true
+	for _, g := range gs {
+		if g.SRID != srid {
+			return 0, geom.LayoutXY, errors.Errorf("geometries have different SRIDs: %d and %d", srid, g.SRID)
+		}
+		hasZ = hasZ && g.Layout.HasZ()
+		hasM = hasM && g.Layout.HasM()
+	}
+	return srid, geom.NewLayout(hasZ, hasM), nil
+}
+
+// withProperties returns a copy of a geometry with an SRID and a layout.
+// As when reading a geometry, the layout is set on every part and the SRID
+// only on the geometry itself.
+func withProperties(g *geom.Geometry, srid int, layout geom.Layout) *geom.Geometry {
+	out := &geom.Geometry{}
+	*out = *g
+	out.SRID, out.Layout = srid, layout
+	if g.Collection != nil {
+		out.Collection = make([]*geom.Geometry, len(g.Collection))
+		for i, col := range g.Collection {
+			out.Collection[i] = withProperties(col, 0, layout)
+		}
+	}
+	return out
+}
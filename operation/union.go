@@ -0,0 +1,185 @@
+package operation
+
+import (
+	"github.com/pkg/errors"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+	"github.com/simoncochrane/geoz/index/curve"
+)
+
+// UnaryUnion returns the union of the components of a geometry. See
+// UnionAll.
+func UnaryUnion(g *geom.Geometry) (*geom.Geometry, error) {
+	return UnionAll([]*geom.Geometry{g})
+}
+
+// UnionAll returns the union of geometries of any types. Overlapping
+// polygons are dissolved, lines are noded and merged, and lines and points
+// covered by other components are removed. Each polygon must be valid, but
+// polygons may overlap each other. The union of no components is an empty
+// collection.
+//
+// The geometries must have the same SRID, which the result takes. The
+// result only has the Z and M ordinates which all the geometries have.
+//
+// Polygons are unioned in cascade: they are sorted along a Hilbert curve
+// and neighbouring groups are unioned pairwise, so that each union combines
+// geometries which are close together and of similar size.
+func UnionAll(gs []*geom.Geometry) (*geom.Geometry, error) {
+	srid, layout, err := properties(gs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var c components
+	for _, g := range gs {
+		c.add(g)
+	}
+
+	result, err := cascadedUnion(c.polygons)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to union polygons")
+	}
+	if len(c.lines) > 0 {
+		lines, err := geom.NewMultiLineString(c.lines)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if result, err = computeOverlay(result, lines, graph.OverlayUnion); err != nil {
+			return nil, errors.Wrap(err, "failed to union lines")
+		}
+	}
+	if len(c.points) > 0 {
+		points, err := geom.NewMultiPoint(c.points)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if result, err = computeOverlay(result, points, graph.OverlayUnion); err != nil {
+			return nil, errors.Wrap(err, "failed to union points")
+		}
+	}
+	return withProperties(result, srid, layout), nil
+}
+
+// CascadedUnion returns the union of valid polygons, which may overlap each
+// other, as a polygon or multipolygon. The union of no polygons is an empty
+// collection. The SRID and layout of the result are those given by
+// UnionAll.
+func CascadedUnion(polygons []*geom.Geometry) (*geom.Geometry, error) {
+	srid, layout, err := properties(polygons...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	result, err := cascadedUnion(polygons)
+	if err != nil {
+		return nil, err
+	}
+	return withProperties(result, srid, layout), nil
+}
+
+// cascadedUnion returns the union of polygons, ignoring their SRIDs and
+// layouts.
+func cascadedUnion(polygons []*geom.Geometry) (*geom.Geometry, error) {
+	if len(polygons) == 0 {
+		return geom.NewCollection(nil)
+	}
+	sorted := append([]*geom.Geometry(nil), polygons...)
+	curve.SortHilbert(sorted)
+	return cascade(sorted)
+}
+
+// cascade unions the two halves of a run of geometries, each unioned in
+// the same way.
+func cascade(gs []*geom.Geometry) (*geom.Geometry, error) {
+	if len(gs) == 1 {
+		return gs[0], nil
+	}
+	mid := len(gs) / 2
+	a, err := cascade(gs[:mid])
+	if err != nil {
+		return nil, err
+	}
+	b, err := cascade(gs[mid:])
+	if err != nil {
+		return nil, err
+	}
+	return unionPolygonal(a, b)
+}
+
+// unionPolygonal unions two polygonal geometries. Only the polygons which
+// intersect the envelope shared by the geometries can overlap polygons of
+// the other, so the rest are added to the result without an overlay.
+func unionPolygonal(a, b *geom.Geometry) (*geom.Geometry, error) {
+	common := a.Envelope().Intersection(b.Envelope())
+	var result []*geom.Geometry
+	var overlapping [2][]*geom.Geometry
+	for i, g := range []*geom.Geometry{a, b} {
+		for _, p := range polygonsOf(g) {
+			if p.Envelope().Intersects(common) {
+				overlapping[i] = append(overlapping[i], p)
+			} else {
+				result = append(result, p)
+			}
+		}
+	}
+
+	if len(overlapping[0]) > 0 && len(overlapping[1]) > 0 {
+		var inputs [2]*geom.Geometry
+		for i, polygons := range overlapping {
+			var err error
+			if inputs[i], err = geom.NewMultiPolygon(polygons); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		u, err := computeOverlay(inputs[0], inputs[1], graph.OverlayUnion)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, polygonsOf(u)...)
+	} else {
+		result = append(result, overlapping[0]...)
+		result = append(result, overlapping[1]...)
+	}
+
+	if len(result) == 1 {
+		return result[0], nil
+	}
+	return geom.NewMultiPolygon(result)
+}
+
+// polygonsOf returns the non-empty polygons of a polygonal geometry.
+func polygonsOf(g *geom.Geometry) []*geom.Geometry {
+	if g.IsEmpty() {
+		return nil
+	}
+	if g.Type == geom.TypePolygon {
+		return []*geom.Geometry{g}
+	}
+	var polygons []*geom.Geometry
+	for _, p := range g.Collection {
+		polygons = append(polygons, polygonsOf(p)...)
+	}
+	return polygons
+}
+
+// components holds the non-empty basic components of geometries.
+type components struct {
+	polygons, lines, points []*geom.Geometry
+}
+
+func (c *components) add(g *geom.Geometry) {
+	if g.IsEmpty() {
+		return
+	}
+	switch g.Type {
+	case geom.TypePolygon:
+		c.polygons = append(c.polygons, g)
+	case geom.TypeLineString:
+		c.lines = append(c.lines, g)
+	case geom.TypePoint:
+		c.points = append(c.points, g)
+	default:
+		for _, col := range g.Collection {
+			c.add(col)
+		}
+	}
+}
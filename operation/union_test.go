@@ -0,0 +1,271 @@
+package operation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+)
+
+func TestUnionAll(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want string
+	}{
+		{
+			name: "adjacent polygons are dissolved",
+			in:   []string{square, "POLYGON((10 0,20 0,20 10,10 10,10 0))"},
+			want: "POLYGON((0 0,10 0,20 0,20 10,10 10,0 10,0 0))",
+		},
+		{
+			name: "overlapping polygons are dissolved",
+			in:   []string{square, shiftedSquare, "POLYGON((12 -5,20 -5,20 8,12 8,12 -5))"},
+			want: "POLYGON((0 0,10 0,10 5,12 5,12 -5,20 -5,20 8,15 8,15 15,5 15,5 10,0 10,0 0))",
+		},
+		{
+			name: "disjoint polygons are kept",
+			in:   []string{square, "POLYGON((20 0,30 0,30 10,20 0))"},
+			want: "MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0)),((20 0,30 0,30 10,20 0)))",
+		},
+		{
+			name: "polygons enclosing a hole",
+			in: []string{
+				"POLYGON((0 0,10 0,10 2,0 2,0 0))", "POLYGON((0 8,10 8,10 10,0 10,0 8))",
+				"POLYGON((0 2,2 2,2 8,0 8,0 2))", "POLYGON((8 2,10 2,10 8,8 8,8 2))",
+			},
+			want: "POLYGON((0 0,10 0,10 2,10 8,10 10,0 10,0 8,0 2,0 0),(2 2,2 8,8 8,8 2,2 2))",
+		},
+		{
+			name: "line partly covered by a polygon",
+			in:   []string{square, "LINESTRING(5 5,15 5)"},
+			want: "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 5,10 10,0 10,0 0)),LINESTRING(10 5,15 5))",
+		},
+		{
+			name: "line covered by a polygon",
+			in:   []string{square, "LINESTRING(2 2,8 8)"},
+			want: square,
+		},
+		{
+			name: "crossing lines are noded",
+			in:   []string{"LINESTRING(0 0,10 10)", "LINESTRING(0 10,10 0)"},
+			want: "MULTILINESTRING((0 0,5 5),(5 5,10 10),(0 10,5 5),(5 5,10 0))",
+		},
+		{
+			name: "touching lines are merged",
+			in:   []string{"LINESTRING(0 0,5 0)", "LINESTRING(5 0,10 0)"},
+			want: "LINESTRING(0 0,5 0,10 0)",
+		},
+		{
+			name: "point on a line",
+			in:   []string{"LINESTRING(0 0,10 0)", "POINT(5 0)"},
+			want: "LINESTRING(0 0,10 0)",
+		},
+		{
+			name: "point at the end of a line",
+			in:   []string{"LINESTRING(0 0,10 0)", "POINT(10 0)"},
+			want: "LINESTRING(0 0,10 0)",
+		},
+		{
+			name: "point off a line",
+			in:   []string{"LINESTRING(0 0,10 0)", "POINT(5 1)"},
+			want: "GEOMETRYCOLLECTION(LINESTRING(0 0,10 0),POINT(5 1))",
+		},
+		{
+			name: "point in a polygon",
+			in:   []string{"POINT(5 5)", square},
+			want: square,
+		},
+		{
+			name: "repeated points",
+			in:   []string{"MULTIPOINT((1 1),(2 2))", "POINT(1 1)"},
+			want: "MULTIPOINT((1 1),(2 2))",
+		},
+		{
+			name: "nested collections and empty geometries",
+			in:   []string{"GEOMETRYCOLLECTION(POLYGON EMPTY,MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0))))", "POINT EMPTY"},
+			want: square,
+		},
+		{
+			name: "nothing",
+			want: "GEOMETRYCOLLECTION EMPTY",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gs []*geom.Geometry
+			for _, text := range test.in {
+				gs = append(gs, mustUnmarshal(t, text))
+			}
+			got, err := UnionAll(gs)
+			if err != nil {
+				t.Fatalf("UnionAll() error = %v", err)
+			}
+			if got, want := normalized(t, got), normalized(t, mustUnmarshal(t, test.want)); got != want {
+				t.Errorf("UnionAll() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnaryUnion(t *testing.T) {
+	g := mustUnmarshal(t, "GEOMETRYCOLLECTION(POLYGON((0 0,10 0,10 10,0 10,0 0)),POLYGON((5 5,15 5,15 15,5 15,5 5)),POINT(1 1))")
+	got, err := UnaryUnion(g)
+	if err != nil {
+		t.Fatalf("UnaryUnion() error = %v", err)
+	}
+	want := "POLYGON((0 0,10 0,10 5,15 5,15 15,5 15,5 10,0 10,0 0))"
+	if got, want := normalized(t, got), normalized(t, mustUnmarshal(t, want)); got != want {
+		t.Errorf("UnaryUnion() = %s, want %s", got, want)
+	}
+}
+
+func TestUnionProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         []string
+		wantSRID   int
+		wantLayout geom.Layout
+	}{
+		{"SRID", []string{"SRID=4326;" + square, "SRID=4326;" + shiftedSquare}, 4326, geom.LayoutXY},
+		{"SRID of disjoint polygons", []string{"SRID=4326;" + square, "SRID=4326;POLYGON((20 0,30 0,30 10,20 0))"}, 4326, geom.LayoutXY},
+		{"SRID of mixed types", []string{"SRID=4326;" + square, "SRID=4326;LINESTRING(-5 5,15 5)", "SRID=4326;POINT(20 20)"}, 4326, geom.LayoutXY},
+		{"Z", []string{"GEOMETRYCOLLECTION Z(POLYGON Z((0 0 1,10 0 1,10 10 1,0 10 1,0 0 1)),POINT Z(20 20 1))"}, 0, geom.LayoutXYZ},
+		{"Z and XY", []string{"SRID=3857;POLYGON Z((0 0 1,10 0 1,10 10 1,0 10 1,0 0 1))", "SRID=3857;POLYGON((20 0,30 0,30 10,20 0))"}, 3857, geom.LayoutXY},
+		{"no geometries", nil, 0, geom.LayoutXY},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gs []*geom.Geometry
+			var layouts []geom.Layout
+			for _, text := range test.in {
+				g := mustUnmarshal(t, text)
+				gs = append(gs, g)
+				layouts = append(layouts, g.Layout)
+			}
+			got, err := UnionAll(gs)
+			if err != nil {
+				t.Fatalf("UnionAll() error = %v", err)
+			}
+			if got.SRID != test.wantSRID || got.Layout != test.wantLayout {
+				t.Errorf("UnionAll() has SRID %d and layout %v, want %d and %v", got.SRID, got.Layout, test.wantSRID, test.wantLayout)
+			}
+			for _, part := range got.Collection {
+				if part.SRID != 0 || part.Layout != test.wantLayout {
+					t.Errorf("UnionAll() part has SRID %d and layout %v, want 0 and %v", part.SRID, part.Layout, test.wantLayout)
+				}
+			}
+			for i, g := range gs {
+				if g.Layout != layouts[i] {
+					t.Errorf("UnionAll() changed the layout of geometry %d to %v", i, g.Layout)
+				}
+			}
+		})
+	}
+
+	polygons := grid(t, 3, nil)
+	for _, p := range polygons {
+		p.SRID = 4326
+	}
+	got, err := CascadedUnion(polygons)
+	if err != nil {
+		t.Fatalf("CascadedUnion() error = %v", err)
+	}
+	if got.SRID != 4326 {
+		t.Errorf("CascadedUnion() SRID = %d, want 4326", got.SRID)
+	}
+
+	polygons[4].SRID = 3857
+	if _, err := CascadedUnion(polygons); err == nil {
+		t.Error("CascadedUnion() of polygons with different SRIDs, want an error")
+	}
+	if _, err := UnionAll(polygons); err == nil {
+		t.Error("UnionAll() of geometries with different SRIDs, want an error")
+	}
+}
+
+// grid returns n by n adjacent unit squares, leaving out those for which
+// skip returns true.
+func grid(t testing.TB, n int, skip func(x, y int) bool) []*geom.Geometry {
+	var polygons []*geom.Geometry
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			if skip != nil && skip(x, y) {
+				continue
+			}
+			x0, y0 := float64(x), float64(y)
+			p, err := geom.NewPolygon(coord.Coordinates{
+				{X: x0, Y: y0}, {X: x0 + 1, Y: y0}, {X: x0 + 1, Y: y0 + 1}, {X: x0, Y: y0 + 1}, {X: x0, Y: y0},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			polygons = append(polygons, p)
+		}
+	}
+	return polygons
+}
+
+// area returns the area of a polygonal geometry.
+func area(g *geom.Geometry) float64 {
+	a := 0.0
+	for _, p := range polygonsOf(g) {
+		a += math.Abs(coord.SignedArea(p.Line))
+		for _, hole := range p.MultiLine {
+			a -= math.Abs(coord.SignedArea(hole))
+		}
+	}
+	return a
+}
+
+func TestCascadedUnion(t *testing.T) {
+	tests := []struct {
+		name     string
+		skip     func(x, y int) bool
+		polygons int
+		holes    int
+		area     float64
+	}{
+		{"grid", nil, 1, 0, 400},
+		{"grid with holes", func(x, y int) bool { return x%5 == 2 && y%5 == 2 }, 1, 16, 384},
+		{"alternate rows", func(x, y int) bool { return y%2 == 1 }, 10, 0, 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CascadedUnion(grid(t, 20, test.skip))
+			if err != nil {
+				t.Fatalf("CascadedUnion() error = %v", err)
+			}
+			polygons := polygonsOf(got)
+			holes := 0
+			for _, p := range polygons {
+				holes += len(p.MultiLine)
+			}
+			if len(polygons) != test.polygons || holes != test.holes || area(got) != test.area {
+				t.Errorf("CascadedUnion() = %d polygons with %d holes and area %v, want %d with %d and %v",
+					len(polygons), holes, area(got), test.polygons, test.holes, test.area)
+			}
+		})
+	}
+
+	if got, err := CascadedUnion(nil); err != nil || got.Type != geom.TypeCollection || !got.IsEmpty() {
+		t.Errorf("CascadedUnion(nil) = %v, %v, want an empty collection", got, err)
+	}
+}
+
+// BenchmarkCascadedUnion dissolves grids of adjacent squares, as when
+// dissolving administrative boundaries.
+func BenchmarkCascadedUnion(b *testing.B) {
+	for _, n := range []int{10, 40, 100} {
+		polygons := grid(b, n, nil)
+		b.Run(fmt.Sprint(len(polygons)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := CascadedUnion(polygons); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
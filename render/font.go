@@ -0,0 +1,102 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"unicode"
+)
+
+// The built-in font has glyphs of 3 by 5 pixels, drawn at glyphScale.
+const (
+	glyphWidth   = 3
+	glyphHeight  = 5
+	glyphScale   = 2
+	glyphAdvance = (glyphWidth + 1) * glyphScale
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'0':  {"###", "#.#", "#.#", "#.#", "###"},
+	'1':  {".#.", "##.", ".#.", ".#.", "###"},
+	'2':  {"###", "..#", "###", "#..", "###"},
+	'3':  {"###", "..#", ".##", "..#", "###"},
+	'4':  {"#.#", "#.#", "###", "..#", "..#"},
+	'5':  {"###", "#..", "###", "..#", "###"},
+	'6':  {"###", "#..", "###", "#.#", "###"},
+	'7':  {"###", "..#", "..#", ".#.", ".#."},
+	'8':  {"###", "#.#", "###", "#.#", "###"},
+	'9':  {"###", "#.#", "###", "..#", "###"},
+	'A':  {".#.", "#.#", "###", "#.#", "#.#"},
+	'B':  {"##.", "#.#", "##.", "#.#", "##."},
+	'C':  {".##", "#..", "#..", "#..", ".##"},
+	'D':  {"##.", "#.#", "#.#", "#.#", "##."},
+	'E':  {"###", "#..", "##.", "#..", "###"},
+	'F':  {"###", "#..", "##.", "#..", "#.."},
+	'G':  {".##", "#..", "#.#", "#.#", ".##"},
+	'H':  {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I':  {"###", ".#.", ".#.", ".#.", "###"},
+	'J':  {"..#", "..#", "..#", "#.#", ".#."},
+	'K':  {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L':  {"#..", "#..", "#..", "#..", "###"},
+	'M':  {"#.#", "###", "###", "#.#", "#.#"},
+	'N':  {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O':  {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P':  {"##.", "#.#", "##.", "#..", "#.."},
+	'Q':  {".#.", "#.#", "#.#", "##.", ".##"},
+	'R':  {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S':  {".##", "#..", ".#.", "..#", "##."},
+	'T':  {"###", ".#.", ".#.", ".#.", ".#."},
+	'U':  {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V':  {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W':  {"#.#", "#.#", "###", "###", "#.#"},
+	'X':  {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y':  {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z':  {"###", "..#", ".#.", "#..", "###"},
+	' ':  {"...", "...", "...", "...", "..."},
+	'.':  {"...", "...", "...", "...", ".#."},
+	',':  {"...", "...", "...", ".#.", "#.."},
+	':':  {"...", ".#.", "...", ".#.", "..."},
+	';':  {"...", ".#.", "...", ".#.", "#.."},
+	'-':  {"...", "...", "###", "...", "..."},
+	'+':  {"...", ".#.", "###", ".#.", "..."},
+	'_':  {"...", "...", "...", "...", "###"},
+	'/':  {"..#", "..#", ".#.", "#..", "#.."},
+	'(':  {".#.", "#..", "#..", "#..", ".#."},
+	')':  {".#.", "..#", "..#", "..#", ".#."},
+	'[':  {"##.", "#..", "#..", "#..", "##."},
+	']':  {".##", "..#", "..#", "..#", ".##"},
+	'=':  {"...", "###", "...", "###", "..."},
+	'<':  {"..#", ".#.", "#..", ".#.", "..#"},
+	'>':  {"#..", ".#.", "..#", ".#.", "#.."},
+	'!':  {".#.", ".#.", ".#.", "...", ".#."},
+	'?':  {"##.", "..#", ".#.", "...", ".#."},
+	'\'': {".#.", ".#.", "...", "...", "..."},
+	'*':  {"...", "#.#", ".#.", "#.#", "..."},
+	'|':  {".#.", ".#.", ".#.", ".#.", ".#."},
+	'#':  {"#.#", "###", "#.#", "###", "#.#"},
+}
+
+// drawText sets the pixels of text in a mask, with the bottom left of the
+// first glyph at x, y. Lower case letters are drawn in upper case and
+// other unknown characters as '?'.
+func drawText(mask *image.Alpha, x, y float64, text string) {
+	left, top := int(x+0.5), int(y+0.5)-glyphHeight*glyphScale
+	for _, r := range text {
+		glyph, ok := glyphs[unicode.ToUpper(r)]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for row, bits := range glyph {
+			for col, bit := range bits {
+				if bit != '#' {
+					continue
+				}
+				for dy := 0; dy < glyphScale; dy++ {
+					for dx := 0; dx < glyphScale; dx++ {
+						mask.SetAlpha(left+col*glyphScale+dx, top+row*glyphScale+dy, color.Alpha{A: 0xff})
+					}
+				}
+			}
+		}
+		left += glyphAdvance
+	}
+}
@@ -0,0 +1,210 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// subsamples is the number of scanlines sampled per row of pixels.
+const subsamples = 4
+
+// WritePNG writes a scene as a PNG image using the default Writer.
+func WritePNG(out io.Writer, s *Scene) error {
+	return NewWriter().WritePNG(out, s)
+}
+
+// WritePNG writes a scene as a PNG image.
+func (w *Writer) WritePNG(out io.Writer, s *Scene) error {
+	return errors.WithStack(png.Encode(out, w.Image(s)))
+}
+
+// Image draws a scene. Shapes are anti-aliased; labels are drawn with a
+// small built-in font of upper case letters, digits and punctuation.
+func (w *Writer) Image(s *Scene) *image.RGBA {
+	t := newTransform(s.envelope(), w.Width, w.Height, w.Margin)
+	img := image.NewRGBA(image.Rect(0, 0, w.Width, w.Height))
+	if w.Background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(w.Background), image.Point{}, draw.Src)
+	}
+
+	r := &rasterizer{dst: img}
+	for _, sh := range s.shapes {
+		if sh.label != "" {
+			p := t.apply(sh.points[0])
+			mask := r.newMask()
+			drawText(mask, p.x+sh.labelOffset[0], p.y+sh.labelOffset[1], sh.label)
+			r.draw(mask, sh.labelColor)
+			continue
+		}
+
+		if sh.style.Fill != nil && len(sh.polygons) > 0 {
+			mask := r.newMask()
+			for _, rings := range sh.polygons {
+				paths := make([][]point, len(rings))
+				for i, ring := range rings {
+					paths[i] = t.applyAll(ring)
+				}
+				r.fill(mask, paths)
+			}
+			r.draw(mask, sh.style.Fill)
+		}
+		if sh.style.Stroke != nil && sh.style.StrokeWidth > 0 {
+			mask := r.newMask()
+			for _, rings := range sh.polygons {
+				for _, ring := range rings {
+					r.stroke(mask, t.applyAll(ring), sh.style.StrokeWidth)
+				}
+			}
+			for _, line := range sh.lines {
+				r.stroke(mask, t.applyAll(line), sh.style.StrokeWidth)
+			}
+			r.draw(mask, sh.style.Stroke)
+		}
+		fill := sh.style.Fill
+		if fill == nil {
+			fill = sh.style.Stroke
+		}
+		if fill != nil && len(sh.points) > 0 && sh.style.PointRadius > 0 {
+			mask := r.newMask()
+			for _, c := range sh.points {
+				r.fill(mask, [][]point{circle(t.apply(c), sh.style.PointRadius)})
+			}
+			r.draw(mask, fill)
+		}
+	}
+	return img
+}
+
+// rasterizer fills paths into alpha masks, which are then drawn in a colour
+// so that overlapping parts of a shape are not drawn twice.
+type rasterizer struct {
+	dst *image.RGBA
+	acc []float64
+	xs  []float64
+}
+
+func (r *rasterizer) newMask() *image.Alpha {
+	return image.NewAlpha(r.dst.Bounds())
+}
+
+func (r *rasterizer) draw(mask *image.Alpha, c color.Color) {
+	draw.DrawMask(r.dst, r.dst.Bounds(), image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// fill adds the coverage of closed paths to a mask using the even-odd rule,
+// keeping the maximum of the existing and new coverage.
+func (r *rasterizer) fill(mask *image.Alpha, paths [][]point) {
+	bounds := mask.Bounds()
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, path := range paths {
+		for _, p := range path {
+			minY = math.Min(minY, p.y)
+			maxY = math.Max(maxY, p.y)
+		}
+	}
+	y0 := int(math.Max(math.Floor(minY), float64(bounds.Min.Y)))
+	y1 := int(math.Min(math.Ceil(maxY), float64(bounds.Max.Y)))
+
+	width := bounds.Dx()
+	if cap(r.acc) < width {
+		r.acc = make([]float64, width)
+	}
+	acc := r.acc[:width]
+	for py := y0; py < y1; py++ {
+		for i := range acc {
+			acc[i] = 0
+		}
+		for s := 0; s < subsamples; s++ {
+			y := float64(py) + (float64(s)+0.5)/subsamples
+			xs := r.xs[:0]
+			for _, path := range paths {
+				for i := range path {
+					a, b := path[i], path[(i+1)%len(path)]
+					if (a.y <= y) != (b.y <= y) {
+						xs = append(xs, a.x+(y-a.y)*(b.x-a.x)/(b.y-a.y))
+					}
+				}
+			}
+			sort.Float64s(xs)
+			for i := 0; i+1 < len(xs); i += 2 {
+				addSpan(acc, xs[i]-float64(bounds.Min.X), xs[i+1]-float64(bounds.Min.X), 1.0/subsamples)
+			}
+			r.xs = xs
+		}
+		for i, a := range acc {
+			if a <= 0 {
+				continue
+			}
+			v := uint8(math.Min(a, 1)*0xff + 0.5)
+			x := bounds.Min.X + i
+			if v > mask.AlphaAt(x, py).A {
+				mask.SetAlpha(x, py, color.Alpha{A: v})
+			}
+		}
+	}
+}
+
+// addSpan adds the coverage of a span of a scanline to the pixels it
+// crosses.
+func addSpan(acc []float64, x0, x1, weight float64) {
+	x0 = math.Max(x0, 0)
+	x1 = math.Min(x1, float64(len(acc)))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		acc[i0] += (x1 - x0) * weight
+		return
+	}
+	acc[i0] += (float64(i0+1) - x0) * weight
+	for i := i0 + 1; i < i1; i++ {
+		acc[i] += weight
+	}
+	if i1 < len(acc) {
+		acc[i1] += (x1 - float64(i1)) * weight
+	}
+}
+
+// stroke adds a line of the given width with round joins and caps to a
+// mask.
+func (r *rasterizer) stroke(mask *image.Alpha, line []point, width float64) {
+	hw := width / 2
+	for i, p := range line {
+		r.fill(mask, [][]point{circle(p, hw)})
+		if i == 0 {
+			continue
+		}
+		q := line[i-1]
+		dx, dy := p.x-q.x, p.y-q.y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*hw, dx/length*hw
+		r.fill(mask, [][]point{{
+			{q.x + nx, q.y + ny},
+			{p.x + nx, p.y + ny},
+			{p.x - nx, p.y - ny},
+			{q.x - nx, q.y - ny},
+		}})
+	}
+}
+
+// circle returns a polygon approximating a circle.
+func circle(c point, radius float64) []point {
+	n := int(math.Max(8, math.Ceil(radius*4)))
+	ps := make([]point, n)
+	for i := range ps {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		ps[i] = point{c.x + radius*math.Cos(a), c.y + radius*math.Sin(a)}
+	}
+	return ps
+}
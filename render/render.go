@@ -0,0 +1,227 @@
+// Package render draws geometries and topology graphs as SVG or as images,
+// for debugging. Scenes are fitted to the output size with Y pointing up.
+package render
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+)
+
+// Style describes how geometries are drawn. Sizes are in pixels.
+type Style struct {
+	// Stroke is the colour of lines and polygon rings, or nil for none.
+	Stroke color.Color
+	// Fill is the colour of polygon interiors and points, or nil for none.
+	// Points without a fill are drawn in the stroke colour.
+	Fill        color.Color
+	StrokeWidth float64
+	PointRadius float64
+}
+
+func DefaultStyle() Style {
+	return Style{
+		Stroke:      color.NRGBA{0x1f, 0x4e, 0x9c, 0xff},
+		Fill:        color.NRGBA{0x4a, 0x86, 0xe8, 0x60},
+		StrokeWidth: 1.5,
+		PointRadius: 3,
+	}
+}
+
+// GraphStyle describes how topology graphs are drawn.
+type GraphStyle struct {
+	Edge Style
+	Node Style
+	// LabelColor, if set, draws the label of each edge and node.
+	LabelColor color.Color
+}
+
+func DefaultGraphStyle() GraphStyle {
+	return GraphStyle{
+		Edge: Style{
+			Stroke:      color.NRGBA{0xd0, 0x30, 0x30, 0xff},
+			StrokeWidth: 1,
+		},
+		Node: Style{
+			Fill:        color.NRGBA{0x20, 0x20, 0x20, 0xff},
+			PointRadius: 2.5,
+		},
+		LabelColor: color.NRGBA{0x20, 0x20, 0x20, 0xff},
+	}
+}
+
+// Scene is a list of shapes and labels, drawn in the order added.
+type Scene struct {
+	shapes []shape
+}
+
+// shape is a set of polygons, lines and points drawn with one style, or a
+// label.
+type shape struct {
+	style    Style
+	polygons [][]coord.Coordinates
+	lines    []coord.Coordinates
+	points   coord.Coordinates
+
+	label      string
+	labelColor color.Color
+	// labelOffset moves the label from its point, in pixels
+	labelOffset [2]float64
+}
+
+func NewScene() *Scene {
+	return &Scene{}
+}
+
+// Add adds a geometry.
+func (s *Scene) Add(g *geom.Geometry, style Style) {
+	sh := shape{style: style}
+	sh.add(g)
+	s.shapes = append(s.shapes, sh)
+}
+
+func (sh *shape) add(g *geom.Geometry) {
+	if g.IsEmpty() {
+		return
+	}
+	switch g.Type {
+	case geom.TypePoint:
+		sh.points = append(sh.points, g.Coord)
+	case geom.TypeLineString:
+		sh.lines = append(sh.lines, g.Line)
+	case geom.TypePolygon:
+		sh.polygons = append(sh.polygons, append([]coord.Coordinates{g.Line}, g.MultiLine...))
+	default:
+		for _, col := range g.Collection {
+			sh.add(col)
+		}
+	}
+}
+
+// AddLabel adds text next to a point, in black if no colour is given.
+func (s *Scene) AddLabel(at coord.Coordinate, text string, c color.Color) {
+	if c == nil {
+		c = color.Black
+	}
+	s.shapes = append(s.shapes, shape{
+		points:      coord.Coordinates{at},
+		label:       text,
+		labelColor:  c,
+		labelOffset: [2]float64{4, -4},
+	})
+}
+
+// AddGraph adds the edges and nodes of a topology graph, such as those of
+// graph.Relate, with their labels.
+func (s *Scene) AddGraph(g *graph.Graph, style GraphStyle) {
+	edges := shape{style: style.Edge}
+	for _, e := range g.Edges() {
+		edges.lines = append(edges.lines, e.Coordinates)
+	}
+	s.shapes = append(s.shapes, edges)
+	if style.LabelColor != nil {
+		for _, e := range g.Edges() {
+			if len(e.Coordinates) < 2 {
+				continue
+			}
+			// label the middle of the middle segment
+			i := (len(e.Coordinates) - 1) / 2
+			p, q := e.Coordinates[i], e.Coordinates[i+1]
+			mid := coord.Coordinate{X: (p.X + q.X) / 2, Y: (p.Y + q.Y) / 2}
+			s.AddLabel(mid, e.Label.String(), style.LabelColor)
+		}
+	}
+	s.AddNodes(g.Nodes(), style)
+}
+
+// AddNodes adds topology graph nodes, such as those computed by
+// graph.Relate, with their labels.
+func (s *Scene) AddNodes(nodes []*graph.Node, style GraphStyle) {
+	points := shape{style: style.Node}
+	for _, n := range nodes {
+		points.points = append(points.points, n.Point)
+	}
+	s.shapes = append(s.shapes, points)
+	if style.LabelColor != nil {
+		for _, n := range nodes {
+			s.AddLabel(n.Point, n.Label.String(), style.LabelColor)
+		}
+	}
+}
+
+func (s *Scene) envelope() *coord.Envelope {
+	var env *coord.Envelope
+	expand := func(cs coord.Coordinates) {
+		if len(cs) == 0 {
+			return
+		}
+		if env == nil {
+			env = cs.Envelope()
+		} else {
+			env.ExpandCoords(cs)
+		}
+	}
+	for _, sh := range s.shapes {
+		for _, rings := range sh.polygons {
+			for _, ring := range rings {
+				expand(ring)
+			}
+		}
+		for _, line := range sh.lines {
+			expand(line)
+		}
+		expand(sh.points)
+	}
+	return env
+}
+
+// transform maps scene coordinates to pixels.
+type transform struct {
+	scale, x0, y0 float64
+	height        float64
+}
+
+// newTransform fits an envelope within a width and height less a margin.
+func newTransform(env *coord.Envelope, width, height int, margin float64) transform {
+	t := transform{scale: 1, height: float64(height)}
+	if env == nil {
+		return t
+	}
+	w, h := env.MaxX-env.MinX, env.MaxY-env.MinY
+	availW, availH := float64(width)-2*margin, float64(height)-2*margin
+	switch {
+	case w > 0 && h > 0:
+		t.scale = math.Min(availW/w, availH/h)
+	case w > 0:
+		t.scale = availW / w
+	case h > 0:
+		t.scale = availH / h
+	}
+	// centre the envelope
+	t.x0 = (env.MinX+env.MaxX)/2 - float64(width)/2/t.scale
+	t.y0 = (env.MinY+env.MaxY)/2 - float64(height)/2/t.scale
+	return t
+}
+
+func (t transform) apply(c coord.Coordinate) point {
+	return point{
+		x: (c.X - t.x0) * t.scale,
+		y: t.height - (c.Y-t.y0)*t.scale,
+	}
+}
+
+func (t transform) applyAll(cs coord.Coordinates) []point {
+	ps := make([]point, len(cs))
+	for i, c := range cs {
+		ps[i] = t.apply(c)
+	}
+	return ps
+}
+
+// point is a position in pixels.
+type point struct {
+	x, y float64
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simoncochrane/geoz/coord"
+	"github.com/simoncochrane/geoz/encoding/wkt"
+	"github.com/simoncochrane/geoz/geom"
+	"github.com/simoncochrane/geoz/graph"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func mustUnmarshal(t *testing.T, text string) *geom.Geometry {
+	t.Helper()
+	g, err := wkt.Unmarshal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func geometryScene(t *testing.T) *Scene {
+	s := NewScene()
+	s.Add(mustUnmarshal(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 5, 5 5, 5 2, 2 2))"), DefaultStyle())
+	s.Add(mustUnmarshal(t, "LINESTRING (-2 8, 6 12, 12 4)"), Style{Stroke: color.NRGBA{0, 0x80, 0, 0xff}, StrokeWidth: 2})
+	s.Add(mustUnmarshal(t, "MULTIPOINT ((8 2), (12 12))"), Style{Stroke: color.Black, PointRadius: 2})
+	s.AddLabel(coord.Coordinate{X: 8, Y: 2}, "a < b & c", nil)
+	return s
+}
+
+func relateScene(t *testing.T) *Scene {
+	r, err := graph.NewRelate(
+		mustUnmarshal(t, "POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0))"),
+		mustUnmarshal(t, "LINESTRING (-1 2, 2 2)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.IntersectionMatrix()
+	s := NewScene()
+	style := DefaultGraphStyle()
+	for _, g := range r.Graphs() {
+		s.AddGraph(g, style)
+	}
+	s.AddNodes(r.Nodes(), style)
+	return s
+}
+
+func TestWriteSVG(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene func(t *testing.T) *Scene
+		w     *Writer
+	}{
+		{"geometries", geometryScene, NewWriter()},
+		{"relate", relateScene, &Writer{Width: 300, Height: 200, Margin: 20}},
+		{"empty", func(*testing.T) *Scene { return NewScene() }, NewWriter()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.w.WriteSVG(&buf, test.scene(t)); err != nil {
+				t.Fatalf("WriteSVG() error = %v", err)
+			}
+			golden := filepath.Join("testdata", test.name+".svg")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("WriteSVG() =\n%s\nwant\n%s", buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestImage(t *testing.T) {
+	w := &Writer{Width: 64, Height: 48, Margin: 4, Background: color.White}
+	s := NewScene()
+	s.Add(mustUnmarshal(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"), Style{Fill: color.Black})
+	img := w.Image(s)
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Fatalf("Image() bounds = %v, want 64x48", b)
+	}
+	// the square fills the height less the margins, centred
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{32, 24, color.RGBA{0, 0, 0, 0xff}},
+		{1, 1, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{60, 24, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, test := range tests {
+		if got := img.RGBAAt(test.x, test.y); got != test.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Writer writes scenes as SVG or PNG images.
+type Writer struct {
+	Width  int
+	Height int
+	// Margin is the space left around the scene, in pixels.
+	Margin float64
+	// Background is the colour of the image, or nil for transparent.
+	Background color.Color
+}
+
+func NewWriter() *Writer {
+	return &Writer{
+		Width:      512,
+		Height:     512,
+		Margin:     16,
+		Background: color.White,
+	}
+}
+
+// WriteSVG writes a scene as SVG using the default Writer.
+func WriteSVG(out io.Writer, s *Scene) error {
+	return NewWriter().WriteSVG(out, s)
+}
+
+// WriteSVG writes a scene as an SVG document.
+func (w *Writer) WriteSVG(out io.Writer, s *Scene) error {
+	t := newTransform(s.envelope(), w.Width, w.Height, w.Margin)
+	bw := bufio.NewWriter(out)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		w.Width, w.Height, w.Width, w.Height)
+	if w.Background != nil {
+		fmt.Fprintf(bw, `<rect width="100%%" height="100%%"%s/>`+"\n", paint("fill", w.Background))
+	}
+	for _, sh := range s.shapes {
+		if sh.label != "" {
+			p := t.apply(sh.points[0])
+			fmt.Fprintf(bw, `<text x="%s" y="%s" font-family="monospace" font-size="10"%s>%s</text>`+"\n",
+				formatFloat(p.x+sh.labelOffset[0]), formatFloat(p.y+sh.labelOffset[1]),
+				paint("fill", sh.labelColor), escape(sh.label))
+			continue
+		}
+
+		stroke := paint("stroke", sh.style.Stroke)
+		if sh.style.Stroke != nil {
+			stroke += ` stroke-width="` + formatFloat(sh.style.StrokeWidth) + `" stroke-linejoin="round" stroke-linecap="round"`
+		}
+		for _, rings := range sh.polygons {
+			var d strings.Builder
+			for _, ring := range rings {
+				writePath(&d, t.applyAll(ring))
+				d.WriteString("Z")
+			}
+			fmt.Fprintf(bw, `<path d="%s" fill-rule="evenodd"%s%s/>`+"\n", d.String(), paint("fill", sh.style.Fill), stroke)
+		}
+		for _, line := range sh.lines {
+			var d strings.Builder
+			writePath(&d, t.applyAll(line))
+			fmt.Fprintf(bw, `<path d="%s" fill="none"%s/>`+"\n", d.String(), stroke)
+		}
+		fill := sh.style.Fill
+		if fill == nil {
+			fill = sh.style.Stroke
+		}
+		for _, c := range sh.points {
+			p := t.apply(c)
+			fmt.Fprintf(bw, `<circle cx="%s" cy="%s" r="%s"%s/>`+"\n",
+				formatFloat(p.x), formatFloat(p.y), formatFloat(sh.style.PointRadius), paint("fill", fill))
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return errors.WithStack(bw.Flush())
+}
+
+func writePath(d *strings.Builder, ps []point) {
+	for i, p := range ps {
+		if i == 0 {
+			d.WriteString("M")
+		} else {
+			d.WriteString(" L")
+		}
+		d.WriteString(formatFloat(p.x))
+		d.WriteByte(' ')
+		d.WriteString(formatFloat(p.y))
+	}
+}
+
+// paint returns a fill or stroke attribute with its opacity.
+func paint(attr string, c color.Color) string {
+	if c == nil {
+		return ` ` + attr + `="none"`
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	s := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, n.R, n.G, n.B)
+	if n.A != 0xff {
+		s += fmt.Sprintf(` %s-opacity="%s"`, attr, formatFloat(float64(n.A)/0xff))
+	}
+	return s
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	// writing to a strings.Builder cannot fail
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}